@@ -0,0 +1,267 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package blockstream provides a pull-based API for following the chain
+//
+// A BlockStream combines chain-sync and block-fetch (for node-to-node connections) and delivers
+// typed events through a bounded buffer. When the consumer stops reading events, the buffer fills
+// and chain-sync stops requesting new blocks from the peer.
+package blockstream
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	ouroboros "github.com/blinklabs-io/gouroboros"
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/protocol/chainsync"
+	"github.com/blinklabs-io/gouroboros/protocol/common"
+)
+
+// EventType is an enum of the types of block stream events
+type EventType uint
+
+const (
+	EventTypeRollForward  EventType = 1
+	EventTypeRollBackward EventType = 2
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventTypeRollForward:
+		return "RollForward"
+	case EventTypeRollBackward:
+		return "RollBackward"
+	}
+	return fmt.Sprintf("EventType(%d)", uint(t))
+}
+
+// Event represents a change to the followed chain
+type Event struct {
+	Type EventType
+	// Point is the block point for roll forward events and the rollback point for roll backward events
+	Point common.Point
+	// BlockType and Block are only populated for roll forward events
+	BlockType uint
+	Block     ledger.Block
+	Tip       chainsync.Tip
+}
+
+// Config is used to configure a BlockStream
+type Config struct {
+	BufferSize int
+}
+
+// BlockStreamOptionFunc represents a function used to modify the BlockStream config
+type BlockStreamOptionFunc func(*Config)
+
+// NewConfig returns a new BlockStream config object with the provided options
+func NewConfig(options ...BlockStreamOptionFunc) Config {
+	c := Config{
+		BufferSize: 50,
+	}
+	// Apply provided options functions
+	for _, option := range options {
+		option(&c)
+	}
+	return c
+}
+
+// WithBufferSize specifies the number of events that can be buffered before chain-sync is paused
+func WithBufferSize(size int) BlockStreamOptionFunc {
+	return func(c *Config) {
+		c.BufferSize = size
+	}
+}
+
+// BlockStream delivers blocks from chain-sync (and block-fetch for node-to-node) as a stream of events
+type BlockStream struct {
+	config    *Config
+	conn      *ouroboros.Connection
+	eventChan chan Event
+	errorChan chan error
+	doneChan  chan struct{}
+	onceStop  sync.Once
+	onceError sync.Once
+}
+
+// New returns a new BlockStream object with the provided options
+func New(options ...BlockStreamOptionFunc) *BlockStream {
+	cfg := NewConfig(options...)
+	s := &BlockStream{
+		config:    &cfg,
+		eventChan: make(chan Event, cfg.BufferSize),
+		errorChan: make(chan error, 1),
+		doneChan:  make(chan struct{}),
+	}
+	return s
+}
+
+// ChainSyncOptions returns the options needed to route chain-sync callbacks into the BlockStream. They
+// must be included in the chain-sync config for the connection passed to Start
+func (s *BlockStream) ChainSyncOptions() []chainsync.ChainSyncOptionFunc {
+	return []chainsync.ChainSyncOptionFunc{
+		chainsync.WithRollForwardFunc(s.handleRollForward),
+		chainsync.WithRollBackwardFunc(s.handleRollBackward),
+	}
+}
+
+// Start begins a chain-sync operation on the provided connection using the provided intersect point(s)
+func (s *BlockStream) Start(
+	conn *ouroboros.Connection,
+	intersectPoints []common.Point,
+) error {
+	s.conn = conn
+	return conn.ChainSync().Client.Sync(intersectPoints)
+}
+
+// Next returns the next event. It blocks until an event is available or the context is cancelled. It
+// returns io.EOF after the stream has been stopped, even if there are still buffered events
+func (s *BlockStream) Next(ctx context.Context) (Event, error) {
+	// Check for a stopped stream first, since a select with multiple ready cases picks one at random
+	select {
+	case <-s.doneChan:
+		return Event{}, io.EOF
+	default:
+	}
+	// Return buffered events before any pending error
+	select {
+	case evt := <-s.eventChan:
+		return evt, nil
+	default:
+	}
+	select {
+	case <-ctx.Done():
+		return Event{}, ctx.Err()
+	case evt := <-s.eventChan:
+		return evt, nil
+	case err := <-s.errorChan:
+		return Event{}, err
+	case <-s.doneChan:
+		return Event{}, io.EOF
+	}
+}
+
+// EventChan returns the channel of events, for consumers that prefer to select on it directly
+func (s *BlockStream) EventChan() <-chan Event {
+	return s.eventChan
+}
+
+// ErrorChan returns a channel that receives the error that stopped the stream, for consumers that select on
+// EventChan directly
+func (s *BlockStream) ErrorChan() <-chan error {
+	return s.errorChan
+}
+
+// Stop stops the chain-sync operation and causes any further calls to Next to return io.EOF. Buffered events
+// are discarded. If chain-sync is waiting on the peer, such as when the stream is idle at the chain tip, the
+// chain-sync client finishes shutting down when the peer replies
+func (s *BlockStream) Stop() error {
+	var err error
+	s.onceStop.Do(func() {
+		close(s.doneChan)
+		if s.conn != nil && s.conn.ChainSync() != nil {
+			err = s.conn.ChainSync().Client.Stop()
+		}
+	})
+	return err
+}
+
+func (s *BlockStream) handleRollForward(
+	ctx chainsync.CallbackContext,
+	blockType uint,
+	blockData interface{},
+	tip chainsync.Tip,
+) error {
+	var block ledger.Block
+	switch v := blockData.(type) {
+	case ledger.Block:
+		block = v
+	case ledger.BlockHeader:
+		// Fetch the full block for node-to-node connections
+		blockHash, err := hex.DecodeString(v.Hash())
+		if err != nil {
+			return s.sendError(err)
+		}
+		if s.conn == nil || s.conn.BlockFetch() == nil {
+			return s.sendError(
+				errors.New("received block header but block-fetch is not available"),
+			)
+		}
+		block, err = s.conn.BlockFetch().Client.GetBlock(
+			common.NewPoint(v.SlotNumber(), blockHash),
+		)
+		if err != nil {
+			return s.sendError(fmt.Errorf("failed to fetch block: %w", err))
+		}
+	default:
+		return s.sendError(
+			fmt.Errorf("unexpected block data type: %T", blockData),
+		)
+	}
+	blockHash, err := hex.DecodeString(block.Hash())
+	if err != nil {
+		return s.sendError(err)
+	}
+	return s.sendEvent(
+		Event{
+			Type:      EventTypeRollForward,
+			Point:     common.NewPoint(block.SlotNumber(), blockHash),
+			BlockType: blockType,
+			Block:     block,
+			Tip:       tip,
+		},
+	)
+}
+
+func (s *BlockStream) handleRollBackward(
+	ctx chainsync.CallbackContext,
+	point common.Point,
+	tip chainsync.Tip,
+) error {
+	return s.sendEvent(
+		Event{
+			Type:  EventTypeRollBackward,
+			Point: point,
+			Tip:   tip,
+		},
+	)
+}
+
+// sendEvent blocks until there is room in the buffer, which provides backpressure to chain-sync
+func (s *BlockStream) sendEvent(evt Event) error {
+	select {
+	case <-s.doneChan:
+		return chainsync.StopSyncProcessError
+	default:
+	}
+	select {
+	case <-s.doneChan:
+		return chainsync.StopSyncProcessError
+	case s.eventChan <- evt:
+		return nil
+	}
+}
+
+// sendError passes the first error to the consumer and stops the sync process
+func (s *BlockStream) sendError(err error) error {
+	s.onceError.Do(func() {
+		s.errorChan <- err
+	})
+	return chainsync.StopSyncProcessError
+}
@@ -0,0 +1,301 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blockstream_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	ouroboros "github.com/blinklabs-io/gouroboros"
+	"github.com/blinklabs-io/gouroboros/blockstream"
+	"github.com/blinklabs-io/gouroboros/cbor"
+	"github.com/blinklabs-io/gouroboros/internal/test"
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/protocol"
+	"github.com/blinklabs-io/gouroboros/protocol/chainsync"
+	ocommon "github.com/blinklabs-io/gouroboros/protocol/common"
+
+	ouroboros_mock "github.com/blinklabs-io/ouroboros-mock"
+	"go.uber.org/goleak"
+)
+
+func TestBlockStreamNtC(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	intersect := ocommon.NewPoint(
+		20001,
+		test.DecodeHexString("123456789abcdef0"),
+	)
+	tip := chainsync.Tip{
+		BlockNumber: 12345,
+		Point: ocommon.NewPoint(
+			23456,
+			test.DecodeHexString("0123456789abcdef"),
+		),
+	}
+	// Create basic block and round-trip it through the CBOR encoder to get the hash populated
+	testBlock := ledger.BabbageBlock{
+		Header: &ledger.BabbageBlockHeader{},
+	}
+	testBlock.Header.Body.BlockNumber = 12001
+	testBlock.Header.Body.Slot = 20002
+	blockCbor, err := cbor.Encode(testBlock)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if _, err := cbor.Decode(blockCbor, &testBlock); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	conversation := []ouroboros_mock.ConversationEntry{
+		ouroboros_mock.ConversationEntryHandshakeRequestGeneric,
+		ouroboros_mock.ConversationEntryHandshakeNtCResponse,
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  chainsync.ProtocolIdNtC,
+			MessageType: chainsync.MessageTypeFindIntersect,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: chainsync.ProtocolIdNtC,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				chainsync.NewMsgIntersectFound(intersect, tip),
+			},
+		},
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  chainsync.ProtocolIdNtC,
+			MessageType: chainsync.MessageTypeRequestNext,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: chainsync.ProtocolIdNtC,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				chainsync.NewMsgRollBackward(intersect, tip),
+			},
+		},
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  chainsync.ProtocolIdNtC,
+			MessageType: chainsync.MessageTypeRequestNext,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: chainsync.ProtocolIdNtC,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				chainsync.NewMsgRollForwardNtC(
+					ledger.BlockTypeBabbage,
+					blockCbor,
+					tip,
+				),
+			},
+		},
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  chainsync.ProtocolIdNtC,
+			MessageType: chainsync.MessageTypeRequestNext,
+		},
+	}
+	mockConn := ouroboros_mock.NewConnection(
+		ouroboros_mock.ProtocolRoleClient,
+		conversation,
+	)
+	// Async mock connection error handler
+	asyncErrChan := make(chan error, 1)
+	go func() {
+		err := <-mockConn.(*ouroboros_mock.Connection).ErrorChan()
+		if err != nil {
+			asyncErrChan <- fmt.Errorf("received unexpected error: %s", err)
+		}
+		close(asyncErrChan)
+	}()
+	stream := blockstream.New(blockstream.WithBufferSize(1))
+	oConn, err := ouroboros.New(
+		ouroboros.WithConnection(mockConn),
+		ouroboros.WithNetworkMagic(ouroboros_mock.MockNetworkMagic),
+		ouroboros.WithChainSyncConfig(
+			chainsync.NewConfig(stream.ChainSyncOptions()...),
+		),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error when creating Ouroboros object: %s", err)
+	}
+	// Async error handler
+	go func() {
+		err, ok := <-oConn.ErrorChan()
+		if !ok {
+			return
+		}
+		// We can't call t.Fatalf() from a different Goroutine, so we panic instead
+		panic(fmt.Sprintf("unexpected Ouroboros error: %s", err))
+	}()
+	if err := stream.Start(oConn, []ocommon.Point{intersect}); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	evt, err := stream.Next(ctx)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if evt.Type != blockstream.EventTypeRollBackward ||
+		!reflect.DeepEqual(evt.Point, intersect) {
+		t.Fatalf("did not receive expected rollback event: %#v", evt)
+	}
+	evt, err = stream.Next(ctx)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if evt.Type != blockstream.EventTypeRollForward ||
+		evt.Block.Hash() != testBlock.Hash() ||
+		evt.Point.Slot != testBlock.SlotNumber() {
+		t.Fatalf("did not receive expected roll forward event: %#v", evt)
+	}
+	// Wait for mock connection shutdown
+	select {
+	case err, ok := <-asyncErrChan:
+		if ok {
+			t.Fatal(err.Error())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("did not complete within timeout")
+	}
+	// The last block request is still outstanding, so this only marks chain-sync as stopping
+	if err := stream.Stop(); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if _, err := stream.Next(ctx); err != io.EOF {
+		t.Fatalf("did not receive expected io.EOF, got: %v", err)
+	}
+	// Close Ouroboros connection
+	if err := oConn.Close(); err != nil {
+		t.Fatalf("unexpected error when closing Ouroboros object: %s", err)
+	}
+	// Wait for connection shutdown
+	select {
+	case <-oConn.ErrorChan():
+	case <-time.After(10 * time.Second):
+		t.Errorf("did not shutdown within timeout")
+	}
+}
+
+func TestBlockStreamStop(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	intersect := ocommon.NewPoint(
+		20001,
+		test.DecodeHexString("123456789abcdef0"),
+	)
+	tip := chainsync.Tip{
+		BlockNumber: 12345,
+		Point: ocommon.NewPoint(
+			23456,
+			test.DecodeHexString("0123456789abcdef"),
+		),
+	}
+	// The stream is stopped before the reply to the first block request arrives, so chain-sync should be
+	// shut down after that reply instead of requesting another block
+	conversation := []ouroboros_mock.ConversationEntry{
+		ouroboros_mock.ConversationEntryHandshakeRequestGeneric,
+		ouroboros_mock.ConversationEntryHandshakeNtCResponse,
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  chainsync.ProtocolIdNtC,
+			MessageType: chainsync.MessageTypeFindIntersect,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: chainsync.ProtocolIdNtC,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				chainsync.NewMsgIntersectFound(intersect, tip),
+			},
+		},
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  chainsync.ProtocolIdNtC,
+			MessageType: chainsync.MessageTypeRequestNext,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: chainsync.ProtocolIdNtC,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				chainsync.NewMsgRollBackward(intersect, tip),
+			},
+		},
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  chainsync.ProtocolIdNtC,
+			MessageType: chainsync.MessageTypeDone,
+		},
+	}
+	mockConn := ouroboros_mock.NewConnection(
+		ouroboros_mock.ProtocolRoleClient,
+		conversation,
+	)
+	// Async mock connection error handler
+	asyncErrChan := make(chan error, 1)
+	go func() {
+		err := <-mockConn.(*ouroboros_mock.Connection).ErrorChan()
+		if err != nil {
+			asyncErrChan <- fmt.Errorf("received unexpected error: %s", err)
+		}
+		close(asyncErrChan)
+	}()
+	stream := blockstream.New()
+	oConn, err := ouroboros.New(
+		ouroboros.WithConnection(mockConn),
+		ouroboros.WithNetworkMagic(ouroboros_mock.MockNetworkMagic),
+		ouroboros.WithChainSyncConfig(
+			chainsync.NewConfig(stream.ChainSyncOptions()...),
+		),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error when creating Ouroboros object: %s", err)
+	}
+	// Async error handler
+	go func() {
+		err, ok := <-oConn.ErrorChan()
+		if !ok {
+			return
+		}
+		// We can't call t.Fatalf() from a different Goroutine, so we panic instead
+		panic(fmt.Sprintf("unexpected Ouroboros error: %s", err))
+	}()
+	if err := stream.Start(oConn, []ocommon.Point{intersect}); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err := stream.Stop(); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	// Any event buffered before Stop is discarded
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := stream.Next(ctx); err != io.EOF {
+		t.Fatalf("did not receive expected io.EOF, got: %v", err)
+	}
+	// Wait for mock connection shutdown
+	select {
+	case err, ok := <-asyncErrChan:
+		if ok {
+			t.Fatal(err.Error())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("did not complete within timeout")
+	}
+	// Close Ouroboros connection
+	if err := oConn.Close(); err != nil {
+		t.Fatalf("unexpected error when closing Ouroboros object: %s", err)
+	}
+	// Wait for connection shutdown
+	select {
+	case <-oConn.ErrorChan():
+	case <-time.After(10 * time.Second):
+		t.Errorf("did not shutdown within timeout")
+	}
+}
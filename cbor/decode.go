@@ -140,3 +140,70 @@ func DecodeGeneric(cborData []byte, dest interface{}) error {
 	}
 	return nil
 }
+
+// DecodeMapEntries calls entryFunc with the raw CBOR for the key and value of each entry in the provided
+// CBOR map, one entry at a time. This allows processing very large maps without decoding them in full. Any
+// error returned by entryFunc stops the iteration and is returned as-is
+func DecodeMapEntries(
+	cborData []byte,
+	entryFunc func(key RawMessage, value RawMessage) error,
+) error {
+	if len(cborData) == 0 || cborData[0]&CborTypeMask != CborTypeMap {
+		return fmt.Errorf("data is not a CBOR map")
+	}
+	mapLen, offset, err := decodeHeaderLength(cborData)
+	if err != nil {
+		return err
+	}
+	for i := 0; mapLen < 0 || i < mapLen; i++ {
+		if offset >= len(cborData) {
+			return fmt.Errorf("unexpected end of CBOR map data")
+		}
+		// Indefinite-length maps are terminated by a "break" byte
+		if mapLen < 0 && cborData[offset] == 0xff {
+			break
+		}
+		var key, value RawMessage
+		keyLen, err := Decode(cborData[offset:], &key)
+		if err != nil {
+			return err
+		}
+		offset += keyLen
+		valueLen, err := Decode(cborData[offset:], &value)
+		if err != nil {
+			return err
+		}
+		offset += valueLen
+		if err := entryFunc(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeHeaderLength returns the length value and the header size from the header of a CBOR
+// array/map/string. A length of -1 is returned for indefinite-length items
+func decodeHeaderLength(cborData []byte) (int, int, error) {
+	additionalInfo := cborData[0] & 0x1f
+	switch {
+	case additionalInfo <= CborMaxUintSimple:
+		return int(additionalInfo), 1, nil
+	case additionalInfo == 0x1f:
+		return -1, 1, nil
+	case additionalInfo > 0x1b:
+		return 0, 0, fmt.Errorf("invalid CBOR header: %x", cborData[0])
+	}
+	// The length follows the header byte in 1, 2, 4, or 8 bytes
+	lenSize := 1 << (additionalInfo - 0x18)
+	if len(cborData) < 1+lenSize {
+		return 0, 0, fmt.Errorf("unexpected end of CBOR header data")
+	}
+	var length uint64
+	for _, b := range cborData[1 : 1+lenSize] {
+		length = length<<8 | uint64(b)
+	}
+	if length > uint64(len(cborData)) {
+		return 0, 0, fmt.Errorf("CBOR length exceeds data size: %d", length)
+	}
+	return int(length), 1 + lenSize, nil
+}
@@ -271,3 +271,83 @@ func TestDecodeById(t *testing.T) {
 		}
 	}
 }
+
+type decodeMapEntriesTestDefinition struct {
+	CborHex string
+	Entries [][2]string
+	Error   error
+}
+
+var decodeMapEntriesTests = []decodeMapEntriesTestDefinition{
+	// {1: 2, 3: [4]}
+	{
+		CborHex: "A20102038104",
+		Entries: [][2]string{{"01", "02"}, {"03", "8104"}},
+	},
+	// Indefinite-length {"a": 1, 24: 2}
+	{
+		CborHex: "BF616101181802FF",
+		Entries: [][2]string{{"6161", "01"}, {"1818", "02"}},
+	},
+	// {} with a 1-byte length
+	{
+		CborHex: "B800",
+	},
+	// [1]
+	{
+		CborHex: "8101",
+		Error:   fmt.Errorf("data is not a CBOR map"),
+	},
+	// Truncated {1: 2, 3: 4}
+	{
+		CborHex: "A20102",
+		Error:   fmt.Errorf("unexpected end of CBOR map data"),
+	},
+}
+
+func TestDecodeMapEntries(t *testing.T) {
+	for _, test := range decodeMapEntriesTests {
+		cborData, err := hex.DecodeString(test.CborHex)
+		if err != nil {
+			t.Fatalf("failed to decode CBOR hex: %s", err)
+		}
+		var entries [][2]string
+		err = cbor.DecodeMapEntries(
+			cborData,
+			func(key cbor.RawMessage, value cbor.RawMessage) error {
+				entries = append(
+					entries,
+					[2]string{hex.EncodeToString(key), hex.EncodeToString(value)},
+				)
+				return nil
+			},
+		)
+		if !reflect.DeepEqual(err, test.Error) {
+			t.Fatalf(
+				"did not find expected error\n  got: %#v\n  wanted:  %#v",
+				err,
+				test.Error,
+			)
+		}
+		if test.Error == nil && !reflect.DeepEqual(entries, test.Entries) {
+			t.Fatalf(
+				"did not get expected entries\n  got: %v\n  wanted:  %v",
+				entries,
+				test.Entries,
+			)
+		}
+	}
+	// Returning an error from the entry function stops the iteration
+	stopErr := fmt.Errorf("stop")
+	count := 0
+	err := cbor.DecodeMapEntries(
+		[]byte{0xa2, 0x01, 0x02, 0x03, 0x04},
+		func(key cbor.RawMessage, value cbor.RawMessage) error {
+			count++
+			return stopErr
+		},
+	)
+	if err != stopErr || count != 1 {
+		t.Fatalf("did not stop after error: err = %v, count = %d", err, count)
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cbor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+const (
+	// Major types, after shifting out the additional info bits
+	cborMajorTypeByteString uint8 = 2
+	cborMajorTypeTextString uint8 = 3
+	cborMajorTypeArray      uint8 = 4
+	cborMajorTypeMap        uint8 = 5
+	cborMajorTypeTag        uint8 = 6
+	cborMajorTypeSimple     uint8 = 7
+
+	cborBreak uint8 = 0xff
+
+	// Matches the nesting limit used by Decode
+	itemReaderMaxNestedLevels = 256
+)
+
+// ItemReader reads CBOR data items one at a time from an io.Reader, without reading past the end of each item.
+// This allows processing very large CBOR values, such as a map with millions of entries, as they're received
+// rather than holding the entire value in memory
+type ItemReader struct {
+	r         io.Reader
+	byteBuf   [1]byte
+	peeked    bool
+	peekedVal byte
+}
+
+// NewItemReader returns a new ItemReader that reads from the provided io.Reader
+func NewItemReader(r io.Reader) *ItemReader {
+	return &ItemReader{
+		r: r,
+	}
+}
+
+// ReadArrayHeader reads the header of an array and returns its length, or -1 for an indefinite-length array
+func (r *ItemReader) ReadArrayHeader() (int64, error) {
+	return r.readContainerHeader(cborMajorTypeArray)
+}
+
+// ReadMapHeader reads the header of a map and returns its number of entries, or -1 for an indefinite-length map
+func (r *ItemReader) ReadMapHeader() (int64, error) {
+	return r.readContainerHeader(cborMajorTypeMap)
+}
+
+// ReadBreak consumes the "break" byte that ends an indefinite-length array or map, if it's next. It returns
+// whether the break was found
+func (r *ItemReader) ReadBreak() (bool, error) {
+	b, err := r.peekByte()
+	if err != nil {
+		return false, err
+	}
+	if b != cborBreak {
+		return false, nil
+	}
+	r.peeked = false
+	return true, nil
+}
+
+// ReadItem reads the next complete data item and returns its raw CBOR
+func (r *ItemReader) ReadItem() (RawMessage, error) {
+	var buf bytes.Buffer
+	if err := r.readItem(&buf, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SkipItem reads and discards the next complete data item
+func (r *ItemReader) SkipItem() error {
+	return r.readItem(nil, 0)
+}
+
+func (r *ItemReader) readContainerHeader(expectedMajorType uint8) (int64, error) {
+	b, err := r.readByte()
+	if err != nil {
+		return 0, err
+	}
+	if b>>5 != expectedMajorType {
+		return 0, fmt.Errorf("unexpected CBOR major type: %d", b>>5)
+	}
+	length, indefinite, err := r.readArgument(b, nil)
+	if err != nil {
+		return 0, err
+	}
+	if indefinite {
+		return -1, nil
+	}
+	if length > uint64(1<<62) {
+		return 0, fmt.Errorf("CBOR length too large: %d", length)
+	}
+	return int64(length), nil
+}
+
+// readItem reads a complete data item, appending its raw CBOR to buf if it's not nil
+func (r *ItemReader) readItem(buf *bytes.Buffer, depth int) error {
+	if depth > itemReaderMaxNestedLevels {
+		return fmt.Errorf("exceeded max nested level: %d", itemReaderMaxNestedLevels)
+	}
+	b, err := r.readByte()
+	if err != nil {
+		return err
+	}
+	if buf != nil {
+		buf.WriteByte(b)
+	}
+	majorType := b >> 5
+	arg, indefinite, err := r.readArgument(b, buf)
+	if err != nil {
+		return err
+	}
+	switch majorType {
+	case cborMajorTypeByteString, cborMajorTypeTextString:
+		if !indefinite {
+			return r.readBytes(buf, arg)
+		}
+		// Indefinite-length strings are made up of definite-length chunks
+		for {
+			isBreak, err := r.readBreakInto(buf)
+			if err != nil {
+				return err
+			}
+			if isBreak {
+				return nil
+			}
+			if err := r.readItem(buf, depth+1); err != nil {
+				return err
+			}
+		}
+	case cborMajorTypeArray, cborMajorTypeMap:
+		itemCount := arg
+		if majorType == cborMajorTypeMap {
+			itemCount *= 2
+		}
+		if !indefinite {
+			for i := uint64(0); i < itemCount; i++ {
+				if err := r.readItem(buf, depth+1); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		for {
+			isBreak, err := r.readBreakInto(buf)
+			if err != nil {
+				return err
+			}
+			if isBreak {
+				return nil
+			}
+			if err := r.readItem(buf, depth+1); err != nil {
+				return err
+			}
+		}
+	case cborMajorTypeTag:
+		return r.readItem(buf, depth+1)
+	case cborMajorTypeSimple:
+		if indefinite {
+			return fmt.Errorf("unexpected CBOR break")
+		}
+	}
+	return nil
+}
+
+// readArgument reads the argument that follows the initial byte of a data item, appending the raw bytes to buf
+// if it's not nil. For most types the argument is a length or value, and for simple values and floats it's the
+// value bits. It also returns whether the item has an indefinite length
+func (r *ItemReader) readArgument(initial byte, buf *bytes.Buffer) (uint64, bool, error) {
+	additionalInfo := initial & 0x1f
+	switch {
+	case additionalInfo <= CborMaxUintSimple:
+		return uint64(additionalInfo), false, nil
+	case additionalInfo == 0x1f:
+		switch initial >> 5 {
+		case cborMajorTypeByteString, cborMajorTypeTextString, cborMajorTypeArray, cborMajorTypeMap,
+			cborMajorTypeSimple:
+			return 0, true, nil
+		}
+		return 0, false, fmt.Errorf("invalid CBOR header: %x", initial)
+	case additionalInfo > 0x1b:
+		return 0, false, fmt.Errorf("invalid CBOR header: %x", initial)
+	}
+	// The argument follows the header byte in 1, 2, 4, or 8 bytes
+	argSize := 1 << (additionalInfo - 0x18)
+	var arg uint64
+	for i := 0; i < argSize; i++ {
+		b, err := r.readByte()
+		if err != nil {
+			return 0, false, err
+		}
+		if buf != nil {
+			buf.WriteByte(b)
+		}
+		arg = arg<<8 | uint64(b)
+	}
+	return arg, false, nil
+}
+
+// readBytes reads the content of a definite-length string
+func (r *ItemReader) readBytes(buf *bytes.Buffer, length uint64) error {
+	if length > uint64(1<<62) {
+		return fmt.Errorf("CBOR length too large: %d", length)
+	}
+	dest := io.Discard
+	if buf != nil {
+		dest = buf
+	}
+	// The length isn't trusted for allocation, so the data is copied as it's read
+	n, err := io.CopyN(dest, r.r, int64(length))
+	if err != nil {
+		if err == io.EOF && uint64(n) < length {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
+
+// readBreakInto is like ReadBreak, but it also appends the break byte to buf if it's not nil
+func (r *ItemReader) readBreakInto(buf *bytes.Buffer) (bool, error) {
+	isBreak, err := r.ReadBreak()
+	if err != nil {
+		return false, err
+	}
+	if isBreak && buf != nil {
+		buf.WriteByte(cborBreak)
+	}
+	return isBreak, nil
+}
+
+func (r *ItemReader) peekByte() (byte, error) {
+	if !r.peeked {
+		b, err := r.readByte()
+		if err != nil {
+			return 0, err
+		}
+		r.peeked = true
+		r.peekedVal = b
+	}
+	return r.peekedVal, nil
+}
+
+func (r *ItemReader) readByte() (byte, error) {
+	if r.peeked {
+		r.peeked = false
+		return r.peekedVal, nil
+	}
+	if _, err := io.ReadFull(r.r, r.byteBuf[:]); err != nil {
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	return r.byteBuf[0], nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cbor_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/blinklabs-io/gouroboros/cbor"
+)
+
+var itemReaderTests = []string{
+	// Small uint
+	"01",
+	// Large uint
+	"1b0000000100000000",
+	// Negative int
+	"3863",
+	// Byte string
+	"43abcdef",
+	// Indefinite-length byte string
+	"5f42abcd41efff",
+	// Text string
+	"63666f6f",
+	// Nested list
+	"8401820203a1040580",
+	// Indefinite-length map
+	"bf0102036161ff",
+	// Tag
+	"d8184401020304",
+	// Float and simple values
+	"83f93c00f5f6",
+}
+
+func TestItemReaderReadItem(t *testing.T) {
+	for _, test := range itemReaderTests {
+		cborData, err := hex.DecodeString(test)
+		if err != nil {
+			t.Fatalf("failed to decode CBOR hex: %s", err)
+		}
+		// Data following the item must not be read
+		trailer := []byte{0x01}
+		reader := bytes.NewReader(append(append([]byte{}, cborData...), trailer...))
+		itemReader := cbor.NewItemReader(iotest.OneByteReader(reader))
+		item, err := itemReader.ReadItem()
+		if err != nil {
+			t.Fatalf("failed to read CBOR item %s: %s", test, err)
+		}
+		if !bytes.Equal(item, cborData) {
+			t.Fatalf("did not read expected item\n  got:    %x\n  wanted: %s", item, test)
+		}
+		if reader.Len() != len(trailer) {
+			t.Fatalf("read past the end of item %s: %d bytes left", test, reader.Len())
+		}
+	}
+}
+
+func TestItemReaderMapEntries(t *testing.T) {
+	for _, test := range []string{
+		// Definite-length map
+		"a2018202030405",
+		// Indefinite-length map
+		"bf018202030405ff",
+	} {
+		cborData, err := hex.DecodeString(test)
+		if err != nil {
+			t.Fatalf("failed to decode CBOR hex: %s", err)
+		}
+		itemReader := cbor.NewItemReader(iotest.OneByteReader(bytes.NewReader(cborData)))
+		mapLen, err := itemReader.ReadMapHeader()
+		if err != nil {
+			t.Fatalf("failed to read map header: %s", err)
+		}
+		var keys []uint64
+		for i := int64(0); mapLen < 0 || i < mapLen; i++ {
+			if mapLen < 0 {
+				isBreak, err := itemReader.ReadBreak()
+				if err != nil {
+					t.Fatalf("failed to read break: %s", err)
+				}
+				if isBreak {
+					break
+				}
+			}
+			key, err := itemReader.ReadItem()
+			if err != nil {
+				t.Fatalf("failed to read map key: %s", err)
+			}
+			var keyVal uint64
+			if _, err := cbor.Decode(key, &keyVal); err != nil {
+				t.Fatalf("failed to decode map key: %s", err)
+			}
+			keys = append(keys, keyVal)
+			// Only the first value is read, and the others are skipped
+			if i == 0 {
+				value, err := itemReader.ReadItem()
+				if err != nil {
+					t.Fatalf("failed to read map value: %s", err)
+				}
+				if hex.EncodeToString(value) != "820203" {
+					t.Fatalf("did not read expected map value: %x", value)
+				}
+			} else if err := itemReader.SkipItem(); err != nil {
+				t.Fatalf("failed to skip map value: %s", err)
+			}
+		}
+		if len(keys) != 2 || keys[0] != 1 || keys[1] != 4 {
+			t.Fatalf("did not read expected map keys: %v", keys)
+		}
+	}
+}
+
+func TestItemReaderTruncated(t *testing.T) {
+	cborData, err := hex.DecodeString("8301820203")
+	if err != nil {
+		t.Fatalf("failed to decode CBOR hex: %s", err)
+	}
+	itemReader := cbor.NewItemReader(bytes.NewReader(cborData))
+	if _, err := itemReader.ReadItem(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("did not receive expected error, got: %v", err)
+	}
+}
@@ -0,0 +1,629 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package chainselection implements Ouroboros Praos chain selection across multiple upstream peers
+//
+// A ChainSelector consumes the chain-sync header streams from any number of node-to-node peers, tracks
+// the candidate chain fragment for each peer, and selects the longest candidate that does not fork off
+// from the currently selected chain more than k blocks deep. Changes to the selected chain are emitted
+// as a single stream of events, each of which identifies the peer that the block should be fetched from.
+package chainselection
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/blinklabs-io/gouroboros/connection"
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/protocol/chainsync"
+	"github.com/blinklabs-io/gouroboros/protocol/common"
+)
+
+// ChainSelectorStoppedError is returned from the callback functions after the ChainSelector has been stopped
+var ChainSelectorStoppedError = errors.New("chain selector stopped")
+
+// ChainEventType is an enum of the types of selected chain events
+type ChainEventType uint
+
+const (
+	ChainEventRollForward  ChainEventType = 1 // A block was added to the selected chain
+	ChainEventRollBackward ChainEventType = 2 // The selected chain was rolled back to a point
+)
+
+func (t ChainEventType) String() string {
+	switch t {
+	case ChainEventRollForward:
+		return "RollForward"
+	case ChainEventRollBackward:
+		return "RollBackward"
+	}
+	return fmt.Sprintf("ChainEventType(%d)", uint(t))
+}
+
+// ChainEvent represents a change to the selected chain
+type ChainEvent struct {
+	Type ChainEventType
+	// Point is the block point for roll forward events and the rollback point for roll backward events
+	Point common.Point
+	// BlockNumber is the block number of Point. It is not available for all roll backward events
+	BlockNumber uint64
+	// BlockType and Header are only populated for roll forward events
+	BlockType uint
+	Header    ledger.BlockHeader
+	// Peer identifies the peer providing the selected chain, which the block should be fetched from
+	Peer connection.ConnectionId
+}
+
+// Config is used to configure a ChainSelector
+type Config struct {
+	SecurityParam     uint64
+	EventChanSize     int
+	ChainSwitchFunc   ChainSwitchFunc
+	RejectedChainFunc RejectedChainFunc
+}
+
+// ChainSwitchFunc is called when the selected chain switches to the candidate chain of a different peer. It's
+// called without any locks held, in order with the selected chain events, so it may call ChainSelector functions
+type ChainSwitchFunc func(connection.ConnectionId, connection.ConnectionId)
+
+// RejectedChainFunc is called when a longer candidate chain is not selected because it forks off from the
+// selected chain more than k blocks deep. The rollback depth that would have been required is provided, or 0
+// if the candidate doesn't intersect with the retained portion of the selected chain at all. It's called once per
+// rejected candidate tip, without any locks held
+type RejectedChainFunc func(connection.ConnectionId, uint64)
+
+// ChainSelectorOptionFunc represents a function used to modify the ChainSelector config
+type ChainSelectorOptionFunc func(*Config)
+
+// NewConfig returns a new ChainSelector config object with the provided options
+func NewConfig(options ...ChainSelectorOptionFunc) Config {
+	c := Config{
+		SecurityParam: chainsync.DefaultSecurityParam,
+		EventChanSize: 100,
+	}
+	// Apply provided options functions
+	for _, option := range options {
+		option(&c)
+	}
+	return c
+}
+
+// WithSecurityParam specifies the security parameter (k), which limits how deep a rollback can be
+// when switching to a different candidate chain
+func WithSecurityParam(securityParam uint64) ChainSelectorOptionFunc {
+	return func(c *Config) {
+		c.SecurityParam = securityParam
+	}
+}
+
+// WithEventChanSize specifies the buffer size of the selected chain event channel
+func WithEventChanSize(size int) ChainSelectorOptionFunc {
+	return func(c *Config) {
+		c.EventChanSize = size
+	}
+}
+
+// WithChainSwitchFunc specifies a callback function for when the selected chain switches peers
+func WithChainSwitchFunc(chainSwitchFunc ChainSwitchFunc) ChainSelectorOptionFunc {
+	return func(c *Config) {
+		c.ChainSwitchFunc = chainSwitchFunc
+	}
+}
+
+// WithRejectedChainFunc specifies a callback function for when a longer candidate chain is rejected
+func WithRejectedChainFunc(
+	rejectedChainFunc RejectedChainFunc,
+) ChainSelectorOptionFunc {
+	return func(c *Config) {
+		c.RejectedChainFunc = rejectedChainFunc
+	}
+}
+
+// chainEntry represents a single block header on a chain fragment
+type chainEntry struct {
+	point       common.Point
+	blockNumber uint64
+	blockType   uint
+	header      ledger.BlockHeader
+}
+
+// chainFragment represents the most recent blocks of a chain. The anchor is the point immediately
+// before the first entry
+type chainFragment struct {
+	anchor            common.Point
+	anchorBlockNumber uint64
+	anchorKnown       bool
+	entries           []chainEntry
+}
+
+func (f *chainFragment) tipBlockNumber() uint64 {
+	if len(f.entries) > 0 {
+		return f.entries[len(f.entries)-1].blockNumber
+	}
+	return f.anchorBlockNumber
+}
+
+func (f *chainFragment) tipPoint() common.Point {
+	if len(f.entries) > 0 {
+		return f.entries[len(f.entries)-1].point
+	}
+	return f.anchor
+}
+
+// indexOf returns the index of the entry matching the provided point, -1 for the anchor, or -2 if not found
+func (f *chainFragment) indexOf(point common.Point) int {
+	for i := len(f.entries) - 1; i >= 0; i-- {
+		if pointsEqual(f.entries[i].point, point) {
+			return i
+		}
+	}
+	if pointsEqual(f.anchor, point) {
+		return -1
+	}
+	return -2
+}
+
+func (f *chainFragment) rollForward(entry chainEntry, maxLength uint64) {
+	if len(f.entries) == 0 && !f.anchorKnown && entry.blockNumber > 0 {
+		f.anchorBlockNumber = entry.blockNumber - 1
+		f.anchorKnown = true
+	}
+	f.entries = append(f.entries, entry)
+	// Trim fragment to the maximum length
+	if uint64(len(f.entries)) > maxLength {
+		trimCount := len(f.entries) - int(maxLength)
+		lastTrimmed := f.entries[trimCount-1]
+		f.anchor = lastTrimmed.point
+		f.anchorBlockNumber = lastTrimmed.blockNumber
+		f.anchorKnown = true
+		f.entries = append([]chainEntry{}, f.entries[trimCount:]...)
+	}
+}
+
+func (f *chainFragment) rollBackward(point common.Point) {
+	idx := f.indexOf(point)
+	switch {
+	case idx >= 0:
+		f.entries = f.entries[:idx+1]
+	case idx == -1:
+		f.entries = f.entries[:0]
+	default:
+		// The rollback point is outside of our fragment, so we start over from it
+		f.anchor = point
+		f.anchorBlockNumber = 0
+		f.anchorKnown = pointIsOrigin(point)
+		f.entries = f.entries[:0]
+	}
+}
+
+type peerState struct {
+	fragment chainFragment
+	tip      chainsync.Tip
+	// rejectedTip is the candidate tip that was last reported to the RejectedChainFunc
+	rejectedTip      common.Point
+	rejectedReported bool
+}
+
+// ChainSelector tracks candidate chains from multiple peers and selects the best chain
+type ChainSelector struct {
+	config       *Config
+	mutex        sync.Mutex
+	peers        map[connection.ConnectionId]*peerState
+	selected     chainFragment
+	selectedPeer connection.ConnectionId
+	hasSelected  bool
+	eventChan    chan ChainEvent
+	doneChan     chan struct{}
+	onceStop     sync.Once
+	stopped      bool
+	// pending holds the events and callbacks queued while the mutex was held, which are delivered in order
+	// by deliverPending while holding deliverMutex instead
+	pending      []func()
+	deliverMutex sync.Mutex
+}
+
+// New returns a new ChainSelector object with the provided options
+func New(options ...ChainSelectorOptionFunc) *ChainSelector {
+	cfg := NewConfig(options...)
+	return NewFromConfig(&cfg)
+}
+
+// NewFromConfig returns a new ChainSelector object with the provided config
+func NewFromConfig(cfg *Config) *ChainSelector {
+	if cfg == nil {
+		tmpCfg := NewConfig()
+		cfg = &tmpCfg
+	}
+	c := &ChainSelector{
+		config:    cfg,
+		peers:     make(map[connection.ConnectionId]*peerState),
+		eventChan: make(chan ChainEvent, cfg.EventChanSize),
+		doneChan:  make(chan struct{}),
+	}
+	return c
+}
+
+// EventChan returns the channel for selected chain events. Events must be consumed promptly, since the
+// chain-sync callback functions block while the channel is full. Events are sent without any locks held, so
+// the consumer may call ChainSelector functions such as FetchPeer
+func (c *ChainSelector) EventChan() <-chan ChainEvent {
+	return c.eventChan
+}
+
+// Stop shuts down the ChainSelector and closes the event channel
+func (c *ChainSelector) Stop() {
+	c.onceStop.Do(func() {
+		// Unblock any callback waiting to send an event before taking the lock
+		close(c.doneChan)
+		c.deliverMutex.Lock()
+		defer c.deliverMutex.Unlock()
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		c.stopped = true
+		c.pending = nil
+		close(c.eventChan)
+	})
+}
+
+// AddPeer starts tracking the candidate chain for a peer. Peers are also added automatically when their
+// first chain-sync message is received
+func (c *ChainSelector) AddPeer(connId connection.ConnectionId) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.getPeer(connId)
+}
+
+// RemovePeer stops tracking the candidate chain for a peer, generally because the connection was closed.
+// The selected chain is not rolled back when its peer is removed
+func (c *ChainSelector) RemovePeer(connId connection.ConnectionId) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.peers, connId)
+}
+
+// Peers returns the list of peers with tracked candidate chains
+func (c *ChainSelector) Peers() []connection.ConnectionId {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	ret := make([]connection.ConnectionId, 0, len(c.peers))
+	for connId := range c.peers {
+		ret = append(ret, connId)
+	}
+	return ret
+}
+
+// SelectedPeer returns the peer providing the currently selected chain. The second return value is false
+// if no chain has been selected yet
+func (c *ChainSelector) SelectedPeer() (connection.ConnectionId, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.selectedPeer, c.hasSelected
+}
+
+// SelectedTip returns the point and block number at the tip of the selected chain
+func (c *ChainSelector) SelectedTip() (common.Point, uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.selected.tipPoint(), c.selected.tipBlockNumber()
+}
+
+// FetchPeer returns a peer that the block at the specified point can be fetched from. The peer providing
+// the selected chain is preferred, followed by any other peer with the point on its candidate chain
+func (c *ChainSelector) FetchPeer(point common.Point) (connection.ConnectionId, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.hasSelected {
+		if peer, ok := c.peers[c.selectedPeer]; ok {
+			if peer.fragment.indexOf(point) >= 0 {
+				return c.selectedPeer, true
+			}
+		}
+	}
+	for connId, peer := range c.peers {
+		if peer.fragment.indexOf(point) >= 0 {
+			return connId, true
+		}
+	}
+	return connection.ConnectionId{}, false
+}
+
+// RollForward handles a chain-sync RollForward from a peer. It can be used directly as a chain-sync
+// RollForwardFunc
+func (c *ChainSelector) RollForward(
+	ctx chainsync.CallbackContext,
+	blockType uint,
+	blockData interface{},
+	tip chainsync.Tip,
+) error {
+	header, ok := blockData.(ledger.BlockHeader)
+	if !ok {
+		return fmt.Errorf("unexpected block data type: %T", blockData)
+	}
+	blockHash, err := hex.DecodeString(header.Hash())
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	if c.stopped {
+		c.mutex.Unlock()
+		return ChainSelectorStoppedError
+	}
+	peer := c.getPeer(ctx.ConnectionId)
+	peer.tip = tip
+	peer.fragment.rollForward(
+		chainEntry{
+			point:       common.NewPoint(header.SlotNumber(), blockHash),
+			blockNumber: header.BlockNumber(),
+			blockType:   blockType,
+			header:      header,
+		},
+		c.config.SecurityParam+1,
+	)
+	c.selectChain()
+	c.mutex.Unlock()
+	c.deliverPending()
+	return nil
+}
+
+// RollBackward handles a chain-sync RollBackward from a peer. It can be used directly as a chain-sync
+// RollBackwardFunc
+func (c *ChainSelector) RollBackward(
+	ctx chainsync.CallbackContext,
+	point common.Point,
+	tip chainsync.Tip,
+) error {
+	c.mutex.Lock()
+	if c.stopped {
+		c.mutex.Unlock()
+		return ChainSelectorStoppedError
+	}
+	peer := c.getPeer(ctx.ConnectionId)
+	peer.tip = tip
+	peer.fragment.rollBackward(point)
+	// A peer rolling back never makes its candidate preferable, but it can still reveal a longer
+	// chain from another peer that was previously tied
+	c.selectChain()
+	c.mutex.Unlock()
+	c.deliverPending()
+	return nil
+}
+
+// deliverPending sends the queued events and calls the queued callbacks. It must be called without the mutex
+// held. Holding deliverMutex keeps events from concurrent callers in order while still allowing the event
+// consumer and callbacks to call other ChainSelector functions
+func (c *ChainSelector) deliverPending() {
+	c.deliverMutex.Lock()
+	defer c.deliverMutex.Unlock()
+	for {
+		c.mutex.Lock()
+		pending := c.pending
+		c.pending = nil
+		c.mutex.Unlock()
+		if len(pending) == 0 {
+			return
+		}
+		for _, deliverFunc := range pending {
+			deliverFunc()
+		}
+	}
+}
+
+func (c *ChainSelector) getPeer(connId connection.ConnectionId) *peerState {
+	peer, ok := c.peers[connId]
+	if !ok {
+		peer = &peerState{}
+		c.peers[connId] = peer
+	}
+	return peer
+}
+
+// selectChain compares the candidate chains of all peers against the selected chain and switches to the
+// best one, if any. It must be called with the mutex held
+func (c *ChainSelector) selectChain() {
+	var bestPeer connection.ConnectionId
+	var bestPeerState *peerState
+	var bestIntersect int
+	var bestBlockNumber uint64
+	for connId, peer := range c.peers {
+		if len(peer.fragment.entries) == 0 {
+			continue
+		}
+		candidateBlockNumber := peer.fragment.tipBlockNumber()
+		// Praos only switches to a strictly longer chain
+		if c.hasSelected && candidateBlockNumber <= c.selected.tipBlockNumber() {
+			continue
+		}
+		if bestPeerState != nil {
+			if candidateBlockNumber < bestBlockNumber {
+				continue
+			}
+			// Ties between candidates are broken in favor of the currently selected peer and then
+			// by connection ID, so that the result doesn't depend on map ordering
+			if candidateBlockNumber == bestBlockNumber &&
+				!c.preferPeer(connId, bestPeer) {
+				continue
+			}
+		}
+		intersect, ok := c.findIntersect(&peer.fragment)
+		if !ok {
+			c.rejectChain(connId, peer, 0)
+			continue
+		}
+		if c.hasSelected {
+			intersectBlockNumber := c.selected.anchorBlockNumber
+			if intersect >= 0 {
+				intersectBlockNumber = c.selected.entries[intersect].blockNumber
+			}
+			rollbackDepth := c.selected.tipBlockNumber() - intersectBlockNumber
+			if rollbackDepth > c.config.SecurityParam {
+				c.rejectChain(connId, peer, rollbackDepth)
+				continue
+			}
+		}
+		bestPeer = connId
+		bestPeerState = peer
+		bestBlockNumber = candidateBlockNumber
+		bestIntersect = intersect
+	}
+	if bestPeerState == nil {
+		return
+	}
+	c.switchChain(bestPeer, bestPeerState, bestIntersect)
+}
+
+// rejectChain queues a call to the RejectedChainFunc for a candidate chain, unless it was already reported for the
+// same candidate tip. It must be called with the mutex held
+func (c *ChainSelector) rejectChain(
+	connId connection.ConnectionId,
+	peer *peerState,
+	rollbackDepth uint64,
+) {
+	tipPoint := peer.fragment.tipPoint()
+	if peer.rejectedReported && pointsEqual(peer.rejectedTip, tipPoint) {
+		return
+	}
+	peer.rejectedTip = tipPoint
+	peer.rejectedReported = true
+	if c.config.RejectedChainFunc != nil {
+		rejectedChainFunc := c.config.RejectedChainFunc
+		c.pending = append(c.pending, func() {
+			rejectedChainFunc(connId, rollbackDepth)
+		})
+	}
+}
+
+// preferPeer returns whether the first peer should be preferred over the second for candidates of equal length
+func (c *ChainSelector) preferPeer(
+	connId connection.ConnectionId,
+	otherConnId connection.ConnectionId,
+) bool {
+	if connId == c.selectedPeer {
+		return true
+	}
+	if otherConnId == c.selectedPeer {
+		return false
+	}
+	return connIdString(connId) < connIdString(otherConnId)
+}
+
+// findIntersect finds the most recent point on the candidate fragment that's also on the selected chain.
+// It returns the index of the matching entry on the selected chain, or -1 for the selected anchor
+func (c *ChainSelector) findIntersect(candidate *chainFragment) (int, bool) {
+	if !c.hasSelected {
+		return -1, true
+	}
+	for i := len(candidate.entries) - 1; i >= 0; i-- {
+		if idx := c.selected.indexOf(candidate.entries[i].point); idx > -2 {
+			return idx, true
+		}
+	}
+	if idx := c.selected.indexOf(candidate.anchor); idx > -2 {
+		return idx, true
+	}
+	return 0, false
+}
+
+// switchChain replaces the selected chain after the intersect point with the candidate chain from the
+// specified peer and queues the appropriate events. It must be called with the mutex held
+func (c *ChainSelector) switchChain(
+	connId connection.ConnectionId,
+	peer *peerState,
+	selectedIntersect int,
+) {
+	// Determine where the candidate diverges from the selected chain
+	var intersectPoint common.Point
+	var intersectBlockNumber uint64
+	var newEntries []chainEntry
+	if !c.hasSelected {
+		intersectPoint = peer.fragment.anchor
+		intersectBlockNumber = peer.fragment.anchorBlockNumber
+		newEntries = peer.fragment.entries
+		c.selected = chainFragment{
+			anchor:            peer.fragment.anchor,
+			anchorBlockNumber: peer.fragment.anchorBlockNumber,
+			anchorKnown:       peer.fragment.anchorKnown,
+		}
+	} else {
+		if selectedIntersect >= 0 {
+			intersectPoint = c.selected.entries[selectedIntersect].point
+			intersectBlockNumber = c.selected.entries[selectedIntersect].blockNumber
+		} else {
+			intersectPoint = c.selected.anchor
+			intersectBlockNumber = c.selected.anchorBlockNumber
+		}
+		candidateIdx := peer.fragment.indexOf(intersectPoint)
+		newEntries = peer.fragment.entries[candidateIdx+1:]
+	}
+	previousPeer := c.selectedPeer
+	hadSelected := c.hasSelected
+	c.selectedPeer = connId
+	c.hasSelected = true
+	if hadSelected && previousPeer != connId &&
+		c.config.ChainSwitchFunc != nil {
+		chainSwitchFunc := c.config.ChainSwitchFunc
+		c.pending = append(c.pending, func() {
+			chainSwitchFunc(previousPeer, connId)
+		})
+	}
+	// Roll back the selected chain if the intersect is not at the tip
+	if !hadSelected || !pointsEqual(intersectPoint, c.selected.tipPoint()) {
+		c.selected.rollBackward(intersectPoint)
+		c.queueEvent(
+			ChainEvent{
+				Type:        ChainEventRollBackward,
+				Point:       intersectPoint,
+				BlockNumber: intersectBlockNumber,
+				Peer:        connId,
+			},
+		)
+	}
+	for _, entry := range newEntries {
+		c.selected.rollForward(entry, c.config.SecurityParam+1)
+		c.queueEvent(
+			ChainEvent{
+				Type:        ChainEventRollForward,
+				Point:       entry.point,
+				BlockNumber: entry.blockNumber,
+				BlockType:   entry.blockType,
+				Header:      entry.header,
+				Peer:        connId,
+			},
+		)
+	}
+}
+
+// queueEvent queues an event to be sent by deliverPending. It must be called with the mutex held
+func (c *ChainSelector) queueEvent(evt ChainEvent) {
+	c.pending = append(c.pending, func() {
+		select {
+		case <-c.doneChan:
+		case c.eventChan <- evt:
+		}
+	})
+}
+
+func connIdString(connId connection.ConnectionId) string {
+	return fmt.Sprintf("%v<->%v", connId.LocalAddr, connId.RemoteAddr)
+}
+
+func pointsEqual(a common.Point, b common.Point) bool {
+	return a.Slot == b.Slot && bytes.Equal(a.Hash, b.Hash)
+}
+
+func pointIsOrigin(point common.Point) bool {
+	return point.Slot == 0 && len(point.Hash) == 0
+}
@@ -0,0 +1,297 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainselection_test
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/blinklabs-io/gouroboros/chainselection"
+	"github.com/blinklabs-io/gouroboros/connection"
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/protocol/chainsync"
+	"github.com/blinklabs-io/gouroboros/protocol/common"
+)
+
+// testHeader is a minimal block header implementation for feeding the chain selector
+type testHeader struct {
+	hash        string
+	blockNumber uint64
+	slot        uint64
+}
+
+func (h testHeader) Hash() string                  { return h.hash }
+func (h testHeader) BlockNumber() uint64           { return h.blockNumber }
+func (h testHeader) SlotNumber() uint64            { return h.slot }
+func (h testHeader) IssuerVkey() ledger.IssuerVkey { return ledger.IssuerVkey{} }
+func (h testHeader) BlockBodySize() uint64         { return 0 }
+func (h testHeader) Era() ledger.Era               { return ledger.EraInvalid }
+func (h testHeader) Cbor() []byte                  { return nil }
+
+// newTestHeader generates a header for the specified fork and block number. The slot matches the block number
+func newTestHeader(fork string, blockNumber uint64) testHeader {
+	hash := hex.EncodeToString([]byte(fmt.Sprintf("%s-%d", fork, blockNumber)))
+	return testHeader{
+		hash:        hash,
+		blockNumber: blockNumber,
+		slot:        blockNumber,
+	}
+}
+
+func headerPoint(h testHeader) common.Point {
+	hash, _ := hex.DecodeString(h.hash)
+	return common.NewPoint(h.slot, hash)
+}
+
+func newTestCallbackContext(port int) chainsync.CallbackContext {
+	return chainsync.CallbackContext{
+		ConnectionId: connection.ConnectionId{
+			LocalAddr:  &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1},
+			RemoteAddr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port},
+		},
+	}
+}
+
+// rollForward sends headers for the specified fork and block number range to the chain selector
+func rollForward(
+	t *testing.T,
+	cs *chainselection.ChainSelector,
+	ctx chainsync.CallbackContext,
+	fork string,
+	start uint64,
+	end uint64,
+) {
+	for i := start; i <= end; i++ {
+		if err := cs.RollForward(ctx, ledger.BlockTypeBabbage, newTestHeader(fork, i), chainsync.Tip{}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+}
+
+// drainEvents returns all events currently buffered in the event channel
+func drainEvents(cs *chainselection.ChainSelector) []chainselection.ChainEvent {
+	var ret []chainselection.ChainEvent
+	for {
+		select {
+		case evt := <-cs.EventChan():
+			ret = append(ret, evt)
+		default:
+			return ret
+		}
+	}
+}
+
+func TestChainSelectionSinglePeer(t *testing.T) {
+	cs := chainselection.New(chainselection.WithSecurityParam(10))
+	defer cs.Stop()
+	ctxA := newTestCallbackContext(3001)
+	if err := cs.RollBackward(ctxA, common.NewPointOrigin(), chainsync.Tip{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	rollForward(t, cs, ctxA, "a", 1, 3)
+	events := drainEvents(cs)
+	if len(events) != 4 {
+		t.Fatalf("did not get expected number of events: got %d, wanted %d", len(events), 4)
+	}
+	if events[0].Type != chainselection.ChainEventRollBackward {
+		t.Fatalf("did not get expected initial rollback event: got %s", events[0].Type)
+	}
+	for i, evt := range events[1:] {
+		if evt.Type != chainselection.ChainEventRollForward ||
+			evt.BlockNumber != uint64(i+1) {
+			t.Fatalf("did not get expected event: %#v", evt)
+		}
+		if evt.Peer != ctxA.ConnectionId {
+			t.Fatalf("did not get expected fetch peer: got %s, wanted %s", evt.Peer, ctxA.ConnectionId)
+		}
+	}
+}
+
+func TestChainSelectionSwitchToLongerChain(t *testing.T) {
+	cs := chainselection.New(chainselection.WithSecurityParam(10))
+	defer cs.Stop()
+	ctxA := newTestCallbackContext(3001)
+	ctxB := newTestCallbackContext(3002)
+	// Both peers share blocks 1-3
+	rollForward(t, cs, ctxA, "a", 1, 3)
+	rollForward(t, cs, ctxB, "a", 1, 3)
+	// Peer A extends on one fork, and peer B on a longer fork
+	rollForward(t, cs, ctxA, "a", 4, 5)
+	rollForward(t, cs, ctxB, "b", 4, 5)
+	_ = drainEvents(cs)
+	rollForward(t, cs, ctxB, "b", 6, 6)
+	events := drainEvents(cs)
+	if len(events) != 4 {
+		t.Fatalf("did not get expected number of events: got %d, wanted %d", len(events), 4)
+	}
+	expectedRollback := headerPoint(newTestHeader("a", 3))
+	if events[0].Type != chainselection.ChainEventRollBackward ||
+		events[0].Point.Slot != expectedRollback.Slot ||
+		string(events[0].Point.Hash) != string(expectedRollback.Hash) {
+		t.Fatalf("did not get expected rollback event: %#v", events[0])
+	}
+	for i, evt := range events[1:] {
+		expectedHeader := newTestHeader("b", uint64(i+4))
+		if evt.Type != chainselection.ChainEventRollForward ||
+			evt.Header.Hash() != expectedHeader.Hash() {
+			t.Fatalf("did not get expected event: %#v", evt)
+		}
+		if evt.Peer != ctxB.ConnectionId {
+			t.Fatalf("did not get expected fetch peer: got %s, wanted %s", evt.Peer, ctxB.ConnectionId)
+		}
+	}
+	if peer, ok := cs.SelectedPeer(); !ok || peer != ctxB.ConnectionId {
+		t.Fatalf("did not get expected selected peer: got %s, wanted %s", peer, ctxB.ConnectionId)
+	}
+}
+
+func TestChainSelectionEqualLengthKeepsSelected(t *testing.T) {
+	cs := chainselection.New(chainselection.WithSecurityParam(10))
+	defer cs.Stop()
+	ctxA := newTestCallbackContext(3001)
+	ctxB := newTestCallbackContext(3002)
+	rollForward(t, cs, ctxA, "a", 1, 5)
+	rollForward(t, cs, ctxB, "a", 1, 3)
+	rollForward(t, cs, ctxB, "b", 4, 5)
+	events := drainEvents(cs)
+	for _, evt := range events {
+		if evt.Peer != ctxA.ConnectionId {
+			t.Fatalf("unexpected switch to equal length chain: %#v", evt)
+		}
+	}
+}
+
+func TestChainSelectionRollbackLimit(t *testing.T) {
+	var rejectedPeer connection.ConnectionId
+	rejectedDepth := uint64(1000)
+	rejectedCount := 0
+	var cs *chainselection.ChainSelector
+	cs = chainselection.New(
+		chainselection.WithSecurityParam(3),
+		chainselection.WithRejectedChainFunc(
+			func(connId connection.ConnectionId, depth uint64) {
+				rejectedPeer = connId
+				rejectedDepth = depth
+				rejectedCount++
+				// Callbacks are called without the lock held
+				_, _ = cs.SelectedTip()
+			},
+		),
+	)
+	defer cs.Stop()
+	ctxA := newTestCallbackContext(3001)
+	ctxB := newTestCallbackContext(3002)
+	rollForward(t, cs, ctxA, "a", 1, 2)
+	rollForward(t, cs, ctxB, "a", 1, 2)
+	rollForward(t, cs, ctxA, "a", 3, 7)
+	_ = drainEvents(cs)
+	// Peer B forks off 5 blocks deep, which exceeds k=3
+	rollForward(t, cs, ctxB, "b", 3, 10)
+	events := drainEvents(cs)
+	if len(events) != 0 {
+		t.Fatalf("unexpected events for chain forking beyond k: %#v", events)
+	}
+	if rejectedPeer != ctxB.ConnectionId {
+		t.Fatalf("did not get expected rejected peer: got %s, wanted %s", rejectedPeer, ctxB.ConnectionId)
+	}
+	// The fork point is no longer retained in the selected chain, so the depth is unknown
+	if rejectedDepth != 0 {
+		t.Fatalf("did not get expected rejected rollback depth: got %d, wanted %d", rejectedDepth, 0)
+	}
+	if peer, _ := cs.SelectedPeer(); peer != ctxA.ConnectionId {
+		t.Fatalf("did not get expected selected peer: got %s, wanted %s", peer, ctxA.ConnectionId)
+	}
+	// Peer B's candidate is longer than the selected chain from block 8, and each new candidate tip is
+	// reported once
+	if rejectedCount != 3 {
+		t.Fatalf("did not get expected rejected chain count: got %d, wanted %d", rejectedCount, 3)
+	}
+	// Headers from other peers don't report the same rejected candidate tip again
+	rollForward(t, cs, ctxA, "a", 8, 8)
+	if rejectedCount != 3 {
+		t.Fatalf("rejected chain was reported again for the same candidate tip: got %d reports", rejectedCount)
+	}
+}
+
+func TestChainSelectionFetchPeer(t *testing.T) {
+	cs := chainselection.New(chainselection.WithSecurityParam(10))
+	defer cs.Stop()
+	ctxA := newTestCallbackContext(3001)
+	ctxB := newTestCallbackContext(3002)
+	rollForward(t, cs, ctxA, "a", 1, 3)
+	rollForward(t, cs, ctxB, "a", 1, 2)
+	rollForward(t, cs, ctxB, "b", 3, 3)
+	peer, ok := cs.FetchPeer(headerPoint(newTestHeader("a", 2)))
+	if !ok || peer != ctxA.ConnectionId {
+		t.Fatalf("did not get expected fetch peer: got %s, wanted %s", peer, ctxA.ConnectionId)
+	}
+	peer, ok = cs.FetchPeer(headerPoint(newTestHeader("b", 3)))
+	if !ok || peer != ctxB.ConnectionId {
+		t.Fatalf("did not get expected fetch peer: got %s, wanted %s", peer, ctxB.ConnectionId)
+	}
+	if _, ok := cs.FetchPeer(headerPoint(newTestHeader("c", 3))); ok {
+		t.Fatalf("unexpectedly found fetch peer for unknown point")
+	}
+}
+
+func TestChainSelectionEventConsumerFetchPeer(t *testing.T) {
+	var blockCount uint64 = 500
+	cs := chainselection.New(
+		chainselection.WithSecurityParam(10),
+		chainselection.WithEventChanSize(10),
+	)
+	defer cs.Stop()
+	ctxA := newTestCallbackContext(3001)
+	// The event consumer looks up the fetch peer for each block, which must not deadlock while the chain-sync
+	// callbacks are blocked on a full event channel
+	doneChan := make(chan error, 2)
+	go func() {
+		var rollForwardCount uint64
+		for evt := range cs.EventChan() {
+			if evt.Type != chainselection.ChainEventRollForward {
+				continue
+			}
+			// The block may have already been trimmed from the candidate chain, so we only
+			// care that this returns
+			_, _ = cs.FetchPeer(evt.Point)
+			rollForwardCount++
+			if rollForwardCount == blockCount {
+				doneChan <- nil
+				return
+			}
+		}
+		doneChan <- fmt.Errorf("event channel closed after %d roll forward events", rollForwardCount)
+	}()
+	// Headers are fed from a separate goroutine so that a deadlock is reported by the timeout below
+	go func() {
+		for i := uint64(1); i <= blockCount; i++ {
+			if err := cs.RollForward(ctxA, ledger.BlockTypeBabbage, newTestHeader("a", i), chainsync.Tip{}); err != nil {
+				doneChan <- err
+				return
+			}
+		}
+	}()
+	select {
+	case err := <-doneChan:
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("did not receive all events within timeout")
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	ouroboros "github.com/blinklabs-io/gouroboros"
+	"github.com/blinklabs-io/gouroboros/peercrawler"
+)
+
+type crawlFlags struct {
+	flagset     *flag.FlagSet
+	seeds       string
+	depth       int
+	maxNodes    int
+	concurrency int
+	timeout     time.Duration
+	format      string
+	output      string
+}
+
+func newCrawlFlags() *crawlFlags {
+	f := &crawlFlags{
+		flagset: flag.NewFlagSet("crawl", flag.ExitOnError),
+	}
+	f.flagset.StringVar(
+		&f.seeds,
+		"seeds",
+		"",
+		"comma-separated list of seed addresses in address:port format (defaults to the network's public root)",
+	)
+	f.flagset.IntVar(&f.depth, "depth", 3, "maximum number of hops from the seed addresses")
+	f.flagset.IntVar(&f.maxNodes, "max-nodes", 1000, "maximum number of nodes to discover")
+	f.flagset.IntVar(&f.concurrency, "concurrency", 10, "maximum number of concurrent connections")
+	f.flagset.DurationVar(
+		&f.timeout,
+		"timeout",
+		10*time.Second,
+		"timeout for connecting to each node and requesting its peers",
+	)
+	f.flagset.StringVar(&f.format, "format", "json", "output format (json or dot)")
+	f.flagset.StringVar(&f.output, "output", "", "file to write the output to (defaults to stdout)")
+	return f
+}
+
+func testCrawl(f *globalFlags) {
+	crawlFlags := newCrawlFlags()
+	err := crawlFlags.flagset.Parse(f.flagset.Args()[1:])
+	if err != nil {
+		fmt.Printf("failed to parse subcommand args: %s\n", err)
+		os.Exit(1)
+	}
+	if crawlFlags.format != "json" && crawlFlags.format != "dot" {
+		fmt.Printf("ERROR: unknown output format: %s\n", crawlFlags.format)
+		os.Exit(1)
+	}
+
+	var seeds []string
+	if crawlFlags.seeds != "" {
+		seeds = strings.Split(crawlFlags.seeds, ",")
+	} else if f.address != "" {
+		seeds = []string{f.address}
+	} else {
+		network := ouroboros.NetworkByName(f.network)
+		if network == ouroboros.NetworkInvalid || network.PublicRootAddress == "" {
+			fmt.Printf("ERROR: you must specify seed addresses for this network\n")
+			os.Exit(1)
+		}
+		seeds = []string{
+			net.JoinHostPort(
+				network.PublicRootAddress,
+				strconv.Itoa(int(network.PublicRootPort)),
+			),
+		}
+	}
+
+	crawler := peercrawler.New(
+		peercrawler.WithNetworkMagic(uint32(f.networkMagic)),
+		peercrawler.WithMaxDepth(crawlFlags.depth),
+		peercrawler.WithMaxNodes(crawlFlags.maxNodes),
+		peercrawler.WithConcurrency(crawlFlags.concurrency),
+		peercrawler.WithTimeout(crawlFlags.timeout),
+	)
+	// Stop crawling on interrupt and output what was found so far
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	graph, err := crawler.Crawl(ctx, seeds...)
+	if graph == nil {
+		fmt.Printf("ERROR: %s\n", err)
+		os.Exit(1)
+	}
+
+	out := os.Stdout
+	if crawlFlags.output != "" {
+		out, err = os.Create(crawlFlags.output)
+		if err != nil {
+			fmt.Printf("ERROR: failed to create output file: %s\n", err)
+			os.Exit(1)
+		}
+		defer out.Close()
+	}
+	switch crawlFlags.format {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(graph)
+	case "dot":
+		err = graph.WriteDOT(out)
+	}
+	if err != nil {
+		fmt.Printf("ERROR: failed to write output: %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -105,6 +105,7 @@ func main() {
 		"server":              testServer,
 		"query":               testQuery,
 		"mem-usage":           testMemUsage,
+		"crawl":               testCrawl,
 	}
 
 	if len(f.flagset.Args()) == 0 {
@@ -0,0 +1,261 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hardfork converts between slots, wall-clock time, and epochs across era boundaries
+//
+// An Interpreter is built from the system start and the era history, which can come from the
+// local-state-query GetSystemStart and GetEraHistory queries, from a saved copy of their results, or from
+// genesis-derived data. Each era can use a different slot length and epoch length. Conversions beyond the
+// end of the known era history (the safe forecast horizon) return a PastHorizonError.
+package hardfork
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/blinklabs-io/gouroboros/protocol/localstatequery"
+)
+
+// BeforeSystemStartError is returned when converting a time before the system start
+var BeforeSystemStartError = errors.New("time is before the system start")
+
+// PastHorizonError is returned when a conversion falls beyond the end of the known era history
+type PastHorizonError struct {
+	// Horizon is the end of the known era history
+	Horizon Bound
+}
+
+func (e PastHorizonError) Error() string {
+	return fmt.Sprintf(
+		"past the safe forecast horizon at slot %d (epoch %d)",
+		e.Horizon.Slot,
+		e.Horizon.Epoch,
+	)
+}
+
+// Bound represents the start or end of an era
+type Bound struct {
+	// Time is relative to the system start
+	Time  time.Duration `json:"time"`
+	Slot  uint64        `json:"slot"`
+	Epoch uint64        `json:"epoch"`
+}
+
+// EraSummary describes the bounds and parameters of a single era
+type EraSummary struct {
+	Start Bound `json:"start"`
+	// End is nil for an era without a known end
+	End         *Bound        `json:"end,omitempty"`
+	EpochLength uint64        `json:"epochLength"`
+	SlotLength  time.Duration `json:"slotLength"`
+}
+
+// Interpreter converts between slots, times, and epochs using the era history
+type Interpreter struct {
+	systemStart time.Time
+	eras        []EraSummary
+}
+
+// NewInterpreter returns a new Interpreter for the provided system start and era summaries. The eras
+// must be in order and contiguous
+func NewInterpreter(
+	systemStart time.Time,
+	eras []EraSummary,
+) (*Interpreter, error) {
+	if len(eras) == 0 {
+		return nil, errors.New("era history is empty")
+	}
+	for idx, era := range eras {
+		if era.EpochLength == 0 || era.SlotLength <= 0 {
+			return nil, fmt.Errorf("era %d has invalid params", idx)
+		}
+		if era.End == nil && idx != len(eras)-1 {
+			return nil, fmt.Errorf("era %d has no end but is not the last era", idx)
+		}
+		if idx > 0 && *eras[idx-1].End != era.Start {
+			return nil, fmt.Errorf(
+				"era %d does not start at the end of the previous era",
+				idx,
+			)
+		}
+	}
+	i := &Interpreter{
+		systemStart: systemStart,
+		eras:        append([]EraSummary{}, eras...),
+	}
+	return i, nil
+}
+
+// NewInterpreterFromQuery returns a new Interpreter from the results of the GetSystemStart and GetEraHistory
+// local-state-query queries
+func NewInterpreterFromQuery(
+	systemStart *localstatequery.SystemStartResult,
+	eraHistory []localstatequery.EraHistoryResult,
+) (*Interpreter, error) {
+	eras := make([]EraSummary, 0, len(eraHistory))
+	for idx, eraResult := range eraHistory {
+		startTime, err := picosecondsToDuration(eraResult.Begin.Timespan)
+		if err != nil {
+			return nil, fmt.Errorf("era %d: %w", idx, err)
+		}
+		era := EraSummary{
+			Start: Bound{
+				Time:  startTime,
+				Slot:  uint64(eraResult.Begin.SlotNo),
+				Epoch: uint64(eraResult.Begin.EpochNo),
+			},
+			EpochLength: uint64(eraResult.Params.EpochLength),
+			SlotLength: time.Duration(
+				eraResult.Params.SlotLength,
+			) * time.Millisecond,
+		}
+		// An unbounded era end is encoded as null
+		if eraResult.End.Timespan != nil {
+			endTime, err := picosecondsToDuration(eraResult.End.Timespan)
+			if err != nil {
+				return nil, fmt.Errorf("era %d: %w", idx, err)
+			}
+			era.End = &Bound{
+				Time:  endTime,
+				Slot:  uint64(eraResult.End.SlotNo),
+				Epoch: uint64(eraResult.End.EpochNo),
+			}
+		}
+		eras = append(eras, era)
+	}
+	return NewInterpreter(systemStart.Time(), eras)
+}
+
+// SystemStart returns the system start time
+func (i *Interpreter) SystemStart() time.Time {
+	return i.systemStart
+}
+
+// Eras returns the era summaries
+func (i *Interpreter) Eras() []EraSummary {
+	return append([]EraSummary{}, i.eras...)
+}
+
+// Horizon returns the end of the known era history, or nil if the last era has no known end
+func (i *Interpreter) Horizon() *Bound {
+	lastEra := i.eras[len(i.eras)-1]
+	if lastEra.End == nil {
+		return nil
+	}
+	ret := *lastEra.End
+	return &ret
+}
+
+// SlotToTime returns the wall-clock time at the start of the specified slot
+func (i *Interpreter) SlotToTime(slot uint64) (time.Time, error) {
+	era, err := i.eraForSlot(slot)
+	if err != nil {
+		return time.Time{}, err
+	}
+	relTime := era.Start.Time + time.Duration(slot-era.Start.Slot)*era.SlotLength
+	return i.systemStart.Add(relTime), nil
+}
+
+// TimeToSlot returns the slot that contains the specified wall-clock time
+func (i *Interpreter) TimeToSlot(t time.Time) (uint64, error) {
+	if t.Before(i.systemStart) {
+		return 0, BeforeSystemStartError
+	}
+	relTime := t.Sub(i.systemStart)
+	for _, era := range i.eras {
+		if relTime < era.Start.Time {
+			continue
+		}
+		if era.End != nil && relTime >= era.End.Time {
+			continue
+		}
+		return era.Start.Slot + uint64((relTime-era.Start.Time)/era.SlotLength), nil
+	}
+	return 0, i.pastHorizonError()
+}
+
+// SlotToEpoch returns the epoch containing the specified slot and the slot's offset within that epoch
+func (i *Interpreter) SlotToEpoch(slot uint64) (uint64, uint64, error) {
+	era, err := i.eraForSlot(slot)
+	if err != nil {
+		return 0, 0, err
+	}
+	relSlot := slot - era.Start.Slot
+	return era.Start.Epoch + relSlot/era.EpochLength, relSlot % era.EpochLength, nil
+}
+
+// EpochToFirstSlot returns the first slot of the specified epoch
+func (i *Interpreter) EpochToFirstSlot(epoch uint64) (uint64, error) {
+	for _, era := range i.eras {
+		if epoch < era.Start.Epoch {
+			continue
+		}
+		if era.End != nil && epoch >= era.End.Epoch {
+			continue
+		}
+		return era.Start.Slot + (epoch-era.Start.Epoch)*era.EpochLength, nil
+	}
+	return 0, i.pastHorizonError()
+}
+
+// eraForSlot returns the era containing the specified slot
+func (i *Interpreter) eraForSlot(slot uint64) (*EraSummary, error) {
+	for idx := range i.eras {
+		era := &i.eras[idx]
+		if slot < era.Start.Slot {
+			continue
+		}
+		if era.End != nil && slot >= era.End.Slot {
+			continue
+		}
+		return era, nil
+	}
+	return nil, i.pastHorizonError()
+}
+
+func (i *Interpreter) pastHorizonError() error {
+	lastEra := i.eras[len(i.eras)-1]
+	if lastEra.End == nil {
+		// The value precedes the start of the first era
+		return errors.New("value is before the start of the era history")
+	}
+	return PastHorizonError{
+		Horizon: *lastEra.End,
+	}
+}
+
+// picosecondsToDuration converts a relative time in picoseconds from the era history, which may be
+// decoded as a big integer, to a time.Duration
+func picosecondsToDuration(val interface{}) (time.Duration, error) {
+	var picoseconds *big.Int
+	switch v := val.(type) {
+	case uint64:
+		picoseconds = new(big.Int).SetUint64(v)
+	case int64:
+		picoseconds = big.NewInt(v)
+	case big.Int:
+		picoseconds = &v
+	case *big.Int:
+		picoseconds = v
+	default:
+		return 0, fmt.Errorf("unexpected relative time type: %T", val)
+	}
+	nanoseconds := new(big.Int).Quo(picoseconds, big.NewInt(1000))
+	if !nanoseconds.IsInt64() {
+		return 0, fmt.Errorf("relative time out of range: %s", picoseconds)
+	}
+	return time.Duration(nanoseconds.Int64()), nil
+}
@@ -0,0 +1,242 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hardfork_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/blinklabs-io/gouroboros/cbor"
+	"github.com/blinklabs-io/gouroboros/hardfork"
+	"github.com/blinklabs-io/gouroboros/protocol/localstatequery"
+)
+
+var mainnetSystemStart = time.Date(2017, time.September, 23, 21, 44, 51, 0, time.UTC)
+
+// mainnetEras returns a simplified mainnet-like era history with a Byron era followed by a single Shelley-based era
+func mainnetEras(shelleyEnd *hardfork.Bound) []hardfork.EraSummary {
+	byronEnd := hardfork.Bound{
+		Time:  4492800 * 20 * time.Second,
+		Slot:  4492800,
+		Epoch: 208,
+	}
+	return []hardfork.EraSummary{
+		{
+			Start:       hardfork.Bound{},
+			End:         &byronEnd,
+			EpochLength: 21600,
+			SlotLength:  20 * time.Second,
+		},
+		{
+			Start:       byronEnd,
+			End:         shelleyEnd,
+			EpochLength: 432000,
+			SlotLength:  time.Second,
+		},
+	}
+}
+
+func TestInterpreterConversions(t *testing.T) {
+	interp, err := hardfork.NewInterpreter(mainnetSystemStart, mainnetEras(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testDefs := []struct {
+		slot       uint64
+		time       time.Time
+		epoch      uint64
+		epochSlot  uint64
+		epochStart uint64
+	}{
+		{
+			slot:       0,
+			time:       mainnetSystemStart,
+			epoch:      0,
+			epochSlot:  0,
+			epochStart: 0,
+		},
+		{
+			slot:       21601,
+			time:       mainnetSystemStart.Add(21601 * 20 * time.Second),
+			epoch:      1,
+			epochSlot:  1,
+			epochStart: 21600,
+		},
+		{
+			slot:       4492800,
+			time:       time.Date(2020, time.July, 29, 21, 44, 51, 0, time.UTC),
+			epoch:      208,
+			epochSlot:  0,
+			epochStart: 4492800,
+		},
+		{
+			slot:       4924810,
+			time:       time.Date(2020, time.August, 3, 21, 45, 1, 0, time.UTC),
+			epoch:      209,
+			epochSlot:  10,
+			epochStart: 4924800,
+		},
+	}
+	for _, testDef := range testDefs {
+		slotTime, err := interp.SlotToTime(testDef.slot)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !slotTime.Equal(testDef.time) {
+			t.Fatalf("did not get expected time for slot %d: got %s, expected %s", testDef.slot, slotTime, testDef.time)
+		}
+		slot, err := interp.TimeToSlot(testDef.time)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if slot != testDef.slot {
+			t.Fatalf("did not get expected slot for time %s: got %d, expected %d", testDef.time, slot, testDef.slot)
+		}
+		epoch, epochSlot, err := interp.SlotToEpoch(testDef.slot)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if epoch != testDef.epoch || epochSlot != testDef.epochSlot {
+			t.Fatalf("did not get expected epoch for slot %d: got %d/%d, expected %d/%d", testDef.slot, epoch, epochSlot, testDef.epoch, testDef.epochSlot)
+		}
+		epochStart, err := interp.EpochToFirstSlot(testDef.epoch)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if epochStart != testDef.epochStart {
+			t.Fatalf("did not get expected first slot for epoch %d: got %d, expected %d", testDef.epoch, epochStart, testDef.epochStart)
+		}
+	}
+	// A time within a slot should map to that slot
+	slot, err := interp.TimeToSlot(mainnetSystemStart.Add(45 * time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if slot != 2 {
+		t.Fatalf("did not get expected slot: got %d, expected %d", slot, 2)
+	}
+	if _, err := interp.TimeToSlot(mainnetSystemStart.Add(-time.Second)); !errors.Is(err, hardfork.BeforeSystemStartError) {
+		t.Fatalf("did not get expected error, got: %v", err)
+	}
+}
+
+func TestInterpreterPastHorizon(t *testing.T) {
+	horizon := hardfork.Bound{
+		Time:  4492800*20*time.Second + 864000*time.Second,
+		Slot:  4492800 + 864000,
+		Epoch: 210,
+	}
+	interp, err := hardfork.NewInterpreter(mainnetSystemStart, mainnetEras(&horizon))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := interp.SlotToTime(horizon.Slot - 1); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var pastHorizonErr hardfork.PastHorizonError
+	if _, err := interp.SlotToTime(horizon.Slot); !errors.As(err, &pastHorizonErr) {
+		t.Fatalf("did not get expected error, got: %v", err)
+	}
+	if pastHorizonErr.Horizon != horizon {
+		t.Fatalf("did not get expected horizon: got %#v, expected %#v", pastHorizonErr.Horizon, horizon)
+	}
+	if _, err := interp.TimeToSlot(mainnetSystemStart.Add(horizon.Time)); !errors.As(err, &pastHorizonErr) {
+		t.Fatalf("did not get expected error, got: %v", err)
+	}
+	if _, err := interp.EpochToFirstSlot(horizon.Epoch); !errors.As(err, &pastHorizonErr) {
+		t.Fatalf("did not get expected error, got: %v", err)
+	}
+	if h := interp.Horizon(); h == nil || *h != horizon {
+		t.Fatalf("did not get expected horizon: got %v, expected %v", h, horizon)
+	}
+}
+
+func TestNewInterpreterInvalid(t *testing.T) {
+	if _, err := hardfork.NewInterpreter(mainnetSystemStart, nil); err == nil {
+		t.Fatalf("did not get expected error for empty era history")
+	}
+	eras := mainnetEras(nil)
+	eras[1].Start.Slot++
+	if _, err := hardfork.NewInterpreter(mainnetSystemStart, eras); err == nil {
+		t.Fatalf("did not get expected error for non-contiguous eras")
+	}
+	eras = mainnetEras(nil)
+	eras[0].End = nil
+	if _, err := hardfork.NewInterpreter(mainnetSystemStart, eras); err == nil {
+		t.Fatalf("did not get expected error for unbounded non-final era")
+	}
+}
+
+func TestNewInterpreterFromQuery(t *testing.T) {
+	// Picoseconds beyond the range of a uint64 decode as a big integer
+	byronEndPicoseconds := new(big.Int).Mul(
+		big.NewInt(4492800*20),
+		big.NewInt(1_000_000_000_000),
+	)
+	shelleyEndPicoseconds := new(big.Int).Mul(
+		big.NewInt(4492800*20+864000),
+		big.NewInt(1_000_000_000_000),
+	)
+	eraHistoryCbor, err := cbor.Encode(
+		[]interface{}{
+			[]interface{}{
+				[]interface{}{0, 0, 0},
+				[]interface{}{byronEndPicoseconds, 4492800, 208},
+				[]interface{}{21600, 20000, []interface{}{0, 4492800, 0}},
+			},
+			[]interface{}{
+				[]interface{}{byronEndPicoseconds, 4492800, 208},
+				[]interface{}{shelleyEndPicoseconds, 4492800 + 864000, 210},
+				[]interface{}{432000, 1000, []interface{}{0, 129600, 0}},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var eraHistory []localstatequery.EraHistoryResult
+	if _, err := cbor.Decode(eraHistoryCbor, &eraHistory); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	systemStart := &localstatequery.SystemStartResult{
+		Year:        2017,
+		Day:         266,
+		Picoseconds: 78291000000000000,
+	}
+	interp, err := hardfork.NewInterpreterFromQuery(systemStart, eraHistory)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !interp.SystemStart().Equal(mainnetSystemStart) {
+		t.Fatalf("did not get expected system start: got %s, expected %s", interp.SystemStart(), mainnetSystemStart)
+	}
+	if eras := interp.Eras(); len(eras) != 2 || eras[1].SlotLength != time.Second || eras[0].End == nil || eras[1].End == nil {
+		t.Fatalf("did not get expected eras: %#v", eras)
+	}
+	slotTime, err := interp.SlotToTime(4492800)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expectedTime := time.Date(2020, time.July, 29, 21, 44, 51, 0, time.UTC)
+	if !slotTime.Equal(expectedTime) {
+		t.Fatalf("did not get expected time: got %s, expected %s", slotTime, expectedTime)
+	}
+	var pastHorizonErr hardfork.PastHorizonError
+	if _, err := interp.SlotToTime(4492800 + 864000); !errors.As(err, &pastHorizonErr) {
+		t.Fatalf("did not get expected error, got: %v", err)
+	}
+}
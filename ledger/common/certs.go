@@ -15,6 +15,7 @@
 package common
 
 import (
+	"encoding/hex"
 	"fmt"
 	"net"
 
@@ -154,6 +155,37 @@ func (d *Drep) UnmarshalCBOR(data []byte) error {
 	return nil
 }
 
+func (d Drep) MarshalCBOR() ([]byte, error) {
+	switch d.Type {
+	case DrepTypeAddrKeyHash, DrepTypeScriptHash:
+		return cbor.Encode([]interface{}{d.Type, d.Credential})
+	case DrepTypeAbstain, DrepTypeNoConfidence:
+		return cbor.Encode([]interface{}{d.Type})
+	default:
+		return nil, fmt.Errorf("unknown drep type: %d", d.Type)
+	}
+}
+
+// String returns the DRep in a form such as "keyHash-<hex>", "scriptHash-<hex>", "abstain" or "noConfidence"
+func (d Drep) String() string {
+	switch d.Type {
+	case DrepTypeAddrKeyHash:
+		return "keyHash-" + hex.EncodeToString(d.Credential)
+	case DrepTypeScriptHash:
+		return "scriptHash-" + hex.EncodeToString(d.Credential)
+	case DrepTypeAbstain:
+		return "abstain"
+	case DrepTypeNoConfidence:
+		return "noConfidence"
+	default:
+		return fmt.Sprintf("unknown-%d", d.Type)
+	}
+}
+
+func (d Drep) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
 type StakeRegistrationCertificate struct {
 	cbor.StructAsArray
 	cbor.DecodeStoreCbor
@@ -301,6 +333,26 @@ func (p *PoolRelay) UnmarshalCBOR(data []byte) error {
 	return nil
 }
 
+func (p *PoolRelay) MarshalCBOR() ([]byte, error) {
+	switch p.Type {
+	case PoolRelayTypeSingleHostAddress:
+		var ipv4, ipv6 []byte
+		if p.Ipv4 != nil {
+			ipv4 = p.Ipv4.To4()
+		}
+		if p.Ipv6 != nil {
+			ipv6 = p.Ipv6.To16()
+		}
+		return cbor.Encode([]any{p.Type, p.Port, ipv4, ipv6})
+	case PoolRelayTypeSingleHostName:
+		return cbor.Encode([]any{p.Type, p.Port, p.Hostname})
+	case PoolRelayTypeMultiHostName:
+		return cbor.Encode([]any{p.Type, p.Hostname})
+	default:
+		return nil, fmt.Errorf("invalid relay type: %d", p.Type)
+	}
+}
+
 func (p *PoolRelay) Utxorpc() *utxorpc.Relay {
 	ret := &utxorpc.Relay{}
 	if p.Port != nil {
@@ -456,12 +508,19 @@ func (r *MoveInstantaneousRewardsCertificateReward) UnmarshalCBOR(
 	}{}
 	if _, err := cbor.Decode(data, &tmpCoinData); err == nil {
 		r.OtherPot = tmpCoinData.Coin
-		r.Source = tmpMapData.Source
+		r.Source = tmpCoinData.Source
 		return nil
 	}
 	return fmt.Errorf("failed to decode as known types")
 }
 
+func (r *MoveInstantaneousRewardsCertificateReward) MarshalCBOR() ([]byte, error) {
+	if r.Rewards != nil {
+		return cbor.Encode([]any{r.Source, r.Rewards})
+	}
+	return cbor.Encode([]any{r.Source, r.OtherPot})
+}
+
 type MoveInstantaneousRewardsCertificate struct {
 	cbor.StructAsArray
 	cbor.DecodeStoreCbor
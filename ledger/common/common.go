@@ -47,6 +47,10 @@ func (b Blake2b256) Bytes() []byte {
 	return b[:]
 }
 
+func (b Blake2b256) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
 // Blake2b256Hash generates a Blake2b-256 hash from the provided data
 func Blake2b256Hash(data []byte) Blake2b256 {
 	tmpHash, err := blake2b.New(Blake2b256Size, nil)
@@ -78,6 +82,10 @@ func (b Blake2b224) Bytes() []byte {
 	return b[:]
 }
 
+func (b Blake2b224) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
 // Blake2b224Hash generates a Blake2b-224 hash from the provided data
 func Blake2b224Hash(data []byte) Blake2b224 {
 	tmpHash, err := blake2b.New(Blake2b224Size, nil)
@@ -109,6 +117,10 @@ func (b Blake2b160) Bytes() []byte {
 	return b[:]
 }
 
+func (b Blake2b160) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
 // Blake2b160Hash generates a Blake2b-160 hash from the provided data
 func Blake2b160Hash(data []byte) Blake2b160 {
 	tmpHash, err := blake2b.New(Blake2b160Size, nil)
@@ -280,6 +292,10 @@ func (p PoolId) String() string {
 	return encoded
 }
 
+func (p PoolId) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
 // IssuerVkey represents the verification key for the stake pool that minted a block
 type IssuerVkey [32]byte
 
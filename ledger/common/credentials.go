@@ -15,6 +15,7 @@
 package common
 
 import (
+	"encoding/hex"
 	"fmt"
 
 	"github.com/blinklabs-io/gouroboros/cbor"
@@ -51,6 +52,19 @@ func (c *StakeCredential) Hash() Blake2b224 {
 	return Blake2b224(hash.Sum(nil))
 }
 
+// String returns the credential type and hash in a form such as "keyHash-<hex>" or "scriptHash-<hex>"
+func (c StakeCredential) String() string {
+	prefix := "keyHash"
+	if c.CredType == StakeCredentialTypeScriptHash {
+		prefix = "scriptHash"
+	}
+	return prefix + "-" + hex.EncodeToString(c.Credential)
+}
+
+func (c StakeCredential) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
 func (c *StakeCredential) Utxorpc() *utxorpc.StakeCredential {
 	ret := &utxorpc.StakeCredential{}
 	switch c.CredType {
@@ -48,11 +48,48 @@ const (
 	UtxoFailureOutsideForecast             = 18
 	UtxoFailureTooManyCollateralInputs     = 19
 	UtxoFailureNoCollateralInputs          = 20
+
+	UtxosFailureValidationTagMismatch = 0
+	UtxosFailureCollectErrors         = 1
+	UtxosFailureUpdateFailure         = 2
+
+	TagMismatchDescriptionPassedUnexpectedly = 0
+	TagMismatchDescriptionFailedUnexpectedly = 1
+
+	FailureDescriptionPlutusFailure = 1
 )
 
 // Helper type to make the code a little cleaner
 type NewErrorFromCborFunc func([]byte) (error, error)
 
+// newFailureFromCbor decodes a predicate failure using the failure types in idMap, which are keyed by the
+// failure tag. Unknown failures are decoded as a GenericError
+func newFailureFromCbor(cborData []byte, idMap map[int]any) (error, error) {
+	newErr, err := cbor.DecodeById(cborData, idMap)
+	if err != nil {
+		return NewGenericErrorFromCbor(cborData)
+	}
+	return newErr.(error), nil
+}
+
+// decodeNestedFailure decodes a predicate failure that wraps a failure from another rule, in the form
+// [tag, failure]. It returns the tag and the decoded inner failure
+func decodeNestedFailure(cborData []byte, idMap map[int]any) (uint8, error, error) {
+	var tmpData struct {
+		cbor.StructAsArray
+		Type uint8
+		Err  cbor.RawMessage
+	}
+	if _, err := cbor.Decode(cborData, &tmpData); err != nil {
+		return 0, nil, err
+	}
+	newErr, err := newFailureFromCbor(tmpData.Err, idMap)
+	if err != nil {
+		return 0, nil, err
+	}
+	return tmpData.Type, newErr, nil
+}
+
 func NewGenericErrorFromCbor(cborData []byte) (error, error) {
 	newErr := &GenericError{}
 	if _, err := cbor.Decode(cborData, newErr); err != nil {
@@ -137,14 +174,20 @@ func (e *ShelleyTxValidationError) UnmarshalCBOR(data []byte) error {
 		Inner struct {
 			cbor.StructAsArray
 			Era          uint8
-			ApplyTxError ApplyTxError
+			ApplyTxError cbor.RawMessage
 		}
 	}
 	if _, err := cbor.Decode(data, &tmpData); err != nil {
 		return err
 	}
 	e.Era = tmpData.Inner.Era
-	e.Err = tmpData.Inner.ApplyTxError
+	// The Conway era uses a different predicate failure hierarchy
+	if e.Era >= EraIdConway {
+		return e.Err.unmarshalConwayCbor(tmpData.Inner.ApplyTxError)
+	}
+	if _, err := cbor.Decode(tmpData.Inner.ApplyTxError, &e.Err); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -424,13 +467,120 @@ func (t *TxOut) String() string {
 
 type UtxosFailure struct {
 	UtxoFailureErrorBase
-	Err GenericError
+	Err error
+}
+
+func (e *UtxosFailure) UnmarshalCBOR(data []byte) error {
+	var err error
+	e.Type, e.Err, err = decodeNestedFailure(
+		data,
+		map[int]any{
+			UtxosFailureValidationTagMismatch: &ValidationTagMismatch{},
+			UtxosFailureCollectErrors:         &CollectErrors{},
+			UtxosFailureUpdateFailure:         &UpdateFailure{},
+		},
+	)
+	return err
 }
 
 func (e *UtxosFailure) Error() string {
 	return fmt.Sprintf("UtxosFailure (%s)", e.Err)
 }
 
+type ValidationTagMismatch struct {
+	UtxoFailureErrorBase
+	IsValid     bool
+	Description TagMismatchDescription
+}
+
+func (e *ValidationTagMismatch) Error() string {
+	return fmt.Sprintf(
+		"ValidationTagMismatch (IsValid %t, %s)",
+		e.IsValid,
+		e.Description.String(),
+	)
+}
+
+// TagMismatchDescription describes why the validity flag in a transaction didn't match the result of running
+// its scripts
+type TagMismatchDescription struct {
+	Type     uint8
+	Failures []PlutusFailure
+}
+
+func (d *TagMismatchDescription) UnmarshalCBOR(data []byte) error {
+	tmpData := []cbor.RawMessage{}
+	if _, err := cbor.Decode(data, &tmpData); err != nil {
+		return err
+	}
+	if len(tmpData) == 0 {
+		return fmt.Errorf("invalid TagMismatchDescription: empty list")
+	}
+	if _, err := cbor.Decode(tmpData[0], &d.Type); err != nil {
+		return err
+	}
+	if d.Type == TagMismatchDescriptionFailedUnexpectedly {
+		if len(tmpData) != 2 {
+			return fmt.Errorf(
+				"invalid TagMismatchDescription: unexpected list length %d",
+				len(tmpData),
+			)
+		}
+		if _, err := cbor.Decode(tmpData[1], &d.Failures); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *TagMismatchDescription) String() string {
+	if d.Type == TagMismatchDescriptionPassedUnexpectedly {
+		return "PassedUnexpectedly"
+	}
+	ret := "FailedUnexpectedly (["
+	for idx, failure := range d.Failures {
+		ret = fmt.Sprintf("%s%s", ret, failure.String())
+		if idx < (len(d.Failures) - 1) {
+			ret = fmt.Sprintf("%s, ", ret)
+		}
+	}
+	ret = fmt.Sprintf("%s])", ret)
+	return ret
+}
+
+// PlutusFailure contains the error message from a failed Plutus script, along with the data needed to
+// reproduce the failure
+type PlutusFailure struct {
+	cbor.StructAsArray
+	Type         uint8
+	Message      string
+	ReDebugBytes []byte
+}
+
+func (f *PlutusFailure) String() string {
+	return fmt.Sprintf("PlutusFailure (%s)", f.Message)
+}
+
+type CollectErrors struct {
+	UtxoFailureErrorBase
+	// TODO: determine content/structure of this value
+	Errors cbor.Value
+}
+
+func (e *CollectErrors) Error() string {
+	return fmt.Sprintf("CollectErrors (%v)", e.Errors.Value())
+}
+
+type UpdateFailure struct {
+	UtxoFailureErrorBase
+	// TODO: determine content/structure of this value
+	Err cbor.Value
+}
+
+func (e *UpdateFailure) Error() string {
+	return fmt.Sprintf("UpdateFailure (%v)", e.Err.Value())
+}
+
 type WrongNetwork struct {
 	UtxoFailureErrorBase
 	ExpectedNetworkId int
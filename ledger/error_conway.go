@@ -0,0 +1,1507 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ledger
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blinklabs-io/gouroboros/cbor"
+	"github.com/blinklabs-io/gouroboros/ledger/common"
+)
+
+// Conway era predicate failures
+//
+// The Conway ledger rule hierarchy is LEDGER -> (UTXOW -> UTXO -> UTXOS), (CERTS -> CERT -> (DELEG, POOL,
+// GOVCERT)) and GOV. Failures that wrap a failure from another rule are decoded recursively, and any unknown
+// failure falls back to GenericError
+
+const (
+	ConwayLedgerFailureUtxowFailure           = 1
+	ConwayLedgerFailureCertsFailure           = 2
+	ConwayLedgerFailureGovFailure             = 3
+	ConwayLedgerFailureWdrlNotDelegatedToDRep = 4
+	ConwayLedgerFailureTreasuryValueMismatch  = 5
+	ConwayLedgerFailureTxRefScriptsSizeTooBig = 6
+	ConwayLedgerFailureMempoolFailure         = 7
+
+	ConwayUtxowFailureUtxoFailure                     = 0
+	ConwayUtxowFailureInvalidWitnessesUtxow           = 1
+	ConwayUtxowFailureMissingVKeyWitnessesUtxow       = 2
+	ConwayUtxowFailureMissingScriptWitnessesUtxow     = 3
+	ConwayUtxowFailureScriptWitnessNotValidatingUtxow = 4
+	ConwayUtxowFailureMissingTxBodyMetadataHash       = 5
+	ConwayUtxowFailureMissingTxMetadata               = 6
+	ConwayUtxowFailureConflictingMetadataHash         = 7
+	ConwayUtxowFailureInvalidMetadata                 = 8
+	ConwayUtxowFailureExtraneousScriptWitnessesUtxow  = 9
+	ConwayUtxowFailureMissingRedeemers                = 10
+	ConwayUtxowFailureMissingRequiredDatums           = 11
+	ConwayUtxowFailureNotAllowedSupplementalDatums    = 12
+	ConwayUtxowFailurePPViewHashesDontMatch           = 13
+	ConwayUtxowFailureUnspendableUtxoNoDatumHash      = 14
+	ConwayUtxowFailureExtraRedeemers                  = 15
+	ConwayUtxowFailureMalformedScriptWitnesses        = 16
+	ConwayUtxowFailureMalformedReferenceScripts       = 17
+
+	ConwayUtxoFailureUtxosFailure                  = 0
+	ConwayUtxoFailureBadInputsUtxo                 = 1
+	ConwayUtxoFailureOutsideValidityIntervalUtxo   = 2
+	ConwayUtxoFailureMaxTxSizeUtxo                 = 3
+	ConwayUtxoFailureInputSetEmpty                 = 4
+	ConwayUtxoFailureFeeTooSmallUtxo               = 5
+	ConwayUtxoFailureValueNotConservedUtxo         = 6
+	ConwayUtxoFailureWrongNetwork                  = 7
+	ConwayUtxoFailureWrongNetworkWithdrawal        = 8
+	ConwayUtxoFailureOutputTooSmallUtxo            = 9
+	ConwayUtxoFailureOutputBootAddrAttrsTooBig     = 10
+	ConwayUtxoFailureOutputTooBigUtxo              = 11
+	ConwayUtxoFailureInsufficientCollateral        = 12
+	ConwayUtxoFailureScriptsNotPaidUtxo            = 13
+	ConwayUtxoFailureExUnitsTooBigUtxo             = 14
+	ConwayUtxoFailureCollateralContainsNonAda      = 15
+	ConwayUtxoFailureWrongNetworkInTxBody          = 16
+	ConwayUtxoFailureOutsideForecast               = 17
+	ConwayUtxoFailureTooManyCollateralInputs       = 18
+	ConwayUtxoFailureNoCollateralInputs            = 19
+	ConwayUtxoFailureIncorrectTotalCollateralField = 20
+	ConwayUtxoFailureBabbageOutputTooSmallUtxo     = 21
+	ConwayUtxoFailureBabbageNonDisjointRefInputs   = 22
+
+	ConwayCertsFailureWithdrawalsNotInRewardsCerts = 0
+	ConwayCertsFailureCertFailure                  = 1
+
+	ConwayCertFailureDelegFailure   = 1
+	ConwayCertFailurePoolFailure    = 2
+	ConwayCertFailureGovCertFailure = 3
+
+	ConwayDelegFailureIncorrectDepositDeleg                       = 1
+	ConwayDelegFailureStakeKeyRegisteredDeleg                     = 2
+	ConwayDelegFailureStakeKeyNotRegisteredDeleg                  = 3
+	ConwayDelegFailureStakeKeyHasNonZeroRewardAccountBalanceDeleg = 4
+	ConwayDelegFailureDelegateeDRepNotRegisteredDeleg             = 5
+	ConwayDelegFailureDelegateeStakePoolNotRegisteredDeleg        = 6
+
+	ConwayPoolFailureStakePoolNotRegisteredOnKeyPool   = 0
+	ConwayPoolFailureStakePoolRetirementWrongEpochPool = 1
+	ConwayPoolFailureStakePoolCostTooLowPool           = 3
+	ConwayPoolFailureWrongNetworkPool                  = 4
+	ConwayPoolFailurePoolMetadataHashTooBig            = 5
+
+	ConwayGovCertFailureDRepAlreadyRegistered          = 0
+	ConwayGovCertFailureDRepNotRegistered              = 1
+	ConwayGovCertFailureDRepIncorrectDeposit           = 2
+	ConwayGovCertFailureCommitteeHasPreviouslyResigned = 3
+	ConwayGovCertFailureDRepIncorrectRefund            = 4
+	ConwayGovCertFailureCommitteeIsUnknown             = 5
+
+	ConwayGovFailureGovActionsDoNotExist                       = 0
+	ConwayGovFailureMalformedProposal                          = 1
+	ConwayGovFailureProposalProcedureNetworkIdMismatch         = 2
+	ConwayGovFailureTreasuryWithdrawalsNetworkIdMismatch       = 3
+	ConwayGovFailureProposalDepositIncorrect                   = 4
+	ConwayGovFailureDisallowedVoters                           = 5
+	ConwayGovFailureConflictingCommitteeUpdate                 = 6
+	ConwayGovFailureExpirationEpochTooSmall                    = 7
+	ConwayGovFailureInvalidPrevGovActionId                     = 8
+	ConwayGovFailureVotingOnExpiredGovAction                   = 9
+	ConwayGovFailureProposalCantFollow                         = 10
+	ConwayGovFailureInvalidPolicyHash                          = 11
+	ConwayGovFailureDisallowedProposalDuringBootstrap          = 12
+	ConwayGovFailureDisallowedVotesDuringBootstrap             = 13
+	ConwayGovFailureVotersDoNotExist                           = 14
+	ConwayGovFailureZeroTreasuryWithdrawals                    = 15
+	ConwayGovFailureProposalReturnAccountDoesNotExist          = 16
+	ConwayGovFailureTreasuryWithdrawalReturnAccountsDoNotExist = 17
+	ConwayGovFailureUnelectedCommitteeVoters                   = 18
+)
+
+func (e *ApplyTxError) unmarshalConwayCbor(data []byte) error {
+	var tmpData []cbor.RawMessage
+	if _, err := cbor.Decode(data, &tmpData); err != nil {
+		return err
+	}
+	for _, failure := range tmpData {
+		newErr, err := newFailureFromCbor(
+			failure,
+			map[int]any{
+				ConwayLedgerFailureUtxowFailure:           &ConwayUtxowFailure{},
+				ConwayLedgerFailureCertsFailure:           &ConwayCertsFailure{},
+				ConwayLedgerFailureGovFailure:             &ConwayGovFailure{},
+				ConwayLedgerFailureWdrlNotDelegatedToDRep: &WdrlNotDelegatedToDRep{},
+				ConwayLedgerFailureTreasuryValueMismatch:  &TreasuryValueMismatch{},
+				ConwayLedgerFailureTxRefScriptsSizeTooBig: &TxRefScriptsSizeTooBig{},
+				ConwayLedgerFailureMempoolFailure:         &MempoolFailure{},
+			},
+		)
+		if err != nil {
+			return err
+		}
+		e.Failures = append(e.Failures, newErr)
+	}
+	return nil
+}
+
+// PredicateFailureErrorBase contains the failure tag that starts every predicate failure
+type PredicateFailureErrorBase struct {
+	cbor.StructAsArray
+	Type uint8
+}
+
+// formatList formats a list of items in the form [a, b, c]
+func formatList[T any](items []T, formatFunc func(T) string) string {
+	tmpItems := make([]string, 0, len(items))
+	for _, item := range items {
+		tmpItems = append(tmpItems, formatFunc(item))
+	}
+	return "[" + strings.Join(tmpItems, ", ") + "]"
+}
+
+func formatCredential(cred common.StakeCredential) string {
+	return cred.String()
+}
+
+func formatAddress(addr common.Address) string {
+	return addr.String()
+}
+
+func formatVoter(voter common.Voter) string {
+	var voterType string
+	switch voter.Type {
+	case common.VoterTypeConstitutionalCommitteeHotKeyHash:
+		voterType = "CommitteeVoter keyHash"
+	case common.VoterTypeConstitutionalCommitteeHotScriptHash:
+		voterType = "CommitteeVoter scriptHash"
+	case common.VoterTypeDRepKeyHash:
+		voterType = "DRepVoter keyHash"
+	case common.VoterTypeDRepScriptHash:
+		voterType = "DRepVoter scriptHash"
+	case common.VoterTypeStakingPoolKeyHash:
+		voterType = "StakePoolVoter"
+	default:
+		voterType = fmt.Sprintf("UnknownVoter (%d)", voter.Type)
+	}
+	return fmt.Sprintf("%s %x", voterType, voter.Hash)
+}
+
+func formatGovActionId(govActionId common.GovActionId) string {
+	return fmt.Sprintf(
+		"GovActionId (%x#%d)",
+		govActionId.TransactionId,
+		govActionId.GovActionIdx,
+	)
+}
+
+// VoterGovActionId is a voter and the governance action being voted on
+type VoterGovActionId struct {
+	cbor.StructAsArray
+	Voter       common.Voter
+	GovActionId common.GovActionId
+}
+
+func formatVoterGovActionId(v VoterGovActionId) string {
+	return fmt.Sprintf(
+		"(%s, %s)",
+		formatVoter(v.Voter),
+		formatGovActionId(v.GovActionId),
+	)
+}
+
+// ProtocolVersion is a major and minor protocol version
+type ProtocolVersion struct {
+	cbor.StructAsArray
+	Major uint
+	Minor uint
+}
+
+func (v ProtocolVersion) String() string {
+	return fmt.Sprintf("ProtVer (%d, %d)", v.Major, v.Minor)
+}
+
+// StrictMaybeHash is an optional hash, which is encoded as an empty list when not present
+type StrictMaybeHash []cbor.ByteString
+
+func (h StrictMaybeHash) String() string {
+	if len(h) == 0 {
+		return "SNothing"
+	}
+	return fmt.Sprintf("SJust %x", h[0].Bytes())
+}
+
+// StrictMaybeGovActionId is an optional governance action ID, which is encoded as an empty list when not present
+type StrictMaybeGovActionId []common.GovActionId
+
+func (g StrictMaybeGovActionId) String() string {
+	if len(g) == 0 {
+		return "SNothing"
+	}
+	return "SJust " + formatGovActionId(g[0])
+}
+
+func formatMaybeGovActionId(govActionId *common.GovActionId) string {
+	if govActionId == nil {
+		return "SNothing"
+	}
+	return "SJust " + formatGovActionId(*govActionId)
+}
+
+// formatMaybeHash formats an optional hash that's decoded as nil when not present
+func formatMaybeHash(hash []byte) string {
+	if hash == nil {
+		return "SNothing"
+	}
+	return fmt.Sprintf("SJust %x", hash)
+}
+
+func formatGovAction(govAction common.GovActionWrapper) string {
+	switch action := govAction.Action.(type) {
+	case *common.ParameterChangeGovAction:
+		return fmt.Sprintf(
+			"ParameterChange (PrevGovActionId %s, PolicyHash %s)",
+			formatMaybeGovActionId(action.ActionId),
+			formatMaybeHash(action.PolicyHash),
+		)
+	case *common.HardForkInitiationGovAction:
+		return fmt.Sprintf(
+			"HardForkInitiation (PrevGovActionId %s, ProtVer (%d, %d))",
+			formatMaybeGovActionId(action.ActionId),
+			action.ProtocolVersion.Major,
+			action.ProtocolVersion.Minor,
+		)
+	case *common.TreasuryWithdrawalGovAction:
+		withdrawals := make([]string, 0, len(action.Withdrawals))
+		for addr, amount := range action.Withdrawals {
+			withdrawals = append(
+				withdrawals,
+				fmt.Sprintf("(%s, %d)", addr.String(), amount),
+			)
+		}
+		// Map ordering is random, so sort for a stable error message
+		sort.Strings(withdrawals)
+		return fmt.Sprintf(
+			"TreasuryWithdrawals ([%s], PolicyHash %s)",
+			strings.Join(withdrawals, ", "),
+			formatMaybeHash(action.PolicyHash),
+		)
+	case *common.NoConfidenceGovAction:
+		return fmt.Sprintf(
+			"NoConfidence (PrevGovActionId %s)",
+			formatMaybeGovActionId(action.ActionId),
+		)
+	case *common.UpdateCommitteeGovAction:
+		return fmt.Sprintf(
+			"UpdateCommittee (PrevGovActionId %s)",
+			formatMaybeGovActionId(action.ActionId),
+		)
+	case *common.NewConstitutionGovAction:
+		return fmt.Sprintf(
+			"NewConstitution (PrevGovActionId %s)",
+			formatMaybeGovActionId(action.ActionId),
+		)
+	case *common.InfoGovAction:
+		return "InfoAction"
+	}
+	return fmt.Sprintf("UnknownGovAction (%d)", govAction.Type)
+}
+
+func formatProposalProcedure(proposal common.ProposalProcedure) string {
+	return fmt.Sprintf(
+		"ProposalProcedure (Deposit %d, ReturnAddr %s, GovAction %s, Anchor (Url %s, DataHash %x))",
+		proposal.Deposit,
+		proposal.RewardAccount.String(),
+		formatGovAction(proposal.GovAction),
+		proposal.Anchor.Url,
+		proposal.Anchor.DataHash,
+	)
+}
+
+// Plutus script purposes, which identify what a redeemer applies to
+const (
+	PlutusPurposeSpending   = 0
+	PlutusPurposeMinting    = 1
+	PlutusPurposeCertifying = 2
+	PlutusPurposeRewarding  = 3
+	PlutusPurposeVoting     = 4
+	PlutusPurposeProposing  = 5
+)
+
+func formatPlutusPurposeTag(tag uint8) string {
+	switch tag {
+	case PlutusPurposeSpending:
+		return "ConwaySpending"
+	case PlutusPurposeMinting:
+		return "ConwayMinting"
+	case PlutusPurposeCertifying:
+		return "ConwayCertifying"
+	case PlutusPurposeRewarding:
+		return "ConwayRewarding"
+	case PlutusPurposeVoting:
+		return "ConwayVoting"
+	case PlutusPurposeProposing:
+		return "ConwayProposing"
+	}
+	return fmt.Sprintf("UnknownPurpose (%d)", tag)
+}
+
+// PlutusPurposeIndex identifies a redeemer by its purpose and the index of the item it applies to within the
+// transaction
+type PlutusPurposeIndex struct {
+	cbor.StructAsArray
+	Tag   uint8
+	Index uint32
+}
+
+func (p PlutusPurposeIndex) String() string {
+	return fmt.Sprintf("%s %d", formatPlutusPurposeTag(p.Tag), p.Index)
+}
+
+// PlutusPurposeItem identifies a redeemer by its purpose and the item it applies to. The item is a TxIn for
+// spending, a policy ID (common.Blake2b224) for minting, a common.CertificateWrapper for certifying, a reward
+// account (common.Address) for rewarding, a common.Voter for voting, and a common.ProposalProcedure for
+// proposing
+type PlutusPurposeItem struct {
+	Tag  uint8
+	Item any
+}
+
+func (p *PlutusPurposeItem) UnmarshalCBOR(data []byte) error {
+	tmpData := struct {
+		cbor.StructAsArray
+		Tag  uint8
+		Item cbor.RawMessage
+	}{}
+	if _, err := cbor.Decode(data, &tmpData); err != nil {
+		return err
+	}
+	var err error
+	switch tmpData.Tag {
+	case PlutusPurposeSpending:
+		var item TxIn
+		_, err = cbor.Decode(tmpData.Item, &item)
+		p.Item = item
+	case PlutusPurposeMinting:
+		var item common.Blake2b224
+		_, err = cbor.Decode(tmpData.Item, &item)
+		p.Item = item
+	case PlutusPurposeCertifying:
+		var item common.CertificateWrapper
+		_, err = cbor.Decode(tmpData.Item, &item)
+		p.Item = item
+	case PlutusPurposeRewarding:
+		var item common.Address
+		_, err = cbor.Decode(tmpData.Item, &item)
+		p.Item = item
+	case PlutusPurposeVoting:
+		var item common.Voter
+		_, err = cbor.Decode(tmpData.Item, &item)
+		p.Item = item
+	case PlutusPurposeProposing:
+		var item common.ProposalProcedure
+		_, err = cbor.Decode(tmpData.Item, &item)
+		p.Item = item
+	default:
+		return fmt.Errorf("unknown plutus purpose: %d", tmpData.Tag)
+	}
+	if err != nil {
+		return err
+	}
+	p.Tag = tmpData.Tag
+	return nil
+}
+
+func (p PlutusPurposeItem) String() string {
+	var item string
+	switch v := p.Item.(type) {
+	case TxIn:
+		item = v.String()
+	case common.Blake2b224:
+		item = v.String()
+	case common.CertificateWrapper:
+		item = fmt.Sprintf("CertificateType %d", v.Type)
+	case common.Address:
+		item = v.String()
+	case common.Voter:
+		item = formatVoter(v)
+	case common.ProposalProcedure:
+		item = formatProposalProcedure(v)
+	default:
+		item = fmt.Sprintf("%v", v)
+	}
+	return fmt.Sprintf("%s (%s)", formatPlutusPurposeTag(p.Tag), item)
+}
+
+// MissingRedeemer is a script purpose without a redeemer and the hash of the script that needs it
+type MissingRedeemer struct {
+	cbor.StructAsArray
+	Purpose    PlutusPurposeItem
+	ScriptHash common.Blake2b224
+}
+
+func formatMissingRedeemer(r MissingRedeemer) string {
+	return fmt.Sprintf("(%s, %s)", r.Purpose.String(), r.ScriptHash.String())
+}
+
+// LEDGER rule failures
+
+type ConwayUtxowFailure struct {
+	PredicateFailureErrorBase
+	Err error
+}
+
+func (e *ConwayUtxowFailure) UnmarshalCBOR(data []byte) error {
+	var err error
+	e.Type, e.Err, err = decodeNestedFailure(
+		data,
+		map[int]any{
+			ConwayUtxowFailureUtxoFailure:                     &ConwayUtxoFailure{},
+			ConwayUtxowFailureInvalidWitnessesUtxow:           &InvalidWitnessesUtxow{},
+			ConwayUtxowFailureMissingVKeyWitnessesUtxow:       &MissingVKeyWitnessesUtxow{},
+			ConwayUtxowFailureMissingScriptWitnessesUtxow:     &MissingScriptWitnessesUtxow{},
+			ConwayUtxowFailureScriptWitnessNotValidatingUtxow: &ScriptWitnessNotValidatingUtxow{},
+			ConwayUtxowFailureMissingTxBodyMetadataHash:       &MissingTxBodyMetadataHash{},
+			ConwayUtxowFailureMissingTxMetadata:               &MissingTxMetadata{},
+			ConwayUtxowFailureConflictingMetadataHash:         &ConflictingMetadataHash{},
+			ConwayUtxowFailureInvalidMetadata:                 &InvalidMetadata{},
+			ConwayUtxowFailureExtraneousScriptWitnessesUtxow:  &ExtraneousScriptWitnessesUtxow{},
+			ConwayUtxowFailureMissingRedeemers:                &MissingRedeemers{},
+			ConwayUtxowFailureMissingRequiredDatums:           &MissingRequiredDatums{},
+			ConwayUtxowFailureNotAllowedSupplementalDatums:    &NotAllowedSupplementalDatums{},
+			ConwayUtxowFailurePPViewHashesDontMatch:           &PPViewHashesDontMatch{},
+			ConwayUtxowFailureUnspendableUtxoNoDatumHash:      &UnspendableUtxoNoDatumHash{},
+			ConwayUtxowFailureExtraRedeemers:                  &ExtraRedeemers{},
+			ConwayUtxowFailureMalformedScriptWitnesses:        &MalformedScriptWitnesses{},
+			ConwayUtxowFailureMalformedReferenceScripts:       &MalformedReferenceScripts{},
+		},
+	)
+	return err
+}
+
+func (e *ConwayUtxowFailure) Error() string {
+	return fmt.Sprintf("ConwayUtxowFailure (%s)", e.Err)
+}
+
+type ConwayCertsFailure struct {
+	PredicateFailureErrorBase
+	Err error
+}
+
+func (e *ConwayCertsFailure) UnmarshalCBOR(data []byte) error {
+	var err error
+	e.Type, e.Err, err = decodeNestedFailure(
+		data,
+		map[int]any{
+			ConwayCertsFailureWithdrawalsNotInRewardsCerts: &WithdrawalsNotInRewardsCerts{},
+			ConwayCertsFailureCertFailure:                  &ConwayCertFailure{},
+		},
+	)
+	return err
+}
+
+func (e *ConwayCertsFailure) Error() string {
+	return fmt.Sprintf("ConwayCertsFailure (%s)", e.Err)
+}
+
+type ConwayGovFailure struct {
+	PredicateFailureErrorBase
+	Err error
+}
+
+func (e *ConwayGovFailure) UnmarshalCBOR(data []byte) error {
+	var err error
+	e.Type, e.Err, err = decodeNestedFailure(
+		data,
+		map[int]any{
+			ConwayGovFailureGovActionsDoNotExist:                       &GovActionsDoNotExist{},
+			ConwayGovFailureMalformedProposal:                          &MalformedProposal{},
+			ConwayGovFailureProposalProcedureNetworkIdMismatch:         &ProposalProcedureNetworkIdMismatch{},
+			ConwayGovFailureTreasuryWithdrawalsNetworkIdMismatch:       &TreasuryWithdrawalsNetworkIdMismatch{},
+			ConwayGovFailureProposalDepositIncorrect:                   &ProposalDepositIncorrect{},
+			ConwayGovFailureDisallowedVoters:                           &DisallowedVoters{},
+			ConwayGovFailureConflictingCommitteeUpdate:                 &ConflictingCommitteeUpdate{},
+			ConwayGovFailureExpirationEpochTooSmall:                    &ExpirationEpochTooSmall{},
+			ConwayGovFailureInvalidPrevGovActionId:                     &InvalidPrevGovActionId{},
+			ConwayGovFailureVotingOnExpiredGovAction:                   &VotingOnExpiredGovAction{},
+			ConwayGovFailureProposalCantFollow:                         &ProposalCantFollow{},
+			ConwayGovFailureInvalidPolicyHash:                          &InvalidPolicyHash{},
+			ConwayGovFailureDisallowedProposalDuringBootstrap:          &DisallowedProposalDuringBootstrap{},
+			ConwayGovFailureDisallowedVotesDuringBootstrap:             &DisallowedVotesDuringBootstrap{},
+			ConwayGovFailureVotersDoNotExist:                           &VotersDoNotExist{},
+			ConwayGovFailureZeroTreasuryWithdrawals:                    &ZeroTreasuryWithdrawals{},
+			ConwayGovFailureProposalReturnAccountDoesNotExist:          &ProposalReturnAccountDoesNotExist{},
+			ConwayGovFailureTreasuryWithdrawalReturnAccountsDoNotExist: &TreasuryWithdrawalReturnAccountsDoNotExist{},
+			ConwayGovFailureUnelectedCommitteeVoters:                   &UnelectedCommitteeVoters{},
+		},
+	)
+	return err
+}
+
+func (e *ConwayGovFailure) Error() string {
+	return fmt.Sprintf("ConwayGovFailure (%s)", e.Err)
+}
+
+type WdrlNotDelegatedToDRep struct {
+	PredicateFailureErrorBase
+	KeyHashes []common.Blake2b224
+}
+
+func (e *WdrlNotDelegatedToDRep) Error() string {
+	return fmt.Sprintf(
+		"ConwayWdrlNotDelegatedToDRep (%s)",
+		formatList(e.KeyHashes, common.Blake2b224.String),
+	)
+}
+
+type TreasuryValueMismatch struct {
+	PredicateFailureErrorBase
+	Actual    uint64
+	Submitted uint64
+}
+
+func (e *TreasuryValueMismatch) Error() string {
+	return fmt.Sprintf(
+		"ConwayTreasuryValueMismatch (Actual %d, Submitted %d)",
+		e.Actual,
+		e.Submitted,
+	)
+}
+
+type TxRefScriptsSizeTooBig struct {
+	PredicateFailureErrorBase
+	ActualSize int
+	MaxSize    int
+}
+
+func (e *TxRefScriptsSizeTooBig) Error() string {
+	return fmt.Sprintf(
+		"ConwayTxRefScriptsSizeTooBig (ActualSize %d, MaxSize %d)",
+		e.ActualSize,
+		e.MaxSize,
+	)
+}
+
+type MempoolFailure struct {
+	PredicateFailureErrorBase
+	Message string
+}
+
+func (e *MempoolFailure) Error() string {
+	return fmt.Sprintf("ConwayMempoolFailure (%s)", e.Message)
+}
+
+// UTXOW rule failures
+
+type InvalidWitnessesUtxow struct {
+	PredicateFailureErrorBase
+	VKeys []cbor.ByteString
+}
+
+func (e *InvalidWitnessesUtxow) Error() string {
+	return fmt.Sprintf(
+		"InvalidWitnessesUTXOW (%s)",
+		formatList(
+			e.VKeys,
+			func(vkey cbor.ByteString) string {
+				return hex.EncodeToString(vkey.Bytes())
+			},
+		),
+	)
+}
+
+type MissingVKeyWitnessesUtxow struct {
+	PredicateFailureErrorBase
+	KeyHashes []common.Blake2b224
+}
+
+func (e *MissingVKeyWitnessesUtxow) Error() string {
+	return fmt.Sprintf(
+		"MissingVKeyWitnessesUTXOW (%s)",
+		formatList(e.KeyHashes, common.Blake2b224.String),
+	)
+}
+
+type MissingScriptWitnessesUtxow struct {
+	PredicateFailureErrorBase
+	ScriptHashes []common.Blake2b224
+}
+
+func (e *MissingScriptWitnessesUtxow) Error() string {
+	return fmt.Sprintf(
+		"MissingScriptWitnessesUTXOW (%s)",
+		formatList(e.ScriptHashes, common.Blake2b224.String),
+	)
+}
+
+type ScriptWitnessNotValidatingUtxow struct {
+	PredicateFailureErrorBase
+	ScriptHashes []common.Blake2b224
+}
+
+func (e *ScriptWitnessNotValidatingUtxow) Error() string {
+	return fmt.Sprintf(
+		"ScriptWitnessNotValidatingUTXOW (%s)",
+		formatList(e.ScriptHashes, common.Blake2b224.String),
+	)
+}
+
+type MissingTxBodyMetadataHash struct {
+	PredicateFailureErrorBase
+	Hash common.Blake2b256
+}
+
+func (e *MissingTxBodyMetadataHash) Error() string {
+	return fmt.Sprintf("MissingTxBodyMetadataHash (%s)", e.Hash.String())
+}
+
+type MissingTxMetadata struct {
+	PredicateFailureErrorBase
+	Hash common.Blake2b256
+}
+
+func (e *MissingTxMetadata) Error() string {
+	return fmt.Sprintf("MissingTxMetadata (%s)", e.Hash.String())
+}
+
+type ConflictingMetadataHash struct {
+	PredicateFailureErrorBase
+	Supplied common.Blake2b256
+	Expected common.Blake2b256
+}
+
+func (e *ConflictingMetadataHash) Error() string {
+	return fmt.Sprintf(
+		"ConflictingMetadataHash (Supplied %s, Expected %s)",
+		e.Supplied.String(),
+		e.Expected.String(),
+	)
+}
+
+type InvalidMetadata struct {
+	PredicateFailureErrorBase
+}
+
+func (e *InvalidMetadata) Error() string {
+	return "InvalidMetadata"
+}
+
+type ExtraneousScriptWitnessesUtxow struct {
+	PredicateFailureErrorBase
+	ScriptHashes []common.Blake2b224
+}
+
+func (e *ExtraneousScriptWitnessesUtxow) Error() string {
+	return fmt.Sprintf(
+		"ExtraneousScriptWitnessesUTXOW (%s)",
+		formatList(e.ScriptHashes, common.Blake2b224.String),
+	)
+}
+
+type MissingRedeemers struct {
+	PredicateFailureErrorBase
+	Redeemers []MissingRedeemer
+}
+
+func (e *MissingRedeemers) Error() string {
+	return fmt.Sprintf(
+		"MissingRedeemers (%s)",
+		formatList(e.Redeemers, formatMissingRedeemer),
+	)
+}
+
+type MissingRequiredDatums struct {
+	PredicateFailureErrorBase
+	Missing  []common.Blake2b256
+	Received []common.Blake2b256
+}
+
+func (e *MissingRequiredDatums) Error() string {
+	return fmt.Sprintf(
+		"MissingRequiredDatums (Missing %s, Received %s)",
+		formatList(e.Missing, common.Blake2b256.String),
+		formatList(e.Received, common.Blake2b256.String),
+	)
+}
+
+type NotAllowedSupplementalDatums struct {
+	PredicateFailureErrorBase
+	Unallowed  []common.Blake2b256
+	Acceptable []common.Blake2b256
+}
+
+func (e *NotAllowedSupplementalDatums) Error() string {
+	return fmt.Sprintf(
+		"NotAllowedSupplementalDatums (Unallowed %s, Acceptable %s)",
+		formatList(e.Unallowed, common.Blake2b256.String),
+		formatList(e.Acceptable, common.Blake2b256.String),
+	)
+}
+
+type PPViewHashesDontMatch struct {
+	PredicateFailureErrorBase
+	Supplied StrictMaybeHash
+	Expected StrictMaybeHash
+}
+
+func (e *PPViewHashesDontMatch) Error() string {
+	return fmt.Sprintf(
+		"PPViewHashesDontMatch (Supplied %s, Expected %s)",
+		e.Supplied.String(),
+		e.Expected.String(),
+	)
+}
+
+type UnspendableUtxoNoDatumHash struct {
+	PredicateFailureErrorBase
+	Inputs []TxIn
+}
+
+func (e *UnspendableUtxoNoDatumHash) Error() string {
+	return fmt.Sprintf(
+		"UnspendableUTxONoDatumHash (%s)",
+		formatList(
+			e.Inputs,
+			func(input TxIn) string {
+				return input.String()
+			},
+		),
+	)
+}
+
+type ExtraRedeemers struct {
+	PredicateFailureErrorBase
+	Redeemers []PlutusPurposeIndex
+}
+
+func (e *ExtraRedeemers) Error() string {
+	return fmt.Sprintf(
+		"ExtraRedeemers (%s)",
+		formatList(e.Redeemers, PlutusPurposeIndex.String),
+	)
+}
+
+type MalformedScriptWitnesses struct {
+	PredicateFailureErrorBase
+	ScriptHashes []common.Blake2b224
+}
+
+func (e *MalformedScriptWitnesses) Error() string {
+	return fmt.Sprintf(
+		"MalformedScriptWitnesses (%s)",
+		formatList(e.ScriptHashes, common.Blake2b224.String),
+	)
+}
+
+type MalformedReferenceScripts struct {
+	PredicateFailureErrorBase
+	ScriptHashes []common.Blake2b224
+}
+
+func (e *MalformedReferenceScripts) Error() string {
+	return fmt.Sprintf(
+		"MalformedReferenceScripts (%s)",
+		formatList(e.ScriptHashes, common.Blake2b224.String),
+	)
+}
+
+// UTXO rule failures
+//
+// Most of the Conway UTXO failures have the same structure as in earlier eras, so the existing types are
+// reused with the Conway failure tags
+
+type ConwayUtxoFailure struct {
+	PredicateFailureErrorBase
+	Err error
+}
+
+func (e *ConwayUtxoFailure) UnmarshalCBOR(data []byte) error {
+	var err error
+	e.Type, e.Err, err = decodeNestedFailure(
+		data,
+		map[int]any{
+			ConwayUtxoFailureUtxosFailure:                  &UtxosFailure{},
+			ConwayUtxoFailureBadInputsUtxo:                 &BadInputsUtxo{},
+			ConwayUtxoFailureOutsideValidityIntervalUtxo:   &OutsideValidityIntervalUtxo{},
+			ConwayUtxoFailureMaxTxSizeUtxo:                 &MaxTxSizeUtxo{},
+			ConwayUtxoFailureInputSetEmpty:                 &InputSetEmptyUtxo{},
+			ConwayUtxoFailureFeeTooSmallUtxo:               &FeeTooSmallUtxo{},
+			ConwayUtxoFailureValueNotConservedUtxo:         &ValueNotConservedUtxo{},
+			ConwayUtxoFailureWrongNetwork:                  &WrongNetwork{},
+			ConwayUtxoFailureWrongNetworkWithdrawal:        &WrongNetworkWithdrawal{},
+			ConwayUtxoFailureOutputTooSmallUtxo:            &OutputTooSmallUtxo{},
+			ConwayUtxoFailureOutputBootAddrAttrsTooBig:     &OutputBootAddrAttrsTooBig{},
+			ConwayUtxoFailureOutputTooBigUtxo:              &OutputTooBigUtxo{},
+			ConwayUtxoFailureInsufficientCollateral:        &InsufficientCollateral{},
+			ConwayUtxoFailureScriptsNotPaidUtxo:            &ScriptsNotPaidUtxo{},
+			ConwayUtxoFailureExUnitsTooBigUtxo:             &ExUnitsTooBigUtxo{},
+			ConwayUtxoFailureCollateralContainsNonAda:      &CollateralContainsNonADA{},
+			ConwayUtxoFailureWrongNetworkInTxBody:          &WrongNetworkInTxBody{},
+			ConwayUtxoFailureOutsideForecast:               &OutsideForecast{},
+			ConwayUtxoFailureTooManyCollateralInputs:       &TooManyCollateralInputs{},
+			ConwayUtxoFailureNoCollateralInputs:            &NoCollateralInputs{},
+			ConwayUtxoFailureIncorrectTotalCollateralField: &IncorrectTotalCollateralField{},
+			ConwayUtxoFailureBabbageOutputTooSmallUtxo:     &BabbageOutputTooSmallUtxo{},
+			ConwayUtxoFailureBabbageNonDisjointRefInputs:   &BabbageNonDisjointRefInputs{},
+		},
+	)
+	return err
+}
+
+func (e *ConwayUtxoFailure) Error() string {
+	return fmt.Sprintf("UtxoFailure (%s)", e.Err)
+}
+
+type IncorrectTotalCollateralField struct {
+	UtxoFailureErrorBase
+	BalanceComputed int64
+	TotalCollateral uint64
+}
+
+func (e *IncorrectTotalCollateralField) Error() string {
+	return fmt.Sprintf(
+		"IncorrectTotalCollateralField (BalanceComputed %d, TotalCollateral %d)",
+		e.BalanceComputed,
+		e.TotalCollateral,
+	)
+}
+
+type BabbageOutputTooSmallUtxo struct {
+	UtxoFailureErrorBase
+	Outputs []struct {
+		cbor.StructAsArray
+		Output    TxOut
+		MinAmount uint64
+	}
+}
+
+func (e *BabbageOutputTooSmallUtxo) Error() string {
+	ret := "BabbageOutputTooSmallUTxO (["
+	for idx, output := range e.Outputs {
+		ret = fmt.Sprintf(
+			"%s(Output (%s), MinAmount %d)",
+			ret,
+			output.Output.String(),
+			output.MinAmount,
+		)
+		if idx < (len(e.Outputs) - 1) {
+			ret = fmt.Sprintf("%s, ", ret)
+		}
+	}
+	ret = fmt.Sprintf("%s])", ret)
+	return ret
+}
+
+type BabbageNonDisjointRefInputs struct {
+	UtxoFailureErrorBase
+	Inputs []TxIn
+}
+
+func (e *BabbageNonDisjointRefInputs) Error() string {
+	return fmt.Sprintf(
+		"BabbageNonDisjointRefInputs (%s)",
+		formatList(
+			e.Inputs,
+			func(input TxIn) string {
+				return input.String()
+			},
+		),
+	)
+}
+
+// CERTS rule failures
+
+type WithdrawalsNotInRewardsCerts struct {
+	PredicateFailureErrorBase
+	Withdrawals map[*common.Address]uint64
+}
+
+func (e *WithdrawalsNotInRewardsCerts) Error() string {
+	withdrawals := make([]string, 0, len(e.Withdrawals))
+	for addr, amount := range e.Withdrawals {
+		withdrawals = append(
+			withdrawals,
+			fmt.Sprintf("(%s, %d)", addr.String(), amount),
+		)
+	}
+	return fmt.Sprintf(
+		"WithdrawalsNotInRewardsCERTS ([%s])",
+		strings.Join(withdrawals, ", "),
+	)
+}
+
+type ConwayCertFailure struct {
+	PredicateFailureErrorBase
+	Err error
+}
+
+func (e *ConwayCertFailure) UnmarshalCBOR(data []byte) error {
+	var err error
+	e.Type, e.Err, err = decodeNestedFailure(
+		data,
+		map[int]any{
+			ConwayCertFailureDelegFailure:   &ConwayDelegFailure{},
+			ConwayCertFailurePoolFailure:    &ConwayPoolFailure{},
+			ConwayCertFailureGovCertFailure: &ConwayGovCertFailure{},
+		},
+	)
+	return err
+}
+
+func (e *ConwayCertFailure) Error() string {
+	return fmt.Sprintf("CertFailure (%s)", e.Err)
+}
+
+// DELEG rule failures
+
+type ConwayDelegFailure struct {
+	PredicateFailureErrorBase
+	Err error
+}
+
+func (e *ConwayDelegFailure) UnmarshalCBOR(data []byte) error {
+	var err error
+	e.Type, e.Err, err = decodeNestedFailure(
+		data,
+		map[int]any{
+			ConwayDelegFailureIncorrectDepositDeleg:                       &IncorrectDepositDeleg{},
+			ConwayDelegFailureStakeKeyRegisteredDeleg:                     &StakeKeyRegisteredDeleg{},
+			ConwayDelegFailureStakeKeyNotRegisteredDeleg:                  &StakeKeyNotRegisteredDeleg{},
+			ConwayDelegFailureStakeKeyHasNonZeroRewardAccountBalanceDeleg: &StakeKeyHasNonZeroRewardAccountBalanceDeleg{},
+			ConwayDelegFailureDelegateeDRepNotRegisteredDeleg:             &DelegateeDRepNotRegisteredDeleg{},
+			ConwayDelegFailureDelegateeStakePoolNotRegisteredDeleg:        &DelegateeStakePoolNotRegisteredDeleg{},
+		},
+	)
+	return err
+}
+
+func (e *ConwayDelegFailure) Error() string {
+	return fmt.Sprintf("DelegFailure (%s)", e.Err)
+}
+
+type IncorrectDepositDeleg struct {
+	PredicateFailureErrorBase
+	Deposit uint64
+}
+
+func (e *IncorrectDepositDeleg) Error() string {
+	return fmt.Sprintf("IncorrectDepositDELEG (Deposit %d)", e.Deposit)
+}
+
+type StakeKeyRegisteredDeleg struct {
+	PredicateFailureErrorBase
+	Credential common.StakeCredential
+}
+
+func (e *StakeKeyRegisteredDeleg) Error() string {
+	return fmt.Sprintf(
+		"StakeKeyRegisteredDELEG (%s)",
+		formatCredential(e.Credential),
+	)
+}
+
+type StakeKeyNotRegisteredDeleg struct {
+	PredicateFailureErrorBase
+	Credential common.StakeCredential
+}
+
+func (e *StakeKeyNotRegisteredDeleg) Error() string {
+	return fmt.Sprintf(
+		"StakeKeyNotRegisteredDELEG (%s)",
+		formatCredential(e.Credential),
+	)
+}
+
+type StakeKeyHasNonZeroRewardAccountBalanceDeleg struct {
+	PredicateFailureErrorBase
+	Balance uint64
+}
+
+func (e *StakeKeyHasNonZeroRewardAccountBalanceDeleg) Error() string {
+	return fmt.Sprintf(
+		"StakeKeyHasNonZeroRewardAccountBalanceDELEG (Balance %d)",
+		e.Balance,
+	)
+}
+
+type DelegateeDRepNotRegisteredDeleg struct {
+	PredicateFailureErrorBase
+	Credential common.StakeCredential
+}
+
+func (e *DelegateeDRepNotRegisteredDeleg) Error() string {
+	return fmt.Sprintf(
+		"DelegateeDRepNotRegisteredDELEG (%s)",
+		formatCredential(e.Credential),
+	)
+}
+
+type DelegateeStakePoolNotRegisteredDeleg struct {
+	PredicateFailureErrorBase
+	PoolId common.PoolId
+}
+
+func (e *DelegateeStakePoolNotRegisteredDeleg) Error() string {
+	return fmt.Sprintf(
+		"DelegateeStakePoolNotRegisteredDELEG (%s)",
+		e.PoolId.String(),
+	)
+}
+
+// POOL rule failures
+
+type ConwayPoolFailure struct {
+	PredicateFailureErrorBase
+	Err error
+}
+
+func (e *ConwayPoolFailure) UnmarshalCBOR(data []byte) error {
+	var err error
+	e.Type, e.Err, err = decodeNestedFailure(
+		data,
+		map[int]any{
+			ConwayPoolFailureStakePoolNotRegisteredOnKeyPool:   &StakePoolNotRegisteredOnKeyPool{},
+			ConwayPoolFailureStakePoolRetirementWrongEpochPool: &StakePoolRetirementWrongEpochPool{},
+			ConwayPoolFailureStakePoolCostTooLowPool:           &StakePoolCostTooLowPool{},
+			ConwayPoolFailureWrongNetworkPool:                  &WrongNetworkPool{},
+			ConwayPoolFailurePoolMetadataHashTooBig:            &PoolMetadataHashTooBig{},
+		},
+	)
+	return err
+}
+
+func (e *ConwayPoolFailure) Error() string {
+	return fmt.Sprintf("PoolFailure (%s)", e.Err)
+}
+
+type StakePoolNotRegisteredOnKeyPool struct {
+	PredicateFailureErrorBase
+	PoolId common.PoolId
+}
+
+func (e *StakePoolNotRegisteredOnKeyPool) Error() string {
+	return fmt.Sprintf(
+		"StakePoolNotRegisteredOnKeyPOOL (%s)",
+		e.PoolId.String(),
+	)
+}
+
+type StakePoolRetirementWrongEpochPool struct {
+	PredicateFailureErrorBase
+	CurrentEpoch    uint64
+	RetirementEpoch uint64
+	MaxEpoch        uint64
+}
+
+func (e *StakePoolRetirementWrongEpochPool) Error() string {
+	return fmt.Sprintf(
+		"StakePoolRetirementWrongEpochPOOL (CurrentEpoch %d, RetirementEpoch %d, MaxEpoch %d)",
+		e.CurrentEpoch,
+		e.RetirementEpoch,
+		e.MaxEpoch,
+	)
+}
+
+type StakePoolCostTooLowPool struct {
+	PredicateFailureErrorBase
+	Supplied uint64
+	Minimum  uint64
+}
+
+func (e *StakePoolCostTooLowPool) Error() string {
+	return fmt.Sprintf(
+		"StakePoolCostTooLowPOOL (Supplied %d, Minimum %d)",
+		e.Supplied,
+		e.Minimum,
+	)
+}
+
+type WrongNetworkPool struct {
+	PredicateFailureErrorBase
+	ExpectedNetworkId uint8
+	ActualNetworkId   uint8
+	PoolId            common.PoolId
+}
+
+func (e *WrongNetworkPool) Error() string {
+	return fmt.Sprintf(
+		"WrongNetworkPOOL (ExpectedNetworkId %d, ActualNetworkId %d, PoolId %s)",
+		e.ExpectedNetworkId,
+		e.ActualNetworkId,
+		e.PoolId.String(),
+	)
+}
+
+type PoolMetadataHashTooBig struct {
+	PredicateFailureErrorBase
+	PoolId common.PoolId
+	Size   int
+}
+
+func (e *PoolMetadataHashTooBig) Error() string {
+	return fmt.Sprintf(
+		"PoolMedataHashTooBig (PoolId %s, Size %d)",
+		e.PoolId.String(),
+		e.Size,
+	)
+}
+
+// GOVCERT rule failures
+
+type ConwayGovCertFailure struct {
+	PredicateFailureErrorBase
+	Err error
+}
+
+func (e *ConwayGovCertFailure) UnmarshalCBOR(data []byte) error {
+	var err error
+	e.Type, e.Err, err = decodeNestedFailure(
+		data,
+		map[int]any{
+			ConwayGovCertFailureDRepAlreadyRegistered:          &DRepAlreadyRegistered{},
+			ConwayGovCertFailureDRepNotRegistered:              &DRepNotRegistered{},
+			ConwayGovCertFailureDRepIncorrectDeposit:           &DRepIncorrectDeposit{},
+			ConwayGovCertFailureCommitteeHasPreviouslyResigned: &CommitteeHasPreviouslyResigned{},
+			ConwayGovCertFailureDRepIncorrectRefund:            &DRepIncorrectRefund{},
+			ConwayGovCertFailureCommitteeIsUnknown:             &CommitteeIsUnknown{},
+		},
+	)
+	return err
+}
+
+func (e *ConwayGovCertFailure) Error() string {
+	return fmt.Sprintf("GovCertFailure (%s)", e.Err)
+}
+
+type DRepAlreadyRegistered struct {
+	PredicateFailureErrorBase
+	Credential common.StakeCredential
+}
+
+func (e *DRepAlreadyRegistered) Error() string {
+	return fmt.Sprintf(
+		"ConwayDRepAlreadyRegistered (%s)",
+		formatCredential(e.Credential),
+	)
+}
+
+type DRepNotRegistered struct {
+	PredicateFailureErrorBase
+	Credential common.StakeCredential
+}
+
+func (e *DRepNotRegistered) Error() string {
+	return fmt.Sprintf(
+		"ConwayDRepNotRegistered (%s)",
+		formatCredential(e.Credential),
+	)
+}
+
+type DRepIncorrectDeposit struct {
+	PredicateFailureErrorBase
+	Supplied uint64
+	Expected uint64
+}
+
+func (e *DRepIncorrectDeposit) Error() string {
+	return fmt.Sprintf(
+		"ConwayDRepIncorrectDeposit (Supplied %d, Expected %d)",
+		e.Supplied,
+		e.Expected,
+	)
+}
+
+type CommitteeHasPreviouslyResigned struct {
+	PredicateFailureErrorBase
+	Credential common.StakeCredential
+}
+
+func (e *CommitteeHasPreviouslyResigned) Error() string {
+	return fmt.Sprintf(
+		"ConwayCommitteeHasPreviouslyResigned (%s)",
+		formatCredential(e.Credential),
+	)
+}
+
+type DRepIncorrectRefund struct {
+	PredicateFailureErrorBase
+	Supplied uint64
+	Expected uint64
+}
+
+func (e *DRepIncorrectRefund) Error() string {
+	return fmt.Sprintf(
+		"ConwayDRepIncorrectRefund (Supplied %d, Expected %d)",
+		e.Supplied,
+		e.Expected,
+	)
+}
+
+type CommitteeIsUnknown struct {
+	PredicateFailureErrorBase
+	Credential common.StakeCredential
+}
+
+func (e *CommitteeIsUnknown) Error() string {
+	return fmt.Sprintf(
+		"ConwayCommitteeIsUnknown (%s)",
+		formatCredential(e.Credential),
+	)
+}
+
+// GOV rule failures
+
+type GovActionsDoNotExist struct {
+	PredicateFailureErrorBase
+	GovActionIds []common.GovActionId
+}
+
+func (e *GovActionsDoNotExist) Error() string {
+	return fmt.Sprintf(
+		"GovActionsDoNotExist (%s)",
+		formatList(e.GovActionIds, formatGovActionId),
+	)
+}
+
+type MalformedProposal struct {
+	PredicateFailureErrorBase
+	GovAction common.GovActionWrapper
+}
+
+func (e *MalformedProposal) Error() string {
+	return fmt.Sprintf("MalformedProposal (%s)", formatGovAction(e.GovAction))
+}
+
+type ProposalProcedureNetworkIdMismatch struct {
+	PredicateFailureErrorBase
+	RewardAccount common.Address
+	NetworkId     uint8
+}
+
+func (e *ProposalProcedureNetworkIdMismatch) Error() string {
+	return fmt.Sprintf(
+		"ProposalProcedureNetworkIdMismatch (RewardAccount %s, NetworkId %d)",
+		e.RewardAccount.String(),
+		e.NetworkId,
+	)
+}
+
+type TreasuryWithdrawalsNetworkIdMismatch struct {
+	PredicateFailureErrorBase
+	RewardAccounts []common.Address
+	NetworkId      uint8
+}
+
+func (e *TreasuryWithdrawalsNetworkIdMismatch) Error() string {
+	return fmt.Sprintf(
+		"TreasuryWithdrawalsNetworkIdMismatch (RewardAccounts %s, NetworkId %d)",
+		formatList(e.RewardAccounts, formatAddress),
+		e.NetworkId,
+	)
+}
+
+type ProposalDepositIncorrect struct {
+	PredicateFailureErrorBase
+	Supplied uint64
+	Expected uint64
+}
+
+func (e *ProposalDepositIncorrect) Error() string {
+	return fmt.Sprintf(
+		"ProposalDepositIncorrect (Supplied %d, Expected %d)",
+		e.Supplied,
+		e.Expected,
+	)
+}
+
+type DisallowedVoters struct {
+	PredicateFailureErrorBase
+	Votes []VoterGovActionId
+}
+
+func (e *DisallowedVoters) Error() string {
+	return fmt.Sprintf(
+		"DisallowedVoters (%s)",
+		formatList(e.Votes, formatVoterGovActionId),
+	)
+}
+
+type ConflictingCommitteeUpdate struct {
+	PredicateFailureErrorBase
+	Credentials []common.StakeCredential
+}
+
+func (e *ConflictingCommitteeUpdate) Error() string {
+	return fmt.Sprintf(
+		"ConflictingCommitteeUpdate (%s)",
+		formatList(e.Credentials, formatCredential),
+	)
+}
+
+type ExpirationEpochTooSmall struct {
+	PredicateFailureErrorBase
+	Members map[*common.StakeCredential]uint64
+}
+
+func (e *ExpirationEpochTooSmall) Error() string {
+	members := make([]string, 0, len(e.Members))
+	for cred, epoch := range e.Members {
+		members = append(
+			members,
+			fmt.Sprintf("(%s, Epoch %d)", formatCredential(*cred), epoch),
+		)
+	}
+	return fmt.Sprintf(
+		"ExpirationEpochTooSmall ([%s])",
+		strings.Join(members, ", "),
+	)
+}
+
+type InvalidPrevGovActionId struct {
+	PredicateFailureErrorBase
+	Proposal common.ProposalProcedure
+}
+
+func (e *InvalidPrevGovActionId) Error() string {
+	return fmt.Sprintf(
+		"InvalidPrevGovActionId (%s)",
+		formatProposalProcedure(e.Proposal),
+	)
+}
+
+type VotingOnExpiredGovAction struct {
+	PredicateFailureErrorBase
+	Votes []VoterGovActionId
+}
+
+func (e *VotingOnExpiredGovAction) Error() string {
+	return fmt.Sprintf(
+		"VotingOnExpiredGovAction (%s)",
+		formatList(e.Votes, formatVoterGovActionId),
+	)
+}
+
+type ProposalCantFollow struct {
+	PredicateFailureErrorBase
+	PrevGovActionId StrictMaybeGovActionId
+	Supplied        ProtocolVersion
+	Expected        ProtocolVersion
+}
+
+func (e *ProposalCantFollow) Error() string {
+	return fmt.Sprintf(
+		"ProposalCantFollow (PrevGovActionId %s, Supplied %s, Expected %s)",
+		e.PrevGovActionId.String(),
+		e.Supplied.String(),
+		e.Expected.String(),
+	)
+}
+
+type InvalidPolicyHash struct {
+	PredicateFailureErrorBase
+	Supplied StrictMaybeHash
+	Expected StrictMaybeHash
+}
+
+func (e *InvalidPolicyHash) Error() string {
+	return fmt.Sprintf(
+		"InvalidPolicyHash (Supplied %s, Expected %s)",
+		e.Supplied.String(),
+		e.Expected.String(),
+	)
+}
+
+type DisallowedProposalDuringBootstrap struct {
+	PredicateFailureErrorBase
+	Proposal common.ProposalProcedure
+}
+
+func (e *DisallowedProposalDuringBootstrap) Error() string {
+	return fmt.Sprintf(
+		"DisallowedProposalDuringBootstrap (%s)",
+		formatProposalProcedure(e.Proposal),
+	)
+}
+
+type DisallowedVotesDuringBootstrap struct {
+	PredicateFailureErrorBase
+	Votes []VoterGovActionId
+}
+
+func (e *DisallowedVotesDuringBootstrap) Error() string {
+	return fmt.Sprintf(
+		"DisallowedVotesDuringBootstrap (%s)",
+		formatList(e.Votes, formatVoterGovActionId),
+	)
+}
+
+type VotersDoNotExist struct {
+	PredicateFailureErrorBase
+	Voters []common.Voter
+}
+
+func (e *VotersDoNotExist) Error() string {
+	return fmt.Sprintf(
+		"VotersDoNotExist (%s)",
+		formatList(e.Voters, formatVoter),
+	)
+}
+
+type ZeroTreasuryWithdrawals struct {
+	PredicateFailureErrorBase
+	GovAction common.GovActionWrapper
+}
+
+func (e *ZeroTreasuryWithdrawals) Error() string {
+	return fmt.Sprintf(
+		"ZeroTreasuryWithdrawals (%s)",
+		formatGovAction(e.GovAction),
+	)
+}
+
+type ProposalReturnAccountDoesNotExist struct {
+	PredicateFailureErrorBase
+	RewardAccount common.Address
+}
+
+func (e *ProposalReturnAccountDoesNotExist) Error() string {
+	return fmt.Sprintf(
+		"ProposalReturnAccountDoesNotExist (%s)",
+		e.RewardAccount.String(),
+	)
+}
+
+type TreasuryWithdrawalReturnAccountsDoNotExist struct {
+	PredicateFailureErrorBase
+	RewardAccounts []common.Address
+}
+
+func (e *TreasuryWithdrawalReturnAccountsDoNotExist) Error() string {
+	return fmt.Sprintf(
+		"TreasuryWithdrawalReturnAccountsDoNotExist (%s)",
+		formatList(e.RewardAccounts, formatAddress),
+	)
+}
+
+type UnelectedCommitteeVoters struct {
+	PredicateFailureErrorBase
+	Credentials []common.StakeCredential
+}
+
+func (e *UnelectedCommitteeVoters) Error() string {
+	return fmt.Sprintf(
+		"UnelectedCommitteeVoters (%s)",
+		formatList(e.Credentials, formatCredential),
+	)
+}
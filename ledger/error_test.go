@@ -0,0 +1,192 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ledger_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/blinklabs-io/gouroboros/cbor"
+	"github.com/blinklabs-io/gouroboros/ledger"
+)
+
+func TestTxSubmitErrorFromCbor(t *testing.T) {
+	keyHash := bytes.Repeat([]byte{0xab}, 28)
+	txId := bytes.Repeat([]byte{0xcd}, 32)
+	// Testnet reward account for the key hash
+	rewardAccount := append([]byte{0xe0}, keyHash...)
+	proposal := []any{
+		100000,
+		rewardAccount,
+		[]any{0, []any{txId, 1}, map[uint]any{}, nil},
+		[]any{"https://example.com/proposal.json", bytes.Repeat([]byte{0xef}, 32)},
+	}
+	testDefs := []struct {
+		name        string
+		era         uint8
+		failures    []any
+		expectedErr string
+	}{
+		{
+			name: "ConwayProposalDepositIncorrect",
+			era:  ledger.EraIdConway,
+			failures: []any{
+				[]any{3, []any{4, 1000, 2000}},
+			},
+			expectedErr: "ShelleyTxValidationError ShelleyBasedEraConway (ApplyTxError ([ConwayGovFailure (ProposalDepositIncorrect (Supplied 1000, Expected 2000))]))",
+		},
+		{
+			name: "ConwayMissingVKeyWitnesses",
+			era:  ledger.EraIdConway,
+			failures: []any{
+				[]any{1, []any{2, cbor.Set{keyHash}}},
+			},
+			expectedErr: "ShelleyTxValidationError ShelleyBasedEraConway (ApplyTxError ([ConwayUtxowFailure (MissingVKeyWitnessesUTXOW ([abababababababababababababababababababababababababababab]))]))",
+		},
+		{
+			name: "ConwayDRepNotRegistered",
+			era:  ledger.EraIdConway,
+			failures: []any{
+				[]any{2, []any{1, []any{3, []any{1, []any{0, keyHash}}}}},
+			},
+			expectedErr: "ShelleyTxValidationError ShelleyBasedEraConway (ApplyTxError ([ConwayCertsFailure (CertFailure (GovCertFailure (ConwayDRepNotRegistered (keyHash-abababababababababababababababababababababababababababab))))]))",
+		},
+		{
+			name: "ConwayFeeTooSmallAndTreasuryValueMismatch",
+			era:  ledger.EraIdConway,
+			failures: []any{
+				[]any{1, []any{0, []any{5, 200000, 100000}}},
+				[]any{5, 10, 20},
+			},
+			expectedErr: "ShelleyTxValidationError ShelleyBasedEraConway (ApplyTxError ([ConwayUtxowFailure (UtxoFailure (FeeTooSmallUtxo (MinimumFee 200000, SuppliedFee 100000))), ConwayTreasuryValueMismatch (Actual 10, Submitted 20)]))",
+		},
+		{
+			name: "ConwayMissingAndExtraRedeemers",
+			era:  ledger.EraIdConway,
+			failures: []any{
+				[]any{
+					1,
+					[]any{
+						10,
+						[]any{
+							[]any{[]any{0, []any{txId, 1}}, keyHash},
+							[]any{[]any{4, []any{2, keyHash}}, keyHash},
+						},
+					},
+				},
+				[]any{1, []any{15, []any{[]any{1, 0}, []any{3, 2}}}},
+			},
+			expectedErr: "ShelleyTxValidationError ShelleyBasedEraConway (ApplyTxError ([ConwayUtxowFailure (MissingRedeemers ([(ConwaySpending (TxIn (Utxo cdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcd, TxIx 1)), abababababababababababababababababababababababababababab), (ConwayVoting (DRepVoter keyHash abababababababababababababababababababababababababababab), abababababababababababababababababababababababababababab)])), ConwayUtxowFailure (ExtraRedeemers ([ConwayMinting 0, ConwayRewarding 2]))]))",
+		},
+		{
+			name: "ConwayMalformedProposal",
+			era:  ledger.EraIdConway,
+			failures: []any{
+				[]any{3, []any{1, []any{3, []any{txId, 2}}}},
+			},
+			expectedErr: "ShelleyTxValidationError ShelleyBasedEraConway (ApplyTxError ([ConwayGovFailure (MalformedProposal (NoConfidence (PrevGovActionId SJust GovActionId (cdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcd#2))))]))",
+		},
+		{
+			name: "ConwayZeroTreasuryWithdrawals",
+			era:  ledger.EraIdConway,
+			failures: []any{
+				[]any{3, []any{15, []any{2, map[cbor.ByteString]uint64{cbor.NewByteString(rewardAccount): 0}, nil}}},
+			},
+			expectedErr: "ShelleyTxValidationError ShelleyBasedEraConway (ApplyTxError ([ConwayGovFailure (ZeroTreasuryWithdrawals (TreasuryWithdrawals ([(stake_test1uz46h2at4w46h2at4w46h2at4w46h2at4w46h2at4w46h2cwudutw, 0)], PolicyHash SNothing)))]))",
+		},
+		{
+			name: "ConwayInvalidPrevGovActionId",
+			era:  ledger.EraIdConway,
+			failures: []any{
+				[]any{3, []any{8, proposal}},
+			},
+			expectedErr: "ShelleyTxValidationError ShelleyBasedEraConway (ApplyTxError ([ConwayGovFailure (InvalidPrevGovActionId (ProposalProcedure (Deposit 100000, ReturnAddr stake_test1uz46h2at4w46h2at4w46h2at4w46h2at4w46h2at4w46h2cwudutw, GovAction ParameterChange (PrevGovActionId SJust GovActionId (cdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcd#1), PolicyHash SNothing), Anchor (Url https://example.com/proposal.json, DataHash efefefefefefefefefefefefefefefefefefefefefefefefefefefefefefefef))))]))",
+		},
+		{
+			name: "ConwayProposalCantFollow",
+			era:  ledger.EraIdConway,
+			failures: []any{
+				[]any{3, []any{10, []any{[]any{txId, 0}}, []any{11, 0}, []any{10, 0}}},
+				[]any{3, []any{10, []any{}, []any{11, 0}, []any{10, 0}}},
+			},
+			expectedErr: "ShelleyTxValidationError ShelleyBasedEraConway (ApplyTxError ([ConwayGovFailure (ProposalCantFollow (PrevGovActionId SJust GovActionId (cdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcd#0), Supplied ProtVer (11, 0), Expected ProtVer (10, 0))), ConwayGovFailure (ProposalCantFollow (PrevGovActionId SNothing, Supplied ProtVer (11, 0), Expected ProtVer (10, 0)))]))",
+		},
+		{
+			name: "ConwayDisallowedProposalDuringBootstrap",
+			era:  ledger.EraIdConway,
+			failures: []any{
+				[]any{3, []any{12, proposal}},
+			},
+			expectedErr: "ShelleyTxValidationError ShelleyBasedEraConway (ApplyTxError ([ConwayGovFailure (DisallowedProposalDuringBootstrap (ProposalProcedure (Deposit 100000, ReturnAddr stake_test1uz46h2at4w46h2at4w46h2at4w46h2at4w46h2at4w46h2cwudutw, GovAction ParameterChange (PrevGovActionId SJust GovActionId (cdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcd#1), PolicyHash SNothing), Anchor (Url https://example.com/proposal.json, DataHash efefefefefefefefefefefefefefefefefefefefefefefefefefefefefefefef))))]))",
+		},
+		{
+			name: "ConwayUnknownFailure",
+			era:  ledger.EraIdConway,
+			failures: []any{
+				[]any{99, 1},
+			},
+			expectedErr: "ShelleyTxValidationError ShelleyBasedEraConway (ApplyTxError ([GenericError ([99 1])]))",
+		},
+		{
+			name: "BabbageValidationTagMismatch",
+			era:  ledger.EraIdBabbage,
+			failures: []any{
+				[]any{
+					0,
+					[]any{
+						2,
+						[]any{
+							1,
+							[]any{
+								7,
+								[]any{
+									0,
+									false,
+									[]any{1, []any{[]any{1, "script failed", []byte{0x00}}}},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedErr: "ShelleyTxValidationError ShelleyBasedEraBabbage (ApplyTxError ([UtxowFailure (UtxoFailure (FromAlonzoUtxoFail (UtxosFailure (ValidationTagMismatch (IsValid false, FailedUnexpectedly ([PlutusFailure (script failed)]))))))]))",
+		},
+	}
+	for _, testDef := range testDefs {
+		errCbor, err := cbor.Encode(
+			[]any{
+				[]any{testDef.era, testDef.failures},
+			},
+		)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", testDef.name, err)
+		}
+		txErr, err := ledger.NewTxSubmitErrorFromCbor(errCbor)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", testDef.name, err)
+		}
+		if _, ok := txErr.(*ledger.ShelleyTxValidationError); !ok {
+			t.Fatalf("%s: did not get expected error type: %T", testDef.name, txErr)
+		}
+		if txErr.Error() != testDef.expectedErr {
+			t.Fatalf(
+				"%s: did not get expected error string\n  got:    %s\n  wanted: %s",
+				testDef.name,
+				txErr.Error(),
+				testDef.expectedErr,
+			)
+		}
+	}
+}
@@ -0,0 +1,551 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ledger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/blinklabs-io/gouroboros/cbor"
+	"github.com/blinklabs-io/gouroboros/ledger/babbage"
+	"github.com/blinklabs-io/gouroboros/ledger/common"
+	"github.com/blinklabs-io/gouroboros/ledger/mary"
+	"github.com/blinklabs-io/gouroboros/ledger/shelley"
+)
+
+const (
+	ScriptRefTypeNativeScript = 0
+	ScriptRefTypePlutusV1     = 1
+	ScriptRefTypePlutusV2     = 2
+	ScriptRefTypePlutusV3     = 3
+)
+
+// ScriptRef is a reference script attached to a transaction output
+type ScriptRef struct {
+	Type uint
+	// Script is the CBOR encoding of the script for native scripts, or the serialized script for Plutus scripts
+	Script []byte
+}
+
+// TransactionBuilderOutput describes a transaction output for a TransactionBuilder
+type TransactionBuilderOutput struct {
+	Address   common.Address
+	Amount    uint64
+	Assets    *common.MultiAsset[common.MultiAssetTypeOutput]
+	DatumHash *common.Blake2b256
+	// Datum is the CBOR encoding of an inline datum
+	Datum     []byte
+	ScriptRef *ScriptRef
+}
+
+// TransactionBuilder assembles a Babbage or Conway era transaction and encodes it as CBOR. The inputs,
+// reference inputs, collateral inputs and required signers are sorted, and map keys are sorted by cbor.Encode,
+// so the same transaction always produces the same encoding and body hash
+type TransactionBuilder struct {
+	txType                uint
+	inputs                []shelley.ShelleyTransactionInput
+	referenceInputs       []shelley.ShelleyTransactionInput
+	collateral            []shelley.ShelleyTransactionInput
+	outputs               []TransactionBuilderOutput
+	collateralReturn      *TransactionBuilderOutput
+	totalCollateral       *uint64
+	fee                   uint64
+	ttl                   *uint64
+	validityIntervalStart *uint64
+	certificates          []common.Certificate
+	withdrawals           map[*common.Address]uint64
+	mint                  map[common.Blake2b224]map[cbor.ByteString]int64
+	requiredSigners       []common.Blake2b224
+	auxDataHash           *common.Blake2b256
+	scriptDataHash        *common.Blake2b256
+	networkId             *uint8
+	votingProcedures      common.VotingProcedures
+	proposalProcedures    []common.ProposalProcedure
+	currentTreasuryValue  *int64
+	donation              uint64
+	vkeyWitnesses         []vkeyWitness
+}
+
+// NewTransactionBuilder returns a new TransactionBuilder for the specified transaction type, which must be
+// TxTypeBabbage or TxTypeConway
+func NewTransactionBuilder(txType uint) *TransactionBuilder {
+	return &TransactionBuilder{
+		txType: txType,
+	}
+}
+
+// AddInput adds a transaction input
+func (b *TransactionBuilder) AddInput(input TransactionInput) *TransactionBuilder {
+	b.inputs = append(b.inputs, newBuilderInput(input))
+	return b
+}
+
+// AddReferenceInput adds a reference input
+func (b *TransactionBuilder) AddReferenceInput(input TransactionInput) *TransactionBuilder {
+	b.referenceInputs = append(b.referenceInputs, newBuilderInput(input))
+	return b
+}
+
+// AddCollateral adds a collateral input
+func (b *TransactionBuilder) AddCollateral(input TransactionInput) *TransactionBuilder {
+	b.collateral = append(b.collateral, newBuilderInput(input))
+	return b
+}
+
+// AddOutput adds a transaction output. Outputs are encoded in the order that they are added
+func (b *TransactionBuilder) AddOutput(output TransactionBuilderOutput) *TransactionBuilder {
+	b.outputs = append(b.outputs, output)
+	return b
+}
+
+// SetCollateralReturn sets the output that receives the collateral change if script validation fails
+func (b *TransactionBuilder) SetCollateralReturn(output TransactionBuilderOutput) *TransactionBuilder {
+	b.collateralReturn = &output
+	return b
+}
+
+// SetTotalCollateral sets the total amount of collateral
+func (b *TransactionBuilder) SetTotalCollateral(amount uint64) *TransactionBuilder {
+	b.totalCollateral = &amount
+	return b
+}
+
+// SetFee sets the transaction fee
+func (b *TransactionBuilder) SetFee(fee uint64) *TransactionBuilder {
+	b.fee = fee
+	return b
+}
+
+// SetTTL sets the slot at which the transaction becomes invalid, which is the end of the validity interval
+func (b *TransactionBuilder) SetTTL(slot uint64) *TransactionBuilder {
+	b.ttl = &slot
+	return b
+}
+
+// SetValidityIntervalStart sets the first slot at which the transaction is valid
+func (b *TransactionBuilder) SetValidityIntervalStart(slot uint64) *TransactionBuilder {
+	b.validityIntervalStart = &slot
+	return b
+}
+
+// AddCertificate adds a certificate. The certificate type field must be populated
+func (b *TransactionBuilder) AddCertificate(cert common.Certificate) *TransactionBuilder {
+	b.certificates = append(b.certificates, cert)
+	return b
+}
+
+// AddWithdrawal adds a reward withdrawal from the specified reward address
+func (b *TransactionBuilder) AddWithdrawal(rewardAddress common.Address, amount uint64) *TransactionBuilder {
+	if b.withdrawals == nil {
+		b.withdrawals = make(map[*common.Address]uint64)
+	}
+	// Replace any existing withdrawal for the address, since duplicate map keys are not allowed
+	for tmpAddr := range b.withdrawals {
+		if bytes.Equal(tmpAddr.Bytes(), rewardAddress.Bytes()) {
+			b.withdrawals[tmpAddr] = amount
+			return b
+		}
+	}
+	b.withdrawals[&rewardAddress] = amount
+	return b
+}
+
+// AddMint adds the specified amount of an asset to the mint. A negative amount burns the asset
+func (b *TransactionBuilder) AddMint(policyId common.Blake2b224, assetName []byte, amount int64) *TransactionBuilder {
+	if b.mint == nil {
+		b.mint = make(map[common.Blake2b224]map[cbor.ByteString]int64)
+	}
+	if _, ok := b.mint[policyId]; !ok {
+		b.mint[policyId] = make(map[cbor.ByteString]int64)
+	}
+	b.mint[policyId][cbor.NewByteString(assetName)] += amount
+	return b
+}
+
+// AddRequiredSigner adds a key hash that must sign the transaction
+func (b *TransactionBuilder) AddRequiredSigner(keyHash common.Blake2b224) *TransactionBuilder {
+	b.requiredSigners = append(b.requiredSigners, keyHash)
+	return b
+}
+
+// SetAuxDataHash sets the hash of the auxiliary data
+func (b *TransactionBuilder) SetAuxDataHash(hash common.Blake2b256) *TransactionBuilder {
+	b.auxDataHash = &hash
+	return b
+}
+
+// SetScriptDataHash sets the hash of the redeemers, datums and cost models used by the transaction
+func (b *TransactionBuilder) SetScriptDataHash(hash common.Blake2b256) *TransactionBuilder {
+	b.scriptDataHash = &hash
+	return b
+}
+
+// SetNetworkId sets the network ID
+func (b *TransactionBuilder) SetNetworkId(networkId uint8) *TransactionBuilder {
+	b.networkId = &networkId
+	return b
+}
+
+// AddVote adds a voting procedure for the specified voter and governance action (Conway only)
+func (b *TransactionBuilder) AddVote(
+	voter common.Voter,
+	govActionId common.GovActionId,
+	procedure common.VotingProcedure,
+) *TransactionBuilder {
+	if b.votingProcedures == nil {
+		b.votingProcedures = make(common.VotingProcedures)
+	}
+	// Votes from the same voter must be grouped under a single map key
+	for tmpVoter, votes := range b.votingProcedures {
+		if *tmpVoter == voter {
+			for tmpActionId := range votes {
+				if *tmpActionId == govActionId {
+					votes[tmpActionId] = procedure
+					return b
+				}
+			}
+			votes[&govActionId] = procedure
+			return b
+		}
+	}
+	b.votingProcedures[&voter] = map[*common.GovActionId]common.VotingProcedure{
+		&govActionId: procedure,
+	}
+	return b
+}
+
+// AddProposal adds a proposal procedure. The governance action type field must be populated (Conway only)
+func (b *TransactionBuilder) AddProposal(proposal common.ProposalProcedure) *TransactionBuilder {
+	b.proposalProcedures = append(b.proposalProcedures, proposal)
+	return b
+}
+
+// SetCurrentTreasuryValue sets the expected current treasury value (Conway only)
+func (b *TransactionBuilder) SetCurrentTreasuryValue(amount int64) *TransactionBuilder {
+	b.currentTreasuryValue = &amount
+	return b
+}
+
+// SetDonation sets the amount donated to the treasury (Conway only)
+func (b *TransactionBuilder) SetDonation(amount uint64) *TransactionBuilder {
+	b.donation = amount
+	return b
+}
+
+// AddVkeyWitness adds a verification key witness. The signature should be made over the body hash
+func (b *TransactionBuilder) AddVkeyWitness(vkey []byte, signature []byte) *TransactionBuilder {
+	b.vkeyWitnesses = append(
+		b.vkeyWitnesses,
+		vkeyWitness{
+			Vkey:      vkey,
+			Signature: signature,
+		},
+	)
+	return b
+}
+
+// BodyCbor returns the CBOR encoding of the transaction body
+func (b *TransactionBuilder) BodyCbor() ([]byte, error) {
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+	body := builderTxBody{
+		Inputs:                sortInputs(b.inputs),
+		Fee:                   b.fee,
+		Ttl:                   b.ttl,
+		Withdrawals:           b.withdrawals,
+		AuxDataHash:           b.auxDataHash,
+		ValidityIntervalStart: b.validityIntervalStart,
+		ScriptDataHash:        b.scriptDataHash,
+		Collateral:            sortInputs(b.collateral),
+		NetworkId:             b.networkId,
+		TotalCollateral:       b.totalCollateral,
+		ReferenceInputs:       sortInputs(b.referenceInputs),
+		VotingProcedures:      b.votingProcedures,
+		ProposalProcedures:    b.proposalProcedures,
+		CurrentTreasuryValue:  b.currentTreasuryValue,
+		Donation:              b.donation,
+	}
+	body.Outputs = make([]builderTxOutput, 0, len(b.outputs))
+	for idx, output := range b.outputs {
+		tmpOutput, err := newBuilderOutput(output)
+		if err != nil {
+			return nil, fmt.Errorf("output %d: %w", idx, err)
+		}
+		body.Outputs = append(body.Outputs, tmpOutput)
+	}
+	if b.collateralReturn != nil {
+		tmpOutput, err := newBuilderOutput(*b.collateralReturn)
+		if err != nil {
+			return nil, fmt.Errorf("collateral return: %w", err)
+		}
+		body.CollateralReturn = &tmpOutput
+	}
+	for idx, cert := range b.certificates {
+		certCbor, err := b.encodeCertificate(cert)
+		if err != nil {
+			return nil, fmt.Errorf("certificate %d: %w", idx, err)
+		}
+		body.Certificates = append(body.Certificates, certCbor)
+	}
+	if mint := b.buildMint(); mint != nil {
+		body.Mint = mint
+	}
+	if len(b.requiredSigners) > 0 {
+		body.RequiredSigners = sortKeyHashes(b.requiredSigners)
+	}
+	return cbor.Encode(&body)
+}
+
+// BodyHash returns the hash of the transaction body, which is also the transaction ID
+func (b *TransactionBuilder) BodyHash() (common.Blake2b256, error) {
+	bodyCbor, err := b.BodyCbor()
+	if err != nil {
+		return common.Blake2b256{}, err
+	}
+	return common.Blake2b256Hash(bodyCbor), nil
+}
+
+// TransactionCbor returns the CBOR encoding of the full transaction, including the witness set
+func (b *TransactionBuilder) TransactionCbor() ([]byte, error) {
+	bodyCbor, err := b.BodyCbor()
+	if err != nil {
+		return nil, err
+	}
+	tx := builderTx{
+		Body:    cbor.RawMessage(bodyCbor),
+		IsValid: true,
+	}
+	tx.WitnessSet.VkeyWitnesses = b.vkeyWitnesses
+	return cbor.Encode(&tx)
+}
+
+// Build returns the decoded transaction
+func (b *TransactionBuilder) Build() (Transaction, error) {
+	txCbor, err := b.TransactionCbor()
+	if err != nil {
+		return nil, err
+	}
+	return NewTransactionFromCbor(b.txType, txCbor)
+}
+
+func (b *TransactionBuilder) validate() error {
+	if b.txType != TxTypeBabbage && b.txType != TxTypeConway {
+		return fmt.Errorf("unsupported transaction type: %d", b.txType)
+	}
+	if len(b.inputs) == 0 {
+		return errors.New("transaction must have at least one input")
+	}
+	if b.txType == TxTypeBabbage {
+		if len(b.votingProcedures) > 0 ||
+			len(b.proposalProcedures) > 0 ||
+			b.currentTreasuryValue != nil ||
+			b.donation > 0 {
+			return errors.New("governance features are not supported before the Conway era")
+		}
+	}
+	return nil
+}
+
+func (b *TransactionBuilder) encodeCertificate(cert common.Certificate) (cbor.RawMessage, error) {
+	certCbor, err := cbor.Encode(cert)
+	if err != nil {
+		return nil, err
+	}
+	certType, err := cbor.DecodeIdFromList(certCbor)
+	if err != nil {
+		return nil, err
+	}
+	switch b.txType {
+	case TxTypeBabbage:
+		if certType > common.CertificateTypeMoveInstantaneousRewards {
+			return nil, fmt.Errorf("certificate type %d is not supported before the Conway era", certType)
+		}
+	case TxTypeConway:
+		if certType == common.CertificateTypeGenesisKeyDelegation ||
+			certType == common.CertificateTypeMoveInstantaneousRewards {
+			return nil, fmt.Errorf("certificate type %d is not supported in the Conway era", certType)
+		}
+	}
+	// Make sure that the certificate decodes as the type it claims to be
+	var tmpCert common.CertificateWrapper
+	if _, err := cbor.Decode(certCbor, &tmpCert); err != nil {
+		return nil, err
+	}
+	return cbor.RawMessage(certCbor), nil
+}
+
+func (b *TransactionBuilder) buildMint() *common.MultiAsset[common.MultiAssetTypeMint] {
+	mint := make(map[common.Blake2b224]map[cbor.ByteString]int64)
+	for policyId, assets := range b.mint {
+		for assetName, amount := range assets {
+			// Minting and burning the same amount cancels out
+			if amount == 0 {
+				continue
+			}
+			if _, ok := mint[policyId]; !ok {
+				mint[policyId] = make(map[cbor.ByteString]int64)
+			}
+			mint[policyId][assetName] = amount
+		}
+	}
+	if len(mint) == 0 {
+		return nil
+	}
+	ret := common.NewMultiAsset[common.MultiAssetTypeMint](mint)
+	return &ret
+}
+
+func newBuilderInput(input TransactionInput) shelley.ShelleyTransactionInput {
+	return shelley.ShelleyTransactionInput{
+		TxId:        input.Id(),
+		OutputIndex: input.Index(),
+	}
+}
+
+// sortInputs returns a sorted copy of the inputs with duplicates removed, since inputs are encoded as a set
+func sortInputs(inputs []shelley.ShelleyTransactionInput) []shelley.ShelleyTransactionInput {
+	if len(inputs) == 0 {
+		return nil
+	}
+	ret := make([]shelley.ShelleyTransactionInput, 0, len(inputs))
+	seen := make(map[shelley.ShelleyTransactionInput]bool)
+	for _, input := range inputs {
+		if seen[input] {
+			continue
+		}
+		seen[input] = true
+		ret = append(ret, input)
+	}
+	sort.Slice(
+		ret,
+		func(i, j int) bool {
+			if cmp := bytes.Compare(ret[i].TxId.Bytes(), ret[j].TxId.Bytes()); cmp != 0 {
+				return cmp < 0
+			}
+			return ret[i].OutputIndex < ret[j].OutputIndex
+		},
+	)
+	return ret
+}
+
+// sortKeyHashes returns a sorted copy of the key hashes with duplicates removed
+func sortKeyHashes(keyHashes []common.Blake2b224) []common.Blake2b224 {
+	ret := make([]common.Blake2b224, 0, len(keyHashes))
+	seen := make(map[common.Blake2b224]bool)
+	for _, keyHash := range keyHashes {
+		if seen[keyHash] {
+			continue
+		}
+		seen[keyHash] = true
+		ret = append(ret, keyHash)
+	}
+	sort.Slice(
+		ret,
+		func(i, j int) bool {
+			return bytes.Compare(ret[i].Bytes(), ret[j].Bytes()) < 0
+		},
+	)
+	return ret
+}
+
+func newBuilderOutput(output TransactionBuilderOutput) (builderTxOutput, error) {
+	ret := builderTxOutput{
+		Address: output.Address,
+		Amount: mary.MaryTransactionOutputValue{
+			Amount: output.Amount,
+			Assets: output.Assets,
+		},
+	}
+	if output.DatumHash != nil && output.Datum != nil {
+		return ret, errors.New("output cannot have both a datum hash and an inline datum")
+	}
+	if output.DatumHash != nil {
+		ret.DatumOption = []any{babbage.DatumOptionTypeHash, output.DatumHash}
+	} else if output.Datum != nil {
+		ret.DatumOption = []any{
+			babbage.DatumOptionTypeData,
+			cbor.Tag{Number: cbor.CborTagCbor, Content: output.Datum},
+		}
+	}
+	if output.ScriptRef != nil {
+		var script any
+		switch output.ScriptRef.Type {
+		case ScriptRefTypeNativeScript:
+			script = cbor.RawMessage(output.ScriptRef.Script)
+		case ScriptRefTypePlutusV1, ScriptRefTypePlutusV2, ScriptRefTypePlutusV3:
+			script = output.ScriptRef.Script
+		default:
+			return ret, fmt.Errorf("unknown script type: %d", output.ScriptRef.Type)
+		}
+		scriptCbor, err := cbor.Encode([]any{output.ScriptRef.Type, script})
+		if err != nil {
+			return ret, err
+		}
+		ret.ScriptRef = &cbor.Tag{Number: cbor.CborTagCbor, Content: scriptCbor}
+	}
+	return ret, nil
+}
+
+// builderTxBody is used to encode a transaction body. The fields are the union of the Babbage and Conway
+// transaction body fields, and optional fields with a meaningful zero value use pointers
+type builderTxBody struct {
+	Inputs                []shelley.ShelleyTransactionInput             `cbor:"0,keyasint"`
+	Outputs               []builderTxOutput                             `cbor:"1,keyasint"`
+	Fee                   uint64                                        `cbor:"2,keyasint"`
+	Ttl                   *uint64                                       `cbor:"3,keyasint,omitempty"`
+	Certificates          []cbor.RawMessage                             `cbor:"4,keyasint,omitempty"`
+	Withdrawals           map[*common.Address]uint64                    `cbor:"5,keyasint,omitempty"`
+	AuxDataHash           *common.Blake2b256                            `cbor:"7,keyasint,omitempty"`
+	ValidityIntervalStart *uint64                                       `cbor:"8,keyasint,omitempty"`
+	Mint                  *common.MultiAsset[common.MultiAssetTypeMint] `cbor:"9,keyasint,omitempty"`
+	ScriptDataHash        *common.Blake2b256                            `cbor:"11,keyasint,omitempty"`
+	Collateral            []shelley.ShelleyTransactionInput             `cbor:"13,keyasint,omitempty"`
+	RequiredSigners       []common.Blake2b224                           `cbor:"14,keyasint,omitempty"`
+	NetworkId             *uint8                                        `cbor:"15,keyasint,omitempty"`
+	CollateralReturn      *builderTxOutput                              `cbor:"16,keyasint,omitempty"`
+	TotalCollateral       *uint64                                       `cbor:"17,keyasint,omitempty"`
+	ReferenceInputs       []shelley.ShelleyTransactionInput             `cbor:"18,keyasint,omitempty"`
+	VotingProcedures      common.VotingProcedures                       `cbor:"19,keyasint,omitempty"`
+	ProposalProcedures    []common.ProposalProcedure                    `cbor:"20,keyasint,omitempty"`
+	CurrentTreasuryValue  *int64                                        `cbor:"21,keyasint,omitempty"`
+	Donation              uint64                                        `cbor:"22,keyasint,omitempty"`
+}
+
+// builderTxOutput is used to encode a transaction output in the post-Alonzo map format
+type builderTxOutput struct {
+	Address     common.Address                  `cbor:"0,keyasint"`
+	Amount      mary.MaryTransactionOutputValue `cbor:"1,keyasint"`
+	DatumOption []any                           `cbor:"2,keyasint,omitempty"`
+	ScriptRef   *cbor.Tag                       `cbor:"3,keyasint,omitempty"`
+}
+
+type vkeyWitness struct {
+	cbor.StructAsArray
+	Vkey      []byte
+	Signature []byte
+}
+
+type builderTx struct {
+	cbor.StructAsArray
+	Body       cbor.RawMessage
+	WitnessSet struct {
+		VkeyWitnesses []vkeyWitness `cbor:"0,keyasint,omitempty"`
+	}
+	IsValid bool
+	AuxData any
+}
@@ -0,0 +1,519 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ledger_test
+
+import (
+	"bytes"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/blinklabs-io/gouroboros/cbor"
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/ledger/common"
+	"github.com/blinklabs-io/gouroboros/ledger/shelley"
+)
+
+func testHash28(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 28)
+}
+
+func testHash32(b byte) common.Blake2b256 {
+	return common.NewBlake2b256(bytes.Repeat([]byte{b}, 32))
+}
+
+func testInput(b byte, idx uint32) shelley.ShelleyTransactionInput {
+	return shelley.ShelleyTransactionInput{
+		TxId:        testHash32(b),
+		OutputIndex: idx,
+	}
+}
+
+func testAddress(t *testing.T, addr string) common.Address {
+	ret, err := common.NewAddress(addr)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return ret
+}
+
+func testRewardAddress(t *testing.T, b byte) common.Address {
+	addrCbor, err := cbor.Encode(append([]byte{0xe0}, testHash28(b)...))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var ret common.Address
+	if _, err := cbor.Decode(addrCbor, &ret); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return ret
+}
+
+func testStakeCredential(b byte) common.StakeCredential {
+	return common.StakeCredential{
+		CredType:   common.StakeCredentialTypeAddrKeyHash,
+		Credential: testHash28(b),
+	}
+}
+
+func testCertTypes(certs []common.Certificate) []uint {
+	ret := []uint{}
+	for _, cert := range certs {
+		certCbor, err := cbor.Encode(cert)
+		if err != nil {
+			panic(err)
+		}
+		certType, err := cbor.DecodeIdFromList(certCbor)
+		if err != nil {
+			panic(err)
+		}
+		ret = append(ret, uint(certType))
+	}
+	return ret
+}
+
+func TestTransactionBuilderConway(t *testing.T) {
+	outputAddr := testAddress(t, "addr1q8fv95d4g2599v3gzq7wnva34ykt4d2zerl0wyke36zml0neqj84x95mgp694rv8gfqy6u67ms38lx30texma843yd5qmvkqcz")
+	scriptAddr := testAddress(t, "addr1w8nz307k3sr60gu0e47cmajssy4fmld7u493a4xztjrll0cm9703s")
+	rewardAddr := testRewardAddress(t, 0x01)
+	policyId := common.NewBlake2b224(testHash28(0xaa))
+	datumHash := testHash32(0x33)
+	// Constr 0 [42]
+	inlineDatum := []byte{0xd8, 0x79, 0x9f, 0x18, 0x2a, 0xff}
+	outputAssets := common.NewMultiAsset[common.MultiAssetTypeOutput](
+		map[common.Blake2b224]map[cbor.ByteString]uint64{
+			policyId: {cbor.NewByteString([]byte("token")): 10},
+		},
+	)
+	anchor := &common.GovAnchor{
+		Url:      "https://example.com/anchor.json",
+		DataHash: testHash32(0x44),
+	}
+	drep := common.Drep{
+		Type:       common.DrepTypeAddrKeyHash,
+		Credential: testHash28(0x05),
+	}
+	certs := []common.Certificate{
+		&common.StakeRegistrationCertificate{
+			CertType:          common.CertificateTypeStakeRegistration,
+			StakeRegistration: testStakeCredential(0x01),
+		},
+		&common.StakeDeregistrationCertificate{
+			CertType:            common.CertificateTypeStakeDeregistration,
+			StakeDeregistration: testStakeCredential(0x01),
+		},
+		&common.StakeDelegationCertificate{
+			CertType:        common.CertificateTypeStakeDelegation,
+			StakeCredential: func() *common.StakeCredential { c := testStakeCredential(0x01); return &c }(),
+			PoolKeyHash:     common.PoolKeyHash(common.NewBlake2b224(testHash28(0x02))),
+		},
+		&common.PoolRegistrationCertificate{
+			CertType:      common.CertificateTypePoolRegistration,
+			Operator:      common.PoolKeyHash(common.NewBlake2b224(testHash28(0x02))),
+			VrfKeyHash:    common.VrfKeyHash(testHash32(0x03)),
+			Pledge:        1_000_000,
+			Cost:          340_000_000,
+			Margin:        cbor.Rat{Rat: big.NewRat(1, 100)},
+			RewardAccount: common.AddrKeyHash(common.NewBlake2b224(testHash28(0x01))),
+			PoolOwners:    []common.AddrKeyHash{common.AddrKeyHash(common.NewBlake2b224(testHash28(0x01)))},
+			Relays: []common.PoolRelay{
+				{
+					Type: common.PoolRelayTypeSingleHostAddress,
+					Port: func() *uint32 { v := uint32(3001); return &v }(),
+					Ipv4: func() *net.IP { v := net.ParseIP("1.2.3.4"); return &v }(),
+				},
+				{
+					Type:     common.PoolRelayTypeSingleHostName,
+					Port:     func() *uint32 { v := uint32(3001); return &v }(),
+					Hostname: func() *string { v := "relay.example.com"; return &v }(),
+				},
+				{
+					Type:     common.PoolRelayTypeMultiHostName,
+					Hostname: func() *string { v := "example.com"; return &v }(),
+				},
+			},
+			PoolMetadata: &common.PoolMetadata{
+				Url:  "https://example.com/pool.json",
+				Hash: common.PoolMetadataHash(testHash32(0x04)),
+			},
+		},
+		&common.PoolRetirementCertificate{
+			CertType:    common.CertificateTypePoolRetirement,
+			PoolKeyHash: common.PoolKeyHash(common.NewBlake2b224(testHash28(0x02))),
+			Epoch:       500,
+		},
+		&common.RegistrationCertificate{
+			CertType:        common.CertificateTypeRegistration,
+			StakeCredential: testStakeCredential(0x01),
+			Amount:          2_000_000,
+		},
+		&common.DeregistrationCertificate{
+			CertType:        common.CertificateTypeDeregistration,
+			StakeCredential: testStakeCredential(0x01),
+			Amount:          2_000_000,
+		},
+		&common.VoteDelegationCertificate{
+			CertType:        common.CertificateTypeVoteDelegation,
+			StakeCredential: testStakeCredential(0x01),
+			Drep:            drep,
+		},
+		&common.StakeVoteDelegationCertificate{
+			CertType:        common.CertificateTypeStakeVoteDelegation,
+			StakeCredential: testStakeCredential(0x01),
+			PoolKeyHash:     testHash28(0x02),
+			Drep:            common.Drep{Type: common.DrepTypeAbstain},
+		},
+		&common.StakeRegistrationDelegationCertificate{
+			CertType:        common.CertificateTypeStakeRegistrationDelegation,
+			StakeCredential: testStakeCredential(0x01),
+			PoolKeyHash:     testHash28(0x02),
+			Amount:          2_000_000,
+		},
+		&common.VoteRegistrationDelegationCertificate{
+			CertType:        common.CertificateTypeVoteRegistrationDelegation,
+			StakeCredential: testStakeCredential(0x01),
+			Drep:            common.Drep{Type: common.DrepTypeNoConfidence},
+			Amount:          2_000_000,
+		},
+		&common.StakeVoteRegistrationDelegationCertificate{
+			CertType:        common.CertificateTypeStakeVoteRegistrationDelegation,
+			StakeCredential: testStakeCredential(0x01),
+			PoolKeyHash:     testHash28(0x02),
+			Drep:            drep,
+			Amount:          2_000_000,
+		},
+		&common.AuthCommitteeHotCertificate{
+			CertType:       common.CertificateTypeAuthCommitteeHot,
+			ColdCredential: testStakeCredential(0x06),
+			HostCredential: testStakeCredential(0x07),
+		},
+		&common.ResignCommitteeColdCertificate{
+			CertType:       common.CertificateTypeResignCommitteeCold,
+			ColdCredential: testStakeCredential(0x06),
+			Anchor:         anchor,
+		},
+		&common.RegistrationDrepCertificate{
+			CertType:       common.CertificateTypeRegistrationDrep,
+			DrepCredential: testStakeCredential(0x05),
+			Amount:         500_000_000,
+			Anchor:         anchor,
+		},
+		&common.DeregistrationDrepCertificate{
+			CertType:       common.CertificateTypeDeregistrationDrep,
+			DrepCredential: testStakeCredential(0x05),
+			Amount:         500_000_000,
+		},
+		&common.UpdateDrepCertificate{
+			CertType:       common.CertificateTypeUpdateDrep,
+			DrepCredential: testStakeCredential(0x05),
+		},
+	}
+	govActionId := common.GovActionId{
+		TransactionId: testHash32(0x55),
+		GovActionIdx:  1,
+	}
+	voter := common.Voter{
+		Type: common.VoterTypeDRepKeyHash,
+		Hash: [28]byte(testHash28(0x05)),
+	}
+
+	builder := ledger.NewTransactionBuilder(ledger.TxTypeConway).
+		// Inputs are added out of order and with a duplicate
+		AddInput(testInput(0x02, 0)).
+		AddInput(testInput(0x01, 1)).
+		AddInput(testInput(0x01, 0)).
+		AddInput(testInput(0x02, 0)).
+		AddReferenceInput(testInput(0x03, 0)).
+		AddCollateral(testInput(0x04, 0)).
+		AddOutput(
+			ledger.TransactionBuilderOutput{
+				Address: outputAddr,
+				Amount:  5_000_000,
+				Assets:  &outputAssets,
+			},
+		).
+		AddOutput(
+			ledger.TransactionBuilderOutput{
+				Address: scriptAddr,
+				Amount:  2_000_000,
+				Datum:   inlineDatum,
+				ScriptRef: &ledger.ScriptRef{
+					Type:   ledger.ScriptRefTypePlutusV3,
+					Script: []byte{0x01, 0x02, 0x03},
+				},
+			},
+		).
+		AddOutput(
+			ledger.TransactionBuilderOutput{
+				Address:   scriptAddr,
+				Amount:    2_000_000,
+				DatumHash: &datumHash,
+			},
+		).
+		SetCollateralReturn(
+			ledger.TransactionBuilderOutput{
+				Address: outputAddr,
+				Amount:  4_000_000,
+			},
+		).
+		SetTotalCollateral(1_000_000).
+		SetFee(200_000).
+		SetValidityIntervalStart(1000).
+		SetTTL(2000).
+		// Repeated withdrawals and votes replace the earlier entry
+		AddWithdrawal(rewardAddr, 1_000_000).
+		AddWithdrawal(rewardAddr, 1_500_000).
+		AddMint(policyId, []byte("token"), 10).
+		AddMint(policyId, []byte("burned"), -5).
+		AddMint(policyId, []byte("cancelled"), 5).
+		AddMint(policyId, []byte("cancelled"), -5).
+		AddRequiredSigner(common.NewBlake2b224(testHash28(0x09))).
+		AddRequiredSigner(common.NewBlake2b224(testHash28(0x08))).
+		SetAuxDataHash(testHash32(0x66)).
+		SetScriptDataHash(testHash32(0x77)).
+		SetNetworkId(1).
+		AddVote(voter, govActionId, common.VotingProcedure{Vote: common.GovVoteNo}).
+		AddVote(voter, govActionId, common.VotingProcedure{Vote: common.GovVoteYes, Anchor: anchor}).
+		AddProposal(
+			common.ProposalProcedure{
+				Deposit:       100_000_000_000,
+				RewardAccount: rewardAddr,
+				GovAction: common.GovActionWrapper{
+					Type:   common.GovActionTypeInfo,
+					Action: &common.InfoGovAction{Type: common.GovActionTypeInfo},
+				},
+				Anchor: *anchor,
+			},
+		).
+		SetCurrentTreasuryValue(1_000_000_000).
+		SetDonation(1_000_000).
+		AddVkeyWitness(bytes.Repeat([]byte{0x0a}, 32), bytes.Repeat([]byte{0x0b}, 64))
+	for _, cert := range certs {
+		builder.AddCertificate(cert)
+	}
+
+	txCbor, err := builder.TransactionCbor()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tx, err := ledger.NewConwayTransactionFromCbor(txCbor)
+	if err != nil {
+		t.Fatalf("unexpected error decoding transaction: %s", err)
+	}
+	// The decoded transaction encodes to the same CBOR as the builder
+	bodyCbor, err := builder.BodyCbor()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !bytes.Equal(tx.Body.Cbor(), bodyCbor) {
+		t.Fatalf("decoded body CBOR does not match:\n  got:    %x\n  wanted: %x", tx.Body.Cbor(), bodyCbor)
+	}
+	if !bytes.Equal(tx.Cbor(), txCbor) {
+		t.Fatalf("decoded transaction CBOR does not match:\n  got:    %x\n  wanted: %x", tx.Cbor(), txCbor)
+	}
+	bodyHash, err := builder.BodyHash()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if tx.Hash() != bodyHash.String() || bodyHash != common.Blake2b256Hash(bodyCbor) {
+		t.Fatalf("did not get expected body hash: got %s, wanted %s", bodyHash.String(), tx.Hash())
+	}
+	// Check decoded fields
+	expectedInputs := []string{
+		testInput(0x01, 0).String(),
+		testInput(0x01, 1).String(),
+		testInput(0x02, 0).String(),
+	}
+	inputs := []string{}
+	for _, input := range tx.Inputs() {
+		inputs = append(inputs, input.(shelley.ShelleyTransactionInput).String())
+	}
+	if !reflect.DeepEqual(inputs, expectedInputs) {
+		t.Fatalf("did not get expected inputs: got %v, wanted %v", inputs, expectedInputs)
+	}
+	if len(tx.ReferenceInputs()) != 1 || len(tx.Collateral()) != 1 {
+		t.Fatalf("did not get expected reference and collateral inputs")
+	}
+	outputs := tx.Outputs()
+	if len(outputs) != 3 {
+		t.Fatalf("did not get expected number of outputs: got %d, wanted %d", len(outputs), 3)
+	}
+	if outputs[0].Address().String() != outputAddr.String() ||
+		outputs[0].Amount() != 5_000_000 ||
+		outputs[0].Assets().Asset(policyId, []byte("token")) != 10 {
+		t.Fatalf("did not get expected first output: %#v", outputs[0])
+	}
+	if outputs[1].Datum() == nil || !bytes.Equal(outputs[1].Datum().Cbor(), inlineDatum) {
+		t.Fatalf("did not get expected inline datum")
+	}
+	if tx.Body.TxOutputs[1].ScriptRef == nil {
+		t.Fatalf("did not get expected script ref")
+	}
+	if *outputs[2].DatumHash() != datumHash {
+		t.Fatalf("did not get expected datum hash")
+	}
+	if tx.CollateralReturn().Amount() != 4_000_000 || tx.TotalCollateral() != 1_000_000 {
+		t.Fatalf("did not get expected collateral return and total collateral")
+	}
+	if tx.Fee() != 200_000 || tx.TTL() != 2000 || tx.ValidityIntervalStart() != 1000 {
+		t.Fatalf("did not get expected fee and validity interval")
+	}
+	expectedCertTypes := testCertTypes(certs)
+	certTypes := []uint{}
+	for _, cert := range tx.Body.TxCertificates {
+		certTypes = append(certTypes, cert.Type)
+	}
+	if !reflect.DeepEqual(certTypes, expectedCertTypes) {
+		t.Fatalf("did not get expected certificates: got %v, wanted %v", certTypes, expectedCertTypes)
+	}
+	poolReg := tx.Body.TxCertificates[3].Certificate.(*common.PoolRegistrationCertificate)
+	if len(poolReg.Relays) != 3 ||
+		poolReg.Relays[0].Ipv4.String() != "1.2.3.4" ||
+		*poolReg.Relays[1].Hostname != "relay.example.com" {
+		t.Fatalf("did not get expected pool relays: %#v", poolReg.Relays)
+	}
+	if len(tx.Withdrawals()) != 1 {
+		t.Fatalf("did not get expected number of withdrawals: got %d, wanted %d", len(tx.Withdrawals()), 1)
+	}
+	for addr, amount := range tx.Withdrawals() {
+		if addr.String() != rewardAddr.String() || amount != 1_500_000 {
+			t.Fatalf("did not get expected withdrawal: %s %d", addr.String(), amount)
+		}
+	}
+	mint := tx.AssetMint()
+	if mint == nil ||
+		mint.Asset(policyId, []byte("token")) != 10 ||
+		mint.Asset(policyId, []byte("burned")) != -5 ||
+		len(mint.Assets(policyId)) != 2 {
+		t.Fatalf("did not get expected mint")
+	}
+	requiredSigners := tx.RequiredSigners()
+	if len(requiredSigners) != 2 || requiredSigners[0] != common.NewBlake2b224(testHash28(0x08)) {
+		t.Fatalf("did not get expected required signers: %v", requiredSigners)
+	}
+	if *tx.AuxDataHash() != testHash32(0x66) || *tx.ScriptDataHash() != testHash32(0x77) || tx.Body.NetworkId != 1 {
+		t.Fatalf("did not get expected hashes and network ID")
+	}
+	if len(tx.VotingProcedures()) != 1 {
+		t.Fatalf("did not get expected voting procedures")
+	}
+	for tmpVoter, votes := range tx.VotingProcedures() {
+		if *tmpVoter != voter || len(votes) != 1 {
+			t.Fatalf("did not get expected votes")
+		}
+		for tmpActionId, vote := range votes {
+			if *tmpActionId != govActionId || vote.Vote != common.GovVoteYes {
+				t.Fatalf("did not get expected vote")
+			}
+		}
+	}
+	proposals := tx.ProposalProcedures()
+	if len(proposals) != 1 || proposals[0].GovAction.Type != common.GovActionTypeInfo || proposals[0].Deposit != 100_000_000_000 {
+		t.Fatalf("did not get expected proposal procedures")
+	}
+	if tx.CurrentTreasuryValue() != 1_000_000_000 || tx.Donation() != 1_000_000 {
+		t.Fatalf("did not get expected treasury value and donation")
+	}
+	if len(tx.WitnessSet.VkeyWitnesses) != 1 || !tx.IsValid() {
+		t.Fatalf("did not get expected witness set")
+	}
+	// The generic Build function returns the same transaction
+	builtTx, err := builder.Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if builtTx.Hash() != tx.Hash() {
+		t.Fatalf("did not get expected transaction hash: got %s, wanted %s", builtTx.Hash(), tx.Hash())
+	}
+}
+
+func TestTransactionBuilderBabbage(t *testing.T) {
+	outputAddr := testAddress(t, "addr1v887yfpftg5z660dmf063hj0zv0zh8xjrfkfyd2e07j076cecha5k")
+	builder := ledger.NewTransactionBuilder(ledger.TxTypeBabbage).
+		AddInput(testInput(0x01, 0)).
+		AddOutput(
+			ledger.TransactionBuilderOutput{
+				Address: outputAddr,
+				Amount:  1_000_000,
+				ScriptRef: &ledger.ScriptRef{
+					Type: ledger.ScriptRefTypeNativeScript,
+					// [0, <key hash>]
+					Script: append([]byte{0x82, 0x00, 0x58, 0x1c}, testHash28(0x01)...),
+				},
+			},
+		).
+		SetFee(170_000).
+		AddCertificate(
+			&common.GenesisKeyDelegationCertificate{
+				CertType:            common.CertificateTypeGenesisKeyDelegation,
+				GenesisHash:         testHash28(0x01),
+				GenesisDelegateHash: testHash28(0x02),
+				VrfKeyHash:          common.VrfKeyHash(testHash32(0x03)),
+			},
+		).
+		AddCertificate(
+			&common.MoveInstantaneousRewardsCertificate{
+				CertType: common.CertificateTypeMoveInstantaneousRewards,
+				Reward: common.MoveInstantaneousRewardsCertificateReward{
+					Source:   uint(common.MirSourceTreasury),
+					OtherPot: 1_000_000,
+				},
+			},
+		)
+	tx, err := builder.Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	babbageTx, ok := tx.(*ledger.BabbageTransaction)
+	if !ok {
+		t.Fatalf("did not get expected transaction type: %T", tx)
+	}
+	if len(babbageTx.Certificates()) != 2 || babbageTx.Fee() != 170_000 {
+		t.Fatalf("did not get expected transaction")
+	}
+	mirCert := babbageTx.Body.TxCertificates[1].Certificate.(*common.MoveInstantaneousRewardsCertificate)
+	if mirCert.Reward.Source != uint(common.MirSourceTreasury) || mirCert.Reward.OtherPot != 1_000_000 {
+		t.Fatalf("did not get expected MIR certificate: %#v", mirCert.Reward)
+	}
+	// Babbage transactions don't support Conway features
+	_, err = ledger.NewTransactionBuilder(ledger.TxTypeBabbage).
+		AddInput(testInput(0x01, 0)).
+		SetDonation(1_000_000).
+		BodyCbor()
+	if err == nil {
+		t.Fatalf("did not get expected error for donation in Babbage transaction")
+	}
+	_, err = ledger.NewTransactionBuilder(ledger.TxTypeBabbage).
+		AddInput(testInput(0x01, 0)).
+		AddCertificate(
+			&common.UpdateDrepCertificate{
+				CertType:       common.CertificateTypeUpdateDrep,
+				DrepCredential: testStakeCredential(0x05),
+			},
+		).
+		BodyCbor()
+	if err == nil {
+		t.Fatalf("did not get expected error for Conway certificate in Babbage transaction")
+	}
+	// Conway transactions don't support removed certificates
+	_, err = ledger.NewTransactionBuilder(ledger.TxTypeConway).
+		AddInput(testInput(0x01, 0)).
+		AddCertificate(babbageTx.Body.TxCertificates[1].Certificate).
+		BodyCbor()
+	if err == nil {
+		t.Fatalf("did not get expected error for MIR certificate in Conway transaction")
+	}
+}
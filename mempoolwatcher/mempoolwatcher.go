@@ -0,0 +1,192 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mempoolwatcher provides a stream of changes to a node's mempool
+//
+// A Watcher repeatedly acquires mempool snapshots using local-tx-monitor. After the first snapshot, the node
+// blocks each acquire until the mempool has changed. The transactions added and removed between snapshots are
+// delivered as decoded transactions, along with the snapshot slot and mempool sizes.
+package mempoolwatcher
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/protocol/localtxmonitor"
+)
+
+// Snapshot represents the changes to the mempool between two snapshots
+type Snapshot struct {
+	// Slot is the slot number that the snapshot was acquired at
+	Slot uint64
+	// Capacity and Size are in bytes
+	Capacity    uint32
+	Size        uint32
+	NumberOfTxs uint32
+	// Added contains the transactions that weren't in the previous snapshot. For the first snapshot, this
+	// contains every transaction in the mempool
+	Added []ledger.Transaction
+	// Removed contains the transactions from the previous snapshot that are no longer in the mempool
+	Removed []ledger.Transaction
+}
+
+// Config is used to configure a Watcher
+type Config struct {
+	BufferSize int
+}
+
+// WatcherOptionFunc represents a function used to modify the Watcher config
+type WatcherOptionFunc func(*Config)
+
+// NewConfig returns a new Watcher config object with the provided options
+func NewConfig(options ...WatcherOptionFunc) Config {
+	c := Config{
+		BufferSize: 10,
+	}
+	// Apply provided options functions
+	for _, option := range options {
+		option(&c)
+	}
+	return c
+}
+
+// WithBufferSize specifies the number of snapshots that can be buffered before the Watcher stops acquiring new
+// snapshots
+func WithBufferSize(size int) WatcherOptionFunc {
+	return func(c *Config) {
+		c.BufferSize = size
+	}
+}
+
+// Watcher delivers the changes between mempool snapshots
+type Watcher struct {
+	config       *Config
+	client       *localtxmonitor.Client
+	snapshotChan chan Snapshot
+	errorChan    chan error
+	doneChan     chan struct{}
+	onceStart    sync.Once
+	onceStop     sync.Once
+	// Transactions in the previous snapshot, in mempool order and by hash
+	txList []ledger.Transaction
+	txs    map[string]ledger.Transaction
+}
+
+// New returns a new Watcher object for the provided local-tx-monitor client with the provided options
+func New(
+	client *localtxmonitor.Client,
+	options ...WatcherOptionFunc,
+) *Watcher {
+	cfg := NewConfig(options...)
+	w := &Watcher{
+		config:       &cfg,
+		client:       client,
+		snapshotChan: make(chan Snapshot, cfg.BufferSize),
+		errorChan:    make(chan error, 1),
+		doneChan:     make(chan struct{}),
+		txs:          make(map[string]ledger.Transaction),
+	}
+	return w
+}
+
+// Start begins acquiring mempool snapshots
+func (w *Watcher) Start() {
+	w.onceStart.Do(func() {
+		go w.run()
+	})
+}
+
+// SnapshotChan returns the channel of mempool snapshots
+func (w *Watcher) SnapshotChan() <-chan Snapshot {
+	return w.snapshotChan
+}
+
+// ErrorChan returns a channel that receives the error that stopped the Watcher
+func (w *Watcher) ErrorChan() <-chan error {
+	return w.errorChan
+}
+
+// Stop stops acquiring mempool snapshots. A pending acquire completes when the mempool changes or the
+// connection is closed
+func (w *Watcher) Stop() {
+	w.onceStop.Do(func() {
+		close(w.doneChan)
+	})
+}
+
+func (w *Watcher) run() {
+	for {
+		snapshot, err := w.nextSnapshot()
+		if err != nil {
+			select {
+			case <-w.doneChan:
+			case w.errorChan <- err:
+			}
+			return
+		}
+		select {
+		case <-w.doneChan:
+			return
+		case w.snapshotChan <- snapshot:
+		}
+	}
+}
+
+// nextSnapshot acquires the next mempool snapshot and compares it to the previous snapshot
+func (w *Watcher) nextSnapshot() (Snapshot, error) {
+	// The node blocks this until the mempool differs from the previously acquired snapshot
+	if err := w.client.Acquire(); err != nil {
+		return Snapshot{}, err
+	}
+	capacity, size, numberOfTxs, err := w.client.GetSizes()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snapshot := Snapshot{
+		Slot:        w.client.AcquiredSlot(),
+		Capacity:    capacity,
+		Size:        size,
+		NumberOfTxs: numberOfTxs,
+	}
+	var txList []ledger.Transaction
+	txs := make(map[string]ledger.Transaction)
+	for {
+		eraId, txCbor, err := w.client.NextTxWithEra()
+		if err != nil {
+			return Snapshot{}, err
+		}
+		if txCbor == nil {
+			break
+		}
+		tx, err := ledger.NewTransactionFromCbor(uint(eraId), txCbor)
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("failed to decode transaction: %w", err)
+		}
+		txHash := tx.Hash()
+		txList = append(txList, tx)
+		txs[txHash] = tx
+		if _, ok := w.txs[txHash]; !ok {
+			snapshot.Added = append(snapshot.Added, tx)
+		}
+	}
+	for _, tx := range w.txList {
+		if _, ok := txs[tx.Hash()]; !ok {
+			snapshot.Removed = append(snapshot.Removed, tx)
+		}
+	}
+	w.txList = txList
+	w.txs = txs
+	return snapshot, nil
+}
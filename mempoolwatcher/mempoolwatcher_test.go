@@ -0,0 +1,203 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mempoolwatcher_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	ouroboros "github.com/blinklabs-io/gouroboros"
+	"github.com/blinklabs-io/gouroboros/cbor"
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/mempoolwatcher"
+	"github.com/blinklabs-io/gouroboros/protocol"
+	"github.com/blinklabs-io/gouroboros/protocol/localtxmonitor"
+
+	ouroboros_mock "github.com/blinklabs-io/ouroboros-mock"
+	"go.uber.org/goleak"
+)
+
+// testTx builds a minimal Conway transaction, which is made unique by its fee
+func testTx(t *testing.T, fee uint64) ([]byte, string) {
+	txCbor, err := cbor.Encode(
+		[]any{
+			map[uint]any{
+				0: []any{[]any{make([]byte, 32), 0}},
+				1: []any{},
+				2: fee,
+			},
+			map[uint]any{},
+			true,
+			nil,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tx, err := ledger.NewConwayTransactionFromCbor(txCbor)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return txCbor, tx.Hash()
+}
+
+// snapshotConversation returns the conversation entries for acquiring a snapshot with the provided transactions
+func snapshotConversation(slot uint64, txs ...[]byte) []ouroboros_mock.ConversationEntry {
+	ret := []ouroboros_mock.ConversationEntry{
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  localtxmonitor.ProtocolId,
+			MessageType: localtxmonitor.MessageTypeAcquire,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: localtxmonitor.ProtocolId,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				localtxmonitor.NewMsgAcquired(slot),
+			},
+		},
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  localtxmonitor.ProtocolId,
+			MessageType: localtxmonitor.MessageTypeGetSizes,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: localtxmonitor.ProtocolId,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				localtxmonitor.NewMsgReplyGetSizes(100000, 1000, uint32(len(txs))),
+			},
+		},
+	}
+	// The end of the snapshot is indicated by an empty reply
+	for _, tx := range append(txs, nil) {
+		ret = append(
+			ret,
+			ouroboros_mock.ConversationEntryInput{
+				ProtocolId:  localtxmonitor.ProtocolId,
+				MessageType: localtxmonitor.MessageTypeNextTx,
+			},
+			ouroboros_mock.ConversationEntryOutput{
+				ProtocolId: localtxmonitor.ProtocolId,
+				IsResponse: true,
+				Messages: []protocol.Message{
+					localtxmonitor.NewMsgReplyNextTx(ledger.TxTypeConway, tx),
+				},
+			},
+		)
+	}
+	return ret
+}
+
+func txHashes(txs []ledger.Transaction) []string {
+	ret := []string{}
+	for _, tx := range txs {
+		ret = append(ret, tx.Hash())
+	}
+	return ret
+}
+
+func TestWatcher(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	tx1, tx1Hash := testTx(t, 1)
+	tx2, tx2Hash := testTx(t, 2)
+	tx3, tx3Hash := testTx(t, 3)
+	conversation := []ouroboros_mock.ConversationEntry{
+		ouroboros_mock.ConversationEntryHandshakeRequestGeneric,
+		ouroboros_mock.ConversationEntryHandshakeNtCResponse,
+	}
+	conversation = append(conversation, snapshotConversation(100, tx1, tx2)...)
+	conversation = append(conversation, snapshotConversation(200, tx2, tx3)...)
+	mockConn := ouroboros_mock.NewConnection(
+		ouroboros_mock.ProtocolRoleClient,
+		conversation,
+	)
+	// Async mock connection error handler
+	asyncErrChan := make(chan error, 1)
+	go func() {
+		err := <-mockConn.(*ouroboros_mock.Connection).ErrorChan()
+		if err != nil {
+			asyncErrChan <- fmt.Errorf("received unexpected error: %s", err)
+		}
+		close(asyncErrChan)
+	}()
+	oConn, err := ouroboros.New(
+		ouroboros.WithConnection(mockConn),
+		ouroboros.WithNetworkMagic(ouroboros_mock.MockNetworkMagic),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error when creating Ouroboros object: %s", err)
+	}
+	watcher := mempoolwatcher.New(oConn.LocalTxMonitor().Client)
+	watcher.Start()
+	testDefs := []struct {
+		slot    uint64
+		added   []string
+		removed []string
+	}{
+		// The first snapshot contains all transactions in the mempool
+		{
+			slot:    100,
+			added:   []string{tx1Hash, tx2Hash},
+			removed: []string{},
+		},
+		{
+			slot:    200,
+			added:   []string{tx3Hash},
+			removed: []string{tx1Hash},
+		},
+	}
+	for _, testDef := range testDefs {
+		select {
+		case snapshot := <-watcher.SnapshotChan():
+			if snapshot.Slot != testDef.slot {
+				t.Fatalf("did not get expected snapshot slot: got %d, wanted %d", snapshot.Slot, testDef.slot)
+			}
+			if snapshot.Capacity != 100000 || snapshot.Size != 1000 || snapshot.NumberOfTxs != 2 {
+				t.Fatalf("did not get expected snapshot sizes: %#v", snapshot)
+			}
+			if added := txHashes(snapshot.Added); !reflect.DeepEqual(added, testDef.added) {
+				t.Fatalf("did not get expected added transactions at slot %d: got %v, wanted %v", testDef.slot, added, testDef.added)
+			}
+			if removed := txHashes(snapshot.Removed); !reflect.DeepEqual(removed, testDef.removed) {
+				t.Fatalf("did not get expected removed transactions at slot %d: got %v, wanted %v", testDef.slot, removed, testDef.removed)
+			}
+		case err := <-watcher.ErrorChan():
+			t.Fatalf("received unexpected error: %s", err)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("did not receive expected snapshot")
+		}
+	}
+	watcher.Stop()
+	// Wait for mock connection shutdown
+	select {
+	case err, ok := <-asyncErrChan:
+		if ok {
+			t.Fatal(err.Error())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("did not complete within timeout")
+	}
+	// Close Ouroboros connection, which completes the pending acquire
+	if err := oConn.Close(); err != nil {
+		t.Fatalf("unexpected error when closing Ouroboros object: %s", err)
+	}
+	// Wait for connection shutdown
+	select {
+	case <-oConn.ErrorChan():
+	case <-time.After(10 * time.Second):
+		t.Errorf("did not shutdown within timeout")
+	}
+}
@@ -0,0 +1,376 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package peercrawler provides recursive peer discovery using the PeerSharing protocol
+//
+// A Crawler connects to each seed address using node-to-node with peer sharing enabled, requests peers, and
+// then connects to the returned peers in turn, up to a maximum depth. The result is a Graph of the nodes that
+// were found, with their negotiated protocol version and reachability, which can be exported as JSON or DOT.
+package peercrawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	ouroboros "github.com/blinklabs-io/gouroboros"
+)
+
+// DialFunc is used to open a connection to a peer address in host:port format
+type DialFunc func(ctx context.Context, address string) (net.Conn, error)
+
+// Config is used to configure a Crawler
+type Config struct {
+	NetworkMagic    uint32
+	MaxDepth        int
+	MaxNodes        int
+	Concurrency     int
+	PeersPerRequest uint8
+	Timeout         time.Duration
+	DialFunc        DialFunc
+}
+
+// CrawlerOptionFunc represents a function used to modify the Crawler config
+type CrawlerOptionFunc func(*Config)
+
+// NewConfig returns a new Crawler config object with the provided options
+func NewConfig(options ...CrawlerOptionFunc) Config {
+	c := Config{
+		NetworkMagic:    ouroboros.NetworkMainnet.NetworkMagic,
+		MaxDepth:        3,
+		MaxNodes:        1000,
+		Concurrency:     10,
+		PeersPerRequest: 10,
+		Timeout:         10 * time.Second,
+	}
+	// Apply provided options functions
+	for _, option := range options {
+		option(&c)
+	}
+	if c.DialFunc == nil {
+		dialer := net.Dialer{Timeout: c.Timeout}
+		c.DialFunc = func(ctx context.Context, address string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "tcp", address)
+		}
+	}
+	return c
+}
+
+// WithNetworkMagic specifies the network magic to use when connecting to peers
+func WithNetworkMagic(networkMagic uint32) CrawlerOptionFunc {
+	return func(c *Config) {
+		c.NetworkMagic = networkMagic
+	}
+}
+
+// WithMaxDepth specifies the maximum number of hops from the seed addresses. Seed addresses are at depth 0
+func WithMaxDepth(maxDepth int) CrawlerOptionFunc {
+	return func(c *Config) {
+		c.MaxDepth = maxDepth
+	}
+}
+
+// WithMaxNodes specifies the maximum number of nodes to add to the graph
+func WithMaxNodes(maxNodes int) CrawlerOptionFunc {
+	return func(c *Config) {
+		c.MaxNodes = maxNodes
+	}
+}
+
+// WithConcurrency specifies the maximum number of peers to connect to at the same time
+func WithConcurrency(concurrency int) CrawlerOptionFunc {
+	return func(c *Config) {
+		c.Concurrency = concurrency
+	}
+}
+
+// WithPeersPerRequest specifies the number of peers to request from each node
+func WithPeersPerRequest(amount uint8) CrawlerOptionFunc {
+	return func(c *Config) {
+		c.PeersPerRequest = amount
+	}
+}
+
+// WithTimeout specifies the timeout for connecting to a peer and requesting its peers
+func WithTimeout(timeout time.Duration) CrawlerOptionFunc {
+	return func(c *Config) {
+		c.Timeout = timeout
+	}
+}
+
+// WithDialFunc specifies a custom function for connecting to peers
+func WithDialFunc(dialFunc DialFunc) CrawlerOptionFunc {
+	return func(c *Config) {
+		c.DialFunc = dialFunc
+	}
+}
+
+// Node represents a peer that was found while crawling
+type Node struct {
+	Address string `json:"address"`
+	// Depth is the number of hops from a seed address on the path that the node was first found through
+	Depth     int  `json:"depth"`
+	Reachable bool `json:"reachable"`
+	// Error is the reason that the node wasn't reachable or its peers couldn't be requested
+	Error string `json:"error,omitempty"`
+	// Version is the negotiated node-to-node protocol version
+	Version uint16 `json:"version,omitempty"`
+	// PeerSharing indicates whether the node has peer sharing enabled
+	PeerSharing bool `json:"peerSharing"`
+}
+
+// Edge represents a peer that was shared by a node. Both ends of an edge are nodes in the graph
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Graph is the result of a crawl
+type Graph struct {
+	Nodes []*Node `json:"nodes"`
+	Edges []Edge  `json:"edges"`
+}
+
+// Node returns the node with the specified address, or nil if it's not in the graph
+func (g *Graph) Node(address string) *Node {
+	for _, node := range g.Nodes {
+		if node.Address == address {
+			return node
+		}
+	}
+	return nil
+}
+
+// WriteDOT writes the graph in Graphviz DOT format. Unreachable nodes are drawn with a dashed outline
+func (g *Graph) WriteDOT(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "digraph peers {"); err != nil {
+		return err
+	}
+	for _, node := range g.Nodes {
+		label := node.Address
+		if node.Reachable {
+			label = fmt.Sprintf("%s\\nv%d", node.Address, node.Version)
+		}
+		style := "solid"
+		if !node.Reachable {
+			style = "dashed"
+		}
+		if _, err := fmt.Fprintf(w, "  %q [label=%q, style=%s];\n", node.Address, label, style); err != nil {
+			return err
+		}
+	}
+	for _, edge := range g.Edges {
+		if _, err := fmt.Fprintf(w, "  %q -> %q;\n", edge.From, edge.To); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintln(w, "}"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Crawler discovers peers recursively using the PeerSharing protocol
+type Crawler struct {
+	config    *Config
+	mutex     sync.Mutex
+	waitGroup sync.WaitGroup
+	sem       chan struct{}
+	graph     *Graph
+	nodes     map[string]*Node
+	edges     map[Edge]bool
+}
+
+// New returns a new Crawler object with the provided options
+func New(options ...CrawlerOptionFunc) *Crawler {
+	cfg := NewConfig(options...)
+	c := &Crawler{
+		config: &cfg,
+	}
+	return c
+}
+
+// Crawl connects to the seed addresses and recursively requests peers until the maximum depth or number of
+// nodes is reached. Seed addresses are in host:port format. It blocks until the crawl finishes or the context
+// is cancelled, and returns the graph of the nodes found so far
+func (c *Crawler) Crawl(ctx context.Context, seeds ...string) (*Graph, error) {
+	if len(seeds) == 0 {
+		return nil, fmt.Errorf("no seed addresses specified")
+	}
+	concurrency := c.config.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	c.sem = make(chan struct{}, concurrency)
+	c.graph = &Graph{
+		Nodes: []*Node{},
+		Edges: []Edge{},
+	}
+	c.nodes = make(map[string]*Node)
+	c.edges = make(map[Edge]bool)
+	for _, seed := range seeds {
+		c.addNode(ctx, seed, 0)
+	}
+	c.waitGroup.Wait()
+	// Drop edges to peers that weren't added to the graph because of the depth or node limit
+	edges := make([]Edge, 0, len(c.graph.Edges))
+	for _, edge := range c.graph.Edges {
+		if _, ok := c.nodes[edge.To]; ok {
+			edges = append(edges, edge)
+		}
+	}
+	c.graph.Edges = edges
+	// Sort for deterministic output
+	sort.Slice(c.graph.Nodes, func(i, j int) bool {
+		if c.graph.Nodes[i].Depth != c.graph.Nodes[j].Depth {
+			return c.graph.Nodes[i].Depth < c.graph.Nodes[j].Depth
+		}
+		return c.graph.Nodes[i].Address < c.graph.Nodes[j].Address
+	})
+	sort.Slice(c.graph.Edges, func(i, j int) bool {
+		if c.graph.Edges[i].From != c.graph.Edges[j].From {
+			return c.graph.Edges[i].From < c.graph.Edges[j].From
+		}
+		return c.graph.Edges[i].To < c.graph.Edges[j].To
+	})
+	return c.graph, ctx.Err()
+}
+
+// addNode adds a node to the graph and starts visiting it, unless it's already known or a limit is reached
+func (c *Crawler) addNode(ctx context.Context, address string, depth int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.nodes[address]; ok {
+		return
+	}
+	if c.config.MaxNodes > 0 && len(c.nodes) >= c.config.MaxNodes {
+		return
+	}
+	node := &Node{
+		Address: address,
+		Depth:   depth,
+	}
+	c.nodes[address] = node
+	c.graph.Nodes = append(c.graph.Nodes, node)
+	c.waitGroup.Add(1)
+	go func() {
+		defer c.waitGroup.Done()
+		// Limit the number of concurrent connections
+		select {
+		case c.sem <- struct{}{}:
+		case <-ctx.Done():
+			c.setNodeError(node, ctx.Err())
+			return
+		}
+		peers, err := c.visit(ctx, node)
+		<-c.sem
+		if err != nil {
+			c.setNodeError(node, err)
+			return
+		}
+		for _, peer := range peers {
+			c.addEdge(node.Address, peer)
+			if depth < c.config.MaxDepth {
+				c.addNode(ctx, peer, depth+1)
+			}
+		}
+	}()
+}
+
+func (c *Crawler) addEdge(from string, to string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	edge := Edge{From: from, To: to}
+	if c.edges[edge] {
+		return
+	}
+	c.edges[edge] = true
+	c.graph.Edges = append(c.graph.Edges, edge)
+}
+
+func (c *Crawler) setNodeError(node *Node, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	node.Error = err.Error()
+}
+
+// visit connects to a node, records its version information, and requests its peers
+func (c *Crawler) visit(ctx context.Context, node *Node) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+	conn, err := c.config.DialFunc(ctx, node.Address)
+	if err != nil {
+		return nil, err
+	}
+	// Abort the handshake and share request when the context is done
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stopChan:
+		}
+	}()
+	oConn, err := ouroboros.New(
+		ouroboros.WithConnection(conn),
+		ouroboros.WithNetworkMagic(c.config.NetworkMagic),
+		ouroboros.WithNodeToNode(true),
+		ouroboros.WithKeepAlive(false),
+		ouroboros.WithPeerSharing(true),
+	)
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	defer func() {
+		oConn.Close()
+		// Wait for connection shutdown
+		for range oConn.ErrorChan() {
+		}
+	}()
+	version, versionData := oConn.ProtocolVersion()
+	c.mutex.Lock()
+	node.Reachable = true
+	node.Version = version
+	node.PeerSharing = versionData.PeerSharing()
+	c.mutex.Unlock()
+	// Nodes without peer sharing enabled don't answer share requests
+	if oConn.PeerSharing() == nil || !versionData.PeerSharing() {
+		return nil, nil
+	}
+	peerAddrs, err := oConn.PeerSharing().Client.GetPeers(c.config.PeersPerRequest)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	peers := make([]string, 0, len(peerAddrs))
+	for _, peerAddr := range peerAddrs {
+		peers = append(
+			peers,
+			net.JoinHostPort(peerAddr.IP.String(), strconv.Itoa(int(peerAddr.Port))),
+		)
+	}
+	return peers, nil
+}
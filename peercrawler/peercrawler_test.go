@@ -0,0 +1,184 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peercrawler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blinklabs-io/gouroboros/cbor"
+	"github.com/blinklabs-io/gouroboros/peercrawler"
+	"github.com/blinklabs-io/gouroboros/protocol"
+	"github.com/blinklabs-io/gouroboros/protocol/handshake"
+	"github.com/blinklabs-io/gouroboros/protocol/peersharing"
+	ouroboros_mock "github.com/blinklabs-io/ouroboros-mock"
+	"go.uber.org/goleak"
+)
+
+// conversationEntryHandshakeNtNResponsePeerSharing is a NtN handshake response with peer sharing enabled
+var conversationEntryHandshakeNtNResponsePeerSharing = ouroboros_mock.ConversationEntryOutput{
+	ProtocolId: handshake.ProtocolId,
+	IsResponse: true,
+	Messages: []protocol.Message{
+		handshake.NewMsgAcceptVersion(
+			ouroboros_mock.MockProtocolVersionNtN,
+			protocol.VersionDataNtN13andUp{
+				VersionDataNtN11to12: protocol.VersionDataNtN11to12{
+					CborNetworkMagic:                       ouroboros_mock.MockNetworkMagic,
+					CborInitiatorAndResponderDiffusionMode: protocol.DiffusionModeInitiatorOnly,
+					CborPeerSharing:                        protocol.PeerSharingModePeerSharingPublic,
+					CborQuery:                              protocol.QueryModeDisabled,
+				},
+			},
+		),
+	},
+}
+
+// testNetwork serves mocked node-to-node conversations that share a static list of peers
+type testNetwork struct {
+	peers map[string][]string
+}
+
+// dial returns a mock connection for the address. Addresses that aren't in the peer map are unreachable
+func (n *testNetwork) dial(ctx context.Context, address string) (net.Conn, error) {
+	peers, ok := n.peers[address]
+	if !ok {
+		return nil, fmt.Errorf("connection refused")
+	}
+	// Build the share peers reply by hand, since the peer address encoding isn't under test here
+	peerAddrs := []any{}
+	for _, peer := range peers {
+		host, portStr, err := net.SplitHostPort(peer)
+		if err != nil {
+			return nil, err
+		}
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		peerAddrs = append(
+			peerAddrs,
+			[]any{0, binary.LittleEndian.Uint32(net.ParseIP(host).To4()), port},
+		)
+	}
+	sharePeersCbor, err := cbor.Encode(
+		[]any{peersharing.MessageTypeSharePeers, peerAddrs},
+	)
+	if err != nil {
+		return nil, err
+	}
+	sharePeers := peersharing.NewMsgSharePeers(nil)
+	sharePeers.SetCbor(sharePeersCbor)
+	return ouroboros_mock.NewConnection(
+		ouroboros_mock.ProtocolRoleClient,
+		[]ouroboros_mock.ConversationEntry{
+			ouroboros_mock.ConversationEntryHandshakeRequestGeneric,
+			conversationEntryHandshakeNtNResponsePeerSharing,
+			ouroboros_mock.ConversationEntryInput{
+				ProtocolId:  peersharing.ProtocolId,
+				MessageType: peersharing.MessageTypeShareRequest,
+			},
+			ouroboros_mock.ConversationEntryOutput{
+				ProtocolId: peersharing.ProtocolId,
+				IsResponse: true,
+				Messages:   []protocol.Message{sharePeers},
+			},
+		},
+	), nil
+}
+
+func TestCrawl(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	network := &testNetwork{
+		peers: map[string][]string{
+			"10.0.0.1:3001": {"10.0.0.2:3001", "10.0.0.3:3001"},
+			"10.0.0.2:3001": {"10.0.0.1:3001", "10.0.0.4:3001"},
+			// This peer shares a peer past the maximum depth, which is left out of the graph
+			"10.0.0.4:3001": {"10.0.0.5:3001"},
+		},
+	}
+	crawler := peercrawler.New(
+		peercrawler.WithNetworkMagic(ouroboros_mock.MockNetworkMagic),
+		peercrawler.WithMaxDepth(2),
+		peercrawler.WithConcurrency(2),
+		peercrawler.WithTimeout(5*time.Second),
+		peercrawler.WithDialFunc(network.dial),
+	)
+	graph, err := crawler.Crawl(context.Background(), "10.0.0.1:3001")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expectedNodes := []struct {
+		address   string
+		depth     int
+		reachable bool
+	}{
+		{"10.0.0.1:3001", 0, true},
+		{"10.0.0.2:3001", 1, true},
+		{"10.0.0.3:3001", 1, false},
+		{"10.0.0.4:3001", 2, true},
+	}
+	if len(graph.Nodes) != len(expectedNodes) {
+		t.Fatalf("did not get expected number of nodes: got %d, wanted %d", len(graph.Nodes), len(expectedNodes))
+	}
+	for idx, expected := range expectedNodes {
+		node := graph.Nodes[idx]
+		if node.Address != expected.address || node.Depth != expected.depth || node.Reachable != expected.reachable {
+			t.Fatalf("did not get expected node: %#v", node)
+		}
+		if node.Reachable {
+			if node.Version == 0 || !node.PeerSharing || node.Error != "" {
+				t.Fatalf("did not get expected version info for reachable node: %#v", node)
+			}
+		} else if node.Error == "" {
+			t.Fatalf("did not get expected error for unreachable node: %#v", node)
+		}
+	}
+	expectedEdges := []peercrawler.Edge{
+		{From: "10.0.0.1:3001", To: "10.0.0.2:3001"},
+		{From: "10.0.0.1:3001", To: "10.0.0.3:3001"},
+		{From: "10.0.0.2:3001", To: "10.0.0.1:3001"},
+		{From: "10.0.0.2:3001", To: "10.0.0.4:3001"},
+	}
+	if !reflect.DeepEqual(graph.Edges, expectedEdges) {
+		t.Fatalf("did not get expected edges:\n  got:    %v\n  wanted: %v", graph.Edges, expectedEdges)
+	}
+	// Check DOT output
+	var buf bytes.Buffer
+	if err := graph.WriteDOT(&buf); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	dot := buf.String()
+	for _, expected := range []string{
+		"digraph peers {",
+		`"10.0.0.3:3001" [label="10.0.0.3:3001", style=dashed];`,
+		`"10.0.0.2:3001" -> "10.0.0.4:3001";`,
+	} {
+		if !strings.Contains(dot, expected) {
+			t.Fatalf("DOT output does not contain %q:\n%s", expected, dot)
+		}
+	}
+	if strings.Contains(dot, "10.0.0.5:3001") {
+		t.Fatalf("DOT output contains peer past the maximum depth:\n%s", dot)
+	}
+}
@@ -86,9 +86,15 @@ type BlockFetch struct {
 	Server *Server
 }
 
+// DefaultMaxRangeBlocks is the default limit on the number of blocks served for a single range request
+// when using a BlockStore
+const DefaultMaxRangeBlocks = 1000
+
 type Config struct {
 	BlockFunc         BlockFunc
 	RequestRangeFunc  RequestRangeFunc
+	BlockStore        BlockStore
+	MaxRangeBlocks    int
 	BatchStartTimeout time.Duration
 	BlockTimeout      time.Duration
 }
@@ -118,6 +124,7 @@ func NewConfig(options ...BlockFetchOptionFunc) Config {
 	c := Config{
 		BatchStartTimeout: 5 * time.Second,
 		BlockTimeout:      60 * time.Second,
+		MaxRangeBlocks:    DefaultMaxRangeBlocks,
 	}
 	// Apply provided options functions
 	for _, option := range options {
@@ -140,6 +147,22 @@ func WithRequestRangeFunc(
 	}
 }
 
+// WithBlockStore specifies a BlockStore used to serve range requests. It takes precedence over the
+// RequestRange callback function
+func WithBlockStore(blockStore BlockStore) BlockFetchOptionFunc {
+	return func(c *Config) {
+		c.BlockStore = blockStore
+	}
+}
+
+// WithMaxRangeBlocks specifies the maximum number of blocks served for a single range request when using
+// a BlockStore. Larger requests are answered with NoBlocks. A value of 0 disables the limit
+func WithMaxRangeBlocks(maxRangeBlocks int) BlockFetchOptionFunc {
+	return func(c *Config) {
+		c.MaxRangeBlocks = maxRangeBlocks
+	}
+}
+
 func WithBatchStartTimeout(timeout time.Duration) BlockFetchOptionFunc {
 	return func(c *Config) {
 		c.BatchStartTimeout = timeout
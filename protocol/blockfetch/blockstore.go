@@ -0,0 +1,78 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blockfetch
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/blinklabs-io/gouroboros/protocol/common"
+)
+
+// BlockStore provides the blocks served by the block-fetch server
+type BlockStore interface {
+	// GetBlockRange returns an iterator over the blocks from the start point to the end point (inclusive). It
+	// should return a nil iterator and no error if either point is unknown or the points are not on the
+	// same chain
+	GetBlockRange(start common.Point, end common.Point) (BlockStoreIterator, error)
+}
+
+// BlockStoreIterator provides the blocks for a single range from a BlockStore
+type BlockStoreIterator interface {
+	// Len returns the number of blocks in the range
+	Len() int
+	// Next returns the block type and raw block CBOR for the next block in the range, or io.EOF after
+	// the last block
+	Next() (uint, []byte, error)
+	// Close releases any resources held by the iterator
+	Close() error
+}
+
+// serveBlockStoreRange handles a range request using the configured BlockStore
+func (s *Server) serveBlockStoreRange(
+	start common.Point,
+	end common.Point,
+) error {
+	iter, err := s.config.BlockStore.GetBlockRange(start, end)
+	if err != nil {
+		return fmt.Errorf("%s: block store error: %w", ProtocolName, err)
+	}
+	if iter == nil {
+		return s.NoBlocks()
+	}
+	defer iter.Close()
+	blockCount := iter.Len()
+	if blockCount == 0 ||
+		(s.config.MaxRangeBlocks > 0 && blockCount > s.config.MaxRangeBlocks) {
+		return s.NoBlocks()
+	}
+	if err := s.StartBatch(); err != nil {
+		return err
+	}
+	// Blocks are sent as they are read from the store, so large ranges don't need to be held in memory
+	for {
+		blockType, blockCbor, err := iter.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("%s: block store error: %w", ProtocolName, err)
+		}
+		if err := s.Block(blockType, blockCbor); err != nil {
+			return err
+		}
+	}
+	return s.BatchDone()
+}
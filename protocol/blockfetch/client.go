@@ -33,6 +33,7 @@ type Client struct {
 	startBatchResultChan chan error
 	busyMutex            sync.Mutex
 	blockUseCallback     bool
+	blockRangeIter       *BlockRangeIterator
 	onceStart            sync.Once
 	onceStop             sync.Once
 }
@@ -119,6 +120,34 @@ func (c *Client) GetBlockRange(start common.Point, end common.Point) error {
 	return nil
 }
 
+// GetBlockRangeIterator requests all blocks in the specified range (inclusive) and returns an iterator
+// that yields them in order. The iterator must be read until it returns io.EOF or closed
+func (c *Client) GetBlockRangeIterator(
+	start common.Point,
+	end common.Point,
+) (*BlockRangeIterator, error) {
+	c.busyMutex.Lock()
+	c.blockUseCallback = false
+	iter := newBlockRangeIterator(c)
+	c.blockRangeIter = iter
+	msg := NewMsgRequestRange(start, end)
+	if err := c.SendMessage(msg); err != nil {
+		c.blockRangeIter = nil
+		c.busyMutex.Unlock()
+		return nil, err
+	}
+	err, ok := <-c.startBatchResultChan
+	if !ok {
+		return nil, protocol.ProtocolShuttingDownError
+	}
+	if err != nil {
+		c.blockRangeIter = nil
+		c.busyMutex.Unlock()
+		return nil, err
+	}
+	return iter, nil
+}
+
 // GetBlock requests and returns a single block specified by the provided point
 func (c *Client) GetBlock(point common.Point) (ledger.Block, error) {
 	c.busyMutex.Lock()
@@ -190,7 +219,9 @@ func (c *Client) handleBlock(msgGeneric protocol.Message) error {
 		return err
 	}
 	// We use the callback when requesting ranges and the internal channel for a single block
-	if c.blockUseCallback {
+	if c.blockRangeIter != nil {
+		c.blockRangeIter.push(blk)
+	} else if c.blockUseCallback {
 		if err := c.config.BlockFunc(c.callbackContext, wrappedBlock.Type, blk); err != nil {
 			return err
 		}
@@ -201,6 +232,10 @@ func (c *Client) handleBlock(msgGeneric protocol.Message) error {
 }
 
 func (c *Client) handleBatchDone() error {
+	if c.blockRangeIter != nil {
+		c.blockRangeIter.finish()
+		c.blockRangeIter = nil
+	}
 	c.busyMutex.Unlock()
 	return nil
 }
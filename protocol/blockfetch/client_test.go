@@ -15,7 +15,9 @@
 package blockfetch_test
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"testing"
 	"time"
 
@@ -207,3 +209,86 @@ func TestGetBlockNoBlocks(t *testing.T) {
 		},
 	)
 }
+
+func TestGetBlockRangeIterator(t *testing.T) {
+	// Create basic blocks and round-trip them through the CBOR encoder to get the hash populated
+	var testBlocks []ledger.BabbageBlock
+	var blockMessages []protocol.Message
+	for i := uint64(0); i < 3; i++ {
+		testBlock := ledger.BabbageBlock{
+			Header: &ledger.BabbageBlockHeader{},
+		}
+		testBlock.Header.Body.BlockNumber = 12345 + i
+		testBlock.Header.Body.Slot = 23456 + i
+		blockCbor, err := cbor.Encode(testBlock)
+		if err != nil {
+			t.Fatalf("received unexpected error: %s", err)
+		}
+		if _, err := cbor.Decode(blockCbor, &testBlock); err != nil {
+			t.Fatalf("received unexpected error: %s", err)
+		}
+		wrappedBlockCbor, err := cbor.Encode(
+			blockfetch.WrappedBlock{
+				Type:     ledger.BlockTypeBabbage,
+				RawBlock: cbor.RawMessage(blockCbor),
+			},
+		)
+		if err != nil {
+			t.Fatalf("received unexpected error: %s", err)
+		}
+		testBlocks = append(testBlocks, testBlock)
+		blockMessages = append(
+			blockMessages,
+			blockfetch.NewMsgBlock(wrappedBlockCbor),
+		)
+	}
+	messages := []protocol.Message{blockfetch.NewMsgStartBatch()}
+	messages = append(messages, blockMessages...)
+	messages = append(messages, blockfetch.NewMsgBatchDone())
+	conversation := append(
+		conversationHandshakeRequestRange,
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: blockfetch.ProtocolId,
+			IsResponse: true,
+			Messages:   messages,
+		},
+	)
+	runTest(
+		t,
+		conversation,
+		func(t *testing.T, oConn *ouroboros.Connection) {
+			iter, err := oConn.BlockFetch().Client.GetBlockRangeIterator(
+				ocommon.NewPoint(
+					testBlocks[0].SlotNumber(),
+					test.DecodeHexString(testBlocks[0].Hash()),
+				),
+				ocommon.NewPoint(
+					testBlocks[2].SlotNumber(),
+					test.DecodeHexString(testBlocks[2].Hash()),
+				),
+			)
+			if err != nil {
+				t.Fatalf("received unexpected error: %s", err)
+			}
+			defer iter.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			for _, testBlock := range testBlocks {
+				blk, err := iter.Next(ctx)
+				if err != nil {
+					t.Fatalf("received unexpected error: %s", err)
+				}
+				if blk.Hash() != testBlock.Hash() {
+					t.Fatalf(
+						"did not receive expected block hash: got %s, wanted %s",
+						blk.Hash(),
+						testBlock.Hash(),
+					)
+				}
+			}
+			if _, err := iter.Next(ctx); err != io.EOF {
+				t.Fatalf("did not receive expected io.EOF, got: %v", err)
+			}
+		},
+	)
+}
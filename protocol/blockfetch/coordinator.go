@@ -0,0 +1,441 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blockfetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/blinklabs-io/gouroboros/connection"
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/protocol/common"
+)
+
+// NoPeersAvailableError is returned by the Coordinator when there are no usable peers left to fetch from
+var NoPeersAvailableError = errors.New("no block-fetch peers available")
+
+// RangeTimeoutError is returned by the Coordinator when a peer doesn't finish sending a range within the range
+// timeout
+var RangeTimeoutError = errors.New("block-fetch range timed out")
+
+// FetchRange represents an inclusive range of blocks to fetch
+type FetchRange struct {
+	Start common.Point
+	End   common.Point
+}
+
+// CoordinatorBlockFunc is called for each fetched block, in chain order
+type CoordinatorBlockFunc func(ledger.Block) error
+
+// CoordinatorOptionFunc represents a function used to modify the Coordinator config
+type CoordinatorOptionFunc func(*Coordinator)
+
+// WithCoordinatorMaxRetries specifies how many times a failed range is retried before giving up
+func WithCoordinatorMaxRetries(maxRetries int) CoordinatorOptionFunc {
+	return func(c *Coordinator) {
+		c.maxRetries = maxRetries
+	}
+}
+
+// WithCoordinatorRangeTimeout specifies the maximum time allowed for fetching a single range from a
+// peer before it's retried elsewhere. A peer that times out is removed from the coordinator, since its
+// client stays busy until the peer finishes the range. The per-message timeouts from the client config
+// (WithBlockTimeout and WithBatchStartTimeout) still apply and shut down the protocol for unresponsive peers
+func WithCoordinatorRangeTimeout(timeout time.Duration) CoordinatorOptionFunc {
+	return func(c *Coordinator) {
+		c.rangeTimeout = timeout
+	}
+}
+
+// Coordinator fetches ranges of blocks in parallel from multiple peers. Ranges are distributed across
+// the peers, failed or timed out ranges are retried with a different peer, and the resulting blocks
+// are delivered in the order of the requested ranges
+type Coordinator struct {
+	mutex        sync.Mutex
+	peers        map[connection.ConnectionId]*Client
+	inFlight     map[connection.ConnectionId][]FetchRange
+	maxRetries   int
+	rangeTimeout time.Duration
+}
+
+// NewCoordinator returns a new Coordinator object with the provided options
+func NewCoordinator(options ...CoordinatorOptionFunc) *Coordinator {
+	c := &Coordinator{
+		peers:      make(map[connection.ConnectionId]*Client),
+		inFlight:   make(map[connection.ConnectionId][]FetchRange),
+		maxRetries: 3,
+	}
+	// Apply provided options functions
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// AddPeer adds the block-fetch client for a peer to the pool used for future fetch operations
+func (c *Coordinator) AddPeer(client *Client) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.peers[client.callbackContext.ConnectionId] = client
+}
+
+// RemovePeer removes a peer from the pool used for future fetch operations
+func (c *Coordinator) RemovePeer(connId connection.ConnectionId) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.peers, connId)
+}
+
+// InFlight returns the ranges currently being fetched from each peer
+func (c *Coordinator) InFlight() map[connection.ConnectionId][]FetchRange {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	ret := make(map[connection.ConnectionId][]FetchRange, len(c.inFlight))
+	for connId, ranges := range c.inFlight {
+		if len(ranges) > 0 {
+			ret[connId] = append([]FetchRange{}, ranges...)
+		}
+	}
+	return ret
+}
+
+// FetchPoints fetches the blocks at the provided points, which must be in chain order and contiguous.
+// The points are split into ranges of up to batchSize blocks
+func (c *Coordinator) FetchPoints(
+	ctx context.Context,
+	points []common.Point,
+	batchSize int,
+	blockFunc CoordinatorBlockFunc,
+) error {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	var ranges []FetchRange
+	for i := 0; i < len(points); i += batchSize {
+		end := i + batchSize - 1
+		if end >= len(points) {
+			end = len(points) - 1
+		}
+		ranges = append(
+			ranges,
+			FetchRange{
+				Start: points[i],
+				End:   points[end],
+			},
+		)
+	}
+	return c.FetchRanges(ctx, ranges, blockFunc)
+}
+
+// coordinatorJob tracks the state of a single range during a fetch operation
+type coordinatorJob struct {
+	fetchRange  FetchRange
+	attempts    int
+	failedPeers map[connection.ConnectionId]bool
+	inFlight    bool
+	done        bool
+	blocks      []ledger.Block
+}
+
+// coordinatorFetch tracks the state of a single FetchRanges call
+type coordinatorFetch struct {
+	mutex       sync.Mutex
+	cond        *sync.Cond
+	jobs        []*coordinatorJob
+	activePeers map[connection.ConnectionId]bool
+	err         error
+	finished    bool
+}
+
+func (f *coordinatorFetch) setError(err error) {
+	if f.err == nil && !f.finished {
+		f.err = err
+	}
+	f.cond.Broadcast()
+}
+
+// FetchRanges fetches all blocks in the provided ranges using the available peers. The provided function is
+// called for each block in order. An error is returned if a range could not be fetched from any peer
+func (c *Coordinator) FetchRanges(
+	ctx context.Context,
+	ranges []FetchRange,
+	blockFunc CoordinatorBlockFunc,
+) error {
+	c.mutex.Lock()
+	peers := make(map[connection.ConnectionId]*Client, len(c.peers))
+	for connId, client := range c.peers {
+		peers[connId] = client
+	}
+	c.mutex.Unlock()
+	if len(peers) == 0 {
+		return NoPeersAvailableError
+	}
+	fetch := &coordinatorFetch{
+		activePeers: make(map[connection.ConnectionId]bool),
+	}
+	fetch.cond = sync.NewCond(&fetch.mutex)
+	for _, fetchRange := range ranges {
+		fetch.jobs = append(
+			fetch.jobs,
+			&coordinatorJob{
+				fetchRange:  fetchRange,
+				failedPeers: make(map[connection.ConnectionId]bool),
+			},
+		)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Wake everything up when the context is cancelled
+	go func() {
+		<-ctx.Done()
+		fetch.mutex.Lock()
+		defer fetch.mutex.Unlock()
+		fetch.setError(ctx.Err())
+	}()
+	// Start a worker for each peer
+	var wg sync.WaitGroup
+	for connId, client := range peers {
+		fetch.activePeers[connId] = true
+		wg.Add(1)
+		go func(connId connection.ConnectionId, client *Client) {
+			defer wg.Done()
+			c.fetchWorker(ctx, fetch, connId, client)
+		}(connId, client)
+	}
+	// Deliver blocks in order as the ranges complete
+	var err error
+	fetch.mutex.Lock()
+	for _, job := range fetch.jobs {
+		for !job.done && fetch.err == nil {
+			fetch.cond.Wait()
+		}
+		if fetch.err != nil {
+			break
+		}
+		blocks := job.blocks
+		job.blocks = nil
+		fetch.mutex.Unlock()
+		for _, block := range blocks {
+			if err = blockFunc(block); err != nil {
+				break
+			}
+		}
+		fetch.mutex.Lock()
+		if err != nil {
+			fetch.setError(err)
+			break
+		}
+	}
+	err = fetch.err
+	fetch.finished = true
+	fetch.cond.Broadcast()
+	fetch.mutex.Unlock()
+	cancel()
+	wg.Wait()
+	return err
+}
+
+// allDone returns whether all ranges have been fetched. It must be called with the fetch mutex held
+func (f *coordinatorFetch) allDone() bool {
+	for _, job := range f.jobs {
+		if !job.done {
+			return false
+		}
+	}
+	return true
+}
+
+// nextJob finds the next range that the specified peer can fetch. It returns nil when the peer should stop.
+// It must be called with the fetch mutex held
+func (f *coordinatorFetch) nextJob(connId connection.ConnectionId) *coordinatorJob {
+	for {
+		if f.err != nil || f.finished {
+			return nil
+		}
+		remaining := false
+		for _, job := range f.jobs {
+			if job.done {
+				continue
+			}
+			remaining = true
+			if job.inFlight || job.failedPeers[connId] {
+				continue
+			}
+			return job
+		}
+		if !remaining {
+			return nil
+		}
+		// Fail if a pending range has already failed with every active peer
+		for _, job := range f.jobs {
+			if job.done || job.inFlight {
+				continue
+			}
+			usable := false
+			for activeConnId := range f.activePeers {
+				if !job.failedPeers[activeConnId] {
+					usable = true
+					break
+				}
+			}
+			if !usable {
+				f.setError(
+					fmt.Errorf(
+						"%w: range from slot %d to slot %d failed with all peers",
+						NoPeersAvailableError,
+						job.fetchRange.Start.Slot,
+						job.fetchRange.End.Slot,
+					),
+				)
+				return nil
+			}
+		}
+		// Wait for another peer to finish or fail a range
+		f.cond.Wait()
+	}
+}
+
+func (c *Coordinator) fetchWorker(
+	ctx context.Context,
+	fetch *coordinatorFetch,
+	connId connection.ConnectionId,
+	client *Client,
+) {
+	fetch.mutex.Lock()
+	defer fetch.mutex.Unlock()
+	defer func() {
+		delete(fetch.activePeers, connId)
+		if len(fetch.activePeers) == 0 && !fetch.allDone() {
+			fetch.setError(NoPeersAvailableError)
+		}
+		fetch.cond.Broadcast()
+	}()
+	for {
+		job := fetch.nextJob(connId)
+		if job == nil {
+			return
+		}
+		job.inFlight = true
+		fetchRange := job.fetchRange
+		fetch.mutex.Unlock()
+		c.addInFlight(connId, fetchRange)
+		blocks, err := c.fetchRange(ctx, client, fetchRange)
+		c.removeInFlight(connId, fetchRange)
+		fetch.mutex.Lock()
+		job.inFlight = false
+		if err == nil {
+			job.blocks = blocks
+			job.done = true
+			fetch.cond.Broadcast()
+			continue
+		}
+		job.attempts++
+		job.failedPeers[connId] = true
+		// The client for a peer that timed out stays busy until the peer finishes the range, so stop using it
+		retirePeer := errors.Is(err, RangeTimeoutError)
+		if retirePeer {
+			c.RemovePeer(connId)
+		}
+		if job.attempts > c.maxRetries {
+			fetch.setError(
+				fmt.Errorf(
+					"range from slot %d to slot %d failed after %d attempts: %w",
+					fetchRange.Start.Slot,
+					fetchRange.End.Slot,
+					job.attempts,
+					err,
+				),
+			)
+			return
+		}
+		fetch.cond.Broadcast()
+		if retirePeer {
+			return
+		}
+		// Stop using this peer if its protocol has shut down
+		select {
+		case <-client.DoneChan():
+			return
+		default:
+		}
+	}
+}
+
+// fetchRange fetches all blocks in a single range from the specified peer
+func (c *Coordinator) fetchRange(
+	ctx context.Context,
+	client *Client,
+	fetchRange FetchRange,
+) ([]ledger.Block, error) {
+	rangeCtx := ctx
+	if c.rangeTimeout > 0 {
+		var cancel context.CancelFunc
+		rangeCtx, cancel = context.WithTimeout(ctx, c.rangeTimeout)
+		defer cancel()
+	}
+	iter, err := client.GetBlockRangeIterator(fetchRange.Start, fetchRange.End)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	var blocks []ledger.Block
+	for {
+		block, err := iter.Next(rangeCtx)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			// Only the range timeout is reported as a timeout, not the expiry of the caller's context
+			if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+				return nil, RangeTimeoutError
+			}
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+func (c *Coordinator) addInFlight(
+	connId connection.ConnectionId,
+	fetchRange FetchRange,
+) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.inFlight[connId] = append(c.inFlight[connId], fetchRange)
+}
+
+func (c *Coordinator) removeInFlight(
+	connId connection.ConnectionId,
+	fetchRange FetchRange,
+) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	ranges := c.inFlight[connId]
+	for i, tmpRange := range ranges {
+		if tmpRange.Start.Slot == fetchRange.Start.Slot &&
+			tmpRange.End.Slot == fetchRange.End.Slot {
+			c.inFlight[connId] = append(ranges[:i], ranges[i+1:]...)
+			break
+		}
+	}
+	if len(c.inFlight[connId]) == 0 {
+		delete(c.inFlight, connId)
+	}
+}
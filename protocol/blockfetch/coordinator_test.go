@@ -0,0 +1,340 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blockfetch_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ouroboros "github.com/blinklabs-io/gouroboros"
+	"github.com/blinklabs-io/gouroboros/cbor"
+	"github.com/blinklabs-io/gouroboros/internal/test"
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/protocol/blockfetch"
+	ocommon "github.com/blinklabs-io/gouroboros/protocol/common"
+	"go.uber.org/goleak"
+)
+
+type testChainBlock struct {
+	point ocommon.Point
+	hash  string
+	cbor  []byte
+}
+
+// newTestChain generates a sequence of basic blocks
+func newTestChain(t *testing.T, count int) []testChainBlock {
+	var ret []testChainBlock
+	for i := 0; i < count; i++ {
+		testBlock := ledger.BabbageBlock{
+			Header: &ledger.BabbageBlockHeader{},
+		}
+		testBlock.Header.Body.BlockNumber = uint64(1000 + i)
+		testBlock.Header.Body.Slot = uint64(2000 + i)
+		blockCbor, err := cbor.Encode(testBlock)
+		if err != nil {
+			t.Fatalf("received unexpected error: %s", err)
+		}
+		if _, err := cbor.Decode(blockCbor, &testBlock); err != nil {
+			t.Fatalf("received unexpected error: %s", err)
+		}
+		ret = append(
+			ret,
+			testChainBlock{
+				point: ocommon.NewPoint(
+					testBlock.SlotNumber(),
+					test.DecodeHexString(testBlock.Hash()),
+				),
+				hash: testBlock.Hash(),
+				cbor: blockCbor,
+			},
+		)
+	}
+	return ret
+}
+
+// newTestChainServer starts an in-process block-fetch server for the provided blocks and returns a
+// client connection to it. Blocks with slots in missingSlots are not served
+func newTestChainServer(
+	t *testing.T,
+	chain []testChainBlock,
+	missingSlots map[uint64]bool,
+) (*ouroboros.Connection, *ouroboros.Connection) {
+	return newTestServerConns(
+		t,
+		blockfetch.NewConfig(
+			blockfetch.WithRequestRangeFunc(newTestChainRequestRangeFunc(chain, missingSlots)),
+		),
+	)
+}
+
+// newTestChainRequestRangeFunc returns a block-fetch server request handler that serves the provided blocks.
+// Blocks with slots in missingSlots are not served
+func newTestChainRequestRangeFunc(
+	chain []testChainBlock,
+	missingSlots map[uint64]bool,
+) blockfetch.RequestRangeFunc {
+	return func(ctx blockfetch.CallbackContext, start ocommon.Point, end ocommon.Point) error {
+		var blocks []testChainBlock
+		for _, blk := range chain {
+			if blk.point.Slot < start.Slot || blk.point.Slot > end.Slot {
+				continue
+			}
+			if missingSlots[blk.point.Slot] {
+				return ctx.Server.NoBlocks()
+			}
+			blocks = append(blocks, blk)
+		}
+		if len(blocks) == 0 {
+			return ctx.Server.NoBlocks()
+		}
+		if err := ctx.Server.StartBatch(); err != nil {
+			return err
+		}
+		for _, blk := range blocks {
+			if err := ctx.Server.Block(ledger.BlockTypeBabbage, blk.cbor); err != nil {
+				return err
+			}
+		}
+		return ctx.Server.BatchDone()
+	}
+}
+
+// testPipeConn gives each end of a net.Pipe a unique address, since the coordinator tracks peers by
+// connection ID
+type testPipeConn struct {
+	net.Conn
+	localAddr  net.Addr
+	remoteAddr net.Addr
+}
+
+func (c *testPipeConn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+func (c *testPipeConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+var testPipePort atomic.Int32
+
+// newTestPipe returns a connected pair of connections with unique addresses
+func newTestPipe() (net.Conn, net.Conn) {
+	clientConn, serverConn := net.Pipe()
+	clientAddr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: int(testPipePort.Add(1))}
+	serverAddr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: int(testPipePort.Add(1))}
+	return &testPipeConn{Conn: clientConn, localAddr: clientAddr, remoteAddr: serverAddr},
+		&testPipeConn{Conn: serverConn, localAddr: serverAddr, remoteAddr: clientAddr}
+}
+
+// newTestServerConns starts an in-process node-to-node server with the provided block-fetch config and
+// returns the client and server connections
+func newTestServerConns(
+	t *testing.T,
+	serverCfg blockfetch.Config,
+) (*ouroboros.Connection, *ouroboros.Connection) {
+	clientConn, serverConn := newTestPipe()
+	serverConnChan := make(chan *ouroboros.Connection, 1)
+	go func() {
+		oConn, err := ouroboros.New(
+			ouroboros.WithConnection(serverConn),
+			ouroboros.WithNetworkMagic(ouroboros.NetworkPreview.NetworkMagic),
+			ouroboros.WithNodeToNode(true),
+			ouroboros.WithServer(true),
+			ouroboros.WithBlockFetchConfig(serverCfg),
+		)
+		if err != nil {
+			panic(err)
+		}
+		serverConnChan <- oConn
+	}()
+	oConn, err := ouroboros.New(
+		ouroboros.WithConnection(clientConn),
+		ouroboros.WithNetworkMagic(ouroboros.NetworkPreview.NetworkMagic),
+		ouroboros.WithNodeToNode(true),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error when creating Ouroboros object: %s", err)
+	}
+	return oConn, <-serverConnChan
+}
+
+func closeTestConns(t *testing.T, conns ...*ouroboros.Connection) {
+	for _, oConn := range conns {
+		if err := oConn.Close(); err != nil {
+			t.Fatalf("unexpected error when closing Ouroboros object: %s", err)
+		}
+		select {
+		case <-oConn.ErrorChan():
+		case <-time.After(10 * time.Second):
+			t.Errorf("did not shutdown within timeout")
+		}
+	}
+}
+
+func TestCoordinatorFetchPoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	chain := newTestChain(t, 10)
+	// The first peer is missing a block, so any range including it must be retried with the second peer
+	clientA, serverA := newTestChainServer(t, chain, map[uint64]bool{chain[4].point.Slot: true})
+	clientB, serverB := newTestChainServer(t, chain, nil)
+	defer closeTestConns(t, clientA, clientB, serverA, serverB)
+	coord := blockfetch.NewCoordinator()
+	coord.AddPeer(clientA.BlockFetch().Client)
+	coord.AddPeer(clientB.BlockFetch().Client)
+	var points []ocommon.Point
+	for _, blk := range chain {
+		points = append(points, blk.point)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var hashes []string
+	err := coord.FetchPoints(
+		ctx,
+		points,
+		3,
+		func(blk ledger.Block) error {
+			hashes = append(hashes, blk.Hash())
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if len(hashes) != len(chain) {
+		t.Fatalf("did not receive expected number of blocks: got %d, wanted %d", len(hashes), len(chain))
+	}
+	for i, blk := range chain {
+		if hashes[i] != blk.hash {
+			t.Fatalf("did not receive blocks in order: got %s at index %d, wanted %s", hashes[i], i, blk.hash)
+		}
+	}
+	if inFlight := coord.InFlight(); len(inFlight) != 0 {
+		t.Fatalf("unexpected in-flight ranges after completion: %v", inFlight)
+	}
+}
+
+func TestCoordinatorAllPeersFail(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	chain := newTestChain(t, 4)
+	missing := map[uint64]bool{chain[2].point.Slot: true}
+	clientA, serverA := newTestChainServer(t, chain, missing)
+	clientB, serverB := newTestChainServer(t, chain, missing)
+	defer closeTestConns(t, clientA, clientB, serverA, serverB)
+	coord := blockfetch.NewCoordinator()
+	coord.AddPeer(clientA.BlockFetch().Client)
+	coord.AddPeer(clientB.BlockFetch().Client)
+	var points []ocommon.Point
+	for _, blk := range chain {
+		points = append(points, blk.point)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := coord.FetchPoints(
+		ctx,
+		points,
+		2,
+		func(blk ledger.Block) error {
+			return nil
+		},
+	)
+	if err == nil {
+		t.Fatalf("did not receive expected error")
+	}
+}
+
+func TestCoordinatorRangeTimeout(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	chain := newTestChain(t, 10)
+	// The first peer starts each batch but never sends BatchDone
+	hungRequestChan := make(chan struct{})
+	var hungRequestOnce sync.Once
+	clientA, serverA := newTestServerConns(
+		t,
+		blockfetch.NewConfig(
+			blockfetch.WithRequestRangeFunc(
+				func(ctx blockfetch.CallbackContext, start ocommon.Point, end ocommon.Point) error {
+					hungRequestOnce.Do(func() { close(hungRequestChan) })
+					if err := ctx.Server.StartBatch(); err != nil {
+						return err
+					}
+					for _, blk := range chain {
+						if blk.point.Slot == start.Slot {
+							return ctx.Server.Block(ledger.BlockTypeBabbage, blk.cbor)
+						}
+					}
+					return nil
+				},
+			),
+		),
+	)
+	// The second peer waits until the first peer has a range, so that the range must be moved from the hung peer
+	chainRequestRangeFunc := newTestChainRequestRangeFunc(chain, nil)
+	clientB, serverB := newTestServerConns(
+		t,
+		blockfetch.NewConfig(
+			blockfetch.WithRequestRangeFunc(
+				func(ctx blockfetch.CallbackContext, start ocommon.Point, end ocommon.Point) error {
+					select {
+					case <-hungRequestChan:
+					case <-time.After(5 * time.Second):
+					}
+					return chainRequestRangeFunc(ctx, start, end)
+				},
+			),
+		),
+	)
+	defer closeTestConns(t, clientA, clientB, serverA, serverB)
+	coord := blockfetch.NewCoordinator(
+		blockfetch.WithCoordinatorRangeTimeout(200 * time.Millisecond),
+	)
+	coord.AddPeer(clientA.BlockFetch().Client)
+	coord.AddPeer(clientB.BlockFetch().Client)
+	var points []ocommon.Point
+	for _, blk := range chain {
+		points = append(points, blk.point)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		var hashes []string
+		err := coord.FetchPoints(
+			ctx,
+			points,
+			3,
+			func(blk ledger.Block) error {
+				hashes = append(hashes, blk.Hash())
+				return nil
+			},
+		)
+		if err != nil {
+			t.Fatalf("received unexpected error: %s", err)
+		}
+		if len(hashes) != len(chain) {
+			t.Fatalf("did not receive expected number of blocks: got %d, wanted %d", len(hashes), len(chain))
+		}
+		for i, blk := range chain {
+			if hashes[i] != blk.hash {
+				t.Fatalf("did not receive blocks in order: got %s at index %d, wanted %s", hashes[i], i, blk.hash)
+			}
+		}
+	}
+	if inFlight := coord.InFlight(); len(inFlight) != 0 {
+		t.Fatalf("unexpected in-flight ranges after completion: %v", inFlight)
+	}
+}
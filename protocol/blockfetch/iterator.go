@@ -0,0 +1,88 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blockfetch
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/protocol"
+)
+
+// Number of received blocks that can be buffered before the iterator stops reading from the peer
+const blockRangeIteratorBufferSize = 10
+
+// BlockRangeIterator provides pull-based access to the blocks from a range request
+type BlockRangeIterator struct {
+	client    *Client
+	blockChan chan ledger.Block
+	doneChan  chan struct{}
+	onceClose sync.Once
+}
+
+func newBlockRangeIterator(client *Client) *BlockRangeIterator {
+	return &BlockRangeIterator{
+		client:    client,
+		blockChan: make(chan ledger.Block, blockRangeIteratorBufferSize),
+		doneChan:  make(chan struct{}),
+	}
+}
+
+// Next returns the next block in the range. It blocks until a block is available or the context
+// is cancelled, and returns io.EOF once all blocks in the range have been returned
+func (i *BlockRangeIterator) Next(ctx context.Context) (ledger.Block, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case blk, ok := <-i.blockChan:
+		if !ok {
+			return nil, io.EOF
+		}
+		return blk, nil
+	case <-i.client.DoneChan():
+		// Return any block that was already received before the shutdown
+		select {
+		case blk, ok := <-i.blockChan:
+			if ok {
+				return blk, nil
+			}
+			return nil, io.EOF
+		default:
+		}
+		return nil, protocol.ProtocolShuttingDownError
+	}
+}
+
+// Close stops delivery of any remaining blocks. The remaining blocks in the range are still received
+// from the peer, but they are discarded
+func (i *BlockRangeIterator) Close() {
+	i.onceClose.Do(func() {
+		close(i.doneChan)
+	})
+}
+
+func (i *BlockRangeIterator) push(blk ledger.Block) {
+	select {
+	case i.blockChan <- blk:
+	case <-i.doneChan:
+	case <-i.client.DoneChan():
+	}
+}
+
+func (i *BlockRangeIterator) finish() {
+	close(i.blockChan)
+}
@@ -104,6 +104,13 @@ func (s *Server) messageHandler(msg protocol.Message) error {
 }
 
 func (s *Server) handleRequestRange(msg protocol.Message) error {
+	if s.config != nil && s.config.BlockStore != nil {
+		msgRequestRange := msg.(*MsgRequestRange)
+		return s.serveBlockStoreRange(
+			msgRequestRange.Start,
+			msgRequestRange.End,
+		)
+	}
 	if s.config == nil || s.config.RequestRangeFunc == nil {
 		return fmt.Errorf(
 			"received block-fetch RequestRange message but no callback function is defined",
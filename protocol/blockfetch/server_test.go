@@ -0,0 +1,115 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blockfetch_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/protocol/blockfetch"
+	ocommon "github.com/blinklabs-io/gouroboros/protocol/common"
+	"go.uber.org/goleak"
+)
+
+// testBlockStore is a BlockStore backed by a slice of blocks
+type testBlockStore struct {
+	chain []testChainBlock
+}
+
+type testBlockStoreIterator struct {
+	blocks []testChainBlock
+}
+
+func (s *testBlockStore) GetBlockRange(
+	start ocommon.Point,
+	end ocommon.Point,
+) (blockfetch.BlockStoreIterator, error) {
+	startIdx, endIdx := -1, -1
+	for i, blk := range s.chain {
+		if blk.point.Slot == start.Slot && bytes.Equal(blk.point.Hash, start.Hash) {
+			startIdx = i
+		}
+		if blk.point.Slot == end.Slot && bytes.Equal(blk.point.Hash, end.Hash) {
+			endIdx = i
+		}
+	}
+	if startIdx < 0 || endIdx < startIdx {
+		return nil, nil
+	}
+	return &testBlockStoreIterator{blocks: s.chain[startIdx : endIdx+1]}, nil
+}
+
+func (i *testBlockStoreIterator) Len() int {
+	return len(i.blocks)
+}
+
+func (i *testBlockStoreIterator) Next() (uint, []byte, error) {
+	if len(i.blocks) == 0 {
+		return 0, nil, io.EOF
+	}
+	blk := i.blocks[0]
+	i.blocks = i.blocks[1:]
+	return ledger.BlockTypeBabbage, blk.cbor, nil
+}
+
+func (i *testBlockStoreIterator) Close() error {
+	return nil
+}
+
+func TestServerBlockStore(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	chain := newTestChain(t, 6)
+	clientConn, serverConn := newTestServerConns(
+		t,
+		blockfetch.NewConfig(
+			blockfetch.WithBlockStore(&testBlockStore{chain: chain}),
+			blockfetch.WithMaxRangeBlocks(4),
+		),
+	)
+	defer closeTestConns(t, clientConn, serverConn)
+	client := clientConn.BlockFetch().Client
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// Fetch a range within the limit
+	iter, err := client.GetBlockRangeIterator(chain[1].point, chain[4].point)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	for _, expected := range chain[1:5] {
+		blk, err := iter.Next(ctx)
+		if err != nil {
+			t.Fatalf("received unexpected error: %s", err)
+		}
+		if blk.Hash() != expected.hash {
+			t.Fatalf("did not receive expected block hash: got %s, wanted %s", blk.Hash(), expected.hash)
+		}
+	}
+	if _, err := iter.Next(ctx); err != io.EOF {
+		t.Fatalf("did not receive expected io.EOF, got: %v", err)
+	}
+	// Fetch a range beyond the limit
+	if _, err := client.GetBlockRangeIterator(chain[0].point, chain[5].point); err == nil {
+		t.Fatalf("did not receive expected error for range exceeding limit")
+	}
+	// Fetch an unknown range
+	unknownPoint := ocommon.NewPoint(999999, []byte{0xab, 0xcd})
+	if _, err := client.GetBlock(unknownPoint); err == nil {
+		t.Fatalf("did not receive expected error for unknown block")
+	}
+}
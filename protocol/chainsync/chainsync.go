@@ -31,6 +31,10 @@ const (
 	ProtocolIdNtC uint16 = 5
 )
 
+// DefaultSecurityParam is the security parameter (k) used on the public networks. It's the maximum
+// number of blocks that can be rolled back
+const DefaultSecurityParam = 2160
+
 var (
 	stateIdle      = protocol.NewState(1, "Idle")
 	stateCanAwait  = protocol.NewState(2, "CanAwait")
@@ -34,6 +34,9 @@ type Client struct {
 	readyForNextBlockChan chan bool
 	onceStart             sync.Once
 	onceStop              sync.Once
+	// syncing and stopRequested are protected by busyMutex
+	syncing       bool
+	stopRequested bool
 
 	// waitingForCurrentTipChan will process all the requests for the current tip until the channel
 	// is empty.
@@ -147,12 +150,18 @@ func (c *Client) messageHandler(msg protocol.Message) error {
 	return err
 }
 
-// Stop transitions the protocol to the Done state. No more protocol operations will be possible afterward
+// Stop transitions the protocol to the Done state. No more protocol operations will be possible afterward.
+// During a sync operation, the server has agency until it replies to the outstanding block request, so the
+// Done message is sent after that reply instead of requesting another block
 func (c *Client) Stop() error {
 	var err error
 	c.onceStop.Do(func() {
 		c.busyMutex.Lock()
 		defer c.busyMutex.Unlock()
+		if c.syncing {
+			c.stopRequested = true
+			return
+		}
 		msg := NewMsgDone()
 		if err = c.SendMessage(msg); err != nil {
 			return
@@ -323,6 +332,7 @@ func (c *Client) Sync(intersectPoints []common.Point) error {
 			return err
 		}
 	}
+	c.syncing = true
 	go c.syncLoop()
 	return nil
 }
@@ -330,14 +340,29 @@ func (c *Client) Sync(intersectPoints []common.Point) error {
 func (c *Client) syncLoop() {
 	for {
 		// Wait for a block to be received
-		if ready, ok := <-c.readyForNextBlockChan; !ok {
+		ready, ok := <-c.readyForNextBlockChan
+		if !ok {
 			// Channel is closed, which means we're shutting down
 			return
-		} else if !ready {
+		}
+		c.busyMutex.Lock()
+		if c.stopRequested {
+			// Stop was called while waiting on the server, so we finish the deferred shutdown now that we
+			// have agency again
+			c.syncing = false
+			msg := NewMsgDone()
+			if err := c.SendMessage(msg); err != nil {
+				c.SendError(err)
+			}
+			c.busyMutex.Unlock()
+			return
+		}
+		if !ready {
 			// Sync was cancelled
+			c.syncing = false
+			c.busyMutex.Unlock()
 			return
 		}
-		c.busyMutex.Lock()
 		// Request the next block
 		// In practice we already have multiple block requests pipelined
 		// and this just adds another one to the pile
@@ -0,0 +1,185 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainsync
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/protocol/common"
+)
+
+// RollbackTooDeepError is returned when a rollback reaches past the blocks held by a ConfirmationBuffer,
+// which means that blocks that were already delivered as confirmed have been rolled back
+type RollbackTooDeepError struct {
+	Point common.Point
+	Depth uint64
+}
+
+func (e RollbackTooDeepError) Error() string {
+	return fmt.Sprintf(
+		"rollback to slot %d (%x) exceeds confirmation depth of %d blocks",
+		e.Point.Slot,
+		e.Point.Hash,
+		e.Depth,
+	)
+}
+
+// ConfirmationBufferOptionFunc represents a function used to modify the ConfirmationBuffer config
+type ConfirmationBufferOptionFunc func(*ConfirmationBuffer)
+
+// WithConfirmationDepth specifies the number of blocks that must be on top of a block before it's
+// delivered downstream. The default is DefaultSecurityParam
+func WithConfirmationDepth(depth uint64) ConfirmationBufferOptionFunc {
+	return func(b *ConfirmationBuffer) {
+		b.depth = depth
+	}
+}
+
+type confirmationBufferEntry struct {
+	callbackContext CallbackContext
+	blockType       uint
+	blockData       interface{}
+	tip             Tip
+	point           common.Point
+}
+
+// ConfirmationBuffer wraps a RollForward callback function and only delivers blocks once they are
+// buried under a configurable number of blocks. Rollbacks within the buffered window are absorbed
+// without notifying the wrapped callback function, so consumers never see rolled back blocks
+type ConfirmationBuffer struct {
+	mutex           sync.Mutex
+	depth           uint64
+	rollForwardFunc RollForwardFunc
+	entries         []confirmationBufferEntry
+	lastConfirmed   common.Point
+	started         bool
+}
+
+// NewConfirmationBuffer returns a new ConfirmationBuffer that delivers confirmed blocks to the
+// provided RollForward callback function
+func NewConfirmationBuffer(
+	rollForwardFunc RollForwardFunc,
+	options ...ConfirmationBufferOptionFunc,
+) *ConfirmationBuffer {
+	b := &ConfirmationBuffer{
+		depth:           DefaultSecurityParam,
+		rollForwardFunc: rollForwardFunc,
+	}
+	// Apply provided options functions
+	for _, option := range options {
+		option(b)
+	}
+	return b
+}
+
+// RollForward adds a block to the buffer and delivers the oldest buffered block if it has reached
+// the confirmation depth. It can be used directly as a RollForwardFunc
+func (b *ConfirmationBuffer) RollForward(
+	ctx CallbackContext,
+	blockType uint,
+	blockData interface{},
+	tip Tip,
+) error {
+	point, err := blockDataPoint(blockData)
+	if err != nil {
+		return err
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.started = true
+	b.entries = append(
+		b.entries,
+		confirmationBufferEntry{
+			callbackContext: ctx,
+			blockType:       blockType,
+			blockData:       blockData,
+			tip:             tip,
+			point:           point,
+		},
+	)
+	for uint64(len(b.entries)) > b.depth {
+		entry := b.entries[0]
+		if err := b.rollForwardFunc(entry.callbackContext, entry.blockType, entry.blockData, entry.tip); err != nil {
+			// Keep the block buffered if the consumer didn't accept it
+			return err
+		}
+		b.lastConfirmed = entry.point
+		b.entries[0] = confirmationBufferEntry{}
+		b.entries = b.entries[1:]
+	}
+	return nil
+}
+
+// RollBackward discards buffered blocks after the specified point. An error is returned if the point is
+// older than the buffered window. It can be used directly as a RollBackwardFunc
+func (b *ConfirmationBuffer) RollBackward(
+	ctx CallbackContext,
+	point common.Point,
+	tip Tip,
+) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	// The initial rollback to the intersect point arrives before any blocks
+	if !b.started {
+		b.lastConfirmed = point
+		b.started = true
+		return nil
+	}
+	for i := len(b.entries) - 1; i >= 0; i-- {
+		if pointsEqual(b.entries[i].point, point) {
+			b.entries = b.entries[:i+1]
+			return nil
+		}
+	}
+	if pointsEqual(b.lastConfirmed, point) {
+		b.entries = b.entries[:0]
+		return nil
+	}
+	return RollbackTooDeepError{
+		Point: point,
+		Depth: b.depth,
+	}
+}
+
+// Pending returns the number of blocks that are buffered awaiting confirmation
+func (b *ConfirmationBuffer) Pending() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return len(b.entries)
+}
+
+// blockDataPoint returns the point for the block or block header provided to a RollForward callback
+func blockDataPoint(blockData interface{}) (common.Point, error) {
+	header, ok := blockData.(ledger.BlockHeader)
+	if !ok {
+		return common.Point{}, fmt.Errorf(
+			"unexpected block data type: %T",
+			blockData,
+		)
+	}
+	blockHash, err := hex.DecodeString(header.Hash())
+	if err != nil {
+		return common.Point{}, err
+	}
+	return common.NewPoint(header.SlotNumber(), blockHash), nil
+}
+
+func pointsEqual(a common.Point, b common.Point) bool {
+	return a.Slot == b.Slot && bytes.Equal(a.Hash, b.Hash)
+}
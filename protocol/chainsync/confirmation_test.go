@@ -0,0 +1,120 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainsync_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/protocol/chainsync"
+	ocommon "github.com/blinklabs-io/gouroboros/protocol/common"
+)
+
+// testHeader is a minimal block header implementation
+type testHeader struct {
+	hash        string
+	blockNumber uint64
+}
+
+func (h testHeader) Hash() string                  { return h.hash }
+func (h testHeader) BlockNumber() uint64           { return h.blockNumber }
+func (h testHeader) SlotNumber() uint64            { return h.blockNumber }
+func (h testHeader) IssuerVkey() ledger.IssuerVkey { return ledger.IssuerVkey{} }
+func (h testHeader) BlockBodySize() uint64         { return 0 }
+func (h testHeader) Era() ledger.Era               { return ledger.EraInvalid }
+func (h testHeader) Cbor() []byte                  { return nil }
+
+func newTestHeader(fork string, blockNumber uint64) testHeader {
+	return testHeader{
+		hash:        hex.EncodeToString([]byte(fmt.Sprintf("%s-%d", fork, blockNumber))),
+		blockNumber: blockNumber,
+	}
+}
+
+func testHeaderPoint(h testHeader) ocommon.Point {
+	hash, _ := hex.DecodeString(h.hash)
+	return ocommon.NewPoint(h.SlotNumber(), hash)
+}
+
+func TestConfirmationBuffer(t *testing.T) {
+	var confirmed []string
+	buf := chainsync.NewConfirmationBuffer(
+		func(ctx chainsync.CallbackContext, blockType uint, blockData interface{}, tip chainsync.Tip) error {
+			confirmed = append(confirmed, blockData.(ledger.BlockHeader).Hash())
+			return nil
+		},
+		chainsync.WithConfirmationDepth(3),
+	)
+	ctx := chainsync.CallbackContext{}
+	if err := buf.RollBackward(ctx, ocommon.NewPointOrigin(), chainsync.Tip{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i := uint64(1); i <= 5; i++ {
+		if err := buf.RollForward(ctx, 0, newTestHeader("a", i), chainsync.Tip{}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	// Blocks 1 and 2 are 3 blocks deep
+	if len(confirmed) != 2 {
+		t.Fatalf("did not get expected confirmed block count: got %d, wanted %d", len(confirmed), 2)
+	}
+	// Rolling back within the window and switching forks is not visible downstream
+	if err := buf.RollBackward(ctx, testHeaderPoint(newTestHeader("a", 3)), chainsync.Tip{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i := uint64(4); i <= 6; i++ {
+		if err := buf.RollForward(ctx, 0, newTestHeader("b", i), chainsync.Tip{}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	expected := []string{
+		newTestHeader("a", 1).Hash(),
+		newTestHeader("a", 2).Hash(),
+		newTestHeader("a", 3).Hash(),
+	}
+	if fmt.Sprint(confirmed) != fmt.Sprint(expected) {
+		t.Fatalf("did not get expected confirmed blocks\n  got:    %v\n  wanted: %v", confirmed, expected)
+	}
+	if buf.Pending() != 3 {
+		t.Fatalf("did not get expected pending block count: got %d, wanted %d", buf.Pending(), 3)
+	}
+}
+
+func TestConfirmationBufferRollbackTooDeep(t *testing.T) {
+	buf := chainsync.NewConfirmationBuffer(
+		func(ctx chainsync.CallbackContext, blockType uint, blockData interface{}, tip chainsync.Tip) error {
+			return nil
+		},
+		chainsync.WithConfirmationDepth(2),
+	)
+	ctx := chainsync.CallbackContext{}
+	for i := uint64(1); i <= 5; i++ {
+		if err := buf.RollForward(ctx, 0, newTestHeader("a", i), chainsync.Tip{}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	// Rolling back to the last confirmed block is allowed
+	if err := buf.RollBackward(ctx, testHeaderPoint(newTestHeader("a", 3)), chainsync.Tip{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err := buf.RollBackward(ctx, testHeaderPoint(newTestHeader("a", 2)), chainsync.Tip{})
+	var tooDeepErr chainsync.RollbackTooDeepError
+	if !errors.As(err, &tooDeepErr) {
+		t.Fatalf("did not get expected error: got %v", err)
+	}
+}
@@ -15,11 +15,15 @@
 package localstatequery
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"sort"
 	"sync"
 
 	"github.com/blinklabs-io/gouroboros/cbor"
 	"github.com/blinklabs-io/gouroboros/ledger"
+	lcommon "github.com/blinklabs-io/gouroboros/ledger/common"
 	"github.com/blinklabs-io/gouroboros/protocol"
 	"github.com/blinklabs-io/gouroboros/protocol/common"
 )
@@ -27,17 +31,16 @@ import (
 // Client implements the LocalStateQuery client
 type Client struct {
 	*protocol.Protocol
-	config                        *Config
-	callbackContext               CallbackContext
-	enableGetChainBlockNo         bool
-	enableGetChainPoint           bool
-	enableGetRewardInfoPoolsBlock bool
-	busyMutex                     sync.Mutex
-	acquired                      bool
-	queryResultChan               chan []byte
-	acquireResultChan             chan error
-	currentEra                    int
-	onceStart                     sync.Once
+	config            *Config
+	callbackContext   CallbackContext
+	protocolVersion   uint16
+	busyMutex         sync.Mutex
+	acquired          bool
+	queryResultChan   chan []byte
+	acquireResultChan chan error
+	currentEra        int
+	currentEraMutex   sync.Mutex
+	onceStart         sync.Once
 }
 
 // NewClient returns a new LocalStateQuery client object
@@ -80,13 +83,10 @@ func NewClient(protoOptions protocol.ProtocolOptions, cfg *Config) *Client {
 		StateMap:            stateMap,
 		InitialState:        stateIdle,
 	}
-	// Enable version-dependent features
-	if (protoOptions.Version - protocol.ProtocolVersionNtCOffset) >= 10 {
-		c.enableGetChainBlockNo = true
-		c.enableGetChainPoint = true
-	}
-	if (protoOptions.Version - protocol.ProtocolVersionNtCOffset) >= 11 {
-		c.enableGetRewardInfoPoolsBlock = true
+	// Version-dependent queries are checked against the negotiated version. Without a negotiated NtC version,
+	// the version is left at 0 and all version-dependent queries are reported as unsupported
+	if protoOptions.Version >= protocol.ProtocolVersionNtCOffset {
+		c.protocolVersion = protoOptions.Version - protocol.ProtocolVersionNtCOffset
 	}
 	c.Protocol = protocol.New(protoConfig)
 	return c
@@ -125,13 +125,17 @@ func (c *Client) messageHandler(msg protocol.Message) error {
 
 func (c *Client) handleAcquired() error {
 	c.acquired = true
+	// Clear the cached era before signalling, so that queries against the new state don't use the old era
+	c.setCurrentEra(-1)
 	c.acquireResultChan <- nil
-	c.currentEra = -1
 	return nil
 }
 
 func (c *Client) handleFailure(msg protocol.Message) error {
 	msgFailure := msg.(*MsgFailure)
+	// A failed acquire returns the protocol to the idle state, even when reacquiring
+	c.acquired = false
+	c.setCurrentEra(-1)
 	switch msgFailure.Failure {
 	case AcquireFailurePointTooOld:
 		c.acquireResultChan <- AcquireFailurePointTooOldError{}
@@ -175,55 +179,100 @@ func (c *Client) acquire(point *common.Point) error {
 }
 
 func (c *Client) release() error {
+	// There's nothing to release after a failed acquire
+	if !c.acquired {
+		return nil
+	}
 	msg := NewMsgRelease()
 	if err := c.SendMessage(msg); err != nil {
 		return err
 	}
 	c.acquired = false
-	c.currentEra = -1
+	c.setCurrentEra(-1)
 	return nil
 }
 
 func (c *Client) runQuery(query interface{}, result interface{}) error {
+	resultCbor, err := c.runQueryRaw(query)
+	if err != nil {
+		return err
+	}
+	if _, err := cbor.Decode(resultCbor, result); err != nil {
+		return err
+	}
+	return nil
+}
+
+// runQueryRaw runs the query and returns the result CBOR without decoding it
+func (c *Client) runQueryRaw(query interface{}) ([]byte, error) {
 	msg := NewMsgQuery(query)
 	if !c.acquired {
 		if err := c.acquire(nil); err != nil {
-			return err
+			return nil, err
 		}
 	}
 	if err := c.SendMessage(msg); err != nil {
-		return err
+		return nil, err
 	}
 	resultCbor, ok := <-c.queryResultChan
 	if !ok {
-		return protocol.ProtocolShuttingDownError
+		return nil, protocol.ProtocolShuttingDownError
 	}
-	if _, err := cbor.Decode(resultCbor, result); err != nil {
-		return err
+	return resultCbor, nil
+}
+
+// checkQueryVersion returns an error if the negotiated protocol version is older than the minimum version
+// for the query
+func (c *Client) checkQueryVersion(queryName string, minVersion uint16) error {
+	if c.protocolVersion < minVersion {
+		return QueryVersionUnsupportedError{
+			Query:      queryName,
+			Version:    c.protocolVersion,
+			MinVersion: minVersion,
+		}
 	}
 	return nil
 }
 
+func (c *Client) setCurrentEra(era int) {
+	c.currentEraMutex.Lock()
+	defer c.currentEraMutex.Unlock()
+	c.currentEra = era
+}
+
 // Helper function for getting the current era
 // The current era is needed for many other queries
 func (c *Client) getCurrentEra() (int, error) {
 	// Return cached era, if available
-	if c.currentEra > -1 {
-		return c.currentEra, nil
+	c.currentEraMutex.Lock()
+	currentEra := c.currentEra
+	c.currentEraMutex.Unlock()
+	if currentEra > -1 {
+		return currentEra, nil
 	}
 	query := buildHardForkQuery(QueryTypeHardForkCurrentEra)
 	var result int
 	if err := c.runQuery(query, &result); err != nil {
 		return -1, err
 	}
+	// The era can't change until the next acquire, so we cache it
+	c.setCurrentEra(result)
 	return result, nil
 }
 
-// Acquire starts the acquire process for the specified chain point
-func (c *Client) Acquire(point *common.Point) error {
+// Acquire acquires the ledger state at the specified chain point, or at the current tip if point is nil, and
+// returns a Snapshot for running queries against it. All queries run against the same ledger state until the
+// snapshot is reacquired or closed. Other calls on the client block until the snapshot is closed
+func (c *Client) Acquire(point *common.Point) (*Snapshot, error) {
 	c.busyMutex.Lock()
-	defer c.busyMutex.Unlock()
-	return c.acquire(point)
+	if err := c.acquire(point); err != nil {
+		c.busyMutex.Unlock()
+		return nil, err
+	}
+	s := &Snapshot{
+		client: c,
+	}
+	return s, nil
 }
 
 // Release releases the previously acquired chain point
@@ -244,6 +293,10 @@ func (c *Client) GetCurrentEra() (int, error) {
 func (c *Client) GetSystemStart() (*SystemStartResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getSystemStart()
+}
+
+func (c *Client) getSystemStart() (*SystemStartResult, error) {
 	query := buildQuery(
 		QueryTypeSystemStart,
 	)
@@ -258,6 +311,13 @@ func (c *Client) GetSystemStart() (*SystemStartResult, error) {
 func (c *Client) GetChainBlockNo() (int64, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getChainBlockNo()
+}
+
+func (c *Client) getChainBlockNo() (int64, error) {
+	if err := c.checkQueryVersion("GetChainBlockNo", queryMinVersionChainBlockNo); err != nil {
+		return 0, err
+	}
 	query := buildQuery(
 		QueryTypeChainBlockNo,
 	)
@@ -272,6 +332,13 @@ func (c *Client) GetChainBlockNo() (int64, error) {
 func (c *Client) GetChainPoint() (*common.Point, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getChainPoint()
+}
+
+func (c *Client) getChainPoint() (*common.Point, error) {
+	if err := c.checkQueryVersion("GetChainPoint", queryMinVersionChainPoint); err != nil {
+		return nil, err
+	}
 	query := buildQuery(
 		QueryTypeChainPoint,
 	)
@@ -286,6 +353,10 @@ func (c *Client) GetChainPoint() (*common.Point, error) {
 func (c *Client) GetEraHistory() ([]EraHistoryResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getEraHistory()
+}
+
+func (c *Client) getEraHistory() ([]EraHistoryResult, error) {
 	query := buildHardForkQuery(QueryTypeHardForkEraHistory)
 	var result []EraHistoryResult
 	if err := c.runQuery(query, &result); err != nil {
@@ -298,6 +369,10 @@ func (c *Client) GetEraHistory() ([]EraHistoryResult, error) {
 func (c *Client) GetEpochNo() (int, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getEpochNo()
+}
+
+func (c *Client) getEpochNo() (int, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return 0, err
@@ -313,13 +388,19 @@ func (c *Client) GetEpochNo() (int, error) {
 	return result[0], nil
 }
 
-// TODO
-/*
-query	[2 #6.258([*[0 int]])	int is the stake the user intends to delegate, the array must be sorted
-*/
-func (c *Client) GetNonMyopicMemberRewards() (*NonMyopicMemberRewardsResult, error) {
+// GetNonMyopicMemberRewards returns the rewards that each of the specified stake amounts or stake credentials
+// would receive from each pool
+func (c *Client) GetNonMyopicMemberRewards(
+	targets []NonMyopicMemberRewardsTarget,
+) (*NonMyopicMemberRewardsResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getNonMyopicMemberRewards(targets)
+}
+
+func (c *Client) getNonMyopicMemberRewards(
+	targets []NonMyopicMemberRewardsTarget,
+) (*NonMyopicMemberRewardsResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -327,6 +408,7 @@ func (c *Client) GetNonMyopicMemberRewards() (*NonMyopicMemberRewardsResult, err
 	query := buildShelleyQuery(
 		currentEra,
 		QueryTypeShelleyNonMyopicMemberRewards,
+		buildSetParam(targets),
 	)
 	var result NonMyopicMemberRewardsResult
 	if err := c.runQuery(query, &result); err != nil {
@@ -339,6 +421,10 @@ func (c *Client) GetNonMyopicMemberRewards() (*NonMyopicMemberRewardsResult, err
 func (c *Client) GetCurrentProtocolParams() (CurrentProtocolParamsResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getCurrentProtocolParams()
+}
+
+func (c *Client) getCurrentProtocolParams() (CurrentProtocolParamsResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -389,9 +475,14 @@ func (c *Client) GetCurrentProtocolParams() (CurrentProtocolParamsResult, error)
 	}
 }
 
+// GetProposedProtocolParamsUpdates returns the protocol parameter updates proposed by the genesis delegates
 func (c *Client) GetProposedProtocolParamsUpdates() (*ProposedProtocolParamsUpdatesResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getProposedProtocolParamsUpdates()
+}
+
+func (c *Client) getProposedProtocolParamsUpdates() (*ProposedProtocolParamsUpdatesResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -401,17 +492,53 @@ func (c *Client) GetProposedProtocolParamsUpdates() (*ProposedProtocolParamsUpda
 		QueryTypeShelleyProposedProtocolParamsUpdates,
 	)
 	var result ProposedProtocolParamsUpdatesResult
-	if err := c.runQuery(query, &result); err != nil {
+	switch currentEra {
+	case ledger.EraIdConway:
+		result, err = runProposedProtocolParamsUpdatesQuery[ledger.ConwayProtocolParameterUpdate](c, query)
+	case ledger.EraIdBabbage:
+		result, err = runProposedProtocolParamsUpdatesQuery[ledger.BabbageProtocolParameterUpdate](c, query)
+	case ledger.EraIdAlonzo:
+		result, err = runProposedProtocolParamsUpdatesQuery[ledger.AlonzoProtocolParameterUpdate](c, query)
+	case ledger.EraIdMary:
+		result, err = runProposedProtocolParamsUpdatesQuery[ledger.MaryProtocolParameterUpdate](c, query)
+	case ledger.EraIdAllegra:
+		result, err = runProposedProtocolParamsUpdatesQuery[ledger.AllegraProtocolParameterUpdate](c, query)
+	case ledger.EraIdShelley:
+		result, err = runProposedProtocolParamsUpdatesQuery[ledger.ShelleyProtocolParameterUpdate](c, query)
+	default:
+		return nil, fmt.Errorf("unknown era ID: %d", currentEra)
+	}
+	if err != nil {
 		return nil, err
 	}
 	return &result, nil
+}
 
+// runProposedProtocolParamsUpdatesQuery runs the proposed protocol params updates query and decodes the
+// result using the protocol param update type for the current era
+func runProposedProtocolParamsUpdatesQuery[T any](
+	c *Client,
+	query interface{},
+) (ProposedProtocolParamsUpdatesResult, error) {
+	result := []map[ledger.Blake2b224]T{}
+	if err := c.runQuery(query, &result); err != nil {
+		return nil, err
+	}
+	ret := ProposedProtocolParamsUpdatesResult{}
+	for genesisKeyHash, update := range result[0] {
+		ret[genesisKeyHash] = update
+	}
+	return ret, nil
 }
 
 // GetStakeDistribution returns the stake distribution
 func (c *Client) GetStakeDistribution() (*StakeDistributionResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getStakeDistribution()
+}
+
+func (c *Client) getStakeDistribution() (*StakeDistributionResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -432,6 +559,12 @@ func (c *Client) GetUTxOByAddress(
 ) (*UTxOByAddressResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getUTxOByAddress(addrs)
+}
+
+func (c *Client) getUTxOByAddress(
+	addrs []ledger.Address,
+) (*UTxOByAddressResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -441,8 +574,10 @@ func (c *Client) GetUTxOByAddress(
 		QueryTypeShelleyUtxoByAddress,
 		addrs,
 	)
-	var result UTxOByAddressResult
-	if err := c.runQuery(query, &result); err != nil {
+	result := UTxOByAddressResult{
+		Results: make(map[UtxoId]ledger.BabbageTransactionOutput),
+	}
+	if err := c.runUTxOQueryRawForEach(query, utxoResultEntryFunc(result.Results)); err != nil {
 		return nil, err
 	}
 	return &result, nil
@@ -451,6 +586,10 @@ func (c *Client) GetUTxOByAddress(
 func (c *Client) GetUTxOWhole() (*UTxOWholeResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getUTxOWhole()
+}
+
+func (c *Client) getUTxOWhole() (*UTxOWholeResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -459,17 +598,229 @@ func (c *Client) GetUTxOWhole() (*UTxOWholeResult, error) {
 		currentEra,
 		QueryTypeShelleyUtxoWhole,
 	)
-	var result UTxOWholeResult
-	if err := c.runQuery(query, &result); err != nil {
+	result := UTxOWholeResult{
+		Results: make(map[UtxoId]ledger.BabbageTransactionOutput),
+	}
+	if err := c.runUTxOQueryRawForEach(query, utxoResultEntryFunc(result.Results)); err != nil {
 		return nil, err
 	}
 	return &result, nil
 }
 
-// TODO
+// ForEachUTxOWhole calls utxoFunc for each UTxO in the whole UTxO set. UTxOs are decoded as the query result is
+// received, so the whole result is never held in memory. utxoFunc is called from the protocol's receive loop, so
+// it must not use the client, and the query timeout includes the time spent in utxoFunc
+func (c *Client) ForEachUTxOWhole(utxoFunc UTxOFunc) error {
+	c.busyMutex.Lock()
+	defer c.busyMutex.Unlock()
+	return c.forEachUTxOWhole(utxoFunc)
+}
+
+func (c *Client) forEachUTxOWhole(utxoFunc UTxOFunc) error {
+	currentEra, err := c.getCurrentEra()
+	if err != nil {
+		return err
+	}
+	query := buildShelleyQuery(
+		currentEra,
+		QueryTypeShelleyUtxoWhole,
+	)
+	return c.runUTxOQueryForEach(currentEra, query, utxoFunc)
+}
+
+// ForEachUTxOByAddress calls utxoFunc for each UTxO at the specified addresses. UTxOs are decoded as the query
+// result is received, so the whole result is never held in memory. utxoFunc is called from the protocol's
+// receive loop, so it must not use the client, and the query timeout includes the time spent in utxoFunc
+func (c *Client) ForEachUTxOByAddress(
+	addrs []ledger.Address,
+	utxoFunc UTxOFunc,
+) error {
+	c.busyMutex.Lock()
+	defer c.busyMutex.Unlock()
+	return c.forEachUTxOByAddress(addrs, utxoFunc)
+}
+
+func (c *Client) forEachUTxOByAddress(
+	addrs []ledger.Address,
+	utxoFunc UTxOFunc,
+) error {
+	currentEra, err := c.getCurrentEra()
+	if err != nil {
+		return err
+	}
+	query := buildShelleyQuery(
+		currentEra,
+		QueryTypeShelleyUtxoByAddress,
+		addrs,
+	)
+	return c.runUTxOQueryForEach(currentEra, query, utxoFunc)
+}
+
+// runUTxOQueryForEach runs a query that returns a UTxO map and calls utxoFunc for each entry as it's received
+func (c *Client) runUTxOQueryForEach(
+	era int,
+	query interface{},
+	utxoFunc UTxOFunc,
+) error {
+	return c.runUTxOQueryRawForEach(
+		query,
+		func(key cbor.RawMessage, value cbor.RawMessage) error {
+			var txIn ledger.ShelleyTransactionInput
+			if _, err := cbor.Decode(key, &txIn); err != nil {
+				return fmt.Errorf("failed to decode UTxO input: %w", err)
+			}
+			txOut, err := newUTxOOutputFromCbor(era, value)
+			if err != nil {
+				return fmt.Errorf("failed to decode UTxO output: %w", err)
+			}
+			return utxoFunc(txIn, txOut)
+		},
+	)
+}
+
+// runUTxOQueryRawForEach runs a query that returns a UTxO map and calls entryFunc with the raw CBOR for each
+// entry as it's received. The result message is read directly from the muxer segments, so the whole result is
+// never held in memory. After entryFunc returns an error, the rest of the result is read and discarded to keep
+// the protocol in sync, and the error is returned
+func (c *Client) runUTxOQueryRawForEach(
+	query interface{},
+	entryFunc func(key cbor.RawMessage, value cbor.RawMessage) error,
+) error {
+	var entryErr error
+	c.SetMessageStreamFunc(
+		MessageTypeResult,
+		func(msgType uint, r io.Reader) (protocol.Message, error) {
+			entryErr = readUTxOResult(cbor.NewItemReader(r), entryFunc)
+			return NewMsgResult(nil), nil
+		},
+	)
+	// Make sure that the stream function isn't left behind for another query if this one fails to run
+	defer c.SetMessageStreamFunc(MessageTypeResult, nil)
+	if _, err := c.runQueryRaw(query); err != nil {
+		return err
+	}
+	return entryErr
+}
+
+// readUTxOResult reads a result message containing a UTxO map and calls entryFunc for each entry. The message
+// is always read in full. Errors in the message structure and errors from entryFunc are returned
+func readUTxOResult(
+	itemReader *cbor.ItemReader,
+	entryFunc func(key cbor.RawMessage, value cbor.RawMessage) error,
+) error {
+	// The message is [MessageTypeResult, result]
+	msgLen, err := itemReader.ReadArrayHeader()
+	if err != nil {
+		return err
+	}
+	if msgLen != 2 {
+		return fmt.Errorf("unexpected result message length: %d", msgLen)
+	}
+	if err := itemReader.SkipItem(); err != nil {
+		return err
+	}
+	// The UTxO map is wrapped in a single element list. Anything else, such as an era mismatch, is skipped
+	resultLen, err := itemReader.ReadArrayHeader()
+	if err != nil {
+		return err
+	}
+	if resultLen != 1 {
+		for i := int64(0); resultLen < 0 || i < resultLen; i++ {
+			if resultLen < 0 {
+				isBreak, err := itemReader.ReadBreak()
+				if err != nil {
+					return err
+				}
+				if isBreak {
+					break
+				}
+			}
+			if err := itemReader.SkipItem(); err != nil {
+				return err
+			}
+		}
+		return fmt.Errorf("unexpected UTxO query result format")
+	}
+	mapLen, err := itemReader.ReadMapHeader()
+	if err != nil {
+		return err
+	}
+	var entryErr error
+	for i := int64(0); mapLen < 0 || i < mapLen; i++ {
+		// Indefinite-length maps are terminated by a "break" byte
+		if mapLen < 0 {
+			isBreak, err := itemReader.ReadBreak()
+			if err != nil {
+				return err
+			}
+			if isBreak {
+				break
+			}
+		}
+		// Skip the remaining entries after an error
+		if entryErr != nil {
+			if err := itemReader.SkipItem(); err != nil {
+				return err
+			}
+			if err := itemReader.SkipItem(); err != nil {
+				return err
+			}
+			continue
+		}
+		key, err := itemReader.ReadItem()
+		if err != nil {
+			return err
+		}
+		value, err := itemReader.ReadItem()
+		if err != nil {
+			return err
+		}
+		entryErr = entryFunc(key, value)
+	}
+	return entryErr
+}
+
+// utxoResultEntryFunc returns an entry function for runUTxOQueryRawForEach that adds each entry to the results
+func utxoResultEntryFunc(
+	results map[UtxoId]ledger.BabbageTransactionOutput,
+) func(cbor.RawMessage, cbor.RawMessage) error {
+	return func(key cbor.RawMessage, value cbor.RawMessage) error {
+		var utxoId UtxoId
+		if _, err := cbor.Decode(key, &utxoId); err != nil {
+			return err
+		}
+		var txOut ledger.BabbageTransactionOutput
+		if _, err := cbor.Decode(value, &txOut); err != nil {
+			return err
+		}
+		results[utxoId] = txOut
+		return nil
+	}
+}
+
+func newUTxOOutputFromCbor(era int, data []byte) (ledger.TransactionOutput, error) {
+	switch era {
+	case ledger.EraIdShelley, ledger.EraIdAllegra:
+		return ledger.NewShelleyTransactionOutputFromCbor(data)
+	case ledger.EraIdMary:
+		return ledger.NewMaryTransactionOutputFromCbor(data)
+	case ledger.EraIdAlonzo:
+		return ledger.NewAlonzoTransactionOutputFromCbor(data)
+	case ledger.EraIdBabbage, ledger.EraIdConway:
+		return ledger.NewBabbageTransactionOutputFromCbor(data)
+	default:
+		return nil, fmt.Errorf("unknown era ID: %d", era)
+	}
+}
+
+// DebugEpochState returns the ledger state for the current epoch
 func (c *Client) DebugEpochState() (*DebugEpochStateResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.debugEpochState()
+}
+
+func (c *Client) debugEpochState() (*DebugEpochStateResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -478,41 +829,59 @@ func (c *Client) DebugEpochState() (*DebugEpochStateResult, error) {
 		currentEra,
 		QueryTypeShelleyDebugEpochState,
 	)
-	var result DebugEpochStateResult
+	result := []DebugEpochStateResult{}
 	if err := c.runQuery(query, &result); err != nil {
 		return nil, err
 	}
-	return &result, nil
+	return &result[0], nil
 }
 
-// TODO
-/*
-query	[10 #6.258([ *rwdr ])]
-*/
+// GetFilteredDelegationsAndRewardAccounts returns the pool delegations and reward account balances for the
+// specified stake credentials
 func (c *Client) GetFilteredDelegationsAndRewardAccounts(
-	creds []interface{},
+	creds []lcommon.StakeCredential,
 ) (*FilteredDelegationsAndRewardAccountsResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getFilteredDelegationsAndRewardAccounts(creds)
+}
+
+func (c *Client) getFilteredDelegationsAndRewardAccounts(
+	creds []lcommon.StakeCredential,
+) (*FilteredDelegationsAndRewardAccountsResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
 	}
+	// The node expects the credentials in their canonical order: script hashes first, and then sorted by hash
+	// within each group
+	sortedCreds := make([]lcommon.StakeCredential, len(creds))
+	copy(sortedCreds, creds)
+	sort.Slice(sortedCreds, func(i, j int) bool {
+		if sortedCreds[i].CredType != sortedCreds[j].CredType {
+			return sortedCreds[i].CredType == lcommon.StakeCredentialTypeScriptHash
+		}
+		return bytes.Compare(sortedCreds[i].Credential, sortedCreds[j].Credential) < 0
+	})
 	query := buildShelleyQuery(
 		currentEra,
 		QueryTypeShelleyFilteredDelegationAndRewardAccounts,
-		// TODO: add params
+		buildSetParam(sortedCreds),
 	)
-	var result FilteredDelegationsAndRewardAccountsResult
+	result := []FilteredDelegationsAndRewardAccountsResult{}
 	if err := c.runQuery(query, &result); err != nil {
 		return nil, err
 	}
-	return &result, nil
+	return &result[0], nil
 }
 
 func (c *Client) GetGenesisConfig() (*GenesisConfigResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getGenesisConfig()
+}
+
+func (c *Client) getGenesisConfig() (*GenesisConfigResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -528,10 +897,14 @@ func (c *Client) GetGenesisConfig() (*GenesisConfigResult, error) {
 	return &result[0], nil
 }
 
-// TODO
+// DebugNewEpochState returns the full ledger state, including the current epoch state and block production
 func (c *Client) DebugNewEpochState() (*DebugNewEpochStateResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.debugNewEpochState()
+}
+
+func (c *Client) debugNewEpochState() (*DebugNewEpochStateResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -540,17 +913,21 @@ func (c *Client) DebugNewEpochState() (*DebugNewEpochStateResult, error) {
 		currentEra,
 		QueryTypeShelleyDebugNewEpochState,
 	)
-	var result DebugNewEpochStateResult
+	result := []DebugNewEpochStateResult{}
 	if err := c.runQuery(query, &result); err != nil {
 		return nil, err
 	}
-	return &result, nil
+	return &result[0], nil
 }
 
-// TODO
+// DebugChainDepState returns the consensus state, which includes the epoch nonces and operational certificate counters
 func (c *Client) DebugChainDepState() (*DebugChainDepStateResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.debugChainDepState()
+}
+
+func (c *Client) debugChainDepState() (*DebugChainDepStateResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -559,16 +936,20 @@ func (c *Client) DebugChainDepState() (*DebugChainDepStateResult, error) {
 		currentEra,
 		QueryTypeShelleyDebugChainDepState,
 	)
-	var result DebugChainDepStateResult
+	result := []DebugChainDepStateResult{}
 	if err := c.runQuery(query, &result); err != nil {
 		return nil, err
 	}
-	return &result, nil
+	return &result[0], nil
 }
 
 func (c *Client) GetRewardProvenance() (*RewardProvenanceResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getRewardProvenance()
+}
+
+func (c *Client) getRewardProvenance() (*RewardProvenanceResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -589,6 +970,12 @@ func (c *Client) GetUTxOByTxIn(
 ) (*UTxOByTxInResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getUTxOByTxIn(txIns)
+}
+
+func (c *Client) getUTxOByTxIn(
+	txIns []ledger.TransactionInput,
+) (*UTxOByTxInResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -608,6 +995,10 @@ func (c *Client) GetUTxOByTxIn(
 func (c *Client) GetStakePools() (*StakePoolsResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getStakePools()
+}
+
+func (c *Client) getStakePools() (*StakePoolsResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -628,6 +1019,12 @@ func (c *Client) GetStakePoolParams(
 ) (*StakePoolParamsResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getStakePoolParams(poolIds)
+}
+
+func (c *Client) getStakePoolParams(
+	poolIds []ledger.PoolId,
+) (*StakePoolParamsResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -647,10 +1044,17 @@ func (c *Client) GetStakePoolParams(
 	return &result, nil
 }
 
-// TODO
+// GetRewardInfoPools returns the reward parameters and the per-pool information used to calculate rewards
 func (c *Client) GetRewardInfoPools() (*RewardInfoPoolsResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getRewardInfoPools()
+}
+
+func (c *Client) getRewardInfoPools() (*RewardInfoPoolsResult, error) {
+	if err := c.checkQueryVersion("GetRewardInfoPools", queryMinVersionRewardInfoPools); err != nil {
+		return nil, err
+	}
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -659,17 +1063,25 @@ func (c *Client) GetRewardInfoPools() (*RewardInfoPoolsResult, error) {
 		currentEra,
 		QueryTypeShelleyRewardInfoPools,
 	)
-	var result RewardInfoPoolsResult
+	result := []RewardInfoPoolsResult{}
 	if err := c.runQuery(query, &result); err != nil {
 		return nil, err
 	}
-	return &result, nil
+	return &result[0], nil
 }
 
-// TODO
-func (c *Client) GetPoolState(poolIds []interface{}) (*PoolStateResult, error) {
+// GetPoolState returns the state of the specified pools. All pools are returned if poolIds is nil
+func (c *Client) GetPoolState(
+	poolIds []ledger.PoolId,
+) (*PoolStateResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getPoolState(poolIds)
+}
+
+func (c *Client) getPoolState(
+	poolIds []ledger.PoolId,
+) (*PoolStateResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -677,20 +1089,28 @@ func (c *Client) GetPoolState(poolIds []interface{}) (*PoolStateResult, error) {
 	query := buildShelleyQuery(
 		currentEra,
 		QueryTypeShelleyPoolState,
+		buildMaybeSetParam(poolIds),
 	)
-	var result PoolStateResult
+	result := []PoolStateResult{}
 	if err := c.runQuery(query, &result); err != nil {
 		return nil, err
 	}
-	return &result, nil
+	return &result[0], nil
 }
 
-// TODO
+// GetStakeSnapshots returns the mark, set, and go stake snapshots for the specified pools. All pools are returned
+// if poolIds is nil
 func (c *Client) GetStakeSnapshots(
-	poolId interface{},
+	poolIds []ledger.PoolId,
 ) (*StakeSnapshotsResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getStakeSnapshots(poolIds)
+}
+
+func (c *Client) getStakeSnapshots(
+	poolIds []ledger.PoolId,
+) (*StakeSnapshotsResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -698,18 +1118,27 @@ func (c *Client) GetStakeSnapshots(
 	query := buildShelleyQuery(
 		currentEra,
 		QueryTypeShelleyStakeSnapshots,
+		buildMaybeSetParam(poolIds),
 	)
-	var result StakeSnapshotsResult
+	result := []StakeSnapshotsResult{}
 	if err := c.runQuery(query, &result); err != nil {
 		return nil, err
 	}
-	return &result, nil
+	return &result[0], nil
 }
 
-// TODO
-func (c *Client) GetPoolDistr(poolIds []interface{}) (*PoolDistrResult, error) {
+// GetPoolDistr returns the stake distribution for the specified pools. All pools are returned if poolIds is nil
+func (c *Client) GetPoolDistr(
+	poolIds []ledger.PoolId,
+) (*PoolDistrResult, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
+	return c.getPoolDistr(poolIds)
+}
+
+func (c *Client) getPoolDistr(
+	poolIds []ledger.PoolId,
+) (*PoolDistrResult, error) {
 	currentEra, err := c.getCurrentEra()
 	if err != nil {
 		return nil, err
@@ -717,10 +1146,341 @@ func (c *Client) GetPoolDistr(poolIds []interface{}) (*PoolDistrResult, error) {
 	query := buildShelleyQuery(
 		currentEra,
 		QueryTypeShelleyPoolDistr,
+		buildMaybeSetParam(poolIds),
 	)
-	var result PoolDistrResult
+	result := []PoolDistrResult{}
+	if err := c.runQuery(query, &result); err != nil {
+		return nil, err
+	}
+	return &result[0], nil
+}
+
+// GetConstitution returns the current constitution
+func (c *Client) GetConstitution() (*ConstitutionResult, error) {
+	c.busyMutex.Lock()
+	defer c.busyMutex.Unlock()
+	return c.getConstitution()
+}
+
+func (c *Client) getConstitution() (*ConstitutionResult, error) {
+	if err := c.checkQueryVersion("GetConstitution", queryMinVersionConway); err != nil {
+		return nil, err
+	}
+	currentEra, err := c.getCurrentEra()
+	if err != nil {
+		return nil, err
+	}
+	query := buildShelleyQuery(
+		currentEra,
+		QueryTypeShelleyConstitution,
+	)
+	result := []ConstitutionResult{}
+	if err := c.runQuery(query, &result); err != nil {
+		return nil, err
+	}
+	return &result[0], nil
+}
+
+// GetGovState returns the governance state, which includes the active proposals and their votes
+func (c *Client) GetGovState() (*GovStateResult, error) {
+	c.busyMutex.Lock()
+	defer c.busyMutex.Unlock()
+	return c.getGovState()
+}
+
+func (c *Client) getGovState() (*GovStateResult, error) {
+	if err := c.checkQueryVersion("GetGovState", queryMinVersionConway); err != nil {
+		return nil, err
+	}
+	currentEra, err := c.getCurrentEra()
+	if err != nil {
+		return nil, err
+	}
+	query := buildShelleyQuery(
+		currentEra,
+		QueryTypeShelleyGovState,
+	)
+	result := []GovStateResult{}
+	if err := c.runQuery(query, &result); err != nil {
+		return nil, err
+	}
+	return &result[0], nil
+}
+
+// GetDRepState returns the state of the specified DReps. All DReps are returned if no credentials are specified
+func (c *Client) GetDRepState(
+	drepCreds []lcommon.StakeCredential,
+) (*DRepStateResult, error) {
+	c.busyMutex.Lock()
+	defer c.busyMutex.Unlock()
+	return c.getDRepState(drepCreds)
+}
+
+func (c *Client) getDRepState(
+	drepCreds []lcommon.StakeCredential,
+) (*DRepStateResult, error) {
+	if err := c.checkQueryVersion("GetDRepState", queryMinVersionConway); err != nil {
+		return nil, err
+	}
+	currentEra, err := c.getCurrentEra()
+	if err != nil {
+		return nil, err
+	}
+	query := buildShelleyQuery(
+		currentEra,
+		QueryTypeShelleyDRepState,
+		buildSetParam(drepCreds),
+	)
+	var result DRepStateResult
 	if err := c.runQuery(query, &result); err != nil {
 		return nil, err
 	}
 	return &result, nil
 }
+
+// GetDRepStakeDistr returns the stake delegated to the specified DReps. All DReps are returned if none are specified
+func (c *Client) GetDRepStakeDistr(
+	dreps []lcommon.Drep,
+) (*DRepStakeDistrResult, error) {
+	c.busyMutex.Lock()
+	defer c.busyMutex.Unlock()
+	return c.getDRepStakeDistr(dreps)
+}
+
+func (c *Client) getDRepStakeDistr(
+	dreps []lcommon.Drep,
+) (*DRepStakeDistrResult, error) {
+	if err := c.checkQueryVersion("GetDRepStakeDistr", queryMinVersionConway); err != nil {
+		return nil, err
+	}
+	currentEra, err := c.getCurrentEra()
+	if err != nil {
+		return nil, err
+	}
+	query := buildShelleyQuery(
+		currentEra,
+		QueryTypeShelleyDRepStakeDistr,
+		buildSetParam(dreps),
+	)
+	var result DRepStakeDistrResult
+	if err := c.runQuery(query, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetCommitteeMembersState returns the state of the constitutional committee members matching the
+// specified cold credentials, hot credentials, and statuses. Empty filters match all members
+func (c *Client) GetCommitteeMembersState(
+	coldCreds []lcommon.StakeCredential,
+	hotCreds []lcommon.StakeCredential,
+	statuses []uint,
+) (*CommitteeMembersStateResult, error) {
+	c.busyMutex.Lock()
+	defer c.busyMutex.Unlock()
+	return c.getCommitteeMembersState(coldCreds, hotCreds, statuses)
+}
+
+func (c *Client) getCommitteeMembersState(
+	coldCreds []lcommon.StakeCredential,
+	hotCreds []lcommon.StakeCredential,
+	statuses []uint,
+) (*CommitteeMembersStateResult, error) {
+	if err := c.checkQueryVersion("GetCommitteeMembersState", queryMinVersionConway); err != nil {
+		return nil, err
+	}
+	currentEra, err := c.getCurrentEra()
+	if err != nil {
+		return nil, err
+	}
+	query := buildShelleyQuery(
+		currentEra,
+		QueryTypeShelleyCommitteeMembersState,
+		buildSetParam(coldCreds),
+		buildSetParam(hotCreds),
+		buildSetParam(statuses),
+	)
+	result := []CommitteeMembersStateResult{}
+	if err := c.runQuery(query, &result); err != nil {
+		return nil, err
+	}
+	return &result[0], nil
+}
+
+// GetFilteredVoteDelegatees returns the DReps that the specified stake credentials delegate their vote to
+func (c *Client) GetFilteredVoteDelegatees(
+	stakeCreds []lcommon.StakeCredential,
+) (*FilteredVoteDelegateesResult, error) {
+	c.busyMutex.Lock()
+	defer c.busyMutex.Unlock()
+	return c.getFilteredVoteDelegatees(stakeCreds)
+}
+
+func (c *Client) getFilteredVoteDelegatees(
+	stakeCreds []lcommon.StakeCredential,
+) (*FilteredVoteDelegateesResult, error) {
+	if err := c.checkQueryVersion("GetFilteredVoteDelegatees", queryMinVersionConway); err != nil {
+		return nil, err
+	}
+	currentEra, err := c.getCurrentEra()
+	if err != nil {
+		return nil, err
+	}
+	query := buildShelleyQuery(
+		currentEra,
+		QueryTypeShelleyFilteredVoteDelegatees,
+		buildSetParam(stakeCreds),
+	)
+	var result FilteredVoteDelegateesResult
+	if err := c.runQuery(query, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetAccountState returns the treasury and reserves balances
+func (c *Client) GetAccountState() (*AccountStateResult, error) {
+	c.busyMutex.Lock()
+	defer c.busyMutex.Unlock()
+	return c.getAccountState()
+}
+
+func (c *Client) getAccountState() (*AccountStateResult, error) {
+	if err := c.checkQueryVersion("GetAccountState", queryMinVersionConway); err != nil {
+		return nil, err
+	}
+	currentEra, err := c.getCurrentEra()
+	if err != nil {
+		return nil, err
+	}
+	query := buildShelleyQuery(
+		currentEra,
+		QueryTypeShelleyAccountState,
+	)
+	result := []AccountStateResult{}
+	if err := c.runQuery(query, &result); err != nil {
+		return nil, err
+	}
+	return &result[0], nil
+}
+
+// GetStakeDelegDeposits returns the deposits paid when registering the specified stake credentials
+func (c *Client) GetStakeDelegDeposits(
+	stakeCreds []lcommon.StakeCredential,
+) (*StakeDelegDepositsResult, error) {
+	c.busyMutex.Lock()
+	defer c.busyMutex.Unlock()
+	return c.getStakeDelegDeposits(stakeCreds)
+}
+
+func (c *Client) getStakeDelegDeposits(
+	stakeCreds []lcommon.StakeCredential,
+) (*StakeDelegDepositsResult, error) {
+	if err := c.checkQueryVersion("GetStakeDelegDeposits", queryMinVersionStakeDelegDeposits); err != nil {
+		return nil, err
+	}
+	currentEra, err := c.getCurrentEra()
+	if err != nil {
+		return nil, err
+	}
+	query := buildShelleyQuery(
+		currentEra,
+		QueryTypeShelleyStakeDelegDeposits,
+		buildSetParam(stakeCreds),
+	)
+	var result StakeDelegDepositsResult
+	if err := c.runQuery(query, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetSPOStakeDistr returns the total stake for the specified pools. All pools are returned if none are specified
+func (c *Client) GetSPOStakeDistr(
+	poolIds []ledger.PoolId,
+) (*SPOStakeDistrResult, error) {
+	c.busyMutex.Lock()
+	defer c.busyMutex.Unlock()
+	return c.getSPOStakeDistr(poolIds)
+}
+
+func (c *Client) getSPOStakeDistr(
+	poolIds []ledger.PoolId,
+) (*SPOStakeDistrResult, error) {
+	if err := c.checkQueryVersion("GetSPOStakeDistr", queryMinVersionSPOStakeDistr); err != nil {
+		return nil, err
+	}
+	currentEra, err := c.getCurrentEra()
+	if err != nil {
+		return nil, err
+	}
+	query := buildShelleyQuery(
+		currentEra,
+		QueryTypeShelleySPOStakeDistr,
+		buildSetParam(poolIds),
+	)
+	var result SPOStakeDistrResult
+	if err := c.runQuery(query, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetBigLedgerPeerSnapshot returns a snapshot of the big ledger peers, which can be used to bootstrap peer
+// selection without access to a synced ledger
+func (c *Client) GetBigLedgerPeerSnapshot() (*BigLedgerPeerSnapshotResult, error) {
+	c.busyMutex.Lock()
+	defer c.busyMutex.Unlock()
+	return c.getBigLedgerPeerSnapshot()
+}
+
+func (c *Client) getBigLedgerPeerSnapshot() (*BigLedgerPeerSnapshotResult, error) {
+	if err := c.checkQueryVersion("GetBigLedgerPeerSnapshot", queryMinVersionBigLedgerPeerSnapshot); err != nil {
+		return nil, err
+	}
+	currentEra, err := c.getCurrentEra()
+	if err != nil {
+		return nil, err
+	}
+	query := buildShelleyQuery(
+		currentEra,
+		QueryTypeShelleyBigLedgerPeerSnapshot,
+	)
+	result := []BigLedgerPeerSnapshotResult{}
+	if err := c.runQuery(query, &result); err != nil {
+		return nil, err
+	}
+	return &result[0], nil
+}
+
+// GetStakePoolDefaultVote returns the default vote of the specified pool on governance actions that it has not
+// voted on
+func (c *Client) GetStakePoolDefaultVote(
+	poolId ledger.PoolId,
+) (StakePoolDefaultVote, error) {
+	c.busyMutex.Lock()
+	defer c.busyMutex.Unlock()
+	return c.getStakePoolDefaultVote(poolId)
+}
+
+func (c *Client) getStakePoolDefaultVote(
+	poolId ledger.PoolId,
+) (StakePoolDefaultVote, error) {
+	if err := c.checkQueryVersion("GetStakePoolDefaultVote", queryMinVersionStakePoolDefaultVote); err != nil {
+		return 0, err
+	}
+	currentEra, err := c.getCurrentEra()
+	if err != nil {
+		return 0, err
+	}
+	query := buildShelleyQuery(
+		currentEra,
+		QueryTypeShelleyStakePoolDefaultVote,
+		poolId,
+	)
+	result := []StakePoolDefaultVote{}
+	if err := c.runQuery(query, &result); err != nil {
+		return 0, err
+	}
+	return result[0], nil
+}
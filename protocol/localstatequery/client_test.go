@@ -15,7 +15,11 @@
 package localstatequery_test
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+	"net"
 	"reflect"
 	"testing"
 	"time"
@@ -24,8 +28,10 @@ import (
 	"github.com/blinklabs-io/gouroboros/cbor"
 	"github.com/blinklabs-io/gouroboros/internal/test"
 	"github.com/blinklabs-io/gouroboros/ledger"
+	lcommon "github.com/blinklabs-io/gouroboros/ledger/common"
 	"github.com/blinklabs-io/gouroboros/protocol"
 	ocommon "github.com/blinklabs-io/gouroboros/protocol/common"
+	"github.com/blinklabs-io/gouroboros/protocol/handshake"
 	"github.com/blinklabs-io/gouroboros/protocol/localstatequery"
 
 	ouroboros_mock "github.com/blinklabs-io/ouroboros-mock"
@@ -63,6 +69,27 @@ var conversationCurrentEra = append(
 	},
 )
 
+// conversationCurrentEraConway is like conversationCurrentEra, but it negotiates a protocol version new enough
+// for the Conway governance queries
+var conversationCurrentEraConway = append(
+	[]ouroboros_mock.ConversationEntry{
+		ouroboros_mock.ConversationEntryHandshakeRequestGeneric,
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: handshake.ProtocolId,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				handshake.NewMsgAcceptVersion(
+					(16 + protocol.ProtocolVersionNtCOffset),
+					protocol.VersionDataNtC15andUp{
+						CborNetworkMagic: ouroboros_mock.MockNetworkMagic,
+					},
+				),
+			},
+		},
+	},
+	conversationCurrentEra[2:]...,
+)
+
 type testInnerFunc func(*testing.T, *ouroboros.Connection)
 
 func runTest(
@@ -123,6 +150,64 @@ func runTest(
 	}
 }
 
+func TestQueryVersionUnsupported(t *testing.T) {
+	// The mock handshake negotiates a protocol version that's too old for these queries, so nothing is sent
+	conversation := []ouroboros_mock.ConversationEntry{
+		ouroboros_mock.ConversationEntryHandshakeRequestGeneric,
+		ouroboros_mock.ConversationEntryHandshakeNtCResponse,
+	}
+	runTest(
+		t,
+		conversation,
+		func(t *testing.T, oConn *ouroboros.Connection) {
+			client := oConn.LocalStateQuery().Client
+			var versionErr localstatequery.QueryVersionUnsupportedError
+			if _, err := client.GetBigLedgerPeerSnapshot(); !errors.As(err, &versionErr) {
+				t.Fatalf("did not receive expected error, got: %v", err)
+			}
+			if versionErr.Query != "GetBigLedgerPeerSnapshot" || versionErr.MinVersion != 19 || versionErr.Version != 14 {
+				t.Fatalf("did not receive expected error: %#v", versionErr)
+			}
+			if _, err := client.GetStakePoolDefaultVote(ledger.PoolId{}); !errors.As(err, &versionErr) {
+				t.Fatalf("did not receive expected error, got: %v", err)
+			}
+			if versionErr.Query != "GetStakePoolDefaultVote" || versionErr.MinVersion != 20 {
+				t.Fatalf("did not receive expected error: %#v", versionErr)
+			}
+			if _, err := client.GetStakeDelegDeposits(nil); !errors.As(err, &versionErr) {
+				t.Fatalf("did not receive expected error, got: %v", err)
+			}
+			if versionErr.Query != "GetStakeDelegDeposits" || versionErr.MinVersion != 15 {
+				t.Fatalf("did not receive expected error: %#v", versionErr)
+			}
+			if _, err := client.GetConstitution(); !errors.As(err, &versionErr) {
+				t.Fatalf("did not receive expected error, got: %v", err)
+			}
+			if versionErr.Query != "GetConstitution" || versionErr.MinVersion != 16 {
+				t.Fatalf("did not receive expected error: %#v", versionErr)
+			}
+		},
+	)
+}
+
+func TestQueryVersionNotNegotiated(t *testing.T) {
+	// A client without a negotiated NtC version reports version-dependent queries as unsupported, rather than
+	// wrapping around to a very large version
+	for _, version := range []uint16{0, 13} {
+		client := localstatequery.NewClient(
+			protocol.ProtocolOptions{Version: version},
+			nil,
+		)
+		var versionErr localstatequery.QueryVersionUnsupportedError
+		if _, err := client.GetConstitution(); !errors.As(err, &versionErr) {
+			t.Fatalf("did not receive expected error for version %d, got: %v", version, err)
+		}
+		if versionErr.Version != 0 || versionErr.MinVersion != 16 {
+			t.Fatalf("did not receive expected error for version %d: %#v", version, versionErr)
+		}
+	}
+}
+
 func TestGetCurrentEra(t *testing.T) {
 	runTest(
 		t,
@@ -285,3 +370,930 @@ func TestGetUTxOByAddress(t *testing.T) {
 		},
 	)
 }
+
+func TestForEachUTxOWhole(t *testing.T) {
+	testAddress, err := ledger.NewAddress(
+		"addr_test1vrk294czhxhglflvxla7vxj2cjz7wyrdpxl3fj0vych5wws77xuc7",
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testAddressBytes := testAddress.Bytes()
+	txId := ledger.NewBlake2b256([]byte{0x1, 0x2})
+	// The first output uses the legacy format and the second output uses the post-Babbage format
+	cborData, err := cbor.Encode(
+		[]interface{}{
+			map[interface{}]interface{}{
+				ledger.ShelleyTransactionInput{TxId: txId, OutputIndex: 0}: []interface{}{testAddressBytes, 1000000},
+				ledger.ShelleyTransactionInput{TxId: txId, OutputIndex: 1}: map[int]interface{}{
+					0: testAddressBytes,
+					1: 2000000,
+				},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	conversation := append(
+		conversationCurrentEra,
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  localstatequery.ProtocolId,
+			MessageType: localstatequery.MessageTypeQuery,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: localstatequery.ProtocolId,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				localstatequery.NewMsgResult(cborData),
+			},
+		},
+	)
+	runTest(
+		t,
+		conversation,
+		func(t *testing.T, oConn *ouroboros.Connection) {
+			amounts := map[uint32]uint64{}
+			err := oConn.LocalStateQuery().Client.ForEachUTxOWhole(
+				func(txIn ledger.TransactionInput, txOut ledger.TransactionOutput) error {
+					if txIn.Id() != txId {
+						t.Fatalf("did not receive expected input TX ID: got %s, wanted %s", txIn.Id(), txId)
+					}
+					if _, ok := txOut.(*ledger.BabbageTransactionOutput); !ok {
+						t.Fatalf("did not receive expected output type: %T", txOut)
+					}
+					if txOut.Address().String() != testAddress.String() {
+						t.Fatalf("did not receive expected output address: %s", txOut.Address().String())
+					}
+					amounts[txIn.Index()] = txOut.Amount()
+					return nil
+				},
+			)
+			if err != nil {
+				t.Fatalf("received unexpected error: %s", err)
+			}
+			expectedAmounts := map[uint32]uint64{0: 1000000, 1: 2000000}
+			if !reflect.DeepEqual(amounts, expectedAmounts) {
+				t.Fatalf("did not receive expected UTxOs: got %v, wanted %v", amounts, expectedAmounts)
+			}
+		},
+	)
+}
+
+func TestForEachUTxOWholeSegmented(t *testing.T) {
+	testAddress, err := ledger.NewAddress(
+		"addr_test1vrk294czhxhglflvxla7vxj2cjz7wyrdpxl3fj0vych5wws77xuc7",
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	txId := ledger.NewBlake2b256([]byte{0x1, 0x2})
+	utxoCount := 500
+	utxos := map[interface{}]interface{}{}
+	for i := 0; i < utxoCount; i++ {
+		utxos[ledger.ShelleyTransactionInput{TxId: txId, OutputIndex: uint32(i)}] = []interface{}{
+			testAddress.Bytes(),
+			1000000 + i,
+		}
+	}
+	resultCbor, err := cbor.Encode([]interface{}{utxos})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	msgCbor, err := cbor.Encode(localstatequery.NewMsgResult(resultCbor))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// The result message is split across several muxer segments, which the client reads as they arrive
+	var resultOutputs []ouroboros_mock.ConversationEntry
+	chunkSize := 1000
+	for offset := 0; offset < len(msgCbor); offset += chunkSize {
+		chunk := msgCbor[offset:min(offset+chunkSize, len(msgCbor))]
+		msg := localstatequery.NewMsgResult(nil)
+		msg.SetCbor(chunk)
+		resultOutputs = append(
+			resultOutputs,
+			ouroboros_mock.ConversationEntryOutput{
+				ProtocolId: localstatequery.ProtocolId,
+				IsResponse: true,
+				Messages:   []protocol.Message{msg},
+			},
+		)
+	}
+	if len(resultOutputs) < 3 {
+		t.Fatalf("test result only spans %d segments", len(resultOutputs))
+	}
+	queryInput := ouroboros_mock.ConversationEntryInput{
+		ProtocolId:  localstatequery.ProtocolId,
+		MessageType: localstatequery.MessageTypeQuery,
+	}
+	conversation := append([]ouroboros_mock.ConversationEntry{}, conversationCurrentEra...)
+	conversation = append(conversation, queryInput)
+	conversation = append(conversation, resultOutputs...)
+	conversation = append(conversation, queryInput)
+	conversation = append(conversation, resultOutputs...)
+	runTest(
+		t,
+		conversation,
+		func(t *testing.T, oConn *ouroboros.Connection) {
+			// Stopping early returns the error, and the rest of the result is still consumed
+			stopErr := errors.New("stop")
+			count := 0
+			err := oConn.LocalStateQuery().Client.ForEachUTxOWhole(
+				func(txIn ledger.TransactionInput, txOut ledger.TransactionOutput) error {
+					count++
+					if count == 10 {
+						return stopErr
+					}
+					return nil
+				},
+			)
+			if err != stopErr {
+				t.Fatalf("did not receive expected error, got: %v", err)
+			}
+			if count != 10 {
+				t.Fatalf("UTxO function was called after returning an error: %d calls", count)
+			}
+			// The protocol is still in sync for the next query
+			result, err := oConn.LocalStateQuery().Client.GetUTxOWhole()
+			if err != nil {
+				t.Fatalf("received unexpected error: %s", err)
+			}
+			if len(result.Results) != utxoCount {
+				t.Fatalf("did not receive expected UTxO count: got %d, wanted %d", len(result.Results), utxoCount)
+			}
+			utxoId := localstatequery.UtxoId{Hash: txId, Idx: 123}
+			if amount := result.Results[utxoId].Amount(); amount != 1000123 {
+				t.Fatalf("did not receive expected UTxO amount: got %d, wanted %d", amount, 1000123)
+			}
+		},
+	)
+}
+
+func TestGetConstitution(t *testing.T) {
+	expectedResult := localstatequery.ConstitutionResult{
+		Anchor: lcommon.GovAnchor{
+			Url:      "https://example.com/constitution.txt",
+			DataHash: [32]byte{0x1, 0x2, 0x3},
+		},
+		ScriptHash: test.DecodeHexString(
+			"fa24fb305126805cf2164c161d852a0e7330cf988f1fe558cf7d4a64",
+		),
+	}
+	cborData, err := cbor.Encode(
+		[]interface{}{expectedResult},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	conversation := append(
+		conversationCurrentEraConway,
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  localstatequery.ProtocolId,
+			MessageType: localstatequery.MessageTypeQuery,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: localstatequery.ProtocolId,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				localstatequery.NewMsgResult(cborData),
+			},
+		},
+	)
+	runTest(
+		t,
+		conversation,
+		func(t *testing.T, oConn *ouroboros.Connection) {
+			constitution, err := oConn.LocalStateQuery().Client.GetConstitution()
+			if err != nil {
+				t.Fatalf("received unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(constitution, &expectedResult) {
+				t.Fatalf(
+					"did not receive expected result\n  got:    %#v\n  wanted: %#v",
+					constitution,
+					expectedResult,
+				)
+			}
+		},
+	)
+}
+
+// testConwayProtocolParams returns a generic representation of Conway protocol params for use in building
+// query results
+func testConwayProtocolParams() []interface{} {
+	rat := func() cbor.Tag {
+		return cbor.Tag{Number: cbor.CborTagRational, Content: []uint64{1, 2}}
+	}
+	rats := func(count int) []interface{} {
+		ret := []interface{}{}
+		for i := 0; i < count; i++ {
+			ret = append(ret, rat())
+		}
+		return ret
+	}
+	return []interface{}{
+		44, 155381, 90112, 16384, 1100, 2000000, 500000000, 18, 500,
+		rat(), rat(), rat(),
+		[]uint{9, 0},
+		170000000, 4310,
+		map[uint][]int{},
+		rats(2),
+		[]uint{14000000, 10000000000},
+		[]uint{62000000, 20000000000},
+		5000, 150, 3,
+		rats(5),
+		rats(10),
+		7, 146, 6, 100000000000, 500000000, 20,
+		rat(),
+	}
+}
+
+func TestGetGovState(t *testing.T) {
+	testAddress, err := ledger.NewAddress(
+		"stake_test1uqfu74w3wh4gfzu8m6e7j987h4lq9r3t7ef5gaw497uu85qsqfy27",
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	govActionId := lcommon.GovActionId{
+		TransactionId: [32]byte{0xa, 0xb, 0xc},
+		GovActionIdx:  1,
+	}
+	drepCred := lcommon.StakeCredential{
+		CredType:   lcommon.StakeCredentialTypeAddrKeyHash,
+		Credential: make([]byte, 28),
+	}
+	drepCred.Credential[0] = 0xd
+	poolKeyHash := ledger.Blake2b224{0xe}
+	cborData, err := cbor.Encode(
+		[]interface{}{
+			[]interface{}{
+				// Proposals
+				[]interface{}{
+					// Roots
+					[]interface{}{
+						[]interface{}{},
+						[]interface{}{govActionId},
+						[]interface{}{},
+						[]interface{}{},
+					},
+					// Proposals
+					[]interface{}{
+						[]interface{}{
+							govActionId,
+							map[interface{}]uint8{},
+							map[*lcommon.StakeCredential]uint8{
+								&drepCred: lcommon.GovVoteYes,
+							},
+							map[ledger.Blake2b224]uint8{
+								poolKeyHash: lcommon.GovVoteNo,
+							},
+							lcommon.ProposalProcedure{
+								Deposit:       100000000000,
+								RewardAccount: testAddress,
+								GovAction: lcommon.GovActionWrapper{
+									Type: lcommon.GovActionTypeInfo,
+									Action: &lcommon.InfoGovAction{
+										Type: lcommon.GovActionTypeInfo,
+									},
+								},
+								Anchor: lcommon.GovAnchor{
+									Url: "https://example.com/proposal.json",
+								},
+							},
+							500,
+							506,
+						},
+					},
+				},
+				// Committee
+				[]interface{}{},
+				// Constitution
+				localstatequery.ConstitutionResult{
+					Anchor: lcommon.GovAnchor{
+						Url: "https://example.com/constitution.txt",
+					},
+				},
+				// Current and previous protocol params
+				testConwayProtocolParams(),
+				testConwayProtocolParams(),
+				// Future protocol params
+				[]interface{}{0},
+				// DRep pulsing state
+				[]interface{}{},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	conversation := append(
+		conversationCurrentEraConway,
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  localstatequery.ProtocolId,
+			MessageType: localstatequery.MessageTypeQuery,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: localstatequery.ProtocolId,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				localstatequery.NewMsgResult(cborData),
+			},
+		},
+	)
+	runTest(
+		t,
+		conversation,
+		func(t *testing.T, oConn *ouroboros.Connection) {
+			govState, err := oConn.LocalStateQuery().Client.GetGovState()
+			if err != nil {
+				t.Fatalf("received unexpected error: %s", err)
+			}
+			if govState.Committee != nil {
+				t.Fatalf("did not receive expected empty committee")
+			}
+			roots := govState.Proposals.Roots
+			if roots.HardFork == nil || !reflect.DeepEqual(*roots.HardFork, govActionId) {
+				t.Fatalf("did not receive expected hard fork root: %#v", roots.HardFork)
+			}
+			if roots.ProtocolParameterUpdate != nil || roots.Committee != nil || roots.Constitution != nil {
+				t.Fatalf("received unexpected roots: %#v", roots)
+			}
+			if len(govState.Proposals.Proposals) != 1 {
+				t.Fatalf("did not receive expected proposal count: got %d, wanted %d", len(govState.Proposals.Proposals), 1)
+			}
+			proposal := govState.Proposals.Proposals[0]
+			if proposal.ProposalProcedure.GovAction.Type != lcommon.GovActionTypeInfo {
+				t.Fatalf("did not receive expected gov action type: got %d", proposal.ProposalProcedure.GovAction.Type)
+			}
+			if proposal.ExpiresAfter != 506 {
+				t.Fatalf("did not receive expected expiration: got %d, wanted %d", proposal.ExpiresAfter, 506)
+			}
+			expectedVotes := map[lcommon.Voter]uint8{
+				{
+					Type: lcommon.VoterTypeDRepKeyHash,
+					Hash: [28]byte(drepCred.Credential),
+				}: lcommon.GovVoteYes,
+				{
+					Type: lcommon.VoterTypeStakingPoolKeyHash,
+					Hash: poolKeyHash,
+				}: lcommon.GovVoteNo,
+			}
+			if votes := proposal.Votes(); !reflect.DeepEqual(votes, expectedVotes) {
+				t.Fatalf(
+					"did not receive expected votes\n  got:    %#v\n  wanted: %#v",
+					votes,
+					expectedVotes,
+				)
+			}
+		},
+	)
+}
+
+func TestGetCommitteeMembersState(t *testing.T) {
+	coldCred := lcommon.StakeCredential{
+		CredType:   lcommon.StakeCredentialTypeScriptHash,
+		Credential: make([]byte, 28),
+	}
+	hotCred := lcommon.StakeCredential{
+		CredType:   lcommon.StakeCredentialTypeAddrKeyHash,
+		Credential: make([]byte, 28),
+	}
+	hotCred.Credential[0] = 0x1
+	cborData, err := cbor.Encode(
+		[]interface{}{
+			[]interface{}{
+				map[*lcommon.StakeCredential]interface{}{
+					&coldCred: []interface{}{
+						// Hot credential auth status
+						[]interface{}{
+							lcommon.StakeCredentialTypeAddrKeyHash,
+							hotCred,
+						},
+						localstatequery.CommitteeMemberStatusActive,
+						// Expiration
+						[]interface{}{600},
+						// Next epoch change
+						[]interface{}{
+							localstatequery.CommitteeNextEpochChangeTermAdjusted,
+							650,
+						},
+					},
+				},
+				// Threshold
+				[]interface{}{
+					cbor.Tag{Number: cbor.CborTagRational, Content: []uint64{2, 3}},
+				},
+				// Epoch
+				512,
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	conversation := append(
+		conversationCurrentEraConway,
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  localstatequery.ProtocolId,
+			MessageType: localstatequery.MessageTypeQuery,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: localstatequery.ProtocolId,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				localstatequery.NewMsgResult(cborData),
+			},
+		},
+	)
+	runTest(
+		t,
+		conversation,
+		func(t *testing.T, oConn *ouroboros.Connection) {
+			state, err := oConn.LocalStateQuery().Client.GetCommitteeMembersState(
+				nil,
+				nil,
+				nil,
+			)
+			if err != nil {
+				t.Fatalf("received unexpected error: %s", err)
+			}
+			if state.Epoch != 512 {
+				t.Fatalf("did not receive expected epoch: got %d, wanted %d", state.Epoch, 512)
+			}
+			if state.Threshold == nil || state.Threshold.String() != "2/3" {
+				t.Fatalf("did not receive expected threshold: %v", state.Threshold)
+			}
+			if len(state.Members) != 1 {
+				t.Fatalf("did not receive expected member count: got %d, wanted %d", len(state.Members), 1)
+			}
+			for cred, member := range state.Members {
+				if cred.CredType != coldCred.CredType {
+					t.Fatalf("did not receive expected cold credential type: got %d", cred.CredType)
+				}
+				if member.HotCredAuthStatus.Type != localstatequery.CommitteeHotCredAuthStatusAuthorized ||
+					member.HotCredAuthStatus.HotCredential == nil ||
+					member.HotCredAuthStatus.HotCredential.Credential[0] != 0x1 {
+					t.Fatalf("did not receive expected hot credential auth status: %#v", member.HotCredAuthStatus)
+				}
+				if member.Expiration == nil || *member.Expiration != 600 {
+					t.Fatalf("did not receive expected expiration: %v", member.Expiration)
+				}
+				if member.NextEpochChange.Type != localstatequery.CommitteeNextEpochChangeTermAdjusted ||
+					member.NextEpochChange.Epoch != 650 {
+					t.Fatalf("did not receive expected next epoch change: %#v", member.NextEpochChange)
+				}
+			}
+		},
+	)
+}
+
+func TestGetFilteredDelegationsAndRewardAccounts(t *testing.T) {
+	stakeCred := lcommon.StakeCredential{
+		CredType:   lcommon.StakeCredentialTypeAddrKeyHash,
+		Credential: test.DecodeHexString("13cf55d175ea848b87deb3e914febd7e028e2bf6534475d52fb9c3d0"),
+	}
+	poolId := ledger.PoolId{0x1, 0x2, 0x3}
+	cborData, err := cbor.Encode(
+		[]interface{}{
+			[]interface{}{
+				map[*lcommon.StakeCredential]ledger.PoolId{
+					&stakeCred: poolId,
+				},
+				map[*lcommon.StakeCredential]uint64{
+					&stakeCred: 123456,
+				},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	conversation := append(
+		conversationCurrentEra,
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  localstatequery.ProtocolId,
+			MessageType: localstatequery.MessageTypeQuery,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: localstatequery.ProtocolId,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				localstatequery.NewMsgResult(cborData),
+			},
+		},
+	)
+	runTest(
+		t,
+		conversation,
+		func(t *testing.T, oConn *ouroboros.Connection) {
+			result, err := oConn.LocalStateQuery().Client.GetFilteredDelegationsAndRewardAccounts(
+				[]lcommon.StakeCredential{stakeCred},
+			)
+			if err != nil {
+				t.Fatalf("received unexpected error: %s", err)
+			}
+			jsonData, err := json.Marshal(result)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			expectedJson := fmt.Sprintf(
+				`{"delegations":{"%s":"%s"},"rewards":{"%s":123456}}`,
+				stakeCred.String(),
+				poolId.String(),
+				stakeCred.String(),
+			)
+			if string(jsonData) != expectedJson {
+				t.Fatalf(
+					"did not receive expected result\n  got:    %s\n  wanted: %s",
+					jsonData,
+					expectedJson,
+				)
+			}
+		},
+	)
+}
+
+func TestBigLedgerPeerSnapshotResult(t *testing.T) {
+	snapshotCbor, err := cbor.Encode(
+		[]any{
+			1,
+			[]any{
+				[]any{1, 123456},
+				[]any{
+					[]any{
+						[]any{1, 4},
+						[]any{
+							[]any{1, 4},
+							[]any{
+								[]any{0, 3001, []byte("relay.example.com")},
+								[]any{1, 3001, []any{192, 0, 2, 1}},
+								[]any{2, 3001, []any{0x2001, 0xdb8, 0, 0, 0, 0, 0, 1}},
+							},
+						},
+					},
+				},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var snapshot localstatequery.BigLedgerPeerSnapshotResult
+	if _, err := cbor.Decode(snapshotCbor, &snapshot); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if snapshot.Slot == nil || *snapshot.Slot != 123456 || len(snapshot.Pools) != 1 {
+		t.Fatalf("did not get expected snapshot: %#v", snapshot)
+	}
+	pool := snapshot.Pools[0]
+	if pool.AccumulatedStake.Cmp(big.NewRat(1, 4)) != 0 || pool.RelativeStake.Cmp(big.NewRat(1, 4)) != 0 {
+		t.Fatalf("did not get expected pool stake: %s, %s", pool.AccumulatedStake, pool.RelativeStake)
+	}
+	expectedRelays := []localstatequery.LedgerPeerSnapshotRelay{
+		{
+			Type:   localstatequery.LedgerPeerSnapshotRelayTypeDomain,
+			Port:   3001,
+			Domain: "relay.example.com",
+		},
+		{
+			Type: localstatequery.LedgerPeerSnapshotRelayTypeIPv4,
+			Port: 3001,
+			IP:   net.IP{192, 0, 2, 1},
+		},
+		{
+			Type: localstatequery.LedgerPeerSnapshotRelayTypeIPv6,
+			Port: 3001,
+			IP:   net.ParseIP("2001:db8::1"),
+		},
+	}
+	if !reflect.DeepEqual(pool.Relays, expectedRelays) {
+		t.Fatalf("did not get expected relays:\n  got:    %#v\n  wanted: %#v", pool.Relays, expectedRelays)
+	}
+	// Re-encoding should produce the original CBOR
+	encoded, err := cbor.Encode(snapshot)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(encoded, snapshotCbor) {
+		t.Fatalf("did not get expected CBOR:\n  got:    %x\n  wanted: %x", encoded, snapshotCbor)
+	}
+}
+
+func TestGetPoolDistr(t *testing.T) {
+	poolId := ledger.PoolId{0x1, 0x2, 0x3}
+	vrfHash := ledger.NewBlake2b256([]byte{0x4, 0x5, 0x6})
+	stakeFraction := cbor.Tag{Number: cbor.CborTagRational, Content: []uint64{1, 4}}
+	testDefs := []struct {
+		name             string
+		result           interface{}
+		totalStake       uint64
+		totalActiveStake uint64
+	}{
+		{
+			name: "Legacy",
+			result: map[ledger.PoolId]interface{}{
+				poolId: []interface{}{stakeFraction, vrfHash},
+			},
+		},
+		{
+			name: "TotalStake",
+			result: []interface{}{
+				map[ledger.PoolId]interface{}{
+					poolId: []interface{}{stakeFraction, 2500, vrfHash},
+				},
+				10000,
+			},
+			totalStake:       2500,
+			totalActiveStake: 10000,
+		},
+	}
+	for _, testDef := range testDefs {
+		t.Run(testDef.name, func(t *testing.T) {
+			cborData, err := cbor.Encode([]interface{}{testDef.result})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			conversation := append(
+				conversationCurrentEra,
+				ouroboros_mock.ConversationEntryInput{
+					ProtocolId:  localstatequery.ProtocolId,
+					MessageType: localstatequery.MessageTypeQuery,
+				},
+				ouroboros_mock.ConversationEntryOutput{
+					ProtocolId: localstatequery.ProtocolId,
+					IsResponse: true,
+					Messages: []protocol.Message{
+						localstatequery.NewMsgResult(cborData),
+					},
+				},
+			)
+			runTest(
+				t,
+				conversation,
+				func(t *testing.T, oConn *ouroboros.Connection) {
+					result, err := oConn.LocalStateQuery().Client.GetPoolDistr(
+						[]ledger.PoolId{poolId},
+					)
+					if err != nil {
+						t.Fatalf("received unexpected error: %s", err)
+					}
+					if result.TotalActiveStake != testDef.totalActiveStake {
+						t.Fatalf("did not receive expected total active stake: got %d, wanted %d", result.TotalActiveStake, testDef.totalActiveStake)
+					}
+					poolStake, ok := result.Results[poolId]
+					if !ok {
+						t.Fatalf("did not find expected pool in result")
+					}
+					if poolStake.StakeFraction.String() != "1/4" {
+						t.Fatalf("did not receive expected stake fraction: got %s", poolStake.StakeFraction.String())
+					}
+					if poolStake.TotalStake != testDef.totalStake {
+						t.Fatalf("did not receive expected total stake: got %d, wanted %d", poolStake.TotalStake, testDef.totalStake)
+					}
+					if poolStake.VrfHash != vrfHash {
+						t.Fatalf("did not receive expected VRF hash: got %s", poolStake.VrfHash.String())
+					}
+				},
+			)
+		})
+	}
+}
+
+func TestDebugEpochState(t *testing.T) {
+	stakeCred := lcommon.StakeCredential{
+		CredType:   lcommon.StakeCredentialTypeAddrKeyHash,
+		Credential: test.DecodeHexString("13cf55d175ea848b87deb3e914febd7e028e2bf6534475d52fb9c3d0"),
+	}
+	poolId := ledger.PoolId{0x1, 0x2, 0x3}
+	emptyMap := map[interface{}]interface{}{}
+	snapshot := []interface{}{
+		map[*lcommon.StakeCredential]uint64{&stakeCred: 5000000},
+		map[*lcommon.StakeCredential]ledger.PoolId{&stakeCred: poolId},
+		emptyMap,
+	}
+	cborData, err := cbor.Encode(
+		[]interface{}{
+			[]interface{}{
+				// Account state
+				[]interface{}{1000, 2000},
+				// Ledger state
+				[]interface{}{
+					// Cert state
+					[]interface{}{
+						// Voting state
+						[]interface{}{emptyMap, emptyMap, 3},
+						// Pool state
+						[]interface{}{emptyMap, emptyMap, emptyMap, emptyMap},
+						// Delegation state
+						[]interface{}{
+							[]interface{}{
+								map[*lcommon.StakeCredential]interface{}{
+									&stakeCred: []interface{}{
+										[]interface{}{[]interface{}{250, 2000000}},
+										[]interface{}{},
+										[]interface{}{poolId},
+										[]interface{}{},
+									},
+								},
+								emptyMap,
+							},
+							emptyMap,
+							emptyMap,
+							[]interface{}{emptyMap, emptyMap, 0, 0},
+						},
+					},
+					// UTxO state
+					[]interface{}{
+						emptyMap,
+						2000000,
+						300,
+						// Conway governance state
+						[]interface{}{
+							[]interface{}{
+								[]interface{}{
+									[]interface{}{},
+									[]interface{}{},
+									[]interface{}{},
+									[]interface{}{},
+								},
+								[]interface{}{},
+							},
+							[]interface{}{},
+							[]interface{}{
+								lcommon.GovAnchor{Url: "https://example.com/constitution.txt"},
+								nil,
+							},
+							testConwayProtocolParams(),
+							testConwayProtocolParams(),
+							[]interface{}{0},
+							[]interface{}{},
+						},
+						[]interface{}{emptyMap, emptyMap},
+						0,
+					},
+				},
+				// Snapshots
+				[]interface{}{snapshot, snapshot, snapshot, 400},
+				// Non-myopic
+				[]interface{}{
+					map[ledger.PoolId][]float64{poolId: {0.5, 0.25}},
+					12345,
+				},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	conversation := append(
+		conversationCurrentEra,
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  localstatequery.ProtocolId,
+			MessageType: localstatequery.MessageTypeQuery,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: localstatequery.ProtocolId,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				localstatequery.NewMsgResult(cborData),
+			},
+		},
+	)
+	runTest(
+		t,
+		conversation,
+		func(t *testing.T, oConn *ouroboros.Connection) {
+			epochState, err := oConn.LocalStateQuery().Client.DebugEpochState()
+			if err != nil {
+				t.Fatalf("received unexpected error: %s", err)
+			}
+			if epochState.AccountState.Treasury != 1000 || epochState.AccountState.Reserves != 2000 {
+				t.Fatalf("did not receive expected account state: %#v", epochState.AccountState)
+			}
+			certState := epochState.LedgerState.CertState
+			if certState.VState == nil || certState.VState.NumDormantEpochs != 3 {
+				t.Fatalf("did not receive expected voting state: %#v", certState.VState)
+			}
+			if len(certState.DState.Accounts.Elems) != 1 {
+				t.Fatalf("did not receive expected account count: got %d, wanted %d", len(certState.DState.Accounts.Elems), 1)
+			}
+			for _, account := range certState.DState.Accounts.Elems {
+				if account.RewardDeposit == nil || account.RewardDeposit.Reward != 250 {
+					t.Fatalf("did not receive expected reward/deposit: %#v", account.RewardDeposit)
+				}
+				if account.StakePool == nil || *account.StakePool != poolId {
+					t.Fatalf("did not receive expected stake pool: %v", account.StakePool)
+				}
+				if account.DRep != nil {
+					t.Fatalf("received unexpected DRep: %#v", account.DRep)
+				}
+			}
+			utxoState := epochState.LedgerState.UtxoState
+			if utxoState.Fees != 300 {
+				t.Fatalf("did not receive expected fees: got %d, wanted %d", utxoState.Fees, 300)
+			}
+			if utxoState.ConwayGovState == nil || utxoState.ShelleyGovState != nil {
+				t.Fatalf("did not decode expected governance state layout")
+			}
+			if epochState.Snapshots.Fee != 400 {
+				t.Fatalf("did not receive expected snapshot fee: got %d, wanted %d", epochState.Snapshots.Fee, 400)
+			}
+			for _, delegPool := range epochState.Snapshots.Go.Delegations {
+				if delegPool != poolId {
+					t.Fatalf("did not receive expected snapshot delegation: %s", delegPool.String())
+				}
+			}
+			if likelihoods := epochState.NonMyopic.Likelihoods[poolId]; len(likelihoods) != 2 || likelihoods[1] != 0.25 {
+				t.Fatalf("did not receive expected likelihoods: %v", likelihoods)
+			}
+		},
+	)
+}
+
+func TestDebugUtxoStateInvalidGovState(t *testing.T) {
+	// The governance state has the Conway layout length, but its contents aren't valid
+	cborData, err := cbor.Encode(
+		[]interface{}{
+			map[interface{}]interface{}{},
+			100,
+			300,
+			[]interface{}{"a", "b", "c", "d", "e", "f", "g"},
+			[]interface{}{map[interface{}]interface{}{}, map[interface{}]interface{}{}},
+			0,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var utxoState localstatequery.UtxoState
+	if _, err := cbor.Decode(cborData, &utxoState); err == nil {
+		t.Fatalf("did not receive expected error")
+	}
+}
+
+func TestDebugChainDepState(t *testing.T) {
+	issuerHash := ledger.Blake2b224{0x1}
+	nonce := func(val byte) []interface{} {
+		return []interface{}{1, ledger.NewBlake2b256([]byte{val})}
+	}
+	cborData, err := cbor.Encode(
+		[]interface{}{
+			[]interface{}{
+				0,
+				[]interface{}{
+					[]interface{}{1, 123456},
+					map[ledger.Blake2b224]uint64{issuerHash: 7},
+					nonce(1),
+					nonce(2),
+					nonce(3),
+					[]interface{}{0},
+					nonce(5),
+				},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	conversation := append(
+		conversationCurrentEra,
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  localstatequery.ProtocolId,
+			MessageType: localstatequery.MessageTypeQuery,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: localstatequery.ProtocolId,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				localstatequery.NewMsgResult(cborData),
+			},
+		},
+	)
+	runTest(
+		t,
+		conversation,
+		func(t *testing.T, oConn *ouroboros.Connection) {
+			state, err := oConn.LocalStateQuery().Client.DebugChainDepState()
+			if err != nil {
+				t.Fatalf("received unexpected error: %s", err)
+			}
+			if state.LastSlot == nil || *state.LastSlot != 123456 {
+				t.Fatalf("did not receive expected last slot: %v", state.LastSlot)
+			}
+			if state.OCertCounters[issuerHash] != 7 {
+				t.Fatalf("did not receive expected opcert counter: got %d, wanted %d", state.OCertCounters[issuerHash], 7)
+			}
+			if state.EpochNonce.Type != 1 || state.EpochNonce.Value[0] != 3 {
+				t.Fatalf("did not receive expected epoch nonce: %#v", state.EpochNonce)
+			}
+			if state.LabNonce.Type != 0 {
+				t.Fatalf("did not receive expected lab nonce: %#v", state.LabNonce)
+			}
+			if state.PreviousEpochNonce != nil {
+				t.Fatalf("received unexpected previous epoch nonce: %#v", state.PreviousEpochNonce)
+			}
+		},
+	)
+}
@@ -0,0 +1,451 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package localstatequery
+
+import (
+	"fmt"
+
+	"github.com/blinklabs-io/gouroboros/cbor"
+	"github.com/blinklabs-io/gouroboros/ledger"
+	lcommon "github.com/blinklabs-io/gouroboros/ledger/common"
+	"github.com/blinklabs-io/gouroboros/ledger/shelley"
+)
+
+// The types below represent the ledger state snapshots returned by the debug queries. They follow the
+// Babbage and Conway ledger layouts
+
+type DebugEpochStateResult = EpochState
+
+type DebugNewEpochStateResult = NewEpochState
+
+type DebugChainDepStateResult = PraosState
+
+// NewEpochState represents the full ledger state at the current point
+type NewEpochState struct {
+	cbor.StructAsArray
+	EpochNo uint64
+	// BlocksMadePrev and BlocksMadeCur map pools to the number of blocks they produced in the previous
+	// and current epochs
+	BlocksMadePrev map[ledger.PoolId]uint64
+	BlocksMadeCur  map[ledger.PoolId]uint64
+	EpochState     EpochState
+	// The pending reward update is internal to the ledger and not decoded
+	RewardUpdate cbor.RawMessage
+	PoolDistr    PoolDistrResult
+	// Stashed AVVM addresses are only relevant to the Shelley era
+	StashedAVVMAddresses cbor.RawMessage
+}
+
+// EpochState represents the ledger state for the current epoch
+type EpochState struct {
+	cbor.StructAsArray
+	AccountState AccountStateResult
+	LedgerState  LedgerState
+	Snapshots    EpochSnapshots
+	NonMyopic    NonMyopic
+}
+
+type LedgerState struct {
+	cbor.StructAsArray
+	CertState CertState
+	UtxoState UtxoState
+}
+
+// CertState represents the delegation, pool, and (for Conway) voting state
+type CertState struct {
+	// VState is only populated by node versions that include the Conway voting state
+	VState *VState
+	PState PoolStateResult
+	DState DState
+}
+
+func (c *CertState) UnmarshalCBOR(data []byte) error {
+	listLen, err := cbor.ListLength(data)
+	if err != nil {
+		return err
+	}
+	switch listLen {
+	case 2:
+		var tmpData struct {
+			cbor.StructAsArray
+			PState PoolStateResult
+			DState DState
+		}
+		if _, err := cbor.Decode(data, &tmpData); err != nil {
+			return err
+		}
+		c.VState = nil
+		c.PState = tmpData.PState
+		c.DState = tmpData.DState
+	case 3:
+		var tmpData struct {
+			cbor.StructAsArray
+			VState VState
+			PState PoolStateResult
+			DState DState
+		}
+		if _, err := cbor.Decode(data, &tmpData); err != nil {
+			return err
+		}
+		c.VState = &tmpData.VState
+		c.PState = tmpData.PState
+		c.DState = tmpData.DState
+	default:
+		return fmt.Errorf("invalid list length: %d", listLen)
+	}
+	return nil
+}
+
+// VState represents the DRep and constitutional committee state
+type VState struct {
+	cbor.StructAsArray
+	DReps map[*lcommon.StakeCredential]DRepState
+	// CommitteeState is keyed by committee member cold credential
+	CommitteeState   map[*lcommon.StakeCredential]CommitteeAuthorization
+	NumDormantEpochs uint64
+}
+
+type CommitteeAuthorization struct {
+	// HotCredential is populated when the committee member has authorized a hot credential
+	HotCredential *lcommon.StakeCredential
+	Resigned      bool
+	// ResignationAnchor is optionally populated for resigned members
+	ResignationAnchor *lcommon.GovAnchor
+}
+
+func (c *CommitteeAuthorization) UnmarshalCBOR(data []byte) error {
+	authType, err := cbor.DecodeIdFromList(data)
+	if err != nil {
+		return err
+	}
+	switch authType {
+	case 0:
+		var tmpData struct {
+			cbor.StructAsArray
+			Type          uint
+			HotCredential lcommon.StakeCredential
+		}
+		if _, err := cbor.Decode(data, &tmpData); err != nil {
+			return err
+		}
+		c.HotCredential = &tmpData.HotCredential
+	case 1:
+		var tmpData struct {
+			cbor.StructAsArray
+			Type   uint
+			Anchor cbor.RawMessage
+		}
+		if _, err := cbor.Decode(data, &tmpData); err != nil {
+			return err
+		}
+		c.Resigned = true
+		if err := decodeMaybe(tmpData.Anchor, &c.ResignationAnchor); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown committee authorization type: %d", authType)
+	}
+	return nil
+}
+
+// DState represents the stake delegation state
+type DState struct {
+	cbor.StructAsArray
+	Accounts             UMap
+	FutureGenDelegs      map[FutureGenDeleg]GenDelegPair
+	GenDelegs            map[ledger.Blake2b224]GenDelegPair
+	InstantaneousRewards InstantaneousRewards
+}
+
+// UMap contains the registered stake credentials along with their rewards, deposits, and delegations
+type UMap struct {
+	cbor.StructAsArray
+	Elems map[*lcommon.StakeCredential]UMElem
+	Ptrs  map[Ptr]lcommon.StakeCredential
+}
+
+type UMElem struct {
+	RewardDeposit *RewardDepositPair
+	Ptrs          []Ptr
+	StakePool     *ledger.PoolId
+	DRep          *lcommon.Drep
+}
+
+func (u *UMElem) UnmarshalCBOR(data []byte) error {
+	var tmpData struct {
+		cbor.StructAsArray
+		RewardDeposit cbor.RawMessage
+		Ptrs          []Ptr
+		StakePool     cbor.RawMessage
+		DRep          cbor.RawMessage
+	}
+	if _, err := cbor.Decode(data, &tmpData); err != nil {
+		return err
+	}
+	if err := decodeMaybe(tmpData.RewardDeposit, &u.RewardDeposit); err != nil {
+		return err
+	}
+	if err := decodeMaybe(tmpData.StakePool, &u.StakePool); err != nil {
+		return err
+	}
+	if err := decodeMaybe(tmpData.DRep, &u.DRep); err != nil {
+		return err
+	}
+	u.Ptrs = tmpData.Ptrs
+	return nil
+}
+
+type RewardDepositPair struct {
+	cbor.StructAsArray
+	Reward  uint64
+	Deposit uint64
+}
+
+// Ptr represents a pointer to the certificate that registered a stake credential
+type Ptr struct {
+	cbor.StructAsArray
+	Slot      uint64
+	TxIndex   uint64
+	CertIndex uint64
+}
+
+type FutureGenDeleg struct {
+	cbor.StructAsArray
+	Slot           uint64
+	GenesisKeyHash ledger.Blake2b224
+}
+
+type GenDelegPair struct {
+	cbor.StructAsArray
+	Delegate   ledger.Blake2b224
+	VrfKeyHash ledger.Blake2b256
+}
+
+type InstantaneousRewards struct {
+	cbor.StructAsArray
+	Reserves      map[*lcommon.StakeCredential]uint64
+	Treasury      map[*lcommon.StakeCredential]uint64
+	DeltaReserves int64
+	DeltaTreasury int64
+}
+
+// UtxoState represents the UTxO, deposits, fees, and governance state
+type UtxoState struct {
+	Utxo      map[UtxoId]ledger.BabbageTransactionOutput
+	Deposited uint64
+	Fees      uint64
+	// GovState contains the original CBOR for the era-specific governance state. ShelleyGovState is
+	// populated for the Babbage layout and ConwayGovState for the Conway layout
+	GovState        cbor.RawMessage
+	ShelleyGovState *ShelleyGovState
+	ConwayGovState  *GovStateResult
+	StakeDistr      IncrementalStake
+	Donation        uint64
+}
+
+func (u *UtxoState) UnmarshalCBOR(data []byte) error {
+	var tmpData struct {
+		cbor.StructAsArray
+		Utxo       map[UtxoId]ledger.BabbageTransactionOutput
+		Deposited  uint64
+		Fees       uint64
+		GovState   cbor.RawMessage
+		StakeDistr IncrementalStake
+		Donation   uint64
+	}
+	if _, err := cbor.Decode(data, &tmpData); err != nil {
+		return err
+	}
+	u.Utxo = tmpData.Utxo
+	u.Deposited = tmpData.Deposited
+	u.Fees = tmpData.Fees
+	u.GovState = tmpData.GovState
+	u.StakeDistr = tmpData.StakeDistr
+	u.Donation = tmpData.Donation
+	u.ShelleyGovState = nil
+	u.ConwayGovState = nil
+	// Determine the governance state layout from its length. Layouts from other eras are left undecoded
+	govStateLen, err := cbor.ListLength(tmpData.GovState)
+	if err != nil {
+		return err
+	}
+	switch govStateLen {
+	case 5:
+		var govState ShelleyGovState
+		if _, err := cbor.Decode(tmpData.GovState, &govState); err != nil {
+			return fmt.Errorf("failed to decode Shelley governance state: %w", err)
+		}
+		u.ShelleyGovState = &govState
+	case 7:
+		var govState GovStateResult
+		if _, err := cbor.Decode(tmpData.GovState, &govState); err != nil {
+			return fmt.Errorf("failed to decode Conway governance state: %w", err)
+		}
+		u.ConwayGovState = &govState
+	}
+	return nil
+}
+
+// ShelleyGovState represents the pre-Conway governance state, which contains the protocol parameter update
+// proposals from the genesis delegates
+type ShelleyGovState struct {
+	cbor.StructAsArray
+	Proposals       map[ledger.Blake2b224]ledger.BabbageProtocolParameterUpdate
+	FutureProposals map[ledger.Blake2b224]ledger.BabbageProtocolParameterUpdate
+	CurrentPParams  ledger.BabbageProtocolParameters
+	PreviousPParams ledger.BabbageProtocolParameters
+	// The future protocol params are internal to the ledger and not decoded
+	FuturePParams cbor.RawMessage
+}
+
+// IncrementalStake contains the stake controlled by each credential and pointer
+type IncrementalStake struct {
+	cbor.StructAsArray
+	Credentials map[*lcommon.StakeCredential]uint64
+	Ptrs        map[Ptr]uint64
+}
+
+// EpochSnapshots contains the stake snapshots used for leader election and rewards
+type EpochSnapshots struct {
+	cbor.StructAsArray
+	Mark EpochSnapshot
+	Set  EpochSnapshot
+	Go   EpochSnapshot
+	Fee  uint64
+}
+
+type EpochSnapshot struct {
+	cbor.StructAsArray
+	Stake       map[*lcommon.StakeCredential]uint64
+	Delegations map[*lcommon.StakeCredential]ledger.PoolId
+	PoolParams  map[ledger.PoolId]PoolParams
+}
+
+// NonMyopic contains the data used to calculate non-myopic pool member rewards
+type NonMyopic struct {
+	cbor.StructAsArray
+	Likelihoods map[ledger.PoolId][]float64
+	RewardPot   uint64
+}
+
+// PraosState represents the consensus state for the Praos protocol, which includes the nonces used for
+// leader election and the operational certificate counters for each pool
+type PraosState struct {
+	// LastSlot is nil at the chain origin
+	LastSlot       *uint64
+	OCertCounters  map[ledger.Blake2b224]uint64
+	EvolvingNonce  shelley.Nonce
+	CandidateNonce shelley.Nonce
+	EpochNonce     shelley.Nonce
+	// PreviousEpochNonce is only populated by newer node versions
+	PreviousEpochNonce  *shelley.Nonce
+	LabNonce            shelley.Nonce
+	LastEpochBlockNonce shelley.Nonce
+}
+
+func (p *PraosState) UnmarshalCBOR(data []byte) error {
+	// The state is wrapped with a version number
+	var tmpVersioned struct {
+		cbor.StructAsArray
+		Version uint
+		State   cbor.RawMessage
+	}
+	if _, err := cbor.Decode(data, &tmpVersioned); err != nil {
+		return err
+	}
+	if tmpVersioned.Version != 0 {
+		return fmt.Errorf(
+			"unsupported chain dep state version: %d",
+			tmpVersioned.Version,
+		)
+	}
+	listLen, err := cbor.ListLength(tmpVersioned.State)
+	if err != nil {
+		return err
+	}
+	var lastSlot withOriginSlot
+	switch listLen {
+	case 7:
+		var tmpData struct {
+			cbor.StructAsArray
+			LastSlot            withOriginSlot
+			OCertCounters       map[ledger.Blake2b224]uint64
+			EvolvingNonce       shelley.Nonce
+			CandidateNonce      shelley.Nonce
+			EpochNonce          shelley.Nonce
+			LabNonce            shelley.Nonce
+			LastEpochBlockNonce shelley.Nonce
+		}
+		if _, err := cbor.Decode(tmpVersioned.State, &tmpData); err != nil {
+			return err
+		}
+		lastSlot = tmpData.LastSlot
+		p.OCertCounters = tmpData.OCertCounters
+		p.EvolvingNonce = tmpData.EvolvingNonce
+		p.CandidateNonce = tmpData.CandidateNonce
+		p.EpochNonce = tmpData.EpochNonce
+		p.PreviousEpochNonce = nil
+		p.LabNonce = tmpData.LabNonce
+		p.LastEpochBlockNonce = tmpData.LastEpochBlockNonce
+	case 8:
+		var tmpData struct {
+			cbor.StructAsArray
+			LastSlot            withOriginSlot
+			OCertCounters       map[ledger.Blake2b224]uint64
+			EvolvingNonce       shelley.Nonce
+			CandidateNonce      shelley.Nonce
+			EpochNonce          shelley.Nonce
+			PreviousEpochNonce  shelley.Nonce
+			LabNonce            shelley.Nonce
+			LastEpochBlockNonce shelley.Nonce
+		}
+		if _, err := cbor.Decode(tmpVersioned.State, &tmpData); err != nil {
+			return err
+		}
+		lastSlot = tmpData.LastSlot
+		p.OCertCounters = tmpData.OCertCounters
+		p.EvolvingNonce = tmpData.EvolvingNonce
+		p.CandidateNonce = tmpData.CandidateNonce
+		p.EpochNonce = tmpData.EpochNonce
+		p.PreviousEpochNonce = &tmpData.PreviousEpochNonce
+		p.LabNonce = tmpData.LabNonce
+		p.LastEpochBlockNonce = tmpData.LastEpochBlockNonce
+	default:
+		return fmt.Errorf("unsupported chain dep state list length: %d", listLen)
+	}
+	p.LastSlot = lastSlot.slot
+	return nil
+}
+
+// withOriginSlot decodes a slot number that may instead be the chain origin
+type withOriginSlot struct {
+	slot *uint64
+}
+
+func (w *withOriginSlot) UnmarshalCBOR(data []byte) error {
+	var tmpData []uint64
+	if _, err := cbor.Decode(data, &tmpData); err != nil {
+		return err
+	}
+	switch {
+	case len(tmpData) == 1 && tmpData[0] == 0:
+		w.slot = nil
+	case len(tmpData) == 2 && tmpData[0] == 1:
+		w.slot = &tmpData[1]
+	default:
+		return fmt.Errorf("invalid slot value: %v", tmpData)
+	}
+	return nil
+}
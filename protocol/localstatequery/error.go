@@ -14,6 +14,38 @@
 
 package localstatequery
 
+import (
+	"errors"
+	"fmt"
+)
+
+// QueryNotSupportedError is returned by a QueryHandler for a query that it does not support
+var QueryNotSupportedError = errors.New("query not supported")
+
+// SnapshotClosedError is returned when using a Snapshot after it has been closed
+var SnapshotClosedError = errors.New("snapshot is closed")
+
+// SnapshotNotAcquiredError is returned when running a query on a Snapshot whose ledger state was lost because
+// ReAcquire failed. The snapshot can be used again after a successful ReAcquire
+var SnapshotNotAcquiredError = errors.New("snapshot has no acquired ledger state")
+
+// QueryVersionUnsupportedError is returned when a query requires a newer protocol version than the one negotiated
+// with the peer. The versions are NtC protocol versions, without the NtC version bit
+type QueryVersionUnsupportedError struct {
+	Query      string
+	Version    uint16
+	MinVersion uint16
+}
+
+func (e QueryVersionUnsupportedError) Error() string {
+	return fmt.Sprintf(
+		"query %s requires protocol version %d or newer, but negotiated version is %d",
+		e.Query,
+		e.MinVersion,
+		e.Version,
+	)
+}
+
 // AcquireFailurePointTooOldError indicates a failure to acquire a point due to it being too old
 type AcquireFailurePointTooOldError struct {
 }
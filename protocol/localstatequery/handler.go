@@ -0,0 +1,716 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package localstatequery
+
+import (
+	"fmt"
+
+	"github.com/blinklabs-io/gouroboros/cbor"
+	"github.com/blinklabs-io/gouroboros/ledger"
+	lcommon "github.com/blinklabs-io/gouroboros/ledger/common"
+	"github.com/blinklabs-io/gouroboros/protocol/common"
+)
+
+// Query is implemented by all typed queries returned by DecodeQuery
+type Query interface {
+	isQuery()
+}
+
+type SystemStartQuery struct{}
+
+func (SystemStartQuery) isQuery() {}
+
+type ChainBlockNoQuery struct{}
+
+func (ChainBlockNoQuery) isQuery() {}
+
+type ChainPointQuery struct{}
+
+func (ChainPointQuery) isQuery() {}
+
+type EraHistoryQuery struct{}
+
+func (EraHistoryQuery) isQuery() {}
+
+type CurrentEraQuery struct{}
+
+func (CurrentEraQuery) isQuery() {}
+
+// ShelleyQuery is embedded in all Shelley-based era queries
+type ShelleyQuery struct {
+	// Era is the era that the client expects the ledger to be in
+	Era int
+}
+
+func (ShelleyQuery) isQuery() {}
+
+type EpochNoQuery struct {
+	ShelleyQuery
+}
+
+type NonMyopicMemberRewardsQuery struct {
+	ShelleyQuery
+	Targets []NonMyopicMemberRewardsTarget
+}
+
+type CurrentProtocolParamsQuery struct {
+	ShelleyQuery
+}
+
+type ProposedProtocolParamsUpdatesQuery struct {
+	ShelleyQuery
+}
+
+type StakeDistributionQuery struct {
+	ShelleyQuery
+}
+
+type UTxOByAddressQuery struct {
+	ShelleyQuery
+	Addrs []ledger.Address
+}
+
+type UTxOWholeQuery struct {
+	ShelleyQuery
+}
+
+type DebugEpochStateQuery struct {
+	ShelleyQuery
+}
+
+type FilteredDelegationsAndRewardAccountsQuery struct {
+	ShelleyQuery
+	Creds []lcommon.StakeCredential
+}
+
+type GenesisConfigQuery struct {
+	ShelleyQuery
+}
+
+type DebugNewEpochStateQuery struct {
+	ShelleyQuery
+}
+
+type DebugChainDepStateQuery struct {
+	ShelleyQuery
+}
+
+type RewardProvenanceQuery struct {
+	ShelleyQuery
+}
+
+type UTxOByTxInQuery struct {
+	ShelleyQuery
+	TxIns []ledger.TransactionInput
+}
+
+type StakePoolsQuery struct {
+	ShelleyQuery
+}
+
+type StakePoolParamsQuery struct {
+	ShelleyQuery
+	PoolIds []ledger.PoolId
+}
+
+type RewardInfoPoolsQuery struct {
+	ShelleyQuery
+}
+
+// PoolStateQuery requests the state of the specified pools. PoolIds is nil when all pools are requested
+type PoolStateQuery struct {
+	ShelleyQuery
+	PoolIds []ledger.PoolId
+}
+
+// StakeSnapshotsQuery requests the stake snapshots for the specified pools. PoolIds is nil when all pools are
+// requested
+type StakeSnapshotsQuery struct {
+	ShelleyQuery
+	PoolIds []ledger.PoolId
+}
+
+// PoolDistrQuery requests the stake distribution for the specified pools. PoolIds is nil when all pools are
+// requested
+type PoolDistrQuery struct {
+	ShelleyQuery
+	PoolIds []ledger.PoolId
+}
+
+type StakeDelegDepositsQuery struct {
+	ShelleyQuery
+	Creds []lcommon.StakeCredential
+}
+
+type ConstitutionQuery struct {
+	ShelleyQuery
+}
+
+type GovStateQuery struct {
+	ShelleyQuery
+}
+
+type DRepStateQuery struct {
+	ShelleyQuery
+	Creds []lcommon.StakeCredential
+}
+
+type DRepStakeDistrQuery struct {
+	ShelleyQuery
+	Dreps []lcommon.Drep
+}
+
+type CommitteeMembersStateQuery struct {
+	ShelleyQuery
+	ColdCreds []lcommon.StakeCredential
+	HotCreds  []lcommon.StakeCredential
+	Statuses  []uint
+}
+
+type FilteredVoteDelegateesQuery struct {
+	ShelleyQuery
+	Creds []lcommon.StakeCredential
+}
+
+type AccountStateQuery struct {
+	ShelleyQuery
+}
+
+type SPOStakeDistrQuery struct {
+	ShelleyQuery
+	PoolIds []ledger.PoolId
+}
+
+type BigLedgerPeerSnapshotQuery struct {
+	ShelleyQuery
+}
+
+type StakePoolDefaultVoteQuery struct {
+	ShelleyQuery
+	PoolId ledger.PoolId
+}
+
+// DecodeQuery decodes the CBOR of a query, as built by the client, into the matching typed query
+func DecodeQuery(data []byte) (Query, error) {
+	queryType, params, err := decodeQueryList(data)
+	if err != nil {
+		return nil, err
+	}
+	switch queryType {
+	case QueryTypeBlock:
+		if len(params) != 1 {
+			return nil, fmt.Errorf("invalid block query param count: %d", len(params))
+		}
+		return decodeBlockQuery(params[0])
+	case QueryTypeSystemStart:
+		return &SystemStartQuery{}, nil
+	case QueryTypeChainBlockNo:
+		return &ChainBlockNoQuery{}, nil
+	case QueryTypeChainPoint:
+		return &ChainPointQuery{}, nil
+	default:
+		return nil, fmt.Errorf("unknown query type: %d", queryType)
+	}
+}
+
+func decodeBlockQuery(data []byte) (Query, error) {
+	queryType, params, err := decodeQueryList(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(params) != 1 {
+		return nil, fmt.Errorf("invalid block query param count: %d", len(params))
+	}
+	switch queryType {
+	case QueryTypeShelley:
+		return decodeShelleyQuery(params[0])
+	case QueryTypeHardFork:
+		return decodeHardForkQuery(params[0])
+	default:
+		return nil, fmt.Errorf("unknown block query type: %d", queryType)
+	}
+}
+
+func decodeHardForkQuery(data []byte) (Query, error) {
+	queryType, _, err := decodeQueryList(data)
+	if err != nil {
+		return nil, err
+	}
+	switch queryType {
+	case QueryTypeHardForkEraHistory:
+		return &EraHistoryQuery{}, nil
+	case QueryTypeHardForkCurrentEra:
+		return &CurrentEraQuery{}, nil
+	default:
+		return nil, fmt.Errorf("unknown hard fork query type: %d", queryType)
+	}
+}
+
+func decodeShelleyQuery(data []byte) (Query, error) {
+	era, eraParams, err := decodeQueryList(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(eraParams) != 1 {
+		return nil, fmt.Errorf("invalid era query param count: %d", len(eraParams))
+	}
+	queryType, params, err := decodeQueryList(eraParams[0])
+	if err != nil {
+		return nil, err
+	}
+	base := ShelleyQuery{Era: era}
+	var ret Query
+	switch queryType {
+	case QueryTypeShelleyEpochNo:
+		ret, err = &EpochNoQuery{ShelleyQuery: base}, decodeQueryParams(params)
+	case QueryTypeShelleyNonMyopicMemberRewards:
+		q := &NonMyopicMemberRewardsQuery{ShelleyQuery: base}
+		ret, err = q, decodeQueryParams(params, &q.Targets)
+	case QueryTypeShelleyCurrentProtocolParams:
+		ret, err = &CurrentProtocolParamsQuery{ShelleyQuery: base}, decodeQueryParams(params)
+	case QueryTypeShelleyProposedProtocolParamsUpdates:
+		ret, err = &ProposedProtocolParamsUpdatesQuery{ShelleyQuery: base}, decodeQueryParams(params)
+	case QueryTypeShelleyStakeDistribution:
+		ret, err = &StakeDistributionQuery{ShelleyQuery: base}, decodeQueryParams(params)
+	case QueryTypeShelleyUtxoByAddress:
+		q := &UTxOByAddressQuery{ShelleyQuery: base}
+		ret, err = q, decodeQueryParams(params, &q.Addrs)
+	case QueryTypeShelleyUtxoWhole:
+		ret, err = &UTxOWholeQuery{ShelleyQuery: base}, decodeQueryParams(params)
+	case QueryTypeShelleyDebugEpochState:
+		ret, err = &DebugEpochStateQuery{ShelleyQuery: base}, decodeQueryParams(params)
+	case QueryTypeShelleyFilteredDelegationAndRewardAccounts:
+		q := &FilteredDelegationsAndRewardAccountsQuery{ShelleyQuery: base}
+		ret, err = q, decodeQueryParams(params, &q.Creds)
+	case QueryTypeShelleyGenesisConfig:
+		ret, err = &GenesisConfigQuery{ShelleyQuery: base}, decodeQueryParams(params)
+	case QueryTypeShelleyDebugNewEpochState:
+		ret, err = &DebugNewEpochStateQuery{ShelleyQuery: base}, decodeQueryParams(params)
+	case QueryTypeShelleyDebugChainDepState:
+		ret, err = &DebugChainDepStateQuery{ShelleyQuery: base}, decodeQueryParams(params)
+	case QueryTypeShelleyRewardProvenance:
+		ret, err = &RewardProvenanceQuery{ShelleyQuery: base}, decodeQueryParams(params)
+	case QueryTypeShelleyUtxoByTxin:
+		q := &UTxOByTxInQuery{ShelleyQuery: base}
+		var txIns []ledger.ShelleyTransactionInput
+		err = decodeQueryParams(params, &txIns)
+		for _, txIn := range txIns {
+			q.TxIns = append(q.TxIns, txIn)
+		}
+		ret = q
+	case QueryTypeShelleyStakePools:
+		ret, err = &StakePoolsQuery{ShelleyQuery: base}, decodeQueryParams(params)
+	case QueryTypeShelleyStakePoolParams:
+		q := &StakePoolParamsQuery{ShelleyQuery: base}
+		ret, err = q, decodeQueryParams(params, &q.PoolIds)
+	case QueryTypeShelleyRewardInfoPools:
+		ret, err = &RewardInfoPoolsQuery{ShelleyQuery: base}, decodeQueryParams(params)
+	case QueryTypeShelleyPoolState:
+		q := &PoolStateQuery{ShelleyQuery: base}
+		ret, err = q, decodeMaybeSetQueryParam(params, &q.PoolIds)
+	case QueryTypeShelleyStakeSnapshots:
+		q := &StakeSnapshotsQuery{ShelleyQuery: base}
+		ret, err = q, decodeMaybeSetQueryParam(params, &q.PoolIds)
+	case QueryTypeShelleyPoolDistr:
+		q := &PoolDistrQuery{ShelleyQuery: base}
+		ret, err = q, decodeMaybeSetQueryParam(params, &q.PoolIds)
+	case QueryTypeShelleyStakeDelegDeposits:
+		q := &StakeDelegDepositsQuery{ShelleyQuery: base}
+		ret, err = q, decodeQueryParams(params, &q.Creds)
+	case QueryTypeShelleyConstitution:
+		ret, err = &ConstitutionQuery{ShelleyQuery: base}, decodeQueryParams(params)
+	case QueryTypeShelleyGovState:
+		ret, err = &GovStateQuery{ShelleyQuery: base}, decodeQueryParams(params)
+	case QueryTypeShelleyDRepState:
+		q := &DRepStateQuery{ShelleyQuery: base}
+		ret, err = q, decodeQueryParams(params, &q.Creds)
+	case QueryTypeShelleyDRepStakeDistr:
+		q := &DRepStakeDistrQuery{ShelleyQuery: base}
+		ret, err = q, decodeQueryParams(params, &q.Dreps)
+	case QueryTypeShelleyCommitteeMembersState:
+		q := &CommitteeMembersStateQuery{ShelleyQuery: base}
+		ret, err = q, decodeQueryParams(params, &q.ColdCreds, &q.HotCreds, &q.Statuses)
+	case QueryTypeShelleyFilteredVoteDelegatees:
+		q := &FilteredVoteDelegateesQuery{ShelleyQuery: base}
+		ret, err = q, decodeQueryParams(params, &q.Creds)
+	case QueryTypeShelleyAccountState:
+		ret, err = &AccountStateQuery{ShelleyQuery: base}, decodeQueryParams(params)
+	case QueryTypeShelleySPOStakeDistr:
+		q := &SPOStakeDistrQuery{ShelleyQuery: base}
+		ret, err = q, decodeQueryParams(params, &q.PoolIds)
+	case QueryTypeShelleyBigLedgerPeerSnapshot:
+		ret, err = &BigLedgerPeerSnapshotQuery{ShelleyQuery: base}, decodeQueryParams(params)
+	case QueryTypeShelleyStakePoolDefaultVote:
+		q := &StakePoolDefaultVoteQuery{ShelleyQuery: base}
+		ret, err = q, decodeQueryParams(params, &q.PoolId)
+	default:
+		return nil, fmt.Errorf("unsupported Shelley query type: %d", queryType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Shelley query type %d: %w", queryType, err)
+	}
+	return ret, nil
+}
+
+// decodeQueryList decodes a query list into the query type and the raw query params
+func decodeQueryList(data []byte) (int, []cbor.RawMessage, error) {
+	var tmpData []cbor.RawMessage
+	if _, err := cbor.Decode(data, &tmpData); err != nil {
+		return 0, nil, err
+	}
+	if len(tmpData) == 0 {
+		return 0, nil, fmt.Errorf("empty query")
+	}
+	var queryType int
+	if _, err := cbor.Decode(tmpData[0], &queryType); err != nil {
+		return 0, nil, err
+	}
+	return queryType, tmpData[1:], nil
+}
+
+// decodeQueryParams decodes the raw query params into the provided destinations. Set params are decoded as
+// lists, since the set tag is ignored when decoding into a slice
+func decodeQueryParams(params []cbor.RawMessage, dest ...any) error {
+	if len(params) != len(dest) {
+		return fmt.Errorf(
+			"invalid query param count: expected %d, got %d",
+			len(dest),
+			len(params),
+		)
+	}
+	for idx, param := range params {
+		if _, err := cbor.Decode(param, dest[idx]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeMaybeSetQueryParam decodes an optional set query param, as built by buildMaybeSetParam
+func decodeMaybeSetQueryParam[T any](params []cbor.RawMessage, dest *[]T) error {
+	var tmpItems cbor.RawMessage
+	if err := decodeQueryParams(params, &tmpItems); err != nil {
+		return err
+	}
+	var items *[]T
+	if err := decodeMaybe(tmpItems, &items); err != nil {
+		return err
+	}
+	*dest = nil
+	if items != nil {
+		*dest = append([]T{}, *items...)
+	}
+	return nil
+}
+
+// QueryHandler handles typed queries when acting as a server. Each method corresponds to a query and returns the
+// typed result, which is encoded and sent to the client. Returning an error shuts down the protocol, since
+// the protocol has no way to report a query failure to the client
+//
+// The debug queries return the already encoded ledger or consensus state, since the types used to decode them
+// are not meant to be re-encoded. Implementations can embed UnimplementedQueryHandler and override only the
+// methods for the queries that they support
+type QueryHandler interface {
+	HandleSystemStart(CallbackContext, *SystemStartQuery) (*SystemStartResult, error)
+	HandleChainBlockNo(CallbackContext, *ChainBlockNoQuery) (int64, error)
+	HandleChainPoint(CallbackContext, *ChainPointQuery) (*common.Point, error)
+	HandleEraHistory(CallbackContext, *EraHistoryQuery) ([]EraHistoryResult, error)
+	HandleCurrentEra(CallbackContext, *CurrentEraQuery) (int, error)
+	HandleEpochNo(CallbackContext, *EpochNoQuery) (int, error)
+	HandleNonMyopicMemberRewards(CallbackContext, *NonMyopicMemberRewardsQuery) (*NonMyopicMemberRewardsResult, error)
+	HandleCurrentProtocolParams(CallbackContext, *CurrentProtocolParamsQuery) (CurrentProtocolParamsResult, error)
+	HandleProposedProtocolParamsUpdates(CallbackContext, *ProposedProtocolParamsUpdatesQuery) (*ProposedProtocolParamsUpdatesResult, error)
+	HandleStakeDistribution(CallbackContext, *StakeDistributionQuery) (*StakeDistributionResult, error)
+	HandleUTxOByAddress(CallbackContext, *UTxOByAddressQuery) (*UTxOByAddressResult, error)
+	HandleUTxOWhole(CallbackContext, *UTxOWholeQuery) (*UTxOWholeResult, error)
+	HandleDebugEpochState(CallbackContext, *DebugEpochStateQuery) (cbor.RawMessage, error)
+	HandleFilteredDelegationsAndRewardAccounts(CallbackContext, *FilteredDelegationsAndRewardAccountsQuery) (*FilteredDelegationsAndRewardAccountsResult, error)
+	HandleGenesisConfig(CallbackContext, *GenesisConfigQuery) (*GenesisConfigResult, error)
+	HandleDebugNewEpochState(CallbackContext, *DebugNewEpochStateQuery) (cbor.RawMessage, error)
+	HandleDebugChainDepState(CallbackContext, *DebugChainDepStateQuery) (cbor.RawMessage, error)
+	HandleRewardProvenance(CallbackContext, *RewardProvenanceQuery) (RewardProvenanceResult, error)
+	HandleUTxOByTxIn(CallbackContext, *UTxOByTxInQuery) (*UTxOByTxInResult, error)
+	HandleStakePools(CallbackContext, *StakePoolsQuery) (*StakePoolsResult, error)
+	HandleStakePoolParams(CallbackContext, *StakePoolParamsQuery) (*StakePoolParamsResult, error)
+	HandleRewardInfoPools(CallbackContext, *RewardInfoPoolsQuery) (*RewardInfoPoolsResult, error)
+	HandlePoolState(CallbackContext, *PoolStateQuery) (*PoolStateResult, error)
+	HandleStakeSnapshots(CallbackContext, *StakeSnapshotsQuery) (*StakeSnapshotsResult, error)
+	HandlePoolDistr(CallbackContext, *PoolDistrQuery) (*PoolDistrResult, error)
+	HandleStakeDelegDeposits(CallbackContext, *StakeDelegDepositsQuery) (*StakeDelegDepositsResult, error)
+	HandleConstitution(CallbackContext, *ConstitutionQuery) (*ConstitutionResult, error)
+	HandleGovState(CallbackContext, *GovStateQuery) (*GovStateResult, error)
+	HandleDRepState(CallbackContext, *DRepStateQuery) (*DRepStateResult, error)
+	HandleDRepStakeDistr(CallbackContext, *DRepStakeDistrQuery) (*DRepStakeDistrResult, error)
+	HandleCommitteeMembersState(CallbackContext, *CommitteeMembersStateQuery) (*CommitteeMembersStateResult, error)
+	HandleFilteredVoteDelegatees(CallbackContext, *FilteredVoteDelegateesQuery) (*FilteredVoteDelegateesResult, error)
+	HandleAccountState(CallbackContext, *AccountStateQuery) (*AccountStateResult, error)
+	HandleSPOStakeDistr(CallbackContext, *SPOStakeDistrQuery) (*SPOStakeDistrResult, error)
+	HandleBigLedgerPeerSnapshot(CallbackContext, *BigLedgerPeerSnapshotQuery) (*BigLedgerPeerSnapshotResult, error)
+	HandleStakePoolDefaultVote(CallbackContext, *StakePoolDefaultVoteQuery) (StakePoolDefaultVote, error)
+}
+
+// UnimplementedQueryHandler returns QueryNotSupportedError for all queries. It's meant to be embedded in
+// QueryHandler implementations that only support some queries
+type UnimplementedQueryHandler struct{}
+
+func (UnimplementedQueryHandler) HandleSystemStart(CallbackContext, *SystemStartQuery) (*SystemStartResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleChainBlockNo(CallbackContext, *ChainBlockNoQuery) (int64, error) {
+	return 0, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleChainPoint(CallbackContext, *ChainPointQuery) (*common.Point, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleEraHistory(CallbackContext, *EraHistoryQuery) ([]EraHistoryResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleCurrentEra(CallbackContext, *CurrentEraQuery) (int, error) {
+	return 0, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleEpochNo(CallbackContext, *EpochNoQuery) (int, error) {
+	return 0, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleNonMyopicMemberRewards(CallbackContext, *NonMyopicMemberRewardsQuery) (*NonMyopicMemberRewardsResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleCurrentProtocolParams(CallbackContext, *CurrentProtocolParamsQuery) (CurrentProtocolParamsResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleProposedProtocolParamsUpdates(CallbackContext, *ProposedProtocolParamsUpdatesQuery) (*ProposedProtocolParamsUpdatesResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleStakeDistribution(CallbackContext, *StakeDistributionQuery) (*StakeDistributionResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleUTxOByAddress(CallbackContext, *UTxOByAddressQuery) (*UTxOByAddressResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleUTxOWhole(CallbackContext, *UTxOWholeQuery) (*UTxOWholeResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleDebugEpochState(CallbackContext, *DebugEpochStateQuery) (cbor.RawMessage, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleFilteredDelegationsAndRewardAccounts(CallbackContext, *FilteredDelegationsAndRewardAccountsQuery) (*FilteredDelegationsAndRewardAccountsResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleGenesisConfig(CallbackContext, *GenesisConfigQuery) (*GenesisConfigResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleDebugNewEpochState(CallbackContext, *DebugNewEpochStateQuery) (cbor.RawMessage, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleDebugChainDepState(CallbackContext, *DebugChainDepStateQuery) (cbor.RawMessage, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleRewardProvenance(CallbackContext, *RewardProvenanceQuery) (RewardProvenanceResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleUTxOByTxIn(CallbackContext, *UTxOByTxInQuery) (*UTxOByTxInResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleStakePools(CallbackContext, *StakePoolsQuery) (*StakePoolsResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleStakePoolParams(CallbackContext, *StakePoolParamsQuery) (*StakePoolParamsResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleRewardInfoPools(CallbackContext, *RewardInfoPoolsQuery) (*RewardInfoPoolsResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandlePoolState(CallbackContext, *PoolStateQuery) (*PoolStateResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleStakeSnapshots(CallbackContext, *StakeSnapshotsQuery) (*StakeSnapshotsResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandlePoolDistr(CallbackContext, *PoolDistrQuery) (*PoolDistrResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleStakeDelegDeposits(CallbackContext, *StakeDelegDepositsQuery) (*StakeDelegDepositsResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleConstitution(CallbackContext, *ConstitutionQuery) (*ConstitutionResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleGovState(CallbackContext, *GovStateQuery) (*GovStateResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleDRepState(CallbackContext, *DRepStateQuery) (*DRepStateResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleDRepStakeDistr(CallbackContext, *DRepStakeDistrQuery) (*DRepStakeDistrResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleCommitteeMembersState(CallbackContext, *CommitteeMembersStateQuery) (*CommitteeMembersStateResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleFilteredVoteDelegatees(CallbackContext, *FilteredVoteDelegateesQuery) (*FilteredVoteDelegateesResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleAccountState(CallbackContext, *AccountStateQuery) (*AccountStateResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleSPOStakeDistr(CallbackContext, *SPOStakeDistrQuery) (*SPOStakeDistrResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleBigLedgerPeerSnapshot(CallbackContext, *BigLedgerPeerSnapshotQuery) (*BigLedgerPeerSnapshotResult, error) {
+	return nil, QueryNotSupportedError
+}
+
+func (UnimplementedQueryHandler) HandleStakePoolDefaultVote(CallbackContext, *StakePoolDefaultVoteQuery) (StakePoolDefaultVote, error) {
+	return 0, QueryNotSupportedError
+}
+
+// dispatchQuery calls the handler method for the query and returns the encoded result
+//
+// Results for Shelley-based era queries are wrapped in a single element list, which indicates that the query
+// matched the current era. Some result types already include this wrapping as part of their definition
+func dispatchQuery(
+	ctx CallbackContext,
+	handler QueryHandler,
+	query Query,
+) ([]byte, error) {
+	switch q := query.(type) {
+	case *SystemStartQuery:
+		return encodeQueryResult(handler.HandleSystemStart(ctx, q))
+	case *ChainBlockNoQuery:
+		blockNo, err := handler.HandleChainBlockNo(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		// The block number is wrapped in a WithOrigin value
+		return cbor.Encode([]any{1, blockNo})
+	case *ChainPointQuery:
+		return encodeQueryResult(handler.HandleChainPoint(ctx, q))
+	case *EraHistoryQuery:
+		return encodeQueryResult(handler.HandleEraHistory(ctx, q))
+	case *CurrentEraQuery:
+		return encodeQueryResult(handler.HandleCurrentEra(ctx, q))
+	case *EpochNoQuery:
+		return encodeEraQueryResult(handler.HandleEpochNo(ctx, q))
+	case *NonMyopicMemberRewardsQuery:
+		return encodeQueryResult(handler.HandleNonMyopicMemberRewards(ctx, q))
+	case *CurrentProtocolParamsQuery:
+		return encodeEraQueryResult(handler.HandleCurrentProtocolParams(ctx, q))
+	case *ProposedProtocolParamsUpdatesQuery:
+		return encodeEraQueryResult(handler.HandleProposedProtocolParamsUpdates(ctx, q))
+	case *StakeDistributionQuery:
+		return encodeQueryResult(handler.HandleStakeDistribution(ctx, q))
+	case *UTxOByAddressQuery:
+		return encodeQueryResult(handler.HandleUTxOByAddress(ctx, q))
+	case *UTxOWholeQuery:
+		return encodeQueryResult(handler.HandleUTxOWhole(ctx, q))
+	case *DebugEpochStateQuery:
+		return encodeEraQueryResult(handler.HandleDebugEpochState(ctx, q))
+	case *FilteredDelegationsAndRewardAccountsQuery:
+		return encodeEraQueryResult(handler.HandleFilteredDelegationsAndRewardAccounts(ctx, q))
+	case *GenesisConfigQuery:
+		return encodeEraQueryResult(handler.HandleGenesisConfig(ctx, q))
+	case *DebugNewEpochStateQuery:
+		return encodeEraQueryResult(handler.HandleDebugNewEpochState(ctx, q))
+	case *DebugChainDepStateQuery:
+		return encodeEraQueryResult(handler.HandleDebugChainDepState(ctx, q))
+	case *RewardProvenanceQuery:
+		return encodeEraQueryResult(handler.HandleRewardProvenance(ctx, q))
+	case *UTxOByTxInQuery:
+		return encodeQueryResult(handler.HandleUTxOByTxIn(ctx, q))
+	case *StakePoolsQuery:
+		return encodeQueryResult(handler.HandleStakePools(ctx, q))
+	case *StakePoolParamsQuery:
+		return encodeQueryResult(handler.HandleStakePoolParams(ctx, q))
+	case *RewardInfoPoolsQuery:
+		return encodeEraQueryResult(handler.HandleRewardInfoPools(ctx, q))
+	case *PoolStateQuery:
+		return encodeEraQueryResult(handler.HandlePoolState(ctx, q))
+	case *StakeSnapshotsQuery:
+		return encodeEraQueryResult(handler.HandleStakeSnapshots(ctx, q))
+	case *PoolDistrQuery:
+		return encodeEraQueryResult(handler.HandlePoolDistr(ctx, q))
+	case *StakeDelegDepositsQuery:
+		return encodeQueryResult(handler.HandleStakeDelegDeposits(ctx, q))
+	case *ConstitutionQuery:
+		return encodeEraQueryResult(handler.HandleConstitution(ctx, q))
+	case *GovStateQuery:
+		return encodeEraQueryResult(handler.HandleGovState(ctx, q))
+	case *DRepStateQuery:
+		return encodeQueryResult(handler.HandleDRepState(ctx, q))
+	case *DRepStakeDistrQuery:
+		return encodeQueryResult(handler.HandleDRepStakeDistr(ctx, q))
+	case *CommitteeMembersStateQuery:
+		return encodeEraQueryResult(handler.HandleCommitteeMembersState(ctx, q))
+	case *FilteredVoteDelegateesQuery:
+		return encodeQueryResult(handler.HandleFilteredVoteDelegatees(ctx, q))
+	case *AccountStateQuery:
+		return encodeEraQueryResult(handler.HandleAccountState(ctx, q))
+	case *SPOStakeDistrQuery:
+		return encodeQueryResult(handler.HandleSPOStakeDistr(ctx, q))
+	case *BigLedgerPeerSnapshotQuery:
+		return encodeEraQueryResult(handler.HandleBigLedgerPeerSnapshot(ctx, q))
+	case *StakePoolDefaultVoteQuery:
+		return encodeEraQueryResult(handler.HandleStakePoolDefaultVote(ctx, q))
+	default:
+		return nil, fmt.Errorf("unsupported query type: %T", query)
+	}
+}
+
+func encodeQueryResult[T any](result T, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	return cbor.Encode(result)
+}
+
+func encodeEraQueryResult[T any](result T, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	return cbor.Encode([]any{result})
+}
@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/blinklabs-io/gouroboros/connection"
+	"github.com/blinklabs-io/gouroboros/ledger"
 	"github.com/blinklabs-io/gouroboros/protocol"
 	"github.com/blinklabs-io/gouroboros/protocol/common"
 )
@@ -114,6 +115,7 @@ type LocalStateQuery struct {
 type Config struct {
 	AcquireFunc    AcquireFunc
 	QueryFunc      QueryFunc
+	QueryHandler   QueryHandler
 	ReleaseFunc    ReleaseFunc
 	ReAcquireFunc  ReAcquireFunc
 	DoneFunc       DoneFunc
@@ -135,6 +137,10 @@ type ReleaseFunc func(CallbackContext) error
 type ReAcquireFunc func(CallbackContext, *common.Point) error
 type DoneFunc func(CallbackContext) error
 
+// UTxOFunc is called for each UTxO in a UTxO query result. Returning an error stops the iteration, and the error
+// is returned to the caller
+type UTxOFunc func(ledger.TransactionInput, ledger.TransactionOutput) error
+
 // New returns a new LocalStateQuery object
 func New(protoOptions protocol.ProtocolOptions, cfg *Config) *LocalStateQuery {
 	l := &LocalStateQuery{
@@ -174,6 +180,14 @@ func WithQueryFunc(queryFunc QueryFunc) LocalStateQueryOptionFunc {
 	}
 }
 
+// WithQueryHandler specifies the handler for typed queries when acting as a server. It takes precedence
+// over the Query callback function
+func WithQueryHandler(queryHandler QueryHandler) LocalStateQueryOptionFunc {
+	return func(c *Config) {
+		c.QueryHandler = queryHandler
+	}
+}
+
 // WithReleaseFunc specifies the Release callback function when acting as a server
 func WithReleaseFunc(releaseFunc ReleaseFunc) LocalStateQueryOptionFunc {
 	return func(c *Config) {
@@ -15,10 +15,17 @@
 package localstatequery
 
 import (
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"time"
 
 	"github.com/blinklabs-io/gouroboros/cbor"
 	"github.com/blinklabs-io/gouroboros/ledger"
+	lcommon "github.com/blinklabs-io/gouroboros/ledger/common"
 )
 
 // Query types
@@ -59,6 +66,29 @@ const (
 	QueryTypeShelleyPoolState                           = 19
 	QueryTypeShelleyStakeSnapshots                      = 20
 	QueryTypeShelleyPoolDistr                           = 21
+	QueryTypeShelleyStakeDelegDeposits                  = 22
+	QueryTypeShelleyConstitution                        = 23
+	QueryTypeShelleyGovState                            = 24
+	QueryTypeShelleyDRepState                           = 25
+	QueryTypeShelleyDRepStakeDistr                      = 26
+	QueryTypeShelleyCommitteeMembersState               = 27
+	QueryTypeShelleyFilteredVoteDelegatees              = 28
+	QueryTypeShelleyAccountState                        = 29
+	QueryTypeShelleySPOStakeDistr                       = 30
+	QueryTypeShelleyBigLedgerPeerSnapshot               = 34
+	QueryTypeShelleyStakePoolDefaultVote                = 35
+)
+
+// Minimum NtC protocol versions for version-dependent queries
+const (
+	queryMinVersionChainBlockNo          = 10
+	queryMinVersionChainPoint            = 10
+	queryMinVersionRewardInfoPools       = 11
+	queryMinVersionStakeDelegDeposits    = 15
+	queryMinVersionSPOStakeDistr         = 16
+	queryMinVersionConway                = 16
+	queryMinVersionBigLedgerPeerSnapshot = 19
+	queryMinVersionStakePoolDefaultVote  = 20
 )
 
 func buildQuery(queryType int, params ...interface{}) []interface{} {
@@ -104,6 +134,56 @@ func buildShelleyQuery(
 	return ret
 }
 
+// buildSetParam wraps the provided items in a CBOR set tag for use as a query param
+func buildSetParam[T any](items []T) cbor.Tag {
+	if items == nil {
+		items = []T{}
+	}
+	return cbor.Tag{
+		Number:  cbor.CborTagSet,
+		Content: items,
+	}
+}
+
+// buildMaybeSetParam builds an optional set query param. A nil list of items results in an empty optional value
+func buildMaybeSetParam[T any](items []T) []interface{} {
+	if items == nil {
+		return []interface{}{}
+	}
+	return []interface{}{buildSetParam(items)}
+}
+
+// decodeMaybe decodes an optional value, which is represented as an empty list or null when not present
+// and as a single element list when present
+func decodeMaybe[T any](data cbor.RawMessage, dest **T) error {
+	// 0xf6 is CBOR null
+	if len(data) == 0 || data[0] == 0xf6 {
+		*dest = nil
+		return nil
+	}
+	var tmpData []T
+	if _, err := cbor.Decode(data, &tmpData); err != nil {
+		return err
+	}
+	switch len(tmpData) {
+	case 0:
+		*dest = nil
+	case 1:
+		*dest = &tmpData[0]
+	default:
+		return fmt.Errorf("invalid optional value list length: %d", len(tmpData))
+	}
+	return nil
+}
+
+// encodeMaybe builds the representation of an optional value for encoding, which is the inverse of decodeMaybe
+func encodeMaybe[T any](val *T) []T {
+	if val == nil {
+		return []T{}
+	}
+	return []T{*val}
+}
+
 type SystemStartResult struct {
 	// Tells the CBOR decoder to convert to/from a struct and a CBOR array
 	_           struct{} `cbor:",toarray"`
@@ -112,6 +192,13 @@ type SystemStartResult struct {
 	Picoseconds uint64
 }
 
+// Time returns the system start as a time.Time value
+func (s SystemStartResult) Time() time.Time {
+	return time.Date(s.Year, time.January, 1, 0, 0, 0, 0, time.UTC).
+		AddDate(0, 0, s.Day-1).
+		Add(time.Duration(s.Picoseconds / 1000))
+}
+
 type EraHistoryResult struct {
 	// Tells the CBOR decoder to convert to/from a struct and a CBOR array
 	_      struct{} `cbor:",toarray"`
@@ -142,19 +229,77 @@ type eraHistoryResultParams struct {
 	}
 }
 
-// TODO
-/*
-result	[{ *[0 int] => non_myopic_rewards }]	for each stake display reward
-non_myopic_rewards	{ *poolid => int }	int is the amount of lovelaces each pool would reward
-*/
-type NonMyopicMemberRewardsResult interface{}
+// NonMyopicMemberRewardsTarget represents either a stake amount or a stake credential to calculate
+// non-myopic member rewards for
+type NonMyopicMemberRewardsTarget struct {
+	// Amount is the stake amount in lovelace. It's only used when StakeCredential is nil
+	Amount          uint64
+	StakeCredential *lcommon.StakeCredential
+}
+
+func (t *NonMyopicMemberRewardsTarget) UnmarshalCBOR(data []byte) error {
+	targetType, err := cbor.DecodeIdFromList(data)
+	if err != nil {
+		return err
+	}
+	switch targetType {
+	case 0:
+		var tmpData struct {
+			cbor.StructAsArray
+			Type   uint
+			Amount uint64
+		}
+		if _, err := cbor.Decode(data, &tmpData); err != nil {
+			return err
+		}
+		t.Amount = tmpData.Amount
+	case 1:
+		var tmpData struct {
+			cbor.StructAsArray
+			Type            uint
+			StakeCredential lcommon.StakeCredential
+		}
+		if _, err := cbor.Decode(data, &tmpData); err != nil {
+			return err
+		}
+		t.StakeCredential = &tmpData.StakeCredential
+	default:
+		return fmt.Errorf("unknown non-myopic member rewards target type: %d", targetType)
+	}
+	return nil
+}
+
+func (t NonMyopicMemberRewardsTarget) MarshalCBOR() ([]byte, error) {
+	if t.StakeCredential != nil {
+		return cbor.Encode([]interface{}{1, t.StakeCredential})
+	}
+	return cbor.Encode([]interface{}{0, t.Amount})
+}
+
+func (t NonMyopicMemberRewardsTarget) MarshalText() ([]byte, error) {
+	if t.StakeCredential != nil {
+		return t.StakeCredential.MarshalText()
+	}
+	return []byte(strconv.FormatUint(t.Amount, 10)), nil
+}
+
+// NonMyopicMemberRewardsResult maps each requested target to the rewards that it would receive from each pool
+type NonMyopicMemberRewardsResult struct {
+	cbor.StructAsArray
+	Results map[*NonMyopicMemberRewardsTarget]map[ledger.PoolId]uint64
+}
+
+func (r NonMyopicMemberRewardsResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Results)
+}
 
 type CurrentProtocolParamsResult interface {
 	ledger.BabbageProtocolParameters | any // TODO: add more per-era types
 }
 
-// TODO
-type ProposedProtocolParamsUpdatesResult interface{}
+// ProposedProtocolParamsUpdatesResult maps genesis key hashes to the protocol parameter update that they proposed.
+// The values are the era-specific protocol parameter update type, such as ledger.BabbageProtocolParameterUpdate
+type ProposedProtocolParamsUpdatesResult map[ledger.Blake2b224]any
 
 type StakeDistributionResult struct {
 	cbor.StructAsArray
@@ -170,6 +315,10 @@ type UTxOByAddressResult struct {
 	Results map[UtxoId]ledger.BabbageTransactionOutput
 }
 
+func (r UTxOByAddressResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Results)
+}
+
 type UtxoId struct {
 	cbor.StructAsArray
 	Hash      ledger.Blake2b256
@@ -202,25 +351,26 @@ func (u *UtxoId) UnmarshalCBOR(data []byte) error {
 	return nil
 }
 
-// TODO
-/*
-result	[{* utxo => value }]
-*/
-type UTxOWholeResult interface{}
+func (u UtxoId) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%s#%d", u.Hash.String(), u.Idx)), nil
+}
 
-// TODO
-type DebugEpochStateResult interface{}
+type UTxOWholeResult struct {
+	cbor.StructAsArray
+	Results map[UtxoId]ledger.BabbageTransactionOutput
+}
 
-// TODO
-/*
-rwdr	[flag bytestring]	bytestring is the keyhash of the staking vkey
-flag	0/1	0=keyhash 1=scripthash
-result	[[ delegation rewards] ]
-delegation	{ * rwdr => poolid }	poolid is a bytestring
-rewards	{ * rwdr => int }
-It seems to be a requirement to sort the reward addresses on the query. Scripthash addresses come first, then within a group the bytestring being a network order integer sort ascending.
-*/
-type FilteredDelegationsAndRewardAccountsResult interface{}
+func (r UTxOWholeResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Results)
+}
+
+// FilteredDelegationsAndRewardAccountsResult contains the pool delegations and reward account balances for the
+// requested stake credentials
+type FilteredDelegationsAndRewardAccountsResult struct {
+	cbor.StructAsArray
+	Delegations map[*lcommon.StakeCredential]ledger.PoolId `json:"delegations"`
+	Rewards     map[*lcommon.StakeCredential]uint64        `json:"rewards"`
+}
 
 type GenesisConfigResult struct {
 	// Tells the CBOR decoder to convert to/from a struct and a CBOR array
@@ -264,12 +414,6 @@ type GenesisConfigResult struct {
 	Unknown2  interface{}
 }
 
-// TODO
-type DebugNewEpochStateResult interface{}
-
-// TODO
-type DebugChainDepStateResult interface{}
-
 // TODO
 /*
 result	[ *Element ]	Expanded in order on the next rows.
@@ -298,6 +442,10 @@ type UTxOByTxInResult struct {
 	Results map[UtxoId]ledger.BabbageTransactionOutput
 }
 
+func (r UTxOByTxInResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Results)
+}
+
 type StakePoolsResult struct {
 	cbor.StructAsArray
 	Results []ledger.PoolId
@@ -305,32 +453,837 @@ type StakePoolsResult struct {
 
 type StakePoolParamsResult struct {
 	cbor.StructAsArray
-	Results map[ledger.PoolId]struct {
+	Results map[ledger.PoolId]PoolParams
+}
+
+func (r StakePoolParamsResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Results)
+}
+
+// PoolParams represents the registered parameters for a stake pool
+type PoolParams struct {
+	cbor.StructAsArray
+	Operator      ledger.Blake2b224   `json:"operator"`
+	VrfKeyHash    ledger.Blake2b256   `json:"vrfKeyHash"`
+	Pledge        uint                `json:"pledge"`
+	FixedCost     uint                `json:"fixedCost"`
+	Margin        *cbor.Rat           `json:"margin"`
+	RewardAccount ledger.Address      `json:"rewardAccount"`
+	PoolOwners    []ledger.Blake2b224 `json:"poolOwners"`
+	Relays        []ledger.PoolRelay  `json:"relays"`
+	PoolMetadata  *struct {
+		cbor.StructAsArray
+		Url          string            `json:"url"`
+		MetadataHash ledger.Blake2b256 `json:"metadataHash"`
+	} `json:"poolMetadata,omitempty"`
+}
+
+// RewardInfoPoolsResult contains the global reward parameters and the per-pool information used to calculate
+// rewards
+type RewardInfoPoolsResult struct {
+	cbor.StructAsArray
+	RewardParams RewardParams                     `json:"rewardParams"`
+	Pools        map[ledger.PoolId]RewardInfoPool `json:"pools"`
+}
+
+type RewardParams struct {
+	cbor.StructAsArray
+	NOpt       uint      `json:"nOpt"`
+	A0         *cbor.Rat `json:"a0"`
+	Rewards    uint64    `json:"rewards"`
+	TotalStake uint64    `json:"totalStake"`
+}
+
+type RewardInfoPool struct {
+	cbor.StructAsArray
+	Stake               uint64    `json:"stake"`
+	OwnerPledge         uint64    `json:"ownerPledge"`
+	OwnerStake          uint64    `json:"ownerStake"`
+	Cost                uint64    `json:"cost"`
+	Margin              *cbor.Rat `json:"margin"`
+	PerformanceEstimate float64   `json:"performanceEstimate"`
+}
+
+// PoolStateResult contains the current, future, and retiring stake pools along with their deposits
+type PoolStateResult struct {
+	cbor.StructAsArray
+	PoolParams       map[ledger.PoolId]PoolParams `json:"poolParams"`
+	FuturePoolParams map[ledger.PoolId]PoolParams `json:"futurePoolParams"`
+	Retiring         map[ledger.PoolId]uint64     `json:"retiring"`
+	Deposits         map[ledger.PoolId]uint64     `json:"deposits"`
+}
+
+// StakeSnapshotsResult contains the mark, set, and go stake snapshots for the requested pools along with the
+// total active stake for each snapshot
+type StakeSnapshotsResult struct {
+	cbor.StructAsArray
+	PoolSnapshots  map[ledger.PoolId]StakeSnapshot `json:"poolSnapshots"`
+	TotalMarkStake uint64                          `json:"totalMarkStake"`
+	TotalSetStake  uint64                          `json:"totalSetStake"`
+	TotalGoStake   uint64                          `json:"totalGoStake"`
+}
+
+type StakeSnapshot struct {
+	cbor.StructAsArray
+	MarkStake uint64 `json:"markStake"`
+	SetStake  uint64 `json:"setStake"`
+	GoStake   uint64 `json:"goStake"`
+}
+
+// PoolDistrResult contains the stake distribution for the requested pools
+type PoolDistrResult struct {
+	Results map[ledger.PoolId]IndividualPoolStake `json:"results"`
+	// TotalActiveStake is only populated by newer node versions
+	TotalActiveStake uint64 `json:"totalActiveStake,omitempty"`
+}
+
+func (r *PoolDistrResult) UnmarshalCBOR(data []byte) error {
+	// Older node versions return only the map
+	if len(data) > 0 && data[0]&cbor.CborTypeMask == cbor.CborTypeMap {
+		r.TotalActiveStake = 0
+		_, err := cbor.Decode(data, &r.Results)
+		return err
+	}
+	var tmpData struct {
 		cbor.StructAsArray
-		Operator      ledger.Blake2b224
-		VrfKeyHash    ledger.Blake2b256
-		Pledge        uint
-		FixedCost     uint
-		Margin        *cbor.Rat
-		RewardAccount ledger.Address
-		PoolOwners    []ledger.Blake2b224
-		Relays        []ledger.PoolRelay
-		PoolMetadata  *struct {
+		Results          map[ledger.PoolId]IndividualPoolStake
+		TotalActiveStake uint64
+	}
+	if _, err := cbor.Decode(data, &tmpData); err != nil {
+		return err
+	}
+	r.Results = tmpData.Results
+	r.TotalActiveStake = tmpData.TotalActiveStake
+	return nil
+}
+
+func (r PoolDistrResult) MarshalCBOR() ([]byte, error) {
+	// Use the format from older node versions when the newer fields are not populated
+	if r.TotalActiveStake == 0 {
+		tmpResults := make(map[ledger.PoolId]any, len(r.Results))
+		for poolId, stake := range r.Results {
+			tmpResults[poolId] = []any{stake.StakeFraction, stake.VrfHash}
+		}
+		return cbor.Encode(tmpResults)
+	}
+	tmpResults := make(map[ledger.PoolId]any, len(r.Results))
+	for poolId, stake := range r.Results {
+		tmpResults[poolId] = []any{stake.StakeFraction, stake.TotalStake, stake.VrfHash}
+	}
+	return cbor.Encode([]any{tmpResults, r.TotalActiveStake})
+}
+
+type IndividualPoolStake struct {
+	StakeFraction *cbor.Rat `json:"stakeFraction"`
+	// TotalStake is only populated by newer node versions
+	TotalStake uint64            `json:"totalStake,omitempty"`
+	VrfHash    ledger.Blake2b256 `json:"vrfHash"`
+}
+
+func (p *IndividualPoolStake) UnmarshalCBOR(data []byte) error {
+	listLen, err := cbor.ListLength(data)
+	if err != nil {
+		return err
+	}
+	switch listLen {
+	case 2:
+		var tmpData struct {
+			cbor.StructAsArray
+			StakeFraction *cbor.Rat
+			VrfHash       ledger.Blake2b256
+		}
+		if _, err := cbor.Decode(data, &tmpData); err != nil {
+			return err
+		}
+		p.StakeFraction = tmpData.StakeFraction
+		p.TotalStake = 0
+		p.VrfHash = tmpData.VrfHash
+	case 3:
+		var tmpData struct {
 			cbor.StructAsArray
-			Url          string
-			MetadataHash ledger.Blake2b256
+			StakeFraction *cbor.Rat
+			TotalStake    uint64
+			VrfHash       ledger.Blake2b256
 		}
+		if _, err := cbor.Decode(data, &tmpData); err != nil {
+			return err
+		}
+		p.StakeFraction = tmpData.StakeFraction
+		p.TotalStake = tmpData.TotalStake
+		p.VrfHash = tmpData.VrfHash
+	default:
+		return fmt.Errorf("invalid list length: %d", listLen)
 	}
+	return nil
 }
 
-// TODO
-type RewardInfoPoolsResult interface{}
+// ConstitutionResult represents the current constitution
+type ConstitutionResult struct {
+	cbor.StructAsArray
+	Anchor     lcommon.GovAnchor
+	ScriptHash []byte
+}
 
-// TODO
-type PoolStateResult interface{}
+// GovStateResult represents the governance state, which includes the active proposals and their votes
+type GovStateResult struct {
+	Proposals       GovProposals
+	Committee       *GovCommittee
+	Constitution    ConstitutionResult
+	CurrentPParams  ledger.ConwayProtocolParameters
+	PreviousPParams ledger.ConwayProtocolParameters
+	// The remaining values are internal to the ledger and not decoded
+	FuturePParams    cbor.RawMessage
+	DRepPulsingState cbor.RawMessage
+}
 
-// TODO
-type StakeSnapshotsResult interface{}
+func (g *GovStateResult) UnmarshalCBOR(data []byte) error {
+	var tmpData struct {
+		cbor.StructAsArray
+		Proposals        GovProposals
+		Committee        cbor.RawMessage
+		Constitution     ConstitutionResult
+		CurrentPParams   ledger.ConwayProtocolParameters
+		PreviousPParams  ledger.ConwayProtocolParameters
+		FuturePParams    cbor.RawMessage
+		DRepPulsingState cbor.RawMessage
+	}
+	if _, err := cbor.Decode(data, &tmpData); err != nil {
+		return err
+	}
+	if err := decodeMaybe(tmpData.Committee, &g.Committee); err != nil {
+		return err
+	}
+	g.Proposals = tmpData.Proposals
+	g.Constitution = tmpData.Constitution
+	g.CurrentPParams = tmpData.CurrentPParams
+	g.PreviousPParams = tmpData.PreviousPParams
+	g.FuturePParams = tmpData.FuturePParams
+	g.DRepPulsingState = tmpData.DRepPulsingState
+	return nil
+}
 
-// TODO
-type PoolDistrResult interface{}
+func (g GovStateResult) MarshalCBOR() ([]byte, error) {
+	tmpData := []any{
+		g.Proposals,
+		encodeMaybe(g.Committee),
+		g.Constitution,
+		g.CurrentPParams,
+		g.PreviousPParams,
+		g.FuturePParams,
+		g.DRepPulsingState,
+	}
+	return cbor.Encode(tmpData)
+}
+
+// GovProposals represents the active governance proposals along with the most recently enacted
+// action for each purpose
+type GovProposals struct {
+	cbor.StructAsArray
+	Roots     GovProposalRoots
+	Proposals []GovActionState
+}
+
+// GovProposalRoots contains the most recently enacted governance action ID for each purpose. A nil value
+// means that no action of that purpose has been enacted
+type GovProposalRoots struct {
+	ProtocolParameterUpdate *lcommon.GovActionId
+	HardFork                *lcommon.GovActionId
+	Committee               *lcommon.GovActionId
+	Constitution            *lcommon.GovActionId
+}
+
+func (r *GovProposalRoots) UnmarshalCBOR(data []byte) error {
+	var tmpData struct {
+		cbor.StructAsArray
+		ProtocolParameterUpdate cbor.RawMessage
+		HardFork                cbor.RawMessage
+		Committee               cbor.RawMessage
+		Constitution            cbor.RawMessage
+	}
+	if _, err := cbor.Decode(data, &tmpData); err != nil {
+		return err
+	}
+	if err := decodeMaybe(tmpData.ProtocolParameterUpdate, &r.ProtocolParameterUpdate); err != nil {
+		return err
+	}
+	if err := decodeMaybe(tmpData.HardFork, &r.HardFork); err != nil {
+		return err
+	}
+	if err := decodeMaybe(tmpData.Committee, &r.Committee); err != nil {
+		return err
+	}
+	if err := decodeMaybe(tmpData.Constitution, &r.Constitution); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r GovProposalRoots) MarshalCBOR() ([]byte, error) {
+	tmpData := []any{
+		encodeMaybe(r.ProtocolParameterUpdate),
+		encodeMaybe(r.HardFork),
+		encodeMaybe(r.Committee),
+		encodeMaybe(r.Constitution),
+	}
+	return cbor.Encode(tmpData)
+}
+
+// GovActionState represents an active governance proposal and the votes cast on it so far
+type GovActionState struct {
+	cbor.StructAsArray
+	Id lcommon.GovActionId
+	// Committee votes are keyed by committee hot credential
+	CommitteeVotes map[*lcommon.StakeCredential]uint8
+	// DRep votes are keyed by DRep credential
+	DRepVotes map[*lcommon.StakeCredential]uint8
+	// Stake pool votes are keyed by pool key hash
+	StakePoolVotes    map[ledger.Blake2b224]uint8
+	ProposalProcedure lcommon.ProposalProcedure
+	ProposedIn        uint64
+	ExpiresAfter      uint64
+}
+
+// Votes returns all votes cast on the proposal, keyed by voter
+func (s *GovActionState) Votes() map[lcommon.Voter]uint8 {
+	ret := make(
+		map[lcommon.Voter]uint8,
+		len(s.CommitteeVotes)+len(s.DRepVotes)+len(s.StakePoolVotes),
+	)
+	for cred, vote := range s.CommitteeVotes {
+		voter := lcommon.Voter{
+			Type: lcommon.VoterTypeConstitutionalCommitteeHotKeyHash,
+		}
+		if cred.CredType == lcommon.StakeCredentialTypeScriptHash {
+			voter.Type = lcommon.VoterTypeConstitutionalCommitteeHotScriptHash
+		}
+		copy(voter.Hash[:], cred.Credential)
+		ret[voter] = vote
+	}
+	for cred, vote := range s.DRepVotes {
+		voter := lcommon.Voter{
+			Type: lcommon.VoterTypeDRepKeyHash,
+		}
+		if cred.CredType == lcommon.StakeCredentialTypeScriptHash {
+			voter.Type = lcommon.VoterTypeDRepScriptHash
+		}
+		copy(voter.Hash[:], cred.Credential)
+		ret[voter] = vote
+	}
+	for poolKeyHash, vote := range s.StakePoolVotes {
+		voter := lcommon.Voter{
+			Type: lcommon.VoterTypeStakingPoolKeyHash,
+			Hash: poolKeyHash,
+		}
+		ret[voter] = vote
+	}
+	return ret
+}
+
+// GovCommittee represents the constitutional committee
+type GovCommittee struct {
+	cbor.StructAsArray
+	// Members maps the committee member cold credentials to the epoch in which their term expires
+	Members   map[*lcommon.StakeCredential]uint64
+	Threshold *cbor.Rat
+}
+
+// DRepStateResult maps DRep credentials to their current state
+type DRepStateResult struct {
+	cbor.StructAsArray
+	Results map[*lcommon.StakeCredential]DRepState
+}
+
+type DRepState struct {
+	Expiry  uint64
+	Anchor  *lcommon.GovAnchor
+	Deposit uint64
+	// Delegators is only populated by newer node versions
+	Delegators []lcommon.StakeCredential
+}
+
+func (d *DRepState) UnmarshalCBOR(data []byte) error {
+	listLen, err := cbor.ListLength(data)
+	if err != nil {
+		return err
+	}
+	var tmpData struct {
+		cbor.StructAsArray
+		Expiry     uint64
+		Anchor     *lcommon.GovAnchor
+		Deposit    uint64
+		Delegators []lcommon.StakeCredential
+	}
+	switch listLen {
+	case 3:
+		var tmpDataOld struct {
+			cbor.StructAsArray
+			Expiry  uint64
+			Anchor  *lcommon.GovAnchor
+			Deposit uint64
+		}
+		if _, err := cbor.Decode(data, &tmpDataOld); err != nil {
+			return err
+		}
+		tmpData.Expiry = tmpDataOld.Expiry
+		tmpData.Anchor = tmpDataOld.Anchor
+		tmpData.Deposit = tmpDataOld.Deposit
+	case 4:
+		if _, err := cbor.Decode(data, &tmpData); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid list length: %d", listLen)
+	}
+	d.Expiry = tmpData.Expiry
+	d.Anchor = tmpData.Anchor
+	d.Deposit = tmpData.Deposit
+	d.Delegators = tmpData.Delegators
+	return nil
+}
+
+func (d DRepState) MarshalCBOR() ([]byte, error) {
+	// Use the format from older node versions when there are no delegators
+	if d.Delegators == nil {
+		return cbor.Encode([]any{d.Expiry, d.Anchor, d.Deposit})
+	}
+	return cbor.Encode([]any{d.Expiry, d.Anchor, d.Deposit, d.Delegators})
+}
+
+// DRepStakeDistrResult maps DReps to their total delegated stake
+type DRepStakeDistrResult struct {
+	cbor.StructAsArray
+	Results map[*lcommon.Drep]uint64
+}
+
+// Committee member statuses
+const (
+	CommitteeMemberStatusActive       = 0
+	CommitteeMemberStatusExpired      = 1
+	CommitteeMemberStatusUnrecognized = 2
+)
+
+// CommitteeMembersStateResult represents the state of the constitutional committee members
+type CommitteeMembersStateResult struct {
+	// Members are keyed by committee member cold credential
+	Members   map[*lcommon.StakeCredential]CommitteeMemberState
+	Threshold *cbor.Rat
+	Epoch     uint64
+}
+
+func (c *CommitteeMembersStateResult) UnmarshalCBOR(data []byte) error {
+	var tmpData struct {
+		cbor.StructAsArray
+		Members   map[*lcommon.StakeCredential]CommitteeMemberState
+		Threshold cbor.RawMessage
+		Epoch     uint64
+	}
+	if _, err := cbor.Decode(data, &tmpData); err != nil {
+		return err
+	}
+	if err := decodeMaybe(tmpData.Threshold, &c.Threshold); err != nil {
+		return err
+	}
+	c.Members = tmpData.Members
+	c.Epoch = tmpData.Epoch
+	return nil
+}
+
+func (c CommitteeMembersStateResult) MarshalCBOR() ([]byte, error) {
+	tmpData := []any{
+		c.Members,
+		encodeMaybe(c.Threshold),
+		c.Epoch,
+	}
+	return cbor.Encode(tmpData)
+}
+
+type CommitteeMemberState struct {
+	HotCredAuthStatus CommitteeHotCredAuthStatus
+	Status            uint
+	Expiration        *uint64
+	NextEpochChange   CommitteeNextEpochChange
+}
+
+func (c *CommitteeMemberState) UnmarshalCBOR(data []byte) error {
+	var tmpData struct {
+		cbor.StructAsArray
+		HotCredAuthStatus CommitteeHotCredAuthStatus
+		Status            uint
+		Expiration        cbor.RawMessage
+		NextEpochChange   CommitteeNextEpochChange
+	}
+	if _, err := cbor.Decode(data, &tmpData); err != nil {
+		return err
+	}
+	if err := decodeMaybe(tmpData.Expiration, &c.Expiration); err != nil {
+		return err
+	}
+	c.HotCredAuthStatus = tmpData.HotCredAuthStatus
+	c.Status = tmpData.Status
+	c.NextEpochChange = tmpData.NextEpochChange
+	return nil
+}
+
+func (c CommitteeMemberState) MarshalCBOR() ([]byte, error) {
+	tmpData := []any{
+		c.HotCredAuthStatus,
+		c.Status,
+		encodeMaybe(c.Expiration),
+		c.NextEpochChange,
+	}
+	return cbor.Encode(tmpData)
+}
+
+// Committee member hot credential authorization statuses
+const (
+	CommitteeHotCredAuthStatusAuthorized    = 0
+	CommitteeHotCredAuthStatusNotAuthorized = 1
+	CommitteeHotCredAuthStatusResigned      = 2
+)
+
+type CommitteeHotCredAuthStatus struct {
+	Type uint
+	// HotCredential is only populated for authorized members
+	HotCredential *lcommon.StakeCredential
+	// ResignationAnchor is optionally populated for resigned members
+	ResignationAnchor *lcommon.GovAnchor
+}
+
+func (c *CommitteeHotCredAuthStatus) UnmarshalCBOR(data []byte) error {
+	statusType, err := cbor.DecodeIdFromList(data)
+	if err != nil {
+		return err
+	}
+	c.Type = uint(statusType)
+	switch statusType {
+	case CommitteeHotCredAuthStatusAuthorized:
+		var tmpData struct {
+			cbor.StructAsArray
+			Type          uint
+			HotCredential lcommon.StakeCredential
+		}
+		if _, err := cbor.Decode(data, &tmpData); err != nil {
+			return err
+		}
+		c.HotCredential = &tmpData.HotCredential
+	case CommitteeHotCredAuthStatusNotAuthorized:
+	case CommitteeHotCredAuthStatusResigned:
+		var tmpData struct {
+			cbor.StructAsArray
+			Type   uint
+			Anchor *lcommon.GovAnchor
+		}
+		if _, err := cbor.Decode(data, &tmpData); err != nil {
+			return err
+		}
+		c.ResignationAnchor = tmpData.Anchor
+	default:
+		return fmt.Errorf("unknown hot credential auth status: %d", statusType)
+	}
+	return nil
+}
+
+func (c CommitteeHotCredAuthStatus) MarshalCBOR() ([]byte, error) {
+	switch c.Type {
+	case CommitteeHotCredAuthStatusAuthorized:
+		if c.HotCredential == nil {
+			return nil, fmt.Errorf("authorized hot credential auth status has no hot credential")
+		}
+		return cbor.Encode([]any{c.Type, c.HotCredential})
+	case CommitteeHotCredAuthStatusNotAuthorized:
+		return cbor.Encode([]any{c.Type})
+	case CommitteeHotCredAuthStatusResigned:
+		return cbor.Encode([]any{c.Type, c.ResignationAnchor})
+	default:
+		return nil, fmt.Errorf("unknown hot credential auth status: %d", c.Type)
+	}
+}
+
+// Committee member next epoch changes
+const (
+	CommitteeNextEpochChangeToBeEnacted      = 0
+	CommitteeNextEpochChangeToBeRemoved      = 1
+	CommitteeNextEpochChangeNoChangeExpected = 2
+	CommitteeNextEpochChangeToBeExpired      = 3
+	CommitteeNextEpochChangeTermAdjusted     = 4
+)
+
+type CommitteeNextEpochChange struct {
+	Type uint
+	// Epoch is only populated for term adjustments
+	Epoch uint64
+}
+
+func (c *CommitteeNextEpochChange) UnmarshalCBOR(data []byte) error {
+	changeType, err := cbor.DecodeIdFromList(data)
+	if err != nil {
+		return err
+	}
+	c.Type = uint(changeType)
+	switch changeType {
+	case CommitteeNextEpochChangeToBeEnacted,
+		CommitteeNextEpochChangeToBeRemoved,
+		CommitteeNextEpochChangeNoChangeExpected,
+		CommitteeNextEpochChangeToBeExpired:
+	case CommitteeNextEpochChangeTermAdjusted:
+		var tmpData struct {
+			cbor.StructAsArray
+			Type  uint
+			Epoch uint64
+		}
+		if _, err := cbor.Decode(data, &tmpData); err != nil {
+			return err
+		}
+		c.Epoch = tmpData.Epoch
+	default:
+		return fmt.Errorf("unknown next epoch change type: %d", changeType)
+	}
+	return nil
+}
+
+func (c CommitteeNextEpochChange) MarshalCBOR() ([]byte, error) {
+	switch c.Type {
+	case CommitteeNextEpochChangeToBeEnacted,
+		CommitteeNextEpochChangeToBeRemoved,
+		CommitteeNextEpochChangeNoChangeExpected,
+		CommitteeNextEpochChangeToBeExpired:
+		return cbor.Encode([]any{c.Type})
+	case CommitteeNextEpochChangeTermAdjusted:
+		return cbor.Encode([]any{c.Type, c.Epoch})
+	default:
+		return nil, fmt.Errorf("unknown next epoch change type: %d", c.Type)
+	}
+}
+
+// FilteredVoteDelegateesResult maps stake credentials to the DRep that they delegate their vote to
+type FilteredVoteDelegateesResult struct {
+	cbor.StructAsArray
+	Results map[*lcommon.StakeCredential]lcommon.Drep
+}
+
+// AccountStateResult represents the treasury and reserves balances
+type AccountStateResult struct {
+	cbor.StructAsArray
+	Treasury uint64
+	Reserves uint64
+}
+
+// StakeDelegDepositsResult maps stake credentials to the deposit paid when registering them
+type StakeDelegDepositsResult struct {
+	cbor.StructAsArray
+	Results map[*lcommon.StakeCredential]uint64
+}
+
+// SPOStakeDistrResult maps pool IDs to their total stake, including stake delegated to the pool via DReps
+type SPOStakeDistrResult struct {
+	cbor.StructAsArray
+	Results map[ledger.PoolId]uint64
+}
+
+// BigLedgerPeerSnapshotResult represents a snapshot of the big ledger peers, which are the largest pools that
+// together hold 90% of the active stake
+type BigLedgerPeerSnapshotResult struct {
+	// Slot is the slot that the snapshot was taken at. It's nil when the snapshot was taken at origin
+	Slot  *uint64
+	Pools []LedgerPeerSnapshotPool
+}
+
+// The only snapshot encoding version currently produced by the node
+const ledgerPeerSnapshotVersion = 1
+
+func (r *BigLedgerPeerSnapshotResult) UnmarshalCBOR(data []byte) error {
+	var tmpData struct {
+		cbor.StructAsArray
+		Version  uint
+		Snapshot struct {
+			cbor.StructAsArray
+			WithOrigin []uint64
+			Pools      []LedgerPeerSnapshotPool
+		}
+	}
+	if _, err := cbor.Decode(data, &tmpData); err != nil {
+		return err
+	}
+	if tmpData.Version != ledgerPeerSnapshotVersion {
+		return fmt.Errorf("unsupported ledger peer snapshot version: %d", tmpData.Version)
+	}
+	r.Slot = nil
+	switch len(tmpData.Snapshot.WithOrigin) {
+	case 1:
+		// Origin
+	case 2:
+		slot := tmpData.Snapshot.WithOrigin[1]
+		r.Slot = &slot
+	default:
+		return fmt.Errorf("invalid snapshot slot: %v", tmpData.Snapshot.WithOrigin)
+	}
+	r.Pools = tmpData.Snapshot.Pools
+	return nil
+}
+
+func (r BigLedgerPeerSnapshotResult) MarshalCBOR() ([]byte, error) {
+	withOrigin := []uint64{0}
+	if r.Slot != nil {
+		withOrigin = []uint64{1, *r.Slot}
+	}
+	return cbor.Encode(
+		[]any{
+			ledgerPeerSnapshotVersion,
+			[]any{withOrigin, r.Pools},
+		},
+	)
+}
+
+// LedgerPeerSnapshotPool represents a single pool in a ledger peer snapshot
+type LedgerPeerSnapshotPool struct {
+	// AccumulatedStake is the relative stake of this pool and all larger pools in the snapshot
+	AccumulatedStake *big.Rat
+	RelativeStake    *big.Rat
+	Relays           []LedgerPeerSnapshotRelay
+}
+
+func (p *LedgerPeerSnapshotPool) UnmarshalCBOR(data []byte) error {
+	var tmpData struct {
+		cbor.StructAsArray
+		AccumulatedStake cbor.Rat
+		Stake            struct {
+			cbor.StructAsArray
+			RelativeStake cbor.Rat
+			Relays        []LedgerPeerSnapshotRelay
+		}
+	}
+	if _, err := cbor.Decode(data, &tmpData); err != nil {
+		return err
+	}
+	p.AccumulatedStake = tmpData.AccumulatedStake.ToBigRat()
+	p.RelativeStake = tmpData.Stake.RelativeStake.ToBigRat()
+	p.Relays = tmpData.Stake.Relays
+	return nil
+}
+
+func (p LedgerPeerSnapshotPool) MarshalCBOR() ([]byte, error) {
+	// The stake values are encoded as untagged rationals
+	return cbor.Encode(
+		[]any{
+			[]any{p.AccumulatedStake.Num(), p.AccumulatedStake.Denom()},
+			[]any{
+				[]any{p.RelativeStake.Num(), p.RelativeStake.Denom()},
+				p.Relays,
+			},
+		},
+	)
+}
+
+// Ledger peer snapshot relay types
+const (
+	LedgerPeerSnapshotRelayTypeDomain = 0
+	LedgerPeerSnapshotRelayTypeIPv4   = 1
+	LedgerPeerSnapshotRelayTypeIPv6   = 2
+)
+
+// LedgerPeerSnapshotRelay represents a relay for a pool in a ledger peer snapshot. Domain is only set for domain
+// relays, and IP is only set for IPv4 and IPv6 relays
+type LedgerPeerSnapshotRelay struct {
+	Type   uint
+	Port   uint16
+	Domain string
+	IP     net.IP
+}
+
+func (r *LedgerPeerSnapshotRelay) UnmarshalCBOR(data []byte) error {
+	relayType, err := cbor.DecodeIdFromList(data)
+	if err != nil {
+		return err
+	}
+	r.Type = uint(relayType)
+	r.Domain = ""
+	r.IP = nil
+	switch relayType {
+	case LedgerPeerSnapshotRelayTypeDomain:
+		var tmpData struct {
+			cbor.StructAsArray
+			Type   uint
+			Port   uint16
+			Domain []byte
+		}
+		if _, err := cbor.Decode(data, &tmpData); err != nil {
+			return err
+		}
+		r.Port = tmpData.Port
+		r.Domain = string(tmpData.Domain)
+	case LedgerPeerSnapshotRelayTypeIPv4, LedgerPeerSnapshotRelayTypeIPv6:
+		// IPv4 addresses are encoded as a list of 4 octets, and IPv6 addresses as a list of 8 16-bit words
+		var tmpData struct {
+			cbor.StructAsArray
+			Type    uint
+			Port    uint16
+			Address []uint16
+		}
+		if _, err := cbor.Decode(data, &tmpData); err != nil {
+			return err
+		}
+		r.Port = tmpData.Port
+		if relayType == LedgerPeerSnapshotRelayTypeIPv4 {
+			if len(tmpData.Address) != net.IPv4len {
+				return fmt.Errorf("invalid IPv4 address length: %d", len(tmpData.Address))
+			}
+			r.IP = make(net.IP, net.IPv4len)
+			for idx, octet := range tmpData.Address {
+				if octet > 0xff {
+					return fmt.Errorf("invalid IPv4 address octet: %d", octet)
+				}
+				r.IP[idx] = byte(octet)
+			}
+		} else {
+			if len(tmpData.Address) != net.IPv6len/2 {
+				return fmt.Errorf("invalid IPv6 address length: %d", len(tmpData.Address))
+			}
+			r.IP = make(net.IP, net.IPv6len)
+			for idx, word := range tmpData.Address {
+				binary.BigEndian.PutUint16(r.IP[idx*2:], word)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown relay type: %d", relayType)
+	}
+	return nil
+}
+
+func (r LedgerPeerSnapshotRelay) MarshalCBOR() ([]byte, error) {
+	switch r.Type {
+	case LedgerPeerSnapshotRelayTypeDomain:
+		return cbor.Encode([]any{r.Type, r.Port, []byte(r.Domain)})
+	case LedgerPeerSnapshotRelayTypeIPv4:
+		ip := r.IP.To4()
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IPv4 address: %s", r.IP)
+		}
+		address := make([]uint16, len(ip))
+		for idx, octet := range ip {
+			address[idx] = uint16(octet)
+		}
+		return cbor.Encode([]any{r.Type, r.Port, address})
+	case LedgerPeerSnapshotRelayTypeIPv6:
+		ip := r.IP.To16()
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IPv6 address: %s", r.IP)
+		}
+		address := make([]uint16, net.IPv6len/2)
+		for idx := range address {
+			address[idx] = binary.BigEndian.Uint16(ip[idx*2:])
+		}
+		return cbor.Encode([]any{r.Type, r.Port, address})
+	default:
+		return nil, fmt.Errorf("unknown relay type: %d", r.Type)
+	}
+}
+
+// StakePoolDefaultVote is the vote counted for a stake pool on governance actions that it has not voted on. It's
+// determined by the DRep delegation of the pool reward account
+type StakePoolDefaultVote uint8
+
+const (
+	StakePoolDefaultVoteNo           StakePoolDefaultVote = 0
+	StakePoolDefaultVoteAbstain      StakePoolDefaultVote = 1
+	StakePoolDefaultVoteNoConfidence StakePoolDefaultVote = 2
+)
@@ -17,6 +17,7 @@ package localstatequery
 import (
 	"fmt"
 
+	"github.com/blinklabs-io/gouroboros/cbor"
 	"github.com/blinklabs-io/gouroboros/protocol"
 )
 
@@ -108,16 +109,36 @@ func (s *Server) handleAcquire(msg protocol.Message) error {
 }
 
 func (s *Server) handleQuery(msg protocol.Message) error {
+	msgQuery := msg.(*MsgQuery)
+	if s.config.QueryHandler != nil {
+		return s.handleTypedQuery(msgQuery)
+	}
 	if s.config.QueryFunc == nil {
 		return fmt.Errorf(
 			"received local-state-query Query message but no callback function is defined",
 		)
 	}
-	msgQuery := msg.(*MsgQuery)
 	// Call the user callback function
 	return s.config.QueryFunc(s.callbackContext, msgQuery.Query)
 }
 
+func (s *Server) handleTypedQuery(msgQuery *MsgQuery) error {
+	// Re-encode the generically decoded query so that it can be decoded into the typed query
+	queryCbor, err := cbor.Encode(msgQuery.Query)
+	if err != nil {
+		return fmt.Errorf("%s: failed to encode query: %w", ProtocolName, err)
+	}
+	query, err := DecodeQuery(queryCbor)
+	if err != nil {
+		return fmt.Errorf("%s: failed to decode query: %w", ProtocolName, err)
+	}
+	resultCbor, err := dispatchQuery(s.callbackContext, s.config.QueryHandler, query)
+	if err != nil {
+		return fmt.Errorf("%s: failed to handle query: %w", ProtocolName, err)
+	}
+	return s.SendMessage(NewMsgResult(resultCbor))
+}
+
 func (s *Server) handleRelease() error {
 	if s.config.ReleaseFunc == nil {
 		return fmt.Errorf(
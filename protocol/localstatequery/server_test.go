@@ -0,0 +1,609 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package localstatequery_test
+
+import (
+	"math/big"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blinklabs-io/gouroboros"
+	"github.com/blinklabs-io/gouroboros/cbor"
+	"github.com/blinklabs-io/gouroboros/internal/test"
+	"github.com/blinklabs-io/gouroboros/ledger"
+	lcommon "github.com/blinklabs-io/gouroboros/ledger/common"
+	ocommon "github.com/blinklabs-io/gouroboros/protocol/common"
+	"github.com/blinklabs-io/gouroboros/protocol/localstatequery"
+	"go.uber.org/goleak"
+)
+
+// testQueryHandler answers a subset of queries from static data and records the queries that it receives
+type testQueryHandler struct {
+	localstatequery.UnimplementedQueryHandler
+	queries  []localstatequery.Query
+	mutex    sync.Mutex
+	acquires int
+	releases int
+}
+
+func (h *testQueryHandler) acquireCount() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.acquires
+}
+
+func (h *testQueryHandler) releaseCount() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.releases
+}
+
+func (h *testQueryHandler) HandleSystemStart(
+	ctx localstatequery.CallbackContext,
+	q *localstatequery.SystemStartQuery,
+) (*localstatequery.SystemStartResult, error) {
+	h.queries = append(h.queries, q)
+	return &localstatequery.SystemStartResult{
+		Year:        2022,
+		Day:         150,
+		Picoseconds: 0,
+	}, nil
+}
+
+func (h *testQueryHandler) HandleChainBlockNo(
+	ctx localstatequery.CallbackContext,
+	q *localstatequery.ChainBlockNoQuery,
+) (int64, error) {
+	h.queries = append(h.queries, q)
+	return 123456, nil
+}
+
+func (h *testQueryHandler) HandleCurrentEra(
+	ctx localstatequery.CallbackContext,
+	q *localstatequery.CurrentEraQuery,
+) (int, error) {
+	h.queries = append(h.queries, q)
+	return ledger.EraIdConway, nil
+}
+
+func (h *testQueryHandler) HandleEpochNo(
+	ctx localstatequery.CallbackContext,
+	q *localstatequery.EpochNoQuery,
+) (int, error) {
+	h.queries = append(h.queries, q)
+	// The epoch advances with each acquire
+	return 500 + h.acquireCount(), nil
+}
+
+func (h *testQueryHandler) HandlePoolDistr(
+	ctx localstatequery.CallbackContext,
+	q *localstatequery.PoolDistrQuery,
+) (*localstatequery.PoolDistrResult, error) {
+	h.queries = append(h.queries, q)
+	ret := &localstatequery.PoolDistrResult{
+		Results:          map[ledger.PoolId]localstatequery.IndividualPoolStake{},
+		TotalActiveStake: 1000,
+	}
+	for _, poolId := range q.PoolIds {
+		ret.Results[poolId] = localstatequery.IndividualPoolStake{
+			StakeFraction: &cbor.Rat{Rat: big.NewRat(1, 2)},
+			TotalStake:    500,
+		}
+	}
+	return ret, nil
+}
+
+func (h *testQueryHandler) HandleDRepState(
+	ctx localstatequery.CallbackContext,
+	q *localstatequery.DRepStateQuery,
+) (*localstatequery.DRepStateResult, error) {
+	h.queries = append(h.queries, q)
+	ret := &localstatequery.DRepStateResult{
+		Results: map[*lcommon.StakeCredential]localstatequery.DRepState{},
+	}
+	for idx := range q.Creds {
+		ret.Results[&q.Creds[idx]] = localstatequery.DRepState{
+			Expiry:  600,
+			Deposit: 500000000,
+		}
+	}
+	return ret, nil
+}
+
+func (h *testQueryHandler) HandleCommitteeMembersState(
+	ctx localstatequery.CallbackContext,
+	q *localstatequery.CommitteeMembersStateQuery,
+) (*localstatequery.CommitteeMembersStateResult, error) {
+	h.queries = append(h.queries, q)
+	expiration := uint64(700)
+	return &localstatequery.CommitteeMembersStateResult{
+		Members: map[*lcommon.StakeCredential]localstatequery.CommitteeMemberState{
+			&q.ColdCreds[0]: {
+				HotCredAuthStatus: localstatequery.CommitteeHotCredAuthStatus{
+					Type:          localstatequery.CommitteeHotCredAuthStatusAuthorized,
+					HotCredential: &q.HotCreds[0],
+				},
+				Status:     localstatequery.CommitteeMemberStatusActive,
+				Expiration: &expiration,
+				NextEpochChange: localstatequery.CommitteeNextEpochChange{
+					Type:  localstatequery.CommitteeNextEpochChangeTermAdjusted,
+					Epoch: 701,
+				},
+			},
+		},
+		Epoch: 500,
+	}, nil
+}
+
+func (h *testQueryHandler) HandleBigLedgerPeerSnapshot(
+	ctx localstatequery.CallbackContext,
+	q *localstatequery.BigLedgerPeerSnapshotQuery,
+) (*localstatequery.BigLedgerPeerSnapshotResult, error) {
+	h.queries = append(h.queries, q)
+	slot := uint64(123456)
+	return &localstatequery.BigLedgerPeerSnapshotResult{
+		Slot: &slot,
+	}, nil
+}
+
+func (h *testQueryHandler) HandleStakePoolDefaultVote(
+	ctx localstatequery.CallbackContext,
+	q *localstatequery.StakePoolDefaultVoteQuery,
+) (localstatequery.StakePoolDefaultVote, error) {
+	h.queries = append(h.queries, q)
+	return localstatequery.StakePoolDefaultVoteAbstain, nil
+}
+
+func newTestServerConns(
+	t *testing.T,
+	handler *testQueryHandler,
+) (*ouroboros.Connection, *ouroboros.Connection) {
+	acquireFunc := func(ctx localstatequery.CallbackContext, point *ocommon.Point) error {
+		// Only the tip can be acquired
+		if point != nil {
+			return ctx.Server.SendMessage(
+				localstatequery.NewMsgFailure(localstatequery.AcquireFailurePointNotOnChain),
+			)
+		}
+		handler.mutex.Lock()
+		handler.acquires++
+		handler.mutex.Unlock()
+		return ctx.Server.SendMessage(localstatequery.NewMsgAcquired())
+	}
+	serverCfg := localstatequery.NewConfig(
+		localstatequery.WithQueryHandler(handler),
+		localstatequery.WithAcquireFunc(acquireFunc),
+		localstatequery.WithReAcquireFunc(acquireFunc),
+		localstatequery.WithReleaseFunc(
+			func(ctx localstatequery.CallbackContext) error {
+				handler.mutex.Lock()
+				handler.releases++
+				handler.mutex.Unlock()
+				return nil
+			},
+		),
+	)
+	clientConn, serverConn := net.Pipe()
+	serverConnChan := make(chan *ouroboros.Connection, 1)
+	go func() {
+		oConn, err := ouroboros.New(
+			ouroboros.WithConnection(serverConn),
+			ouroboros.WithNetworkMagic(ouroboros.NetworkPreview.NetworkMagic),
+			ouroboros.WithServer(true),
+			ouroboros.WithLocalStateQueryConfig(serverCfg),
+		)
+		if err != nil {
+			panic(err)
+		}
+		serverConnChan <- oConn
+	}()
+	oConn, err := ouroboros.New(
+		ouroboros.WithConnection(clientConn),
+		ouroboros.WithNetworkMagic(ouroboros.NetworkPreview.NetworkMagic),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error when creating Ouroboros object: %s", err)
+	}
+	return oConn, <-serverConnChan
+}
+
+func closeTestConns(t *testing.T, conns ...*ouroboros.Connection) {
+	for _, oConn := range conns {
+		if err := oConn.Close(); err != nil {
+			t.Fatalf("unexpected error when closing Ouroboros object: %s", err)
+		}
+		select {
+		case <-oConn.ErrorChan():
+		case <-time.After(10 * time.Second):
+			t.Errorf("did not shutdown within timeout")
+		}
+	}
+}
+
+func (h *testQueryHandler) HandleStakeDelegDeposits(
+	ctx localstatequery.CallbackContext,
+	q *localstatequery.StakeDelegDepositsQuery,
+) (*localstatequery.StakeDelegDepositsResult, error) {
+	h.queries = append(h.queries, q)
+	ret := &localstatequery.StakeDelegDepositsResult{
+		Results: map[*lcommon.StakeCredential]uint64{},
+	}
+	for idx := range q.Creds {
+		ret.Results[&q.Creds[idx]] = 2000000
+	}
+	return ret, nil
+}
+
+func (h *testQueryHandler) HandleSPOStakeDistr(
+	ctx localstatequery.CallbackContext,
+	q *localstatequery.SPOStakeDistrQuery,
+) (*localstatequery.SPOStakeDistrResult, error) {
+	h.queries = append(h.queries, q)
+	ret := &localstatequery.SPOStakeDistrResult{
+		Results: map[ledger.PoolId]uint64{},
+	}
+	for _, poolId := range q.PoolIds {
+		ret.Results[poolId] = 750
+	}
+	return ret, nil
+}
+
+func TestServerQueryHandler(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	handler := &testQueryHandler{}
+	clientConn, serverConn := newTestServerConns(t, handler)
+	defer closeTestConns(t, clientConn, serverConn)
+	client := clientConn.LocalStateQuery().Client
+	// Queries without params
+	systemStart, err := client.GetSystemStart()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if systemStart.Year != 2022 || systemStart.Day != 150 {
+		t.Fatalf("did not receive expected system start: %#v", systemStart)
+	}
+	blockNo, err := client.GetChainBlockNo()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if blockNo != 123456 {
+		t.Fatalf("did not receive expected block number: got %d, expected %d", blockNo, 123456)
+	}
+	epochNo, err := client.GetEpochNo()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if epochNo != 501 {
+		t.Fatalf("did not receive expected epoch: got %d, expected %d", epochNo, 501)
+	}
+	// Queries with params
+	poolId := ledger.PoolId{0x01, 0x02}
+	poolDistr, err := client.GetPoolDistr([]ledger.PoolId{poolId})
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if poolDistr.TotalActiveStake != 1000 || len(poolDistr.Results) != 1 || poolDistr.Results[poolId].TotalStake != 500 {
+		t.Fatalf("did not receive expected pool distribution: %#v", poolDistr)
+	}
+	drepCred := lcommon.StakeCredential{
+		CredType:   lcommon.StakeCredentialTypeAddrKeyHash,
+		Credential: lcommon.Blake2b224{0xab}.Bytes(),
+	}
+	drepState, err := client.GetDRepState([]lcommon.StakeCredential{drepCred})
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if len(drepState.Results) != 1 {
+		t.Fatalf("did not receive expected DRep state: %#v", drepState)
+	}
+	for cred, state := range drepState.Results {
+		if cred.String() != drepCred.String() || state.Expiry != 600 || state.Anchor != nil {
+			t.Fatalf("did not receive expected DRep state: %s: %#v", cred.String(), state)
+		}
+	}
+	coldCred := lcommon.StakeCredential{
+		CredType:   lcommon.StakeCredentialTypeScriptHash,
+		Credential: lcommon.Blake2b224{0xcd}.Bytes(),
+	}
+	membersState, err := client.GetCommitteeMembersState(
+		[]lcommon.StakeCredential{coldCred},
+		[]lcommon.StakeCredential{drepCred},
+		[]uint{localstatequery.CommitteeMemberStatusActive},
+	)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if membersState.Epoch != 500 || membersState.Threshold != nil || len(membersState.Members) != 1 {
+		t.Fatalf("did not receive expected committee members state: %#v", membersState)
+	}
+	for _, memberState := range membersState.Members {
+		if memberState.Expiration == nil || *memberState.Expiration != 700 ||
+			memberState.NextEpochChange.Epoch != 701 ||
+			memberState.HotCredAuthStatus.HotCredential.String() != drepCred.String() {
+			t.Fatalf("did not receive expected committee member state: %#v", memberState)
+		}
+	}
+	// Check the decoded queries
+	expectedQueries := []localstatequery.Query{
+		&localstatequery.SystemStartQuery{},
+		&localstatequery.ChainBlockNoQuery{},
+		&localstatequery.CurrentEraQuery{},
+		&localstatequery.EpochNoQuery{
+			ShelleyQuery: localstatequery.ShelleyQuery{Era: ledger.EraIdConway},
+		},
+		&localstatequery.PoolDistrQuery{
+			ShelleyQuery: localstatequery.ShelleyQuery{Era: ledger.EraIdConway},
+			PoolIds:      []ledger.PoolId{poolId},
+		},
+		&localstatequery.DRepStateQuery{
+			ShelleyQuery: localstatequery.ShelleyQuery{Era: ledger.EraIdConway},
+			Creds:        []lcommon.StakeCredential{drepCred},
+		},
+		&localstatequery.CommitteeMembersStateQuery{
+			ShelleyQuery: localstatequery.ShelleyQuery{Era: ledger.EraIdConway},
+			ColdCreds:    []lcommon.StakeCredential{coldCred},
+			HotCreds:     []lcommon.StakeCredential{drepCred},
+			Statuses:     []uint{localstatequery.CommitteeMemberStatusActive},
+		},
+	}
+	if !reflect.DeepEqual(handler.queries, expectedQueries) {
+		t.Fatalf("did not receive expected queries:\n  got:    %#v\n  wanted: %#v", handler.queries, expectedQueries)
+	}
+}
+
+func TestServerVersionDependentQueries(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	handler := &testQueryHandler{}
+	clientConn, serverConn := newTestServerConns(t, handler)
+	defer closeTestConns(t, clientConn, serverConn)
+	client := clientConn.LocalStateQuery().Client
+	stakeCred := lcommon.StakeCredential{
+		CredType:   lcommon.StakeCredentialTypeAddrKeyHash,
+		Credential: lcommon.Blake2b224{0xef}.Bytes(),
+	}
+	deposits, err := client.GetStakeDelegDeposits([]lcommon.StakeCredential{stakeCred})
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if len(deposits.Results) != 1 {
+		t.Fatalf("did not receive expected stake deleg deposits: %#v", deposits)
+	}
+	for cred, deposit := range deposits.Results {
+		if cred.String() != stakeCred.String() || deposit != 2000000 {
+			t.Fatalf("did not receive expected stake deleg deposit: %s: %d", cred.String(), deposit)
+		}
+	}
+	poolId := ledger.PoolId{0x03, 0x04}
+	spoStakeDistr, err := client.GetSPOStakeDistr([]ledger.PoolId{poolId})
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if len(spoStakeDistr.Results) != 1 || spoStakeDistr.Results[poolId] != 750 {
+		t.Fatalf("did not receive expected SPO stake distribution: %#v", spoStakeDistr)
+	}
+	snapshot, err := client.GetBigLedgerPeerSnapshot()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if snapshot.Slot == nil || *snapshot.Slot != 123456 || len(snapshot.Pools) != 0 {
+		t.Fatalf("did not receive expected big ledger peer snapshot: %#v", snapshot)
+	}
+	defaultVote, err := client.GetStakePoolDefaultVote(poolId)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if defaultVote != localstatequery.StakePoolDefaultVoteAbstain {
+		t.Fatalf("did not receive expected stake pool default vote: %d", defaultVote)
+	}
+	// Check the decoded queries
+	expectedQueries := []localstatequery.Query{
+		&localstatequery.CurrentEraQuery{},
+		&localstatequery.StakeDelegDepositsQuery{
+			ShelleyQuery: localstatequery.ShelleyQuery{Era: ledger.EraIdConway},
+			Creds:        []lcommon.StakeCredential{stakeCred},
+		},
+		&localstatequery.SPOStakeDistrQuery{
+			ShelleyQuery: localstatequery.ShelleyQuery{Era: ledger.EraIdConway},
+			PoolIds:      []ledger.PoolId{poolId},
+		},
+		&localstatequery.BigLedgerPeerSnapshotQuery{
+			ShelleyQuery: localstatequery.ShelleyQuery{Era: ledger.EraIdConway},
+		},
+		&localstatequery.StakePoolDefaultVoteQuery{
+			ShelleyQuery: localstatequery.ShelleyQuery{Era: ledger.EraIdConway},
+			PoolId:       poolId,
+		},
+	}
+	if !reflect.DeepEqual(handler.queries, expectedQueries) {
+		t.Fatalf("did not receive expected queries:\n  got:    %#v\n  wanted: %#v", handler.queries, expectedQueries)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	handler := &testQueryHandler{}
+	clientConn, serverConn := newTestServerConns(t, handler)
+	defer closeTestConns(t, clientConn, serverConn)
+	client := clientConn.LocalStateQuery().Client
+	snapshot, err := client.Acquire(nil)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	// Multiple queries run against the same acquired state
+	for i := 0; i < 3; i++ {
+		epochNo, err := snapshot.GetEpochNo()
+		if err != nil {
+			t.Fatalf("received unexpected error: %s", err)
+		}
+		if epochNo != 501 {
+			t.Fatalf("did not receive expected epoch: got %d, expected %d", epochNo, 501)
+		}
+	}
+	if acquires := handler.acquireCount(); acquires != 1 {
+		t.Fatalf("did not get expected acquire count: got %d, expected %d", acquires, 1)
+	}
+	// Move the snapshot forward
+	if err := snapshot.ReAcquire(nil); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	epochNo, err := snapshot.GetEpochNo()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if epochNo != 502 {
+		t.Fatalf("did not receive expected epoch: got %d, expected %d", epochNo, 502)
+	}
+	// The current era is queried once per acquire
+	eraQueries := 0
+	for _, query := range handler.queries {
+		if _, ok := query.(*localstatequery.CurrentEraQuery); ok {
+			eraQueries++
+		}
+	}
+	if eraQueries != 2 {
+		t.Fatalf("did not get expected current era query count: got %d, expected %d", eraQueries, 2)
+	}
+	// Close the snapshot
+	if err := snapshot.Close(); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err := snapshot.Close(); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if _, err := snapshot.GetEpochNo(); err != localstatequery.SnapshotClosedError {
+		t.Fatalf("did not receive expected error, got: %v", err)
+	}
+	// The client is usable again after the snapshot is closed, and the next query acquires a new state
+	epochNo, err = client.GetEpochNo()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if epochNo != 503 {
+		t.Fatalf("did not receive expected epoch: got %d, expected %d", epochNo, 503)
+	}
+	if releases := handler.releaseCount(); releases != 1 {
+		t.Fatalf("did not get expected release count: got %d, expected %d", releases, 1)
+	}
+}
+
+func TestDecodeQuery(t *testing.T) {
+	testDefs := []struct {
+		queryCbor     []byte
+		expectedQuery localstatequery.Query
+	}{
+		{
+			// [0, [2, [0]]]
+			queryCbor:     test.DecodeHexString("820082028100"),
+			expectedQuery: &localstatequery.EraHistoryQuery{},
+		},
+		{
+			// [0, [0, [6, [19, []]]]]
+			queryCbor: test.DecodeHexString("820082008206821380"),
+			expectedQuery: &localstatequery.PoolStateQuery{
+				ShelleyQuery: localstatequery.ShelleyQuery{Era: 6},
+			},
+		},
+		{
+			// [0, [0, [6, [20, [258([])]]]]]
+			queryCbor: test.DecodeHexString("820082008206821481d9010280"),
+			expectedQuery: &localstatequery.StakeSnapshotsQuery{
+				ShelleyQuery: localstatequery.ShelleyQuery{Era: 6},
+				PoolIds:      []ledger.PoolId{},
+			},
+		},
+		{
+			// [0, [0, [6, [34]]]]
+			queryCbor: test.DecodeHexString("820082008206811822"),
+			expectedQuery: &localstatequery.BigLedgerPeerSnapshotQuery{
+				ShelleyQuery: localstatequery.ShelleyQuery{Era: 6},
+			},
+		},
+		{
+			// [0, [0, [6, [35, h'0102...']]]]
+			queryCbor: test.DecodeHexString("820082008206821823581c0102" + strings.Repeat("00", 26)),
+			expectedQuery: &localstatequery.StakePoolDefaultVoteQuery{
+				ShelleyQuery: localstatequery.ShelleyQuery{Era: 6},
+				PoolId:       ledger.PoolId{0x01, 0x02},
+			},
+		},
+	}
+	for _, testDef := range testDefs {
+		query, err := localstatequery.DecodeQuery(testDef.queryCbor)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(query, testDef.expectedQuery) {
+			t.Fatalf("did not get expected query:\n  got:    %#v\n  wanted: %#v", query, testDef.expectedQuery)
+		}
+	}
+	// [0, [0, [6, [99]]]]
+	if _, err := localstatequery.DecodeQuery(test.DecodeHexString("820082008206811863")); err == nil {
+		t.Fatalf("did not get expected error for unknown query")
+	}
+}
+
+func TestSnapshotReAcquireFailure(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	handler := &testQueryHandler{}
+	clientConn, serverConn := newTestServerConns(t, handler)
+	defer closeTestConns(t, clientConn, serverConn)
+	client := clientConn.LocalStateQuery().Client
+	snapshot, err := client.Acquire(nil)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	point := ocommon.NewPoint(123, []byte{0xab})
+	err = snapshot.ReAcquire(&point)
+	if _, ok := err.(localstatequery.AcquireFailurePointNotOnChainError); !ok {
+		t.Fatalf("did not receive expected error, got: %v", err)
+	}
+	// Queries on the snapshot don't silently acquire the current tip
+	if _, err := snapshot.GetEpochNo(); err != localstatequery.SnapshotNotAcquiredError {
+		t.Fatalf("did not receive expected error, got: %v", err)
+	}
+	if acquires := handler.acquireCount(); acquires != 1 {
+		t.Fatalf("did not get expected acquire count: got %d, expected %d", acquires, 1)
+	}
+	// A successful reacquire makes the snapshot usable again
+	if err := snapshot.ReAcquire(nil); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if _, err := snapshot.GetEpochNo(); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	err = snapshot.ReAcquire(&point)
+	if _, ok := err.(localstatequery.AcquireFailurePointNotOnChainError); !ok {
+		t.Fatalf("did not receive expected error, got: %v", err)
+	}
+	// There's no acquired state left to release after the failure
+	if err := snapshot.Close(); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if releases := handler.releaseCount(); releases != 0 {
+		t.Fatalf("did not get expected release count: got %d, expected %d", releases, 0)
+	}
+	// The client can acquire a new state
+	epochNo, err := client.GetEpochNo()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if epochNo != 503 {
+		t.Fatalf("did not receive expected epoch: got %d, expected %d", epochNo, 503)
+	}
+}
@@ -0,0 +1,339 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package localstatequery
+
+import (
+	"sync"
+
+	"github.com/blinklabs-io/gouroboros/ledger"
+	lcommon "github.com/blinklabs-io/gouroboros/ledger/common"
+	"github.com/blinklabs-io/gouroboros/protocol/common"
+)
+
+// Snapshot runs queries against a single acquired ledger state. It's obtained from Client.Acquire and holds
+// exclusive use of the client until it's closed
+type Snapshot struct {
+	client *Client
+	mutex  sync.Mutex
+	closed bool
+	// notAcquired is set when a ReAcquire fails, which leaves no ledger state acquired
+	notAcquired bool
+}
+
+// ReAcquire moves the snapshot to the ledger state at the specified chain point, or at the current tip if point
+// is nil. If it fails, queries return SnapshotNotAcquiredError until the snapshot is successfully reacquired
+func (s *Snapshot) ReAcquire(point *common.Point) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return SnapshotClosedError
+	}
+	if err := s.client.acquire(point); err != nil {
+		s.notAcquired = true
+		return err
+	}
+	s.notAcquired = false
+	return nil
+}
+
+// Close releases the acquired ledger state and returns exclusive use of the client. The client is made
+// available again even if the release fails. Calling Close more than once has no effect
+func (s *Snapshot) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	defer s.client.busyMutex.Unlock()
+	return s.client.release()
+}
+
+// runSnapshotQuery runs the provided query function unless the snapshot has been closed or has lost its acquired
+// ledger state. Queries must never acquire a new ledger state on their own, since that would silently answer
+// them from a different ledger state
+func runSnapshotQuery[T any](s *Snapshot, queryFunc func() (T, error)) (T, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var ret T
+	if s.closed {
+		return ret, SnapshotClosedError
+	}
+	if s.notAcquired || !s.client.acquired {
+		return ret, SnapshotNotAcquiredError
+	}
+	return queryFunc()
+}
+
+// GetCurrentEra returns the current era ID
+func (s *Snapshot) GetCurrentEra() (int, error) {
+	return runSnapshotQuery(s, s.client.getCurrentEra)
+}
+
+// GetSystemStart returns the SystemStart value
+func (s *Snapshot) GetSystemStart() (*SystemStartResult, error) {
+	return runSnapshotQuery(s, s.client.getSystemStart)
+}
+
+// GetChainBlockNo returns the latest block number
+func (s *Snapshot) GetChainBlockNo() (int64, error) {
+	return runSnapshotQuery(s, s.client.getChainBlockNo)
+}
+
+// GetChainPoint returns the current chain tip
+func (s *Snapshot) GetChainPoint() (*common.Point, error) {
+	return runSnapshotQuery(s, s.client.getChainPoint)
+}
+
+// GetEraHistory returns the era history
+func (s *Snapshot) GetEraHistory() ([]EraHistoryResult, error) {
+	return runSnapshotQuery(s, s.client.getEraHistory)
+}
+
+// GetEpochNo returns the current epoch number
+func (s *Snapshot) GetEpochNo() (int, error) {
+	return runSnapshotQuery(s, s.client.getEpochNo)
+}
+
+// GetNonMyopicMemberRewards returns the rewards that each of the specified stake amounts or stake credentials
+// would receive from each pool
+func (s *Snapshot) GetNonMyopicMemberRewards(
+	targets []NonMyopicMemberRewardsTarget,
+) (*NonMyopicMemberRewardsResult, error) {
+	return runSnapshotQuery(s, func() (*NonMyopicMemberRewardsResult, error) {
+		return s.client.getNonMyopicMemberRewards(targets)
+	})
+}
+
+// GetCurrentProtocolParams returns the set of protocol params that are currently in effect
+func (s *Snapshot) GetCurrentProtocolParams() (CurrentProtocolParamsResult, error) {
+	return runSnapshotQuery(s, s.client.getCurrentProtocolParams)
+}
+
+// GetProposedProtocolParamsUpdates returns the protocol parameter updates proposed by the genesis delegates
+func (s *Snapshot) GetProposedProtocolParamsUpdates() (*ProposedProtocolParamsUpdatesResult, error) {
+	return runSnapshotQuery(s, s.client.getProposedProtocolParamsUpdates)
+}
+
+// GetStakeDistribution returns the stake distribution
+func (s *Snapshot) GetStakeDistribution() (*StakeDistributionResult, error) {
+	return runSnapshotQuery(s, s.client.getStakeDistribution)
+}
+
+func (s *Snapshot) GetUTxOByAddress(
+	addrs []ledger.Address,
+) (*UTxOByAddressResult, error) {
+	return runSnapshotQuery(s, func() (*UTxOByAddressResult, error) {
+		return s.client.getUTxOByAddress(addrs)
+	})
+}
+
+func (s *Snapshot) GetUTxOWhole() (*UTxOWholeResult, error) {
+	return runSnapshotQuery(s, s.client.getUTxOWhole)
+}
+
+// ForEachUTxOWhole calls utxoFunc for each UTxO in the whole UTxO set as the query result is received. See
+// Client.ForEachUTxOWhole for details
+func (s *Snapshot) ForEachUTxOWhole(utxoFunc UTxOFunc) error {
+	_, err := runSnapshotQuery(s, func() (any, error) {
+		return nil, s.client.forEachUTxOWhole(utxoFunc)
+	})
+	return err
+}
+
+// ForEachUTxOByAddress calls utxoFunc for each UTxO at the specified addresses as the query result is received.
+// See Client.ForEachUTxOByAddress for details
+func (s *Snapshot) ForEachUTxOByAddress(
+	addrs []ledger.Address,
+	utxoFunc UTxOFunc,
+) error {
+	_, err := runSnapshotQuery(s, func() (any, error) {
+		return nil, s.client.forEachUTxOByAddress(addrs, utxoFunc)
+	})
+	return err
+}
+
+// DebugEpochState returns the ledger state for the current epoch
+func (s *Snapshot) DebugEpochState() (*DebugEpochStateResult, error) {
+	return runSnapshotQuery(s, s.client.debugEpochState)
+}
+
+// GetFilteredDelegationsAndRewardAccounts returns the pool delegations and reward account balances for the
+// specified stake credentials
+func (s *Snapshot) GetFilteredDelegationsAndRewardAccounts(
+	creds []lcommon.StakeCredential,
+) (*FilteredDelegationsAndRewardAccountsResult, error) {
+	return runSnapshotQuery(s, func() (*FilteredDelegationsAndRewardAccountsResult, error) {
+		return s.client.getFilteredDelegationsAndRewardAccounts(creds)
+	})
+}
+
+func (s *Snapshot) GetGenesisConfig() (*GenesisConfigResult, error) {
+	return runSnapshotQuery(s, s.client.getGenesisConfig)
+}
+
+// DebugNewEpochState returns the full ledger state, including the current epoch state and block production
+func (s *Snapshot) DebugNewEpochState() (*DebugNewEpochStateResult, error) {
+	return runSnapshotQuery(s, s.client.debugNewEpochState)
+}
+
+// DebugChainDepState returns the consensus state, which includes the epoch nonces and operational certificate counters
+func (s *Snapshot) DebugChainDepState() (*DebugChainDepStateResult, error) {
+	return runSnapshotQuery(s, s.client.debugChainDepState)
+}
+
+func (s *Snapshot) GetRewardProvenance() (*RewardProvenanceResult, error) {
+	return runSnapshotQuery(s, s.client.getRewardProvenance)
+}
+
+func (s *Snapshot) GetUTxOByTxIn(
+	txIns []ledger.TransactionInput,
+) (*UTxOByTxInResult, error) {
+	return runSnapshotQuery(s, func() (*UTxOByTxInResult, error) {
+		return s.client.getUTxOByTxIn(txIns)
+	})
+}
+
+func (s *Snapshot) GetStakePools() (*StakePoolsResult, error) {
+	return runSnapshotQuery(s, s.client.getStakePools)
+}
+
+func (s *Snapshot) GetStakePoolParams(
+	poolIds []ledger.PoolId,
+) (*StakePoolParamsResult, error) {
+	return runSnapshotQuery(s, func() (*StakePoolParamsResult, error) {
+		return s.client.getStakePoolParams(poolIds)
+	})
+}
+
+// GetRewardInfoPools returns the reward parameters and the per-pool information used to calculate rewards
+func (s *Snapshot) GetRewardInfoPools() (*RewardInfoPoolsResult, error) {
+	return runSnapshotQuery(s, s.client.getRewardInfoPools)
+}
+
+// GetPoolState returns the state of the specified pools. All pools are returned if poolIds is nil
+func (s *Snapshot) GetPoolState(
+	poolIds []ledger.PoolId,
+) (*PoolStateResult, error) {
+	return runSnapshotQuery(s, func() (*PoolStateResult, error) {
+		return s.client.getPoolState(poolIds)
+	})
+}
+
+// GetStakeSnapshots returns the mark, set, and go stake snapshots for the specified pools. All pools are returned
+// if poolIds is nil
+func (s *Snapshot) GetStakeSnapshots(
+	poolIds []ledger.PoolId,
+) (*StakeSnapshotsResult, error) {
+	return runSnapshotQuery(s, func() (*StakeSnapshotsResult, error) {
+		return s.client.getStakeSnapshots(poolIds)
+	})
+}
+
+// GetPoolDistr returns the stake distribution for the specified pools. All pools are returned if poolIds is nil
+func (s *Snapshot) GetPoolDistr(
+	poolIds []ledger.PoolId,
+) (*PoolDistrResult, error) {
+	return runSnapshotQuery(s, func() (*PoolDistrResult, error) {
+		return s.client.getPoolDistr(poolIds)
+	})
+}
+
+// GetConstitution returns the current constitution
+func (s *Snapshot) GetConstitution() (*ConstitutionResult, error) {
+	return runSnapshotQuery(s, s.client.getConstitution)
+}
+
+// GetGovState returns the governance state, which includes the active proposals and their votes
+func (s *Snapshot) GetGovState() (*GovStateResult, error) {
+	return runSnapshotQuery(s, s.client.getGovState)
+}
+
+// GetDRepState returns the state of the specified DReps. All DReps are returned if no credentials are specified
+func (s *Snapshot) GetDRepState(
+	drepCreds []lcommon.StakeCredential,
+) (*DRepStateResult, error) {
+	return runSnapshotQuery(s, func() (*DRepStateResult, error) {
+		return s.client.getDRepState(drepCreds)
+	})
+}
+
+// GetDRepStakeDistr returns the stake delegated to the specified DReps. All DReps are returned if none are specified
+func (s *Snapshot) GetDRepStakeDistr(
+	dreps []lcommon.Drep,
+) (*DRepStakeDistrResult, error) {
+	return runSnapshotQuery(s, func() (*DRepStakeDistrResult, error) {
+		return s.client.getDRepStakeDistr(dreps)
+	})
+}
+
+// GetCommitteeMembersState returns the state of the constitutional committee members matching the
+// specified cold credentials, hot credentials, and statuses. Empty filters match all members
+func (s *Snapshot) GetCommitteeMembersState(
+	coldCreds []lcommon.StakeCredential,
+	hotCreds []lcommon.StakeCredential,
+	statuses []uint,
+) (*CommitteeMembersStateResult, error) {
+	return runSnapshotQuery(s, func() (*CommitteeMembersStateResult, error) {
+		return s.client.getCommitteeMembersState(coldCreds, hotCreds, statuses)
+	})
+}
+
+// GetFilteredVoteDelegatees returns the DReps that the specified stake credentials delegate their vote to
+func (s *Snapshot) GetFilteredVoteDelegatees(
+	stakeCreds []lcommon.StakeCredential,
+) (*FilteredVoteDelegateesResult, error) {
+	return runSnapshotQuery(s, func() (*FilteredVoteDelegateesResult, error) {
+		return s.client.getFilteredVoteDelegatees(stakeCreds)
+	})
+}
+
+// GetAccountState returns the treasury and reserves balances
+func (s *Snapshot) GetAccountState() (*AccountStateResult, error) {
+	return runSnapshotQuery(s, s.client.getAccountState)
+}
+
+// GetStakeDelegDeposits returns the deposits paid when registering the specified stake credentials
+func (s *Snapshot) GetStakeDelegDeposits(
+	stakeCreds []lcommon.StakeCredential,
+) (*StakeDelegDepositsResult, error) {
+	return runSnapshotQuery(s, func() (*StakeDelegDepositsResult, error) {
+		return s.client.getStakeDelegDeposits(stakeCreds)
+	})
+}
+
+// GetSPOStakeDistr returns the total stake for the specified pools. All pools are returned if none are specified
+func (s *Snapshot) GetSPOStakeDistr(
+	poolIds []ledger.PoolId,
+) (*SPOStakeDistrResult, error) {
+	return runSnapshotQuery(s, func() (*SPOStakeDistrResult, error) {
+		return s.client.getSPOStakeDistr(poolIds)
+	})
+}
+
+// GetBigLedgerPeerSnapshot returns a snapshot of the big ledger peers
+func (s *Snapshot) GetBigLedgerPeerSnapshot() (*BigLedgerPeerSnapshotResult, error) {
+	return runSnapshotQuery(s, s.client.getBigLedgerPeerSnapshot)
+}
+
+// GetStakePoolDefaultVote returns the default vote of the specified pool on governance actions that it has not
+// voted on
+func (s *Snapshot) GetStakePoolDefaultVote(
+	poolId ledger.PoolId,
+) (StakePoolDefaultVote, error) {
+	return runSnapshotQuery(s, func() (StakePoolDefaultVote, error) {
+		return s.client.getStakePoolDefaultVote(poolId)
+	})
+}
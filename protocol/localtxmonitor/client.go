@@ -15,26 +15,34 @@
 package localtxmonitor
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 
 	"github.com/blinklabs-io/gouroboros/protocol"
 )
 
+// MeasuresNotSupportedError is returned by GetMeasures when the negotiated protocol version does not support it
+var MeasuresNotSupportedError = errors.New(
+	"GetMeasures is not supported by the negotiated protocol version",
+)
+
 // Client implements the LocalTxMonitor client
 type Client struct {
 	*protocol.Protocol
-	config             *Config
-	callbackContext    CallbackContext
-	busyMutex          sync.Mutex
-	acquired           bool
-	acquiredSlot       uint64
-	acquireResultChan  chan bool
-	hasTxResultChan    chan bool
-	nextTxResultChan   chan []byte
-	getSizesResultChan chan MsgReplyGetSizesResult
-	onceStart          sync.Once
-	onceStop           sync.Once
+	config                *Config
+	callbackContext       CallbackContext
+	busyMutex             sync.Mutex
+	acquired              bool
+	acquiredSlot          uint64
+	acquireResultChan     chan bool
+	hasTxResultChan       chan bool
+	nextTxResultChan      chan MsgReplyNextTxTransaction
+	getSizesResultChan    chan MsgReplyGetSizesResult
+	getMeasuresResultChan chan *MsgReplyGetMeasures
+	enableMeasures        bool
+	onceStart             sync.Once
+	onceStop              sync.Once
 }
 
 // NewClient returns a new LocalTxMonitor client object
@@ -44,11 +52,13 @@ func NewClient(protoOptions protocol.ProtocolOptions, cfg *Config) *Client {
 		cfg = &tmpCfg
 	}
 	c := &Client{
-		config:             cfg,
-		acquireResultChan:  make(chan bool),
-		hasTxResultChan:    make(chan bool),
-		nextTxResultChan:   make(chan []byte),
-		getSizesResultChan: make(chan MsgReplyGetSizesResult),
+		config:                cfg,
+		acquireResultChan:     make(chan bool),
+		hasTxResultChan:       make(chan bool),
+		nextTxResultChan:      make(chan MsgReplyNextTxTransaction),
+		getSizesResultChan:    make(chan MsgReplyGetSizesResult),
+		getMeasuresResultChan: make(chan *MsgReplyGetMeasures),
+		enableMeasures:        protocol.GetProtocolVersion(protoOptions.Version).EnableLocalTxMonitorMeasures,
 	}
 	c.callbackContext = CallbackContext{
 		Client:       c,
@@ -91,6 +101,7 @@ func (c *Client) Start() {
 			close(c.hasTxResultChan)
 			close(c.nextTxResultChan)
 			close(c.getSizesResultChan)
+			close(c.getMeasuresResultChan)
 		}()
 	})
 }
@@ -106,6 +117,8 @@ func (c *Client) messageHandler(msg protocol.Message) error {
 		err = c.handleReplyNextTx(msg)
 	case MessageTypeReplyGetSizes:
 		err = c.handleReplyGetSizes(msg)
+	case MessageTypeReplyGetMeasures:
+		err = c.handleReplyGetMeasures(msg)
 	default:
 		err = fmt.Errorf(
 			"%s: received unexpected message type %d",
@@ -188,22 +201,36 @@ func (c *Client) HasTx(txId []byte) (bool, error) {
 
 // NextTx returns the next transaction in the mempool snapshot
 func (c *Client) NextTx() ([]byte, error) {
+	_, tx, err := c.NextTxWithEra()
+	return tx, err
+}
+
+// NextTxWithEra returns the era ID and the next transaction in the mempool snapshot. The transaction will be nil
+// when there are no more transactions in the snapshot
+func (c *Client) NextTxWithEra() (uint8, []byte, error) {
 	c.busyMutex.Lock()
 	defer c.busyMutex.Unlock()
 	if !c.acquired {
 		if err := c.acquire(); err != nil {
-			return nil, err
+			return 0, nil, err
 		}
 	}
 	msg := NewMsgNextTx()
 	if err := c.SendMessage(msg); err != nil {
-		return nil, err
+		return 0, nil, err
 	}
 	tx, ok := <-c.nextTxResultChan
 	if !ok {
-		return nil, protocol.ProtocolShuttingDownError
+		return 0, nil, protocol.ProtocolShuttingDownError
 	}
-	return tx, nil
+	return tx.EraId, tx.Tx, nil
+}
+
+// AcquiredSlot returns the slot of the currently acquired mempool snapshot
+func (c *Client) AcquiredSlot() uint64 {
+	c.busyMutex.Lock()
+	defer c.busyMutex.Unlock()
+	return c.acquiredSlot
 }
 
 // GetSizes returns the capacity (in bytes), size (in bytes), and number of transactions in the mempool snapshot
@@ -226,6 +253,30 @@ func (c *Client) GetSizes() (uint32, uint32, uint32, error) {
 	return result.Capacity, result.Size, result.NumberOfTxs, nil
 }
 
+// GetMeasures returns the number of transactions and the size and capacity of each measure, such as transaction
+// bytes or execution units, in the mempool snapshot. It requires a protocol version that supports it
+func (c *Client) GetMeasures() (uint32, map[string]MempoolMeasure, error) {
+	if !c.enableMeasures {
+		return 0, nil, MeasuresNotSupportedError
+	}
+	c.busyMutex.Lock()
+	defer c.busyMutex.Unlock()
+	if !c.acquired {
+		if err := c.acquire(); err != nil {
+			return 0, nil, err
+		}
+	}
+	msg := NewMsgGetMeasures()
+	if err := c.SendMessage(msg); err != nil {
+		return 0, nil, err
+	}
+	result, ok := <-c.getMeasuresResultChan
+	if !ok {
+		return 0, nil, protocol.ProtocolShuttingDownError
+	}
+	return result.TxCount, result.Measures, nil
+}
+
 func (c *Client) handleAcquired(msg protocol.Message) error {
 	msgAcquired := msg.(*MsgAcquired)
 	c.acquired = true
@@ -242,7 +293,7 @@ func (c *Client) handleReplyHasTx(msg protocol.Message) error {
 
 func (c *Client) handleReplyNextTx(msg protocol.Message) error {
 	msgReplyNextTx := msg.(*MsgReplyNextTx)
-	c.nextTxResultChan <- msgReplyNextTx.Transaction.Tx
+	c.nextTxResultChan <- msgReplyNextTx.Transaction
 	return nil
 }
 
@@ -251,3 +302,9 @@ func (c *Client) handleReplyGetSizes(msg protocol.Message) error {
 	c.getSizesResultChan <- msgReplyGetSizes.Result
 	return nil
 }
+
+func (c *Client) handleReplyGetMeasures(msg protocol.Message) error {
+	msgReplyGetMeasures := msg.(*MsgReplyGetMeasures)
+	c.getMeasuresResultChan <- msgReplyGetMeasures
+	return nil
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/blinklabs-io/gouroboros/internal/test"
 	"github.com/blinklabs-io/gouroboros/ledger"
 	"github.com/blinklabs-io/gouroboros/protocol"
+	"github.com/blinklabs-io/gouroboros/protocol/handshake"
 	"github.com/blinklabs-io/gouroboros/protocol/localtxmonitor"
 
 	ouroboros_mock "github.com/blinklabs-io/ouroboros-mock"
@@ -297,3 +298,143 @@ func TestNextTx(t *testing.T) {
 		},
 	)
 }
+
+func TestNextTxWithEra(t *testing.T) {
+	expectedTxEra := ledger.TxTypeBabbage
+	expectedTx := test.DecodeHexString("abcdef0123456789")
+	var expectedSlot uint64 = 12345
+	conversation := append(
+		conversationHandshakeAcquire,
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  localtxmonitor.ProtocolId,
+			MessageType: localtxmonitor.MessageTypeNextTx,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: localtxmonitor.ProtocolId,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				localtxmonitor.NewMsgReplyNextTx(
+					uint8(expectedTxEra),
+					expectedTx,
+				),
+			},
+		},
+	)
+	runTest(
+		t,
+		conversation,
+		func(t *testing.T, oConn *ouroboros.Connection) {
+			txEra, tx, err := oConn.LocalTxMonitor().Client.NextTxWithEra()
+			if err != nil {
+				t.Fatalf("received unexpected error: %s", err)
+			}
+			if txEra != uint8(expectedTxEra) {
+				t.Fatalf(
+					"did not get expected TX era: got %d, wanted %d",
+					txEra,
+					expectedTxEra,
+				)
+			}
+			if !reflect.DeepEqual(tx, expectedTx) {
+				t.Fatalf(
+					"did not get expected TX content\n  got:    %x\n  wanted: %x",
+					tx,
+					expectedTx,
+				)
+			}
+			slot := oConn.LocalTxMonitor().Client.AcquiredSlot()
+			if slot != expectedSlot {
+				t.Fatalf(
+					"did not get expected acquired slot: got %d, wanted %d",
+					slot,
+					expectedSlot,
+				)
+			}
+		},
+	)
+}
+
+func TestGetMeasures(t *testing.T) {
+	var expectedTxCount uint32 = 5
+	expectedMeasures := map[string]localtxmonitor.MempoolMeasure{
+		"transaction_bytes": {Size: 12345, Capacity: 100000},
+	}
+	conversation := []ouroboros_mock.ConversationEntry{
+		ouroboros_mock.ConversationEntryHandshakeRequestGeneric,
+		// GetMeasures requires a newer protocol version than the default mock handshake response
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: handshake.ProtocolId,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				handshake.NewMsgAcceptVersion(
+					(20 + protocol.ProtocolVersionNtCOffset),
+					protocol.VersionDataNtC15andUp{
+						CborNetworkMagic: ouroboros_mock.MockNetworkMagic,
+					},
+				),
+			},
+		},
+	}
+	conversation = append(
+		conversation,
+		conversationHandshakeAcquire[2:]...,
+	)
+	conversation = append(
+		conversation,
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  localtxmonitor.ProtocolId,
+			MessageType: localtxmonitor.MessageTypeGetMeasures,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: localtxmonitor.ProtocolId,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				localtxmonitor.NewMsgReplyGetMeasures(
+					expectedTxCount,
+					expectedMeasures,
+				),
+			},
+		},
+	)
+	runTest(
+		t,
+		conversation,
+		func(t *testing.T, oConn *ouroboros.Connection) {
+			txCount, measures, err := oConn.LocalTxMonitor().Client.GetMeasures()
+			if err != nil {
+				t.Fatalf("received unexpected error: %s", err)
+			}
+			if txCount != expectedTxCount {
+				t.Fatalf(
+					"did not receive expected TX count result: got %d, wanted %d",
+					txCount,
+					expectedTxCount,
+				)
+			}
+			if !reflect.DeepEqual(measures, expectedMeasures) {
+				t.Fatalf(
+					"did not receive expected measures result\n  got:    %#v\n  wanted: %#v",
+					measures,
+					expectedMeasures,
+				)
+			}
+		},
+	)
+}
+
+func TestGetMeasuresUnsupported(t *testing.T) {
+	conversation := []ouroboros_mock.ConversationEntry{
+		ouroboros_mock.ConversationEntryHandshakeRequestGeneric,
+		ouroboros_mock.ConversationEntryHandshakeNtCResponse,
+	}
+	runTest(
+		t,
+		conversation,
+		func(t *testing.T, oConn *ouroboros.Connection) {
+			_, _, err := oConn.LocalTxMonitor().Client.GetMeasures()
+			if err != localtxmonitor.MeasuresNotSupportedError {
+				t.Fatalf("did not receive expected error: got %v, wanted %s", err, localtxmonitor.MeasuresNotSupportedError)
+			}
+		},
+	)
+}
@@ -84,6 +84,10 @@ var StateMap = protocol.StateMap{
 				MsgType:  MessageTypeGetSizes,
 				NewState: stateBusy,
 			},
+			{
+				MsgType:  MessageTypeGetMeasures,
+				NewState: stateBusy,
+			},
 		},
 	},
 	stateBusy: protocol.StateMapEntry{
@@ -101,6 +105,10 @@ var StateMap = protocol.StateMap{
 				MsgType:  MessageTypeReplyGetSizes,
 				NewState: stateAcquired,
 			},
+			{
+				MsgType:  MessageTypeReplyGetMeasures,
+				NewState: stateAcquired,
+			},
 		},
 	},
 	stateDone: protocol.StateMapEntry{
@@ -116,9 +124,10 @@ type LocalTxMonitor struct {
 
 // Config is used to configure the LocalTxMonitor protocol instance
 type Config struct {
-	GetMempoolFunc GetMempoolFunc
-	AcquireTimeout time.Duration
-	QueryTimeout   time.Duration
+	GetMempoolFunc  GetMempoolFunc
+	GetMeasuresFunc GetMeasuresFunc
+	AcquireTimeout  time.Duration
+	QueryTimeout    time.Duration
 }
 
 // Helper types
@@ -137,6 +146,7 @@ type CallbackContext struct {
 
 // Callback function types
 type GetMempoolFunc func(CallbackContext) (uint64, uint32, []TxAndEraId, error)
+type GetMeasuresFunc func(CallbackContext) (uint32, map[string]MempoolMeasure, error)
 
 // New returns a new LocalTxMonitor object
 func New(protoOptions protocol.ProtocolOptions, cfg *Config) *LocalTxMonitor {
@@ -172,6 +182,16 @@ func WithGetMempoolFunc(
 	}
 }
 
+// WithGetMeasuresFunc specifies the callback function for retrieving the mempool measures. This is only used
+// with protocol versions that support GetMeasures
+func WithGetMeasuresFunc(
+	getMeasuresFunc GetMeasuresFunc,
+) LocalTxMonitorOptionFunc {
+	return func(c *Config) {
+		c.GetMeasuresFunc = getMeasuresFunc
+	}
+}
+
 // WithAcquireTimeout specifies the timeout for acquire operations when acting as a client
 func WithAcquireTimeout(timeout time.Duration) LocalTxMonitorOptionFunc {
 	return func(c *Config) {
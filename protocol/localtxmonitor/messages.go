@@ -23,16 +23,18 @@ import (
 
 // Message types
 const (
-	MessageTypeDone          = 0
-	MessageTypeAcquire       = 1
-	MessageTypeAcquired      = 2
-	MessageTypeRelease       = 3
-	MessageTypeNextTx        = 5
-	MessageTypeReplyNextTx   = 6
-	MessageTypeHasTx         = 7
-	MessageTypeReplyHasTx    = 8
-	MessageTypeGetSizes      = 9
-	MessageTypeReplyGetSizes = 10
+	MessageTypeDone             = 0
+	MessageTypeAcquire          = 1
+	MessageTypeAcquired         = 2
+	MessageTypeRelease          = 3
+	MessageTypeNextTx           = 5
+	MessageTypeReplyNextTx      = 6
+	MessageTypeHasTx            = 7
+	MessageTypeReplyHasTx       = 8
+	MessageTypeGetSizes         = 9
+	MessageTypeReplyGetSizes    = 10
+	MessageTypeGetMeasures      = 11
+	MessageTypeReplyGetMeasures = 12
 )
 
 // NewMsgFromCbor parses a LocalTxMonitor message from CBOR
@@ -59,6 +61,10 @@ func NewMsgFromCbor(msgType uint, data []byte) (protocol.Message, error) {
 		ret = &MsgGetSizes{}
 	case MessageTypeReplyGetSizes:
 		ret = &MsgReplyGetSizes{}
+	case MessageTypeGetMeasures:
+		ret = &MsgGetMeasures{}
+	case MessageTypeReplyGetMeasures:
+		ret = &MsgReplyGetMeasures{}
 	}
 	if _, err := cbor.Decode(data, ret); err != nil {
 		return nil, fmt.Errorf("%s: decode error: %s", ProtocolName, err)
@@ -282,3 +288,45 @@ func NewMsgReplyGetSizes(
 	}
 	return m
 }
+
+type MsgGetMeasures struct {
+	protocol.MessageBase
+}
+
+func NewMsgGetMeasures() *MsgGetMeasures {
+	m := &MsgGetMeasures{
+		MessageBase: protocol.MessageBase{
+			MessageType: MessageTypeGetMeasures,
+		},
+	}
+	return m
+}
+
+type MsgReplyGetMeasures struct {
+	protocol.MessageBase
+	TxCount  uint32
+	Measures map[string]MempoolMeasure
+}
+
+// MempoolMeasure is the size and capacity of the mempool snapshot for a single measure, such as transaction bytes
+// or execution units
+type MempoolMeasure struct {
+	// Tells the CBOR decoder to convert to/from a struct and a CBOR array
+	_        struct{} `cbor:",toarray"`
+	Size     uint64
+	Capacity uint64
+}
+
+func NewMsgReplyGetMeasures(
+	txCount uint32,
+	measures map[string]MempoolMeasure,
+) *MsgReplyGetMeasures {
+	m := &MsgReplyGetMeasures{
+		MessageBase: protocol.MessageBase{
+			MessageType: MessageTypeReplyGetMeasures,
+		},
+		TxCount:  txCount,
+		Measures: measures,
+	}
+	return m
+}
@@ -86,6 +86,22 @@ var tests = []testDefinition{
 		MessageType: MessageTypeReplyGetSizes,
 		Message:     NewMsgReplyGetSizes(1234, 2345, 3456),
 	},
+	{
+		CborHex:     "810b",
+		MessageType: MessageTypeGetMeasures,
+		Message:     NewMsgGetMeasures(),
+	},
+	{
+		// [12, 5, {"transaction_bytes": [1234, 2345]}]
+		CborHex:     "830c05a1717472616e73616374696f6e5f6279746573821904d2190929",
+		MessageType: MessageTypeReplyGetMeasures,
+		Message: NewMsgReplyGetMeasures(
+			5,
+			map[string]MempoolMeasure{
+				"transaction_bytes": {Size: 1234, Capacity: 2345},
+			},
+		),
+	},
 }
 
 func TestDecode(t *testing.T) {
@@ -27,6 +27,7 @@ type Server struct {
 	*protocol.Protocol
 	config           *Config
 	callbackContext  CallbackContext
+	enableMeasures   bool
 	mempoolCapacity  uint32
 	mempoolTxs       []TxAndEraId
 	mempoolNextTxIdx int
@@ -35,7 +36,8 @@ type Server struct {
 // NewServer returns a new Server object
 func NewServer(protoOptions protocol.ProtocolOptions, cfg *Config) *Server {
 	s := &Server{
-		config: cfg,
+		config:         cfg,
+		enableMeasures: protocol.GetProtocolVersion(protoOptions.Version).EnableLocalTxMonitorMeasures,
 	}
 	s.callbackContext = CallbackContext{
 		Server:       s,
@@ -72,6 +74,8 @@ func (s *Server) messageHandler(msg protocol.Message) error {
 		err = s.handleNextTx()
 	case MessageTypeGetSizes:
 		err = s.handleGetSizes()
+	case MessageTypeGetMeasures:
+		err = s.handleGetMeasures()
 	default:
 		err = fmt.Errorf(
 			"%s: received unexpected message type %d",
@@ -180,3 +184,27 @@ func (s *Server) handleGetSizes() error {
 	}
 	return nil
 }
+
+func (s *Server) handleGetMeasures() error {
+	if !s.enableMeasures {
+		return fmt.Errorf(
+			"%s: received GetMeasures message, which is not supported by the negotiated protocol version",
+			ProtocolName,
+		)
+	}
+	if s.config.GetMeasuresFunc == nil {
+		return fmt.Errorf(
+			"received local-tx-monitor GetMeasures message but no GetMeasures callback function is defined",
+		)
+	}
+	// Call the user callback function to get mempool measures
+	txCount, measures, err := s.config.GetMeasuresFunc(s.callbackContext)
+	if err != nil {
+		return err
+	}
+	newMsg := NewMsgReplyGetMeasures(txCount, measures)
+	if err := s.SendMessage(newMsg); err != nil {
+		return err
+	}
+	return nil
+}
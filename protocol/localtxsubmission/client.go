@@ -15,6 +15,7 @@
 package localtxsubmission
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 
@@ -113,6 +114,127 @@ func (c *Client) SubmitTx(eraId uint16, tx []byte) error {
 	return err
 }
 
+// SubmitTxResult contains the result of submitting a transaction with SubmitTransaction or
+// SubmitTransactionCbor
+type SubmitTxResult struct {
+	// TxHash is the hex-encoded hash of the submitted transaction
+	TxHash string
+	// EraId is the era that the transaction was submitted for
+	EraId uint16
+	// Accepted is true if the transaction was accepted by the node
+	Accepted bool
+	// Rejection is the decoded reason for the transaction being rejected. It is a TransactionRejectedError
+	Rejection error
+}
+
+// SubmitTransaction submits a decoded transaction, using the era that it was decoded for. Rejections from the
+// node are reported in the returned result rather than as an error
+func (c *Client) SubmitTransaction(tx ledger.Transaction) (*SubmitTxResult, error) {
+	txCbor := tx.Cbor()
+	if len(txCbor) == 0 {
+		return nil, fmt.Errorf("%s: transaction has no CBOR", ProtocolName)
+	}
+	txType := uint(tx.Type())
+	// Make sure that the CBOR decodes cleanly for the transaction era
+	if _, err := ledger.NewTransactionFromCbor(txType, txCbor); err != nil {
+		return nil, fmt.Errorf(
+			"%s: failed to decode transaction for era %d: %w",
+			ProtocolName,
+			txType,
+			err,
+		)
+	}
+	return c.submitTransaction(uint16(txType), tx.Hash(), txCbor)
+}
+
+// SubmitTransactionCbor submits a transaction from its CBOR. If a CurrentEraFunc is configured, the transaction is
+// submitted for the node's current era. Otherwise, the era is guessed by decoding the transaction as the newest era
+// that it's valid for, which may not be the node's current era. Rejections from the node are reported in the
+// returned result rather than as an error
+func (c *Client) SubmitTransactionCbor(txCbor []byte) (*SubmitTxResult, error) {
+	if c.config != nil && c.config.CurrentEraFunc != nil {
+		eraId, err := c.config.CurrentEraFunc()
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%s: failed to get current era: %w",
+				ProtocolName,
+				err,
+			)
+		}
+		return c.SubmitTransactionCborWithEra(uint16(eraId), txCbor)
+	}
+	tx, err := decodeTransaction(txCbor)
+	if err != nil {
+		return nil, err
+	}
+	return c.submitTransaction(uint16(tx.Type()), tx.Hash(), txCbor)
+}
+
+// SubmitTransactionCborWithEra submits a transaction from its CBOR for the specified era ID. Rejections from the
+// node are reported in the returned result rather than as an error
+func (c *Client) SubmitTransactionCborWithEra(
+	eraId uint16,
+	txCbor []byte,
+) (*SubmitTxResult, error) {
+	// Byron transactions can't be submitted this way, since we don't fully support decoding them
+	if eraId < ledger.TxTypeShelley {
+		return nil, fmt.Errorf(
+			"%s: unsupported era for transaction submission: %d",
+			ProtocolName,
+			eraId,
+		)
+	}
+	tx, err := ledger.NewTransactionFromCbor(uint(eraId), txCbor)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"%s: failed to decode transaction for era %d: %w",
+			ProtocolName,
+			eraId,
+			err,
+		)
+	}
+	return c.submitTransaction(eraId, tx.Hash(), txCbor)
+}
+
+func (c *Client) submitTransaction(
+	eraId uint16,
+	txHash string,
+	txCbor []byte,
+) (*SubmitTxResult, error) {
+	result := &SubmitTxResult{
+		TxHash: txHash,
+		EraId:  eraId,
+	}
+	if err := c.SubmitTx(eraId, txCbor); err != nil {
+		var rejectErr TransactionRejectedError
+		if !errors.As(err, &rejectErr) {
+			return nil, err
+		}
+		result.Rejection = rejectErr
+		return result, nil
+	}
+	result.Accepted = true
+	return result, nil
+}
+
+// decodeTransaction decodes transaction CBOR as the latest era that it's valid for. This is only a fallback for when
+// the current era isn't known: a transaction without any era-specific features can decode as several eras, and the
+// node will only accept it for the current era
+func decodeTransaction(txCbor []byte) (ledger.Transaction, error) {
+	// Byron transactions can't be submitted this way, since we don't fully support decoding them
+	for txType := uint(ledger.TxTypeConway); txType >= ledger.TxTypeShelley; txType-- {
+		tx, err := ledger.NewTransactionFromCbor(txType, txCbor)
+		if err != nil {
+			continue
+		}
+		return tx, nil
+	}
+	return nil, fmt.Errorf(
+		"%s: failed to decode transaction as any known era",
+		ProtocolName,
+	)
+}
+
 // Stop transitions the protocol to the Done state. No more operations will be possible
 func (c *Client) Stop() error {
 	var err error
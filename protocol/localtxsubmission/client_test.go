@@ -15,11 +15,13 @@
 package localtxsubmission_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
 
 	ouroboros "github.com/blinklabs-io/gouroboros"
+	"github.com/blinklabs-io/gouroboros/cbor"
 	"github.com/blinklabs-io/gouroboros/internal/test"
 	"github.com/blinklabs-io/gouroboros/ledger"
 	"github.com/blinklabs-io/gouroboros/protocol"
@@ -43,6 +45,7 @@ func runTest(
 	t *testing.T,
 	conversation []ouroboros_mock.ConversationEntry,
 	innerFunc testInnerFunc,
+	options ...ouroboros.ConnectionOptionFunc,
 ) {
 	defer goleak.VerifyNone(t)
 	mockConn := ouroboros_mock.NewConnection(
@@ -59,8 +62,13 @@ func runTest(
 		close(asyncErrChan)
 	}()
 	oConn, err := ouroboros.New(
-		ouroboros.WithConnection(mockConn),
-		ouroboros.WithNetworkMagic(ouroboros_mock.MockNetworkMagic),
+		append(
+			[]ouroboros.ConnectionOptionFunc{
+				ouroboros.WithConnection(mockConn),
+				ouroboros.WithNetworkMagic(ouroboros_mock.MockNetworkMagic),
+			},
+			options...,
+		)...,
 	)
 	if err != nil {
 		t.Fatalf("unexpected error when creating Ouroboros object: %s", err)
@@ -162,3 +170,205 @@ func TestSubmitTxRject(t *testing.T) {
 		},
 	)
 }
+
+// testConwayTx builds a minimal Conway transaction
+func testConwayTx(t *testing.T) []byte {
+	txCbor, err := cbor.Encode(
+		[]any{
+			map[uint]any{
+				0: cbor.Set{[]any{make([]byte, 32), 0}},
+				1: []any{},
+				2: 1000,
+			},
+			map[uint]any{},
+			true,
+			nil,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return txCbor
+}
+
+func TestSubmitTransactionCbor(t *testing.T) {
+	txCbor := testConwayTx(t)
+	expectedTx, err := ledger.NewConwayTransactionFromCbor(txCbor)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testDefs := []struct {
+		name     string
+		response protocol.Message
+		accepted bool
+	}{
+		{
+			name:     "Accept",
+			response: localtxsubmission.NewMsgAcceptTx(),
+			accepted: true,
+		},
+		{
+			name: "Reject",
+			// [0, [1, ["foo"]]]
+			response: localtxsubmission.NewMsgRejectTx(
+				test.DecodeHexString("820082018163666f6f"),
+			),
+		},
+	}
+	for _, testDef := range testDefs {
+		conversation := append(
+			conversationHandshakeSubmitTx,
+			ouroboros_mock.ConversationEntryOutput{
+				ProtocolId: localtxsubmission.ProtocolId,
+				IsResponse: true,
+				Messages:   []protocol.Message{testDef.response},
+			},
+		)
+		runTest(
+			t,
+			conversation,
+			func(t *testing.T, oConn *ouroboros.Connection) {
+				result, err := oConn.LocalTxSubmission().Client.SubmitTransactionCbor(
+					txCbor,
+				)
+				if err != nil {
+					t.Fatalf("%s: received unexpected error: %s", testDef.name, err)
+				}
+				// Without a current era function, the transaction is detected as the latest era that it decodes as
+				if result.EraId != ledger.TxTypeConway {
+					t.Fatalf("%s: did not get expected era: got %d, wanted %d", testDef.name, result.EraId, ledger.TxTypeConway)
+				}
+				if result.TxHash != expectedTx.Hash() {
+					t.Fatalf("%s: did not get expected tx hash: got %s, wanted %s", testDef.name, result.TxHash, expectedTx.Hash())
+				}
+				if result.Accepted != testDef.accepted {
+					t.Fatalf("%s: did not get expected accepted value: %t", testDef.name, result.Accepted)
+				}
+				if !testDef.accepted {
+					var rejectErr localtxsubmission.TransactionRejectedError
+					if !errors.As(result.Rejection, &rejectErr) {
+						t.Fatalf("%s: did not get expected rejection: %v", testDef.name, result.Rejection)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestSubmitTransactionCborInvalid(t *testing.T) {
+	runTest(
+		t,
+		[]ouroboros_mock.ConversationEntry{
+			ouroboros_mock.ConversationEntryHandshakeRequestGeneric,
+			ouroboros_mock.ConversationEntryHandshakeNtCResponse,
+		},
+		func(t *testing.T, oConn *ouroboros.Connection) {
+			// Invalid transactions are not sent to the node
+			_, err := oConn.LocalTxSubmission().Client.SubmitTransactionCbor(
+				test.DecodeHexString("abcdef0123456789"),
+			)
+			if err == nil {
+				t.Fatalf("did not receive expected error")
+			}
+		},
+	)
+}
+
+func TestSubmitTransactionCborCurrentEra(t *testing.T) {
+	// The minimal test transaction is also valid for Babbage
+	txCbor := testConwayTx(t)
+	expectedTx, err := ledger.NewBabbageTransactionFromCbor(txCbor)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	conversation := append(
+		conversationHandshakeSubmitTx,
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: localtxsubmission.ProtocolId,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				localtxsubmission.NewMsgAcceptTx(),
+			},
+		},
+	)
+	runTest(
+		t,
+		conversation,
+		func(t *testing.T, oConn *ouroboros.Connection) {
+			result, err := oConn.LocalTxSubmission().Client.SubmitTransactionCbor(
+				txCbor,
+			)
+			if err != nil {
+				t.Fatalf("received unexpected error: %s", err)
+			}
+			// The transaction is submitted for the current era rather than the latest era that it decodes as
+			if result.EraId != ledger.TxTypeBabbage {
+				t.Fatalf("did not get expected era: got %d, wanted %d", result.EraId, ledger.TxTypeBabbage)
+			}
+			if result.TxHash != expectedTx.Hash() {
+				t.Fatalf("did not get expected tx hash: got %s, wanted %s", result.TxHash, expectedTx.Hash())
+			}
+			if !result.Accepted {
+				t.Fatalf("transaction was not accepted")
+			}
+		},
+		ouroboros.WithLocalTxSubmissionConfig(
+			localtxsubmission.NewConfig(
+				localtxsubmission.WithCurrentEraFunc(
+					func() (int, error) {
+						return ledger.EraIdBabbage, nil
+					},
+				),
+			),
+		),
+	)
+}
+
+func TestSubmitTransactionCborCurrentEraError(t *testing.T) {
+	runTest(
+		t,
+		[]ouroboros_mock.ConversationEntry{
+			ouroboros_mock.ConversationEntryHandshakeRequestGeneric,
+			ouroboros_mock.ConversationEntryHandshakeNtCResponse,
+		},
+		func(t *testing.T, oConn *ouroboros.Connection) {
+			// The transaction isn't sent if the current era can't be determined
+			_, err := oConn.LocalTxSubmission().Client.SubmitTransactionCbor(
+				testConwayTx(t),
+			)
+			if err == nil {
+				t.Fatalf("did not receive expected error")
+			}
+		},
+		ouroboros.WithLocalTxSubmissionConfig(
+			localtxsubmission.NewConfig(
+				localtxsubmission.WithCurrentEraFunc(
+					func() (int, error) {
+						return 0, errors.New("no current era")
+					},
+				),
+			),
+		),
+	)
+}
+
+func TestSubmitTransactionCborWithEraByron(t *testing.T) {
+	txCbor := testConwayTx(t)
+	runTest(
+		t,
+		[]ouroboros_mock.ConversationEntry{
+			ouroboros_mock.ConversationEntryHandshakeRequestGeneric,
+			ouroboros_mock.ConversationEntryHandshakeNtCResponse,
+		},
+		func(t *testing.T, oConn *ouroboros.Connection) {
+			// Byron transactions aren't supported, and nothing is sent to the node
+			_, err := oConn.LocalTxSubmission().Client.SubmitTransactionCborWithEra(
+				ledger.TxTypeByron,
+				txCbor,
+			)
+			if err == nil {
+				t.Fatalf("did not receive expected error")
+			}
+		},
+	)
+}
@@ -71,8 +71,9 @@ type LocalTxSubmission struct {
 
 // Config is used to configure the LocalTxSubmission protocol instance
 type Config struct {
-	SubmitTxFunc SubmitTxFunc
-	Timeout      time.Duration
+	SubmitTxFunc   SubmitTxFunc
+	CurrentEraFunc CurrentEraFunc
+	Timeout        time.Duration
 }
 
 // Callback context
@@ -85,6 +86,10 @@ type CallbackContext struct {
 // Callback function types
 type SubmitTxFunc func(CallbackContext, interface{}) error
 
+// CurrentEraFunc returns the era ID of the node's current era. It has the same signature as the
+// local-state-query client's GetCurrentEra function
+type CurrentEraFunc func() (int, error)
+
 // New returns a new LocalTxSubmission object
 func New(
 	protoOptions protocol.ProtocolOptions,
@@ -119,6 +124,15 @@ func WithSubmitTxFunc(submitTxFunc SubmitTxFunc) LocalTxSubmissionOptionFunc {
 	}
 }
 
+// WithCurrentEraFunc specifies the function used to determine the current era when submitting transaction CBOR
+// with SubmitTransactionCbor when acting as a client. This is typically a wrapper around the local-state-query
+// client's GetCurrentEra function
+func WithCurrentEraFunc(currentEraFunc CurrentEraFunc) LocalTxSubmissionOptionFunc {
+	return func(c *Config) {
+		c.CurrentEraFunc = currentEraFunc
+	}
+}
+
 // WithTimeout specifies the timeout for a TX submit operation when acting as a client
 func WithTimeout(timeout time.Duration) LocalTxSubmissionOptionFunc {
 	return func(c *Config) {
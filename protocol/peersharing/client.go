@@ -16,6 +16,7 @@ package peersharing
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/blinklabs-io/gouroboros/protocol"
 )
@@ -25,7 +26,9 @@ type Client struct {
 	*protocol.Protocol
 	config          *Config
 	callbackContext CallbackContext
+	busyMutex       sync.Mutex
 	sharePeersChan  chan []PeerAddress
+	onceStart       sync.Once
 }
 
 // NewClient returns a new PeerSharing client object
@@ -65,7 +68,21 @@ func NewClient(protoOptions protocol.ProtocolOptions, cfg *Config) *Client {
 	return c
 }
 
+func (c *Client) Start() {
+	c.onceStart.Do(func() {
+		c.Protocol.Start()
+		// Start goroutine to cleanup resources on protocol shutdown
+		go func() {
+			<-c.Protocol.DoneChan()
+			close(c.sharePeersChan)
+		}()
+	})
+}
+
+// GetPeers requests up to the specified number of peer addresses from the remote node
 func (c *Client) GetPeers(amount uint8) ([]PeerAddress, error) {
+	c.busyMutex.Lock()
+	defer c.busyMutex.Unlock()
 	msg := NewMsgShareRequest(amount)
 	if err := c.SendMessage(msg); err != nil {
 		return nil, err
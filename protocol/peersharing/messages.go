@@ -69,6 +69,8 @@ func NewMsgShareRequest(amount uint8) *MsgShareRequest {
 type MsgSharePeers struct {
 	protocol.MessageBase
 	PeerAddresses []PeerAddress
+	// Use the IPv6 address format from protocol versions 11 and 12
+	useV11 bool
 }
 
 func NewMsgSharePeers(peerAddresses []PeerAddress) *MsgSharePeers {
@@ -81,6 +83,18 @@ func NewMsgSharePeers(peerAddresses []PeerAddress) *MsgSharePeers {
 	return m
 }
 
+func (m *MsgSharePeers) MarshalCBOR() ([]byte, error) {
+	peerAddresses := make([]any, 0, len(m.PeerAddresses))
+	for _, peerAddress := range m.PeerAddresses {
+		if m.useV11 {
+			peerAddresses = append(peerAddresses, peerAddressV11(peerAddress))
+		} else {
+			peerAddresses = append(peerAddresses, peerAddress)
+		}
+	}
+	return cbor.Encode([]any{m.MessageType, peerAddresses})
+}
+
 type MsgDone struct {
 	protocol.MessageBase
 }
@@ -99,6 +113,47 @@ type PeerAddress struct {
 	Port uint16
 }
 
+func (p PeerAddress) MarshalCBOR() ([]byte, error) {
+	return p.marshalCBOR(false)
+}
+
+func (p PeerAddress) marshalCBOR(useV11 bool) ([]byte, error) {
+	if ip4 := p.IP.To4(); ip4 != nil {
+		// The IPv4 address is a single word holding the address bytes in little-endian order
+		tmpPeer := []any{
+			0,
+			binary.LittleEndian.Uint32(ip4),
+			p.Port,
+		}
+		return cbor.Encode(tmpPeer)
+	}
+	ip6 := p.IP.To16()
+	if ip6 == nil {
+		return nil, fmt.Errorf("invalid peer address: %s", p.IP)
+	}
+	// The IPv6 address is four words, each holding 4 address bytes in big-endian order
+	tmpPeer := []any{
+		1,
+		binary.BigEndian.Uint32(ip6[0:]),
+		binary.BigEndian.Uint32(ip6[4:]),
+		binary.BigEndian.Uint32(ip6[8:]),
+		binary.BigEndian.Uint32(ip6[12:]),
+	}
+	if useV11 {
+		// Flow info and scope ID
+		tmpPeer = append(tmpPeer, 0, 0)
+	}
+	tmpPeer = append(tmpPeer, p.Port)
+	return cbor.Encode(tmpPeer)
+}
+
+// peerAddressV11 encodes a PeerAddress using the format from protocol versions 11 and 12
+type peerAddressV11 PeerAddress
+
+func (p peerAddressV11) MarshalCBOR() ([]byte, error) {
+	return PeerAddress(p).marshalCBOR(true)
+}
+
 func (p *PeerAddress) UnmarshalCBOR(cborData []byte) error {
 	peerType, err := cbor.DecodeIdFromList(cborData)
 	if err != nil {
@@ -142,10 +197,10 @@ func (p *PeerAddress) UnmarshalCBOR(cborData []byte) error {
 				return err
 			}
 			p.IP = make(net.IP, net.IPv6len)
-			binary.LittleEndian.PutUint32(p.IP[0:], tmpPeer.Address1)
-			binary.LittleEndian.PutUint32(p.IP[4:], tmpPeer.Address2)
-			binary.LittleEndian.PutUint32(p.IP[8:], tmpPeer.Address3)
-			binary.LittleEndian.PutUint32(p.IP[12:], tmpPeer.Address4)
+			binary.BigEndian.PutUint32(p.IP[0:], tmpPeer.Address1)
+			binary.BigEndian.PutUint32(p.IP[4:], tmpPeer.Address2)
+			binary.BigEndian.PutUint32(p.IP[8:], tmpPeer.Address3)
+			binary.BigEndian.PutUint32(p.IP[12:], tmpPeer.Address4)
 			p.Port = tmpPeer.Port
 		} else if cborListLen == 6 {
 			// V13+
@@ -162,10 +217,10 @@ func (p *PeerAddress) UnmarshalCBOR(cborData []byte) error {
 				return err
 			}
 			p.IP = make(net.IP, net.IPv6len)
-			binary.LittleEndian.PutUint32(p.IP[0:], tmpPeer.Address1)
-			binary.LittleEndian.PutUint32(p.IP[4:], tmpPeer.Address2)
-			binary.LittleEndian.PutUint32(p.IP[8:], tmpPeer.Address3)
-			binary.LittleEndian.PutUint32(p.IP[12:], tmpPeer.Address4)
+			binary.BigEndian.PutUint32(p.IP[0:], tmpPeer.Address1)
+			binary.BigEndian.PutUint32(p.IP[4:], tmpPeer.Address2)
+			binary.BigEndian.PutUint32(p.IP[8:], tmpPeer.Address3)
+			binary.BigEndian.PutUint32(p.IP[12:], tmpPeer.Address4)
 			p.Port = tmpPeer.Port
 		} else {
 			return fmt.Errorf("invalid peer address length: %d", cborListLen)
@@ -16,6 +16,7 @@ package peersharing
 
 import (
 	"encoding/hex"
+	"net"
 	"reflect"
 	"testing"
 
@@ -35,15 +36,26 @@ var tests = []testDefinition{
 		MessageType: MessageTypeShareRequest,
 		Message:     NewMsgShareRequest(7),
 	},
-	/*
-		{
-			CborHex:     "8201xxxx",
-			MessageType: MessageTypeSharePeers,
-			Message:     NewMsgSharePeers(
-
-			),
-		},
-	*/
+	{
+		// [1, [[0, 192.0.2.1, 3001]]]
+		CborHex:     "82018183001a010200c0190bb9",
+		MessageType: MessageTypeSharePeers,
+		Message: NewMsgSharePeers(
+			[]PeerAddress{
+				{IP: net.IP{192, 0, 2, 1}, Port: 3001},
+			},
+		),
+	},
+	{
+		// [1, [[1, 2001:db8::1, 3001]]]
+		CborHex:     "82018186011a20010db8000001190bb9",
+		MessageType: MessageTypeSharePeers,
+		Message: NewMsgSharePeers(
+			[]PeerAddress{
+				{IP: net.ParseIP("2001:db8::1"), Port: 3001},
+			},
+		),
+	},
 	{
 		CborHex:     "8102",
 		MessageType: MessageTypeDone,
@@ -89,3 +101,38 @@ func TestEncode(t *testing.T) {
 		}
 	}
 }
+
+func TestEncodeSharePeersV11(t *testing.T) {
+	msg := NewMsgSharePeers(
+		[]PeerAddress{
+			{IP: net.IP{192, 0, 2, 1}, Port: 3001},
+			{IP: net.ParseIP("2001:db8::1"), Port: 3001},
+		},
+	)
+	msg.useV11 = true
+	cborData, err := cbor.Encode(msg)
+	if err != nil {
+		t.Fatalf("failed to encode message to CBOR: %s", err)
+	}
+	// [1, [[0, 192.0.2.1, 3001], [1, 2001:db8::1, 0, 0, 3001]]]
+	expectedCborHex := "82018283001a010200c0190bb988011a20010db80000010000190bb9"
+	if cborHex := hex.EncodeToString(cborData); cborHex != expectedCborHex {
+		t.Fatalf(
+			"message did not encode to expected CBOR\n  got: %s\n  wanted: %s",
+			cborHex,
+			expectedCborHex,
+		)
+	}
+	// The V11 format should decode to the same addresses
+	msgDecoded, err := NewMsgFromCbor(MessageTypeSharePeers, cborData)
+	if err != nil {
+		t.Fatalf("failed to decode CBOR: %s", err)
+	}
+	if !reflect.DeepEqual(msgDecoded.(*MsgSharePeers).PeerAddresses, msg.PeerAddresses) {
+		t.Fatalf(
+			"CBOR did not decode to expected peer addresses\n  got:    %#v\n  wanted: %#v",
+			msgDecoded.(*MsgSharePeers).PeerAddresses,
+			msg.PeerAddresses,
+		)
+	}
+}
@@ -0,0 +1,315 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peersharing
+
+import (
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/blinklabs-io/gouroboros/connection"
+	"github.com/blinklabs-io/gouroboros/protocol"
+)
+
+// PeerSource indicates how a peer was added to a PeerRegistry
+type PeerSource uint
+
+const (
+	PeerSourceManual   PeerSource = 1
+	PeerSourceOutbound PeerSource = 2
+	PeerSourceInbound  PeerSource = 3
+)
+
+func (s PeerSource) String() string {
+	switch s {
+	case PeerSourceManual:
+		return "Manual"
+	case PeerSourceOutbound:
+		return "Outbound"
+	case PeerSourceInbound:
+		return "Inbound"
+	}
+	return "Unknown"
+}
+
+// RegisteredPeer is a peer known to a PeerRegistry
+type RegisteredPeer struct {
+	Address PeerAddress
+	Source  PeerSource
+	// PeerSharing indicates whether the peer opted into peer sharing
+	PeerSharing bool
+	// ConnectionId is the connection that the peer was learned from. It's empty for manual entries
+	ConnectionId connection.ConnectionId
+	LastSeen     time.Time
+}
+
+// PeerRegistryConfig is used to configure a PeerRegistry
+type PeerRegistryConfig struct {
+	// AllowNonPublic allows sharing addresses that are not publicly routable, such as private and loopback addresses
+	AllowNonPublic bool
+	// AllowNoPeerSharing allows sharing peers that did not opt into peer sharing
+	AllowNoPeerSharing bool
+	// MaxPeers is the maximum number of peers returned for a single request
+	MaxPeers int
+	// RequestInterval is the minimum time between share requests on the same connection. Requests that arrive
+	// sooner receive an empty response
+	RequestInterval time.Duration
+}
+
+// PeerRegistryOptionFunc represents a function used to modify the PeerRegistry config
+type PeerRegistryOptionFunc func(*PeerRegistryConfig)
+
+// NewPeerRegistryConfig returns a new PeerRegistry config object with the provided options
+func NewPeerRegistryConfig(options ...PeerRegistryOptionFunc) PeerRegistryConfig {
+	c := PeerRegistryConfig{
+		MaxPeers:        10,
+		RequestInterval: 1 * time.Minute,
+	}
+	// Apply provided options functions
+	for _, option := range options {
+		option(&c)
+	}
+	return c
+}
+
+// WithAllowNonPublic specifies whether addresses that are not publicly routable can be shared
+func WithAllowNonPublic(allowNonPublic bool) PeerRegistryOptionFunc {
+	return func(c *PeerRegistryConfig) {
+		c.AllowNonPublic = allowNonPublic
+	}
+}
+
+// WithAllowNoPeerSharing specifies whether peers that did not opt into peer sharing can be shared
+func WithAllowNoPeerSharing(allowNoPeerSharing bool) PeerRegistryOptionFunc {
+	return func(c *PeerRegistryConfig) {
+		c.AllowNoPeerSharing = allowNoPeerSharing
+	}
+}
+
+// WithMaxPeers specifies the maximum number of peers returned for a single request
+func WithMaxPeers(maxPeers int) PeerRegistryOptionFunc {
+	return func(c *PeerRegistryConfig) {
+		c.MaxPeers = maxPeers
+	}
+}
+
+// WithRequestInterval specifies the minimum time between share requests on the same connection
+func WithRequestInterval(interval time.Duration) PeerRegistryOptionFunc {
+	return func(c *PeerRegistryConfig) {
+		c.RequestInterval = interval
+	}
+}
+
+// PeerRegistry keeps track of known peers and answers share requests according to its policy. It's used with
+// the PeerSharing server by passing its ShareRequestFunc method to WithShareRequestFunc
+type PeerRegistry struct {
+	config       PeerRegistryConfig
+	mutex        sync.Mutex
+	peers        map[string]*RegisteredPeer
+	lastRequests map[string]time.Time
+}
+
+// NewPeerRegistry returns a new PeerRegistry object with the provided options
+func NewPeerRegistry(options ...PeerRegistryOptionFunc) *PeerRegistry {
+	r := &PeerRegistry{
+		config:       NewPeerRegistryConfig(options...),
+		peers:        make(map[string]*RegisteredPeer),
+		lastRequests: make(map[string]time.Time),
+	}
+	return r
+}
+
+// AddPeer adds a peer address manually
+func (r *PeerRegistry) AddPeer(address PeerAddress, peerSharing bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.addPeer(
+		RegisteredPeer{
+			Address:     address,
+			Source:      PeerSourceManual,
+			PeerSharing: peerSharing,
+		},
+	)
+}
+
+// RemovePeer removes a peer address
+func (r *PeerRegistry) RemovePeer(address PeerAddress) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.peers, peerAddressKey(address))
+}
+
+// AddConnection learns a peer from an established connection, using the version data that the peer sent in
+// the handshake. The remote address of an inbound connection is only used when the peer is running in
+// initiator and responder mode, since the peer then uses its listening address for outbound connections
+func (r *PeerRegistry) AddConnection(
+	connId connection.ConnectionId,
+	versionData protocol.VersionData,
+	inbound bool,
+) {
+	tcpAddr, ok := connId.RemoteAddr.(*net.TCPAddr)
+	if !ok {
+		return
+	}
+	source := PeerSourceOutbound
+	if inbound {
+		if versionData.DiffusionMode() != protocol.DiffusionModeInitiatorAndResponder {
+			return
+		}
+		source = PeerSourceInbound
+	}
+	ip := tcpAddr.IP
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.addPeer(
+		RegisteredPeer{
+			Address: PeerAddress{
+				IP:   ip,
+				Port: uint16(tcpAddr.Port),
+			},
+			Source:       source,
+			PeerSharing:  versionData.PeerSharing(),
+			ConnectionId: connId,
+		},
+	)
+}
+
+// RemoveConnection forgets the rate limit state for a closed connection. Peers learned from the connection
+// are kept
+func (r *PeerRegistry) RemoveConnection(connId connection.ConnectionId) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.lastRequests, connId.String())
+}
+
+func (r *PeerRegistry) addPeer(peer RegisteredPeer) {
+	peer.LastSeen = time.Now()
+	key := peerAddressKey(peer.Address)
+	// Manual entries are not replaced by learned entries
+	if existing, ok := r.peers[key]; ok && existing.Source == PeerSourceManual &&
+		peer.Source != PeerSourceManual {
+		existing.LastSeen = peer.LastSeen
+		return
+	}
+	r.peers[key] = &peer
+}
+
+// Peers returns all known peers
+func (r *PeerRegistry) Peers() []RegisteredPeer {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	ret := make([]RegisteredPeer, 0, len(r.peers))
+	for _, peer := range r.peers {
+		ret = append(ret, *peer)
+	}
+	return ret
+}
+
+// ShareRequestFunc answers a share request with a random sample of the peers allowed by the policy. The
+// requesting peer is never included in the response
+func (r *PeerRegistry) ShareRequestFunc(
+	ctx CallbackContext,
+	amount int,
+) ([]PeerAddress, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	ret := []PeerAddress{}
+	if ctx.ConnectionId.LocalAddr != nil && ctx.ConnectionId.RemoteAddr != nil {
+		connKey := ctx.ConnectionId.String()
+		now := time.Now()
+		if lastRequest, ok := r.lastRequests[connKey]; ok &&
+			now.Sub(lastRequest) < r.config.RequestInterval {
+			return ret, nil
+		}
+		r.lastRequests[connKey] = now
+	}
+	if r.config.MaxPeers > 0 && amount > r.config.MaxPeers {
+		amount = r.config.MaxPeers
+	}
+	var remoteIP net.IP
+	if tcpAddr, ok := ctx.ConnectionId.RemoteAddr.(*net.TCPAddr); ok {
+		remoteIP = tcpAddr.IP
+	}
+	candidates := []PeerAddress{}
+	for _, peer := range r.peers {
+		if !r.config.AllowNoPeerSharing && !peer.PeerSharing {
+			continue
+		}
+		if !r.config.AllowNonPublic && !isPublicAddress(peer.Address.IP) {
+			continue
+		}
+		if remoteIP != nil && peer.Address.IP.Equal(remoteIP) {
+			continue
+		}
+		candidates = append(candidates, peer.Address)
+	}
+	for _, idx := range rand.Perm(len(candidates)) {
+		if len(ret) >= amount {
+			break
+		}
+		ret = append(ret, candidates[idx])
+	}
+	return ret, nil
+}
+
+func peerAddressKey(address PeerAddress) string {
+	return net.JoinHostPort(address.IP.String(), strconv.Itoa(int(address.Port)))
+}
+
+// Address ranges that aren't publicly routable, in addition to those covered by the net.IP methods
+var nonPublicNetworks = []*net.IPNet{
+	// Shared address space (RFC 6598)
+	mustParseCIDR("100.64.0.0/10"),
+	// Documentation (RFC 5737, RFC 3849)
+	mustParseCIDR("192.0.2.0/24"),
+	mustParseCIDR("198.51.100.0/24"),
+	mustParseCIDR("203.0.113.0/24"),
+	mustParseCIDR("2001:db8::/32"),
+	// Benchmarking (RFC 2544)
+	mustParseCIDR("198.18.0.0/15"),
+	// Reserved (RFC 1112)
+	mustParseCIDR("240.0.0.0/4"),
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return ipNet
+}
+
+// isPublicAddress returns whether the address is publicly routable
+func isPublicAddress(ip net.IP) bool {
+	if ip == nil ||
+		ip.IsUnspecified() ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsMulticast() {
+		return false
+	}
+	for _, ipNet := range nonPublicNetworks {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peersharing_test
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/blinklabs-io/gouroboros/connection"
+	"github.com/blinklabs-io/gouroboros/protocol"
+	"github.com/blinklabs-io/gouroboros/protocol/peersharing"
+)
+
+func testConnectionId(remoteAddr string) connection.ConnectionId {
+	addr, err := net.ResolveTCPAddr("tcp", remoteAddr)
+	if err != nil {
+		panic(err)
+	}
+	return connection.ConnectionId{
+		LocalAddr:  &net.TCPAddr{IP: net.IP{1, 1, 1, 1}, Port: 3001},
+		RemoteAddr: addr,
+	}
+}
+
+func testVersionData(
+	diffusionMode bool,
+	peerSharing uint,
+) protocol.VersionData {
+	return protocol.VersionDataNtN13andUp{
+		VersionDataNtN11to12: protocol.VersionDataNtN11to12{
+			CborNetworkMagic:                       1,
+			CborInitiatorAndResponderDiffusionMode: diffusionMode,
+			CborPeerSharing:                        peerSharing,
+		},
+	}
+}
+
+func sharedAddresses(peers []peersharing.PeerAddress) []string {
+	ret := []string{}
+	for _, peer := range peers {
+		ret = append(ret, net.JoinHostPort(peer.IP.String(), strconv.Itoa(int(peer.Port))))
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func TestPeerRegistryPolicy(t *testing.T) {
+	registry := peersharing.NewPeerRegistry()
+	// Manual entries
+	registry.AddPeer(peersharing.PeerAddress{IP: net.IP{8, 8, 8, 8}, Port: 3001}, true)
+	registry.AddPeer(peersharing.PeerAddress{IP: net.ParseIP("2606:4700::1111"), Port: 3001}, true)
+	// Not publicly routable
+	registry.AddPeer(peersharing.PeerAddress{IP: net.IP{10, 0, 0, 1}, Port: 3001}, true)
+	registry.AddPeer(peersharing.PeerAddress{IP: net.IP{127, 0, 0, 1}, Port: 3001}, true)
+	registry.AddPeer(peersharing.PeerAddress{IP: net.IP{192, 0, 2, 1}, Port: 3001}, true)
+	// Did not opt into peer sharing
+	registry.AddPeer(peersharing.PeerAddress{IP: net.IP{9, 9, 9, 9}, Port: 3001}, false)
+	// Outbound connection to a peer with peer sharing enabled
+	registry.AddConnection(
+		testConnectionId("4.4.4.4:3001"),
+		testVersionData(protocol.DiffusionModeInitiatorOnly, protocol.PeerSharingModePeerSharingPublic),
+		false,
+	)
+	// Inbound connection from an initiator-only peer, which doesn't have a usable address
+	registry.AddConnection(
+		testConnectionId("5.5.5.5:45678"),
+		testVersionData(protocol.DiffusionModeInitiatorOnly, protocol.PeerSharingModePeerSharingPublic),
+		true,
+	)
+	// Inbound connection from an initiator and responder peer
+	registry.AddConnection(
+		testConnectionId("6.6.6.6:3001"),
+		testVersionData(protocol.DiffusionModeInitiatorAndResponder, protocol.PeerSharingModePeerSharingPublic),
+		true,
+	)
+	if len(registry.Peers()) != 8 {
+		t.Fatalf("did not get expected number of registered peers: got %d, wanted %d", len(registry.Peers()), 8)
+	}
+	// The requesting peer is excluded from the response
+	ctx := peersharing.CallbackContext{
+		ConnectionId: testConnectionId("4.4.4.4:3001"),
+	}
+	peers, err := registry.ShareRequestFunc(ctx, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expectedPeers := []string{
+		"6.6.6.6:3001",
+		"8.8.8.8:3001",
+		"[2606:4700::1111]:3001",
+	}
+	sort.Strings(expectedPeers)
+	got := sharedAddresses(peers)
+	if len(got) != len(expectedPeers) {
+		t.Fatalf("did not get expected peers: got %v, wanted %v", got, expectedPeers)
+	}
+	for idx := range got {
+		if got[idx] != expectedPeers[idx] {
+			t.Fatalf("did not get expected peers: got %v, wanted %v", got, expectedPeers)
+		}
+	}
+	// A second request on the same connection is rate limited
+	peers, err = registry.ShareRequestFunc(ctx, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(peers) != 0 {
+		t.Fatalf("did not get expected empty response for rate limited request: %v", peers)
+	}
+	// The rate limit is reset when the connection is removed
+	registry.RemoveConnection(ctx.ConnectionId)
+	peers, err = registry.ShareRequestFunc(ctx, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(peers) != 1 {
+		t.Fatalf("did not get expected number of peers: got %d, wanted %d", len(peers), 1)
+	}
+}
+
+func TestPeerRegistryAllowAll(t *testing.T) {
+	registry := peersharing.NewPeerRegistry(
+		peersharing.WithAllowNonPublic(true),
+		peersharing.WithAllowNoPeerSharing(true),
+		peersharing.WithMaxPeers(2),
+		peersharing.WithRequestInterval(0),
+	)
+	registry.AddPeer(peersharing.PeerAddress{IP: net.IP{10, 0, 0, 1}, Port: 3001}, true)
+	registry.AddPeer(peersharing.PeerAddress{IP: net.IP{10, 0, 0, 2}, Port: 3001}, false)
+	registry.AddPeer(peersharing.PeerAddress{IP: net.IP{10, 0, 0, 3}, Port: 3001}, false)
+	ctx := peersharing.CallbackContext{
+		ConnectionId: testConnectionId("4.4.4.4:3001"),
+	}
+	for i := 0; i < 3; i++ {
+		// The response is limited by MaxPeers
+		peers, err := registry.ShareRequestFunc(ctx, 10)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(peers) != 2 {
+			t.Fatalf("did not get expected number of peers: got %d, wanted %d", len(peers), 2)
+		}
+	}
+	// Removed peers are no longer shared
+	registry.RemovePeer(peersharing.PeerAddress{IP: net.IP{10, 0, 0, 1}, Port: 3001})
+	registry.RemovePeer(peersharing.PeerAddress{IP: net.IP{10, 0, 0, 2}, Port: 3001})
+	peers, err := registry.ShareRequestFunc(ctx, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := sharedAddresses(peers); len(got) != 1 || got[0] != "10.0.0.3:3001" {
+		t.Fatalf("did not get expected peers: got %v", got)
+	}
+}
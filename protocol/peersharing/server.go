@@ -91,6 +91,7 @@ func (s *Server) handleShareRequest(msg protocol.Message) error {
 		return err
 	}
 	msgResp := NewMsgSharePeers(peers)
+	msgResp.useV11 = protocol.GetProtocolVersion(s.protoOptions.Version).PeerSharingUseV11
 	if err := s.SendMessage(msgResp); err != nil {
 		return err
 	}
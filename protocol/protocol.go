@@ -45,6 +45,9 @@ type Protocol struct {
 	stateTransitionChan chan<- protocolStateTransition
 	onceStart           sync.Once
 	onceStop            sync.Once
+	streamMutex         sync.Mutex
+	streamMsgType       uint
+	streamFunc          MessageStreamFunc
 }
 
 // ProtocolConfig provides the configuration for Protocol
@@ -103,6 +106,11 @@ type MessageHandlerFunc func(Message) error
 // MessageFromCborFunc represents a function that parses a mini-protocol message
 type MessageFromCborFunc func(uint, []byte) (Message, error)
 
+// MessageStreamFunc represents a function that reads a mini-protocol message of the provided type from the
+// reader as it's received. It must read exactly the CBOR for the message, and it returns the Message that's
+// handled once the whole message has been read
+type MessageStreamFunc func(uint, io.Reader) (Message, error)
+
 // New returns a new Protocol object
 func New(config ProtocolConfig) *Protocol {
 	p := &Protocol{
@@ -200,6 +208,34 @@ func (p *Protocol) SendError(err error) {
 	}
 }
 
+// SetMessageStreamFunc specifies a function for reading the next received message of the specified type as it's
+// received, rather than waiting for the whole message before decoding it. This allows processing messages that
+// are too large to hold in memory. It only applies to the next message of that type, and a nil function clears it
+func (p *Protocol) SetMessageStreamFunc(msgType uint, streamFunc MessageStreamFunc) {
+	p.streamMutex.Lock()
+	defer p.streamMutex.Unlock()
+	p.streamMsgType = msgType
+	p.streamFunc = streamFunc
+}
+
+// takeMessageStreamFunc returns the message stream function if there's one for the message type, and clears it
+func (p *Protocol) takeMessageStreamFunc(msgType uint) MessageStreamFunc {
+	p.streamMutex.Lock()
+	defer p.streamMutex.Unlock()
+	if p.streamFunc == nil || p.streamMsgType != msgType {
+		return nil
+	}
+	streamFunc := p.streamFunc
+	p.streamFunc = nil
+	return streamFunc
+}
+
+func (p *Protocol) hasMessageStreamFunc() bool {
+	p.streamMutex.Lock()
+	defer p.streamMutex.Unlock()
+	return p.streamFunc != nil
+}
+
 func (p *Protocol) sendLoop() {
 	defer func() {
 		// Close muxer send channel
@@ -345,6 +381,39 @@ func (p *Protocol) recvLoop() {
 			return
 		case <-p.recvReadyChan:
 		}
+		// Hand the message to the stream function, if there's one for this message type
+		if p.hasMessageStreamFunc() {
+			msgType, err := peekMessageType(recvBuffer.Bytes())
+			if err == io.ErrUnexpectedEOF {
+				// Wait until we have enough of the message to determine its type
+				p.recvReadyChan <- true
+				continue
+			}
+			if err == nil {
+				if streamFunc := p.takeMessageStreamFunc(msgType); streamFunc != nil {
+					reader := &segmentReader{
+						protocol: p,
+						data:     recvBuffer.Bytes(),
+					}
+					msg, err := streamFunc(msgType, reader)
+					if reader.shutdown {
+						return
+					}
+					if err != nil {
+						p.SendError(fmt.Errorf("%s: decode error: %s", p.config.Name, err))
+						return
+					}
+					if err := p.handleMessage(msg); err != nil {
+						p.SendError(err)
+						return
+					}
+					// Keep any data following the message
+					recvBuffer = bytes.NewBuffer(reader.data)
+					leftoverData = len(reader.data) > 0
+					continue
+				}
+			}
+		}
 		// Decode message into generic list until we can determine what type of message it is.
 		// This also lets us determine how many bytes the message is. We use RawMessage here to
 		// avoid parsing things that we may not be able to parse
@@ -399,6 +468,53 @@ func (p *Protocol) recvLoop() {
 	}
 }
 
+// peekMessageType determines the type of the message at the start of the data. It returns io.ErrUnexpectedEOF
+// if there's not enough data yet
+func peekMessageType(data []byte) (uint, error) {
+	itemReader := cbor.NewItemReader(bytes.NewReader(data))
+	if _, err := itemReader.ReadArrayHeader(); err != nil {
+		return 0, err
+	}
+	msgTypeCbor, err := itemReader.ReadItem()
+	if err != nil {
+		return 0, err
+	}
+	var msgType uint
+	if _, err := cbor.Decode(msgTypeCbor, &msgType); err != nil {
+		return 0, err
+	}
+	return msgType, nil
+}
+
+// segmentReader reads message data from the receive buffer and then from muxer segments as they're received
+type segmentReader struct {
+	protocol *Protocol
+	data     []byte
+	shutdown bool
+}
+
+func (r *segmentReader) Read(buf []byte) (int, error) {
+	for len(r.data) == 0 {
+		select {
+		case <-r.protocol.sendDoneChan:
+			r.shutdown = true
+			return 0, io.ErrUnexpectedEOF
+		case <-r.protocol.muxerDoneChan:
+			r.shutdown = true
+			return 0, io.ErrUnexpectedEOF
+		case segment, ok := <-r.protocol.muxerRecvChan:
+			if !ok {
+				r.shutdown = true
+				return 0, io.ErrUnexpectedEOF
+			}
+			r.data = segment.Payload
+		}
+	}
+	n := copy(buf, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
 func (p *Protocol) stateLoop(ch <-chan protocolStateTransition) {
 	var currentState State
 	var transitionTimer *time.Timer
@@ -27,6 +27,10 @@ type Client struct {
 	config          *Config
 	callbackContext CallbackContext
 	onceInit        sync.Once
+	// Used when a mempool is configured
+	mempoolMutex sync.Mutex
+	unackedTxs   []MempoolTx
+	lastPosition uint64
 }
 
 // NewClient returns a new TxSubmission client object
@@ -92,12 +96,15 @@ func (c *Client) messageHandler(msg protocol.Message) error {
 }
 
 func (c *Client) handleRequestTxIds(msg protocol.Message) error {
+	msgRequestTxIds := msg.(*MsgRequestTxIds)
+	if c.config.Mempool != nil {
+		return c.handleRequestTxIdsMempool(msgRequestTxIds)
+	}
 	if c.config.RequestTxIdsFunc == nil {
 		return fmt.Errorf(
 			"received tx-submission RequestTxIds message but no callback function is defined",
 		)
 	}
-	msgRequestTxIds := msg.(*MsgRequestTxIds)
 	// Call the user callback function
 	txIds, err := c.config.RequestTxIdsFunc(
 		c.callbackContext,
@@ -116,12 +123,15 @@ func (c *Client) handleRequestTxIds(msg protocol.Message) error {
 }
 
 func (c *Client) handleRequestTxs(msg protocol.Message) error {
+	msgRequestTxs := msg.(*MsgRequestTxs)
+	if c.config.Mempool != nil {
+		return c.handleRequestTxsMempool(msgRequestTxs)
+	}
 	if c.config.RequestTxsFunc == nil {
 		return fmt.Errorf(
 			"received tx-submission RequestTxs message but no callback function is defined",
 		)
 	}
-	msgRequestTxs := msg.(*MsgRequestTxs)
 	// Call the user callback function
 	txs, err := c.config.RequestTxsFunc(c.callbackContext, msgRequestTxs.TxIds)
 	if err != nil {
@@ -133,3 +143,114 @@ func (c *Client) handleRequestTxs(msg protocol.Message) error {
 	}
 	return nil
 }
+
+func (c *Client) handleRequestTxIdsMempool(msg *MsgRequestTxIds) error {
+	c.mempoolMutex.Lock()
+	defer c.mempoolMutex.Unlock()
+	// Drop the acknowledged transactions from the window
+	if int(msg.Ack) > len(c.unackedTxs) {
+		return fmt.Errorf(
+			"%s: remote node acknowledged %d transactions but only %d are outstanding",
+			ProtocolName,
+			msg.Ack,
+			len(c.unackedTxs),
+		)
+	}
+	c.unackedTxs = c.unackedTxs[msg.Ack:]
+	// Get the wait channel before checking for transactions, so that we can't miss one being added
+	var waitChan <-chan struct{}
+	if msg.Blocking {
+		waitChan = c.config.Mempool.WaitChan(c.lastPosition)
+	}
+	txs := c.config.Mempool.TxsAfter(c.lastPosition, int(msg.Req))
+	// A blocking request must wait for at least one transaction. We wait outside of the message handler so
+	// that the protocol can still shut down
+	if len(txs) == 0 && msg.Blocking {
+		go c.waitMempoolTxIds(msg, waitChan)
+		return nil
+	}
+	return c.replyMempoolTxIds(msg, txs)
+}
+
+func (c *Client) waitMempoolTxIds(msg *MsgRequestTxIds, waitChan <-chan struct{}) {
+	for {
+		select {
+		case <-waitChan:
+		case <-c.DoneChan():
+			return
+		}
+		c.mempoolMutex.Lock()
+		waitChan = c.config.Mempool.WaitChan(c.lastPosition)
+		txs := c.config.Mempool.TxsAfter(c.lastPosition, int(msg.Req))
+		if len(txs) > 0 {
+			err := c.replyMempoolTxIds(msg, txs)
+			c.mempoolMutex.Unlock()
+			if err != nil {
+				c.SendError(err)
+			}
+			return
+		}
+		c.mempoolMutex.Unlock()
+	}
+}
+
+// replyMempoolTxIds sends the reply for a RequestTxIds message and adds the offered transactions to the window.
+// The caller must hold mempoolMutex
+func (c *Client) replyMempoolTxIds(msg *MsgRequestTxIds, txs []MempoolTx) error {
+	if len(txs) > int(msg.Req) {
+		txs = txs[:msg.Req]
+	}
+	txIds := make([]TxIdAndSize, 0, len(txs))
+	for _, tx := range txs {
+		txIds = append(
+			txIds,
+			TxIdAndSize{
+				TxId: tx.TxId,
+				Size: tx.Size,
+			},
+		)
+	}
+	if len(txs) > 0 {
+		c.lastPosition = txs[len(txs)-1].Position
+		c.unackedTxs = append(c.unackedTxs, txs...)
+	}
+	resp := NewMsgReplyTxIds(txIds)
+	if err := c.SendMessage(resp); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) handleRequestTxsMempool(msg *MsgRequestTxs) error {
+	c.mempoolMutex.Lock()
+	defer c.mempoolMutex.Unlock()
+	txs := make([]TxBody, 0, len(msg.TxIds))
+	for _, txId := range msg.TxIds {
+		// Only transactions that we've offered and that haven't been acknowledged can be requested
+		found := false
+		for _, tx := range c.unackedTxs {
+			if tx.TxId == txId {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf(
+				"%s: remote node requested transaction %x which is not outstanding",
+				ProtocolName,
+				txId.TxId,
+			)
+		}
+		// Transactions that have since left the mempool are omitted from the reply
+		tx, ok := c.config.Mempool.Tx(txId)
+		if !ok {
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	resp := NewMsgReplyTxs(txs)
+	if err := c.SendMessage(resp); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package txsubmission_test
+
+import (
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	ouroboros "github.com/blinklabs-io/gouroboros"
+	"github.com/blinklabs-io/gouroboros/protocol/txsubmission"
+	"go.uber.org/goleak"
+)
+
+// testMempool is a minimal in-memory Mempool implementation
+type testMempool struct {
+	sync.Mutex
+	txs      []txsubmission.MempoolTx
+	bodies   map[txsubmission.TxId]txsubmission.TxBody
+	waitChan chan struct{}
+}
+
+func newTestMempool() *testMempool {
+	return &testMempool{
+		bodies:   make(map[txsubmission.TxId]txsubmission.TxBody),
+		waitChan: make(chan struct{}),
+	}
+}
+
+func (m *testMempool) AddTx(txHash byte) txsubmission.TxId {
+	m.Lock()
+	defer m.Unlock()
+	txId := txsubmission.TxId{
+		EraId: 6,
+		TxId:  [32]byte{txHash},
+	}
+	m.txs = append(
+		m.txs,
+		txsubmission.MempoolTx{
+			Position: uint64(len(m.txs) + 1),
+			TxId:     txId,
+			Size:     100,
+		},
+	)
+	m.bodies[txId] = txsubmission.TxBody{
+		EraId:  6,
+		TxBody: []byte{0x80, txHash},
+	}
+	// Wake any waiters
+	close(m.waitChan)
+	m.waitChan = make(chan struct{})
+	return txId
+}
+
+func (m *testMempool) TxsAfter(position uint64, count int) []txsubmission.MempoolTx {
+	m.Lock()
+	defer m.Unlock()
+	var ret []txsubmission.MempoolTx
+	for _, tx := range m.txs {
+		if tx.Position > position && len(ret) < count {
+			ret = append(ret, tx)
+		}
+	}
+	return ret
+}
+
+func (m *testMempool) Tx(txId txsubmission.TxId) (txsubmission.TxBody, bool) {
+	m.Lock()
+	defer m.Unlock()
+	body, ok := m.bodies[txId]
+	return body, ok
+}
+
+func (m *testMempool) WaitChan(position uint64) <-chan struct{} {
+	m.Lock()
+	defer m.Unlock()
+	return m.waitChan
+}
+
+func txIdsFromReply(txIdsAndSizes []txsubmission.TxIdAndSize) []txsubmission.TxId {
+	ret := []txsubmission.TxId{}
+	for _, txIdAndSize := range txIdsAndSizes {
+		ret = append(ret, txIdAndSize.TxId)
+	}
+	return ret
+}
+
+func TestClientMempool(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	mempool := newTestMempool()
+	tx1 := mempool.AddTx(0x01)
+	tx2 := mempool.AddTx(0x02)
+	tx3 := mempool.AddTx(0x03)
+	initChan := make(chan struct{})
+	clientConn, serverConn := net.Pipe()
+	serverConnChan := make(chan *ouroboros.Connection, 1)
+	go func() {
+		oConn, err := ouroboros.New(
+			ouroboros.WithConnection(serverConn),
+			ouroboros.WithNetworkMagic(ouroboros.NetworkPreview.NetworkMagic),
+			ouroboros.WithServer(true),
+			ouroboros.WithNodeToNode(true),
+			ouroboros.WithTxSubmissionConfig(
+				txsubmission.NewConfig(
+					txsubmission.WithInitFunc(
+						func(ctx txsubmission.CallbackContext) error {
+							close(initChan)
+							return nil
+						},
+					),
+				),
+			),
+		)
+		if err != nil {
+			panic(err)
+		}
+		serverConnChan <- oConn
+	}()
+	oClient, err := ouroboros.New(
+		ouroboros.WithConnection(clientConn),
+		ouroboros.WithNetworkMagic(ouroboros.NetworkPreview.NetworkMagic),
+		ouroboros.WithNodeToNode(true),
+		ouroboros.WithTxSubmissionConfig(
+			txsubmission.NewConfig(
+				txsubmission.WithMempool(mempool),
+			),
+		),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error when creating Ouroboros object: %s", err)
+	}
+	oServer := <-serverConnChan
+	defer func() {
+		for _, oConn := range []*ouroboros.Connection{oClient, oServer} {
+			if err := oConn.Close(); err != nil {
+				t.Fatalf("unexpected error when closing Ouroboros object: %s", err)
+			}
+			select {
+			case <-oConn.ErrorChan():
+			case <-time.After(10 * time.Second):
+				t.Errorf("did not shutdown within timeout")
+			}
+		}
+	}()
+	oClient.TxSubmission().Client.Init()
+	select {
+	case <-initChan:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("did not receive Init message")
+	}
+	server := oServer.TxSubmission().Server
+	// The request count limits the number of transactions offered
+	txIds, err := server.RequestTxIds(true, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ids := txIdsFromReply(txIds); !reflect.DeepEqual(ids, []txsubmission.TxId{tx1, tx2}) {
+		t.Fatalf("did not get expected tx IDs: %v", ids)
+	}
+	txs, err := server.RequestTxs([]txsubmission.TxId{tx2})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(txs) != 1 || txs[0].TxBody[1] != 0x02 {
+		t.Fatalf("did not get expected txs: %v", txs)
+	}
+	// Acknowledging the previous transactions moves the window forward
+	txIds, err = server.RequestTxIds(false, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ids := txIdsFromReply(txIds); !reflect.DeepEqual(ids, []txsubmission.TxId{tx3}) {
+		t.Fatalf("did not get expected tx IDs: %v", ids)
+	}
+	// A non-blocking request returns immediately when there are no new transactions
+	txIds, err = server.RequestTxIds(false, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(txIds) != 0 {
+		t.Fatalf("did not get expected empty tx IDs: %v", txIdsFromReply(txIds))
+	}
+	// A blocking request waits for a new transaction
+	tx4Chan := make(chan txsubmission.TxId, 1)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		tx4Chan <- mempool.AddTx(0x04)
+	}()
+	txIds, err = server.RequestTxIds(true, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ids := txIdsFromReply(txIds); !reflect.DeepEqual(ids, []txsubmission.TxId{<-tx4Chan}) {
+		t.Fatalf("did not get expected tx IDs: %v", ids)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package txsubmission
+
+// MempoolTx identifies a transaction in a Mempool
+type MempoolTx struct {
+	// Position orders the transaction within the mempool. Positions must be strictly increasing in the order
+	// that transactions are added, and they must start from 1
+	Position uint64
+	TxId     TxId
+	Size     uint32
+}
+
+// Mempool provides the transactions offered to the remote node by a client configured with WithMempool. The
+// mempool can be shared between multiple clients, and its methods can be called concurrently
+type Mempool interface {
+	// TxsAfter returns up to count transactions with a position greater than the specified position, in position
+	// order. A position of 0 returns transactions from the start of the mempool
+	TxsAfter(position uint64, count int) []MempoolTx
+	// Tx returns the transaction with the specified ID, or false if it's no longer in the mempool
+	Tx(txId TxId) (TxBody, bool)
+	// WaitChan returns a channel that is closed when a transaction with a position greater than the specified
+	// position is added. The channel may also be closed early, in which case TxsAfter will be checked again
+	WaitChan(position uint64) <-chan struct{}
+}
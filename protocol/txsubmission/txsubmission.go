@@ -123,6 +123,8 @@ type Config struct {
 	RequestTxsFunc   RequestTxsFunc
 	InitFunc         InitFunc
 	IdleTimeout      time.Duration
+	// Mempool is used instead of RequestTxIdsFunc and RequestTxsFunc when set
+	Mempool Mempool
 }
 
 // Callback context
@@ -184,6 +186,14 @@ func WithInitFunc(initFunc InitFunc) TxSubmissionOptionFunc {
 	}
 }
 
+// WithMempool specifies a mempool to offer transactions from. The client tracks the transactions that the
+// remote node has not yet acknowledged, so RequestTxIdsFunc and RequestTxsFunc are not needed
+func WithMempool(mempool Mempool) TxSubmissionOptionFunc {
+	return func(c *Config) {
+		c.Mempool = mempool
+	}
+}
+
 // WithIdleTimeout specifies the timeout for waiting for new transactions from the remote node's mempool
 func WithIdleTimeout(timeout time.Duration) TxSubmissionOptionFunc {
 	return func(c *Config) {
@@ -14,26 +14,35 @@
 
 package protocol
 
-import "sort"
+import (
+	"sort"
+	"sync"
+)
 
 // The NtC protocol versions have the 15th bit set in the handshake
 const ProtocolVersionNtCOffset = 0x8000
 
 type NewVersionDataFromCborFunc func([]byte) (VersionData, error)
 
+// NewVersionDataFunc builds the version data to propose for a protocol version in the handshake
+type NewVersionDataFunc func(networkMagic uint32, diffusionMode bool, peerSharing bool, queryMode bool) VersionData
+
 type ProtocolVersionMap map[uint16]VersionData
 
 type ProtocolVersion struct {
 	NewVersionDataFromCborFunc NewVersionDataFromCborFunc
-	EnableShelleyEra           bool
-	EnableAllegraEra           bool
-	EnableMaryEra              bool
-	EnableAlonzoEra            bool
-	EnableBabbageEra           bool
-	EnableConwayEra            bool
+	// NewVersionDataFunc is optional. The version data format is chosen by version number when it's not set
+	NewVersionDataFunc NewVersionDataFunc
+	EnableShelleyEra   bool
+	EnableAllegraEra   bool
+	EnableMaryEra      bool
+	EnableAlonzoEra    bool
+	EnableBabbageEra   bool
+	EnableConwayEra    bool
 	// NtC only
 	EnableLocalQueryProtocol     bool
 	EnableLocalTxMonitorProtocol bool
+	EnableLocalTxMonitorMeasures bool
 	// NtN only
 	EnableKeepAliveProtocol   bool
 	EnableFullDuplex          bool
@@ -41,6 +50,10 @@ type ProtocolVersion struct {
 	PeerSharingUseV11         bool
 }
 
+// protocolVersionsMutex guards protocolVersions, which can be modified by RegisterProtocolVersion and
+// UnregisterProtocolVersion
+var protocolVersionsMutex sync.RWMutex
+
 var protocolVersions = map[uint16]ProtocolVersion{
 	// NtC protocol versions
 	//
@@ -102,7 +115,7 @@ var protocolVersions = map[uint16]ProtocolVersion{
 		EnableBabbageEra:             true,
 		EnableLocalTxMonitorProtocol: true,
 	},
-	// added query param to handshake
+	// added query param to handshake, GetStakeDelegDeposits query
 	(15 + ProtocolVersionNtCOffset): ProtocolVersion{
 		NewVersionDataFromCborFunc:   NewVersionDataNtC15andUpFromCbor,
 		EnableLocalQueryProtocol:     true,
@@ -113,6 +126,7 @@ var protocolVersions = map[uint16]ProtocolVersion{
 		EnableBabbageEra:             true,
 		EnableLocalTxMonitorProtocol: true,
 	},
+	// added Conway era, Conway governance queries, GetSPOStakeDistr query
 	(16 + ProtocolVersionNtCOffset): ProtocolVersion{
 		NewVersionDataFromCborFunc:   NewVersionDataNtC15andUpFromCbor,
 		EnableLocalQueryProtocol:     true,
@@ -124,6 +138,55 @@ var protocolVersions = map[uint16]ProtocolVersion{
 		EnableConwayEra:              true,
 		EnableLocalTxMonitorProtocol: true,
 	},
+	// added GetProposals and GetRatifyState queries
+	(17 + ProtocolVersionNtCOffset): ProtocolVersion{
+		NewVersionDataFromCborFunc:   NewVersionDataNtC15andUpFromCbor,
+		EnableLocalQueryProtocol:     true,
+		EnableShelleyEra:             true,
+		EnableAllegraEra:             true,
+		EnableMaryEra:                true,
+		EnableAlonzoEra:              true,
+		EnableBabbageEra:             true,
+		EnableConwayEra:              true,
+		EnableLocalTxMonitorProtocol: true,
+	},
+	// added GetFuturePParams query
+	(18 + ProtocolVersionNtCOffset): ProtocolVersion{
+		NewVersionDataFromCborFunc:   NewVersionDataNtC15andUpFromCbor,
+		EnableLocalQueryProtocol:     true,
+		EnableShelleyEra:             true,
+		EnableAllegraEra:             true,
+		EnableMaryEra:                true,
+		EnableAlonzoEra:              true,
+		EnableBabbageEra:             true,
+		EnableConwayEra:              true,
+		EnableLocalTxMonitorProtocol: true,
+	},
+	// added GetBigLedgerPeerSnapshot query
+	(19 + ProtocolVersionNtCOffset): ProtocolVersion{
+		NewVersionDataFromCborFunc:   NewVersionDataNtC15andUpFromCbor,
+		EnableLocalQueryProtocol:     true,
+		EnableShelleyEra:             true,
+		EnableAllegraEra:             true,
+		EnableMaryEra:                true,
+		EnableAlonzoEra:              true,
+		EnableBabbageEra:             true,
+		EnableConwayEra:              true,
+		EnableLocalTxMonitorProtocol: true,
+	},
+	// added GetMeasures to local-tx-monitor, GetStakePoolDefaultVote query
+	(20 + ProtocolVersionNtCOffset): ProtocolVersion{
+		NewVersionDataFromCborFunc:   NewVersionDataNtC15andUpFromCbor,
+		EnableLocalQueryProtocol:     true,
+		EnableShelleyEra:             true,
+		EnableAllegraEra:             true,
+		EnableMaryEra:                true,
+		EnableAlonzoEra:              true,
+		EnableBabbageEra:             true,
+		EnableConwayEra:              true,
+		EnableLocalTxMonitorProtocol: true,
+		EnableLocalTxMonitorMeasures: true,
+	},
 
 	// NtN versions
 	//
@@ -201,6 +264,35 @@ var protocolVersions = map[uint16]ProtocolVersion{
 		EnableFullDuplex:           true,
 		EnablePeerSharingProtocol:  true,
 	},
+	// Plomin hard fork
+	14: ProtocolVersion{
+		NewVersionDataFromCborFunc: NewVersionDataNtN13andUpFromCbor,
+		EnableShelleyEra:           true,
+		EnableKeepAliveProtocol:    true,
+		EnableAllegraEra:           true,
+		EnableMaryEra:              true,
+		EnableAlonzoEra:            true,
+		EnableBabbageEra:           true,
+		EnableConwayEra:            true,
+		EnableFullDuplex:           true,
+		EnablePeerSharingProtocol:  true,
+	},
+}
+
+// RegisterProtocolVersion adds a protocol version to the supported versions, or replaces an existing one. This
+// allows supporting new protocol versions without changes to this library. NtC protocol versions must include
+// ProtocolVersionNtCOffset. Connections only use the versions that are registered when their handshake starts
+func RegisterProtocolVersion(version uint16, protocolVersion ProtocolVersion) {
+	protocolVersionsMutex.Lock()
+	defer protocolVersionsMutex.Unlock()
+	protocolVersions[version] = protocolVersion
+}
+
+// UnregisterProtocolVersion removes a protocol version from the supported versions
+func UnregisterProtocolVersion(version uint16) {
+	protocolVersionsMutex.Lock()
+	defer protocolVersionsMutex.Unlock()
+	delete(protocolVersions, version)
 }
 
 // GetProtocolVersionMap returns a data structure suitable for use with the protocol handshake
@@ -211,8 +303,21 @@ func GetProtocolVersionMap(
 	peerSharing bool,
 	queryMode bool,
 ) ProtocolVersionMap {
+	protocolVersionsMutex.RLock()
+	defer protocolVersionsMutex.RUnlock()
 	ret := ProtocolVersionMap{}
-	for version := range protocolVersions {
+	for version, versionInfo := range protocolVersions {
+		if versionInfo.NewVersionDataFunc != nil {
+			if (protocolMode == ProtocolModeNodeToClient) == (version >= ProtocolVersionNtCOffset) {
+				ret[version] = versionInfo.NewVersionDataFunc(
+					networkMagic,
+					diffusionMode,
+					peerSharing,
+					queryMode,
+				)
+			}
+			continue
+		}
 		if protocolMode == ProtocolModeNodeToClient {
 			if version >= ProtocolVersionNtCOffset {
 				if version >= (15 + ProtocolVersionNtCOffset) {
@@ -265,6 +370,8 @@ func GetProtocolVersionMap(
 
 // GetProtocolVersionsNtC returns a list of supported NtC protocol versions
 func GetProtocolVersionsNtC() []uint16 {
+	protocolVersionsMutex.RLock()
+	defer protocolVersionsMutex.RUnlock()
 	versions := []uint16{}
 	for key := range protocolVersions {
 		if key >= ProtocolVersionNtCOffset {
@@ -282,6 +389,8 @@ func GetProtocolVersionsNtC() []uint16 {
 
 // GetProtocolVersionsNtN returns a list of supported NtN protocol versions
 func GetProtocolVersionsNtN() []uint16 {
+	protocolVersionsMutex.RLock()
+	defer protocolVersionsMutex.RUnlock()
 	versions := []uint16{}
 	for key := range protocolVersions {
 		if key < ProtocolVersionNtCOffset {
@@ -299,5 +408,7 @@ func GetProtocolVersionsNtN() []uint16 {
 
 // GetProtocolVersion returns the protocol version config for the specified protocol version
 func GetProtocolVersion(version uint16) ProtocolVersion {
+	protocolVersionsMutex.RLock()
+	defer protocolVersionsMutex.RUnlock()
 	return protocolVersions[version]
 }
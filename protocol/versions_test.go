@@ -0,0 +1,143 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// testVersionData is a version data format with an extra field, as a future protocol version might use
+type testVersionData struct {
+	VersionDataNtN11to12
+	CborExtra uint
+}
+
+func TestRegisterProtocolVersion(t *testing.T) {
+	var testVersion uint16 = 100
+	RegisterProtocolVersion(
+		testVersion,
+		ProtocolVersion{
+			NewVersionDataFromCborFunc: NewVersionDataNtN13andUpFromCbor,
+			NewVersionDataFunc: func(networkMagic uint32, diffusionMode bool, peerSharing bool, queryMode bool) VersionData {
+				return testVersionData{
+					VersionDataNtN11to12: VersionDataNtN11to12{
+						CborNetworkMagic:                       networkMagic,
+						CborInitiatorAndResponderDiffusionMode: diffusionMode,
+						CborQuery:                              queryMode,
+					},
+					CborExtra: 1,
+				}
+			},
+			EnableConwayEra: true,
+		},
+	)
+	defer UnregisterProtocolVersion(testVersion)
+	if !GetProtocolVersion(testVersion).EnableConwayEra {
+		t.Fatalf("did not get registered protocol version")
+	}
+	versions := GetProtocolVersionsNtN()
+	if versions[len(versions)-1] != testVersion {
+		t.Fatalf("registered version is not the highest supported NtN version: %v", versions)
+	}
+	for _, version := range GetProtocolVersionsNtC() {
+		if version == testVersion {
+			t.Fatalf("registered NtN version is in supported NtC versions")
+		}
+	}
+	versionMap := GetProtocolVersionMap(
+		ProtocolModeNodeToNode,
+		1,
+		DiffusionModeInitiatorOnly,
+		false,
+		QueryModeDisabled,
+	)
+	expectedVersionData := testVersionData{
+		VersionDataNtN11to12: VersionDataNtN11to12{
+			CborNetworkMagic:                       1,
+			CborInitiatorAndResponderDiffusionMode: DiffusionModeInitiatorOnly,
+		},
+		CborExtra: 1,
+	}
+	if !reflect.DeepEqual(versionMap[testVersion], expectedVersionData) {
+		t.Fatalf(
+			"did not get expected version data\n  got:    %#v\n  wanted: %#v",
+			versionMap[testVersion],
+			expectedVersionData,
+		)
+	}
+	// Built-in versions still use the version data format for their version number
+	if _, ok := versionMap[14].(VersionDataNtN13andUp); !ok {
+		t.Fatalf("did not get expected version data type for version 14: %T", versionMap[14])
+	}
+	// The registered version is not proposed for NtC
+	versionMap = GetProtocolVersionMap(
+		ProtocolModeNodeToClient,
+		1,
+		DiffusionModeInitiatorOnly,
+		false,
+		QueryModeDisabled,
+	)
+	if _, ok := versionMap[testVersion]; ok {
+		t.Fatalf("registered NtN version is in NtC version map")
+	}
+}
+
+func TestUnregisterProtocolVersion(t *testing.T) {
+	var testVersion uint16 = 101
+	RegisterProtocolVersion(
+		testVersion,
+		ProtocolVersion{
+			NewVersionDataFromCborFunc: NewVersionDataNtN13andUpFromCbor,
+		},
+	)
+	UnregisterProtocolVersion(testVersion)
+	for _, version := range GetProtocolVersionsNtN() {
+		if version == testVersion {
+			t.Fatalf("unregistered version is still in supported NtN versions")
+		}
+	}
+}
+
+func TestRegisterProtocolVersionConcurrent(t *testing.T) {
+	var testVersion uint16 = 102
+	defer UnregisterProtocolVersion(testVersion)
+	// This is mostly useful when run with the race detector
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterProtocolVersion(
+				testVersion,
+				ProtocolVersion{
+					NewVersionDataFromCborFunc: NewVersionDataNtN13andUpFromCbor,
+				},
+			)
+		}()
+		go func() {
+			defer wg.Done()
+			GetProtocolVersionMap(
+				ProtocolModeNodeToNode,
+				1,
+				DiffusionModeInitiatorOnly,
+				false,
+				QueryModeDisabled,
+			)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,553 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package querycache provides a caching layer for local-state-query results
+//
+// Queries run against a ledger state that the cache acquires at the current chain tip, and results are cached
+// by query and the point that was acquired, or the epoch for queries that only change at an epoch boundary. A
+// companion chain-sync client keeps the cache informed of the current tip. When the tip changes, the ledger
+// state is reacquired at the new tip before the next query, and cached results are dropped when the tip changes
+// or when the tip crosses an epoch boundary, depending on the query. Per-query TTLs put an upper bound on the age
+// of cached results. Without a known tip, results that depend on the tip or epoch are only cached if they have a
+// TTL
+package querycache
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/blinklabs-io/gouroboros/cbor"
+	"github.com/blinklabs-io/gouroboros/hardfork"
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/protocol/chainsync"
+	"github.com/blinklabs-io/gouroboros/protocol/common"
+	"github.com/blinklabs-io/gouroboros/protocol/localstatequery"
+)
+
+// QueryName identifies a cached query, for use with per-query TTL overrides
+type QueryName string
+
+const (
+	QuerySystemStart           QueryName = "SystemStart"
+	QueryEraHistory            QueryName = "EraHistory"
+	QueryCurrentEra            QueryName = "CurrentEra"
+	QueryEpochNo               QueryName = "EpochNo"
+	QueryCurrentProtocolParams QueryName = "CurrentProtocolParams"
+	QueryGenesisConfig         QueryName = "GenesisConfig"
+	QueryStakeDistribution     QueryName = "StakeDistribution"
+	QueryStakePools            QueryName = "StakePools"
+	QueryStakePoolParams       QueryName = "StakePoolParams"
+	QueryPoolDistr             QueryName = "PoolDistr"
+	QueryConstitution          QueryName = "Constitution"
+	QueryGovState              QueryName = "GovState"
+	QueryAccountState          QueryName = "AccountState"
+)
+
+// invalidation determines which chain changes cause a cached result to be dropped
+type invalidation uint
+
+const (
+	invalidateOnTip invalidation = iota
+	invalidateOnEpoch
+	invalidateNever
+)
+
+// queryInvalidation maps each query to the chain change that can change its result. Queries that aren't listed
+// are invalidated when the tip changes
+var queryInvalidation = map[QueryName]invalidation{
+	QuerySystemStart:           invalidateNever,
+	QueryGenesisConfig:         invalidateNever,
+	QueryEraHistory:            invalidateOnEpoch,
+	QueryCurrentEra:            invalidateOnEpoch,
+	QueryEpochNo:               invalidateOnEpoch,
+	QueryCurrentProtocolParams: invalidateOnEpoch,
+	QueryStakeDistribution:     invalidateOnEpoch,
+	QueryPoolDistr:             invalidateOnEpoch,
+	QueryConstitution:          invalidateOnEpoch,
+	QueryAccountState:          invalidateOnEpoch,
+}
+
+// Config is used to configure a Cache
+type Config struct {
+	// DefaultTTL is the maximum age of cached results. A TTL of zero means that results don't expire based on age
+	DefaultTTL time.Duration
+	// QueryTTLs overrides DefaultTTL for individual queries
+	QueryTTLs map[QueryName]time.Duration
+}
+
+// QueryCacheOptionFunc represents a function used to modify the Cache config
+type QueryCacheOptionFunc func(*Config)
+
+// NewConfig returns a new Cache config object with the provided options
+func NewConfig(options ...QueryCacheOptionFunc) Config {
+	c := Config{
+		QueryTTLs: make(map[QueryName]time.Duration),
+	}
+	// Apply provided options functions
+	for _, option := range options {
+		option(&c)
+	}
+	return c
+}
+
+// WithDefaultTTL specifies the maximum age of cached results for queries without a TTL override
+func WithDefaultTTL(ttl time.Duration) QueryCacheOptionFunc {
+	return func(c *Config) {
+		c.DefaultTTL = ttl
+	}
+}
+
+// WithQueryTTL specifies the maximum age of cached results for the specified query
+func WithQueryTTL(query QueryName, ttl time.Duration) QueryCacheOptionFunc {
+	return func(c *Config) {
+		c.QueryTTLs[query] = ttl
+	}
+}
+
+// Cache wraps a local-state-query client and caches query results
+//
+// The cache acquires a ledger state on the client to run queries that miss the cache, and releases it once no
+// more queries are waiting. Other uses of the client block while the cache's queries are running. Cached
+// results are shared between callers and must not be modified
+type Cache struct {
+	config      *Config
+	client      *localstatequery.Client
+	mutex       sync.Mutex
+	entries     map[string]*cacheEntry
+	tip         *common.Point
+	tipChanged  bool
+	epoch       *uint64
+	interpreter *hardfork.Interpreter
+	// queryWaiting is the number of queries running or waiting for queryMutex. It's guarded by mutex
+	queryWaiting int
+	// queryMutex serializes acquiring the ledger state and running queries against it
+	queryMutex    sync.Mutex
+	snapshot      *localstatequery.Snapshot
+	acquiredPoint *common.Point
+}
+
+type cacheEntry struct {
+	query   QueryName
+	expires time.Time
+	value   any
+}
+
+// New returns a new Cache object wrapping the provided local-state-query client
+func New(
+	client *localstatequery.Client,
+	options ...QueryCacheOptionFunc,
+) *Cache {
+	cfg := NewConfig(options...)
+	c := &Cache{
+		config:  &cfg,
+		client:  client,
+		entries: make(map[string]*cacheEntry),
+	}
+	return c
+}
+
+// ChainSyncOptions returns the options needed to keep the cache informed of the current tip. They must be
+// included in the chain-sync config for a connection to the same node as the local-state-query client.
+// Applications that handle the chain-sync callbacks themselves should call SetTip instead
+func (c *Cache) ChainSyncOptions() []chainsync.ChainSyncOptionFunc {
+	return []chainsync.ChainSyncOptionFunc{
+		chainsync.WithRollForwardFunc(
+			func(ctx chainsync.CallbackContext, blockType uint, blockData interface{}, tip chainsync.Tip) error {
+				c.SetTip(tip.Point)
+				return nil
+			},
+		),
+		chainsync.WithRollBackwardFunc(
+			func(ctx chainsync.CallbackContext, point common.Point, tip chainsync.Tip) error {
+				c.SetTip(tip.Point)
+				return nil
+			},
+		),
+	}
+}
+
+// SetTip updates the current chain tip. Cached results for queries that depend on the tip are dropped
+// immediately, and results for queries that only change at an epoch boundary are dropped on the next query
+// if the new tip is in a different epoch
+func (c *Cache) SetTip(point common.Point) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.tip != nil && pointsEqual(c.tip, &point) {
+		return
+	}
+	c.tip = &point
+	c.tipChanged = true
+	for key, entry := range c.entries {
+		if queryInvalidation[entry.query] == invalidateOnTip {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Invalidate drops all cached results
+func (c *Cache) Invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = make(map[string]*cacheEntry)
+}
+
+// Close releases the acquired ledger state, if any. The ledger state is normally released once no more queries
+// are waiting, so this is only needed to release it early. The cache acquires a new ledger state if it's used
+// again
+func (c *Cache) Close() error {
+	c.queryMutex.Lock()
+	defer c.queryMutex.Unlock()
+	if c.snapshot == nil {
+		return nil
+	}
+	err := c.snapshot.Close()
+	c.snapshot = nil
+	c.acquiredPoint = nil
+	return err
+}
+
+// GetSystemStart returns the SystemStart time
+func (c *Cache) GetSystemStart() (*localstatequery.SystemStartResult, error) {
+	return get(c, QuerySystemStart, nil, (*localstatequery.Snapshot).GetSystemStart)
+}
+
+// GetEraHistory returns the era history
+func (c *Cache) GetEraHistory() ([]localstatequery.EraHistoryResult, error) {
+	return get(c, QueryEraHistory, nil, (*localstatequery.Snapshot).GetEraHistory)
+}
+
+// GetCurrentEra returns the current era ID
+func (c *Cache) GetCurrentEra() (int, error) {
+	return get(c, QueryCurrentEra, nil, (*localstatequery.Snapshot).GetCurrentEra)
+}
+
+// GetEpochNo returns the current epoch number
+func (c *Cache) GetEpochNo() (int, error) {
+	return get(c, QueryEpochNo, nil, (*localstatequery.Snapshot).GetEpochNo)
+}
+
+// GetCurrentProtocolParams returns the set of protocol params that are currently in effect
+func (c *Cache) GetCurrentProtocolParams() (localstatequery.CurrentProtocolParamsResult, error) {
+	return get(c, QueryCurrentProtocolParams, nil, (*localstatequery.Snapshot).GetCurrentProtocolParams)
+}
+
+// GetGenesisConfig returns the Shelley genesis config
+func (c *Cache) GetGenesisConfig() (*localstatequery.GenesisConfigResult, error) {
+	return get(c, QueryGenesisConfig, nil, (*localstatequery.Snapshot).GetGenesisConfig)
+}
+
+// GetStakeDistribution returns the stake distribution
+func (c *Cache) GetStakeDistribution() (*localstatequery.StakeDistributionResult, error) {
+	return get(c, QueryStakeDistribution, nil, (*localstatequery.Snapshot).GetStakeDistribution)
+}
+
+// GetStakePools returns the IDs of all registered stake pools
+func (c *Cache) GetStakePools() (*localstatequery.StakePoolsResult, error) {
+	return get(c, QueryStakePools, nil, (*localstatequery.Snapshot).GetStakePools)
+}
+
+// GetStakePoolParams returns the params of the specified stake pools
+func (c *Cache) GetStakePoolParams(
+	poolIds []ledger.PoolId,
+) (*localstatequery.StakePoolParamsResult, error) {
+	return get(
+		c,
+		QueryStakePoolParams,
+		[]any{poolIds},
+		func(s *localstatequery.Snapshot) (*localstatequery.StakePoolParamsResult, error) {
+			return s.GetStakePoolParams(poolIds)
+		},
+	)
+}
+
+// GetPoolDistr returns the stake distribution for the specified pools. All pools are returned if poolIds is nil
+func (c *Cache) GetPoolDistr(
+	poolIds []ledger.PoolId,
+) (*localstatequery.PoolDistrResult, error) {
+	return get(
+		c,
+		QueryPoolDistr,
+		// A nil list and an empty list request different things
+		[]any{poolIds == nil, poolIds},
+		func(s *localstatequery.Snapshot) (*localstatequery.PoolDistrResult, error) {
+			return s.GetPoolDistr(poolIds)
+		},
+	)
+}
+
+// GetConstitution returns the current constitution
+func (c *Cache) GetConstitution() (*localstatequery.ConstitutionResult, error) {
+	return get(c, QueryConstitution, nil, (*localstatequery.Snapshot).GetConstitution)
+}
+
+// GetGovState returns the governance state
+func (c *Cache) GetGovState() (*localstatequery.GovStateResult, error) {
+	return get(c, QueryGovState, nil, (*localstatequery.Snapshot).GetGovState)
+}
+
+// GetAccountState returns the treasury and reserves balances
+func (c *Cache) GetAccountState() (*localstatequery.AccountStateResult, error) {
+	return get(c, QueryAccountState, nil, (*localstatequery.Snapshot).GetAccountState)
+}
+
+// get returns the cached result for the query and params, or runs the query and caches the result
+func get[T any](
+	c *Cache,
+	query QueryName,
+	params []any,
+	queryFunc func(*localstatequery.Snapshot) (T, error),
+) (T, error) {
+	if err := c.refreshEpoch(); err != nil {
+		var ret T
+		return ret, err
+	}
+	return getCached(c, query, params, queryFunc)
+}
+
+// getCached is like get, but it doesn't check for an epoch change first
+func getCached[T any](
+	c *Cache,
+	query QueryName,
+	params []any,
+	queryFunc func(*localstatequery.Snapshot) (T, error),
+) (T, error) {
+	var ret T
+	c.mutex.Lock()
+	key, err := cacheKey(query, c.tip, c.epoch, params)
+	if err != nil {
+		c.mutex.Unlock()
+		return ret, err
+	}
+	if entry, ok := c.entries[key]; ok && c.entryValid(entry) {
+		c.mutex.Unlock()
+		return entry.value.(T), nil
+	}
+	// Record the epoch before running the query, so that a result is never associated with a newer epoch than
+	// the one it was queried in
+	epoch := c.epoch
+	c.mutex.Unlock()
+	ret, point, err := runQuery(c, queryFunc)
+	if err != nil {
+		return ret, err
+	}
+	ttl := c.queryTTL(query)
+	// Results that depend on an unknown tip or epoch can only be cached with a TTL
+	switch queryInvalidation[query] {
+	case invalidateOnTip:
+		if point == nil && ttl == 0 {
+			return ret, nil
+		}
+	case invalidateOnEpoch:
+		if epoch == nil && ttl == 0 {
+			return ret, nil
+		}
+	}
+	// The result is keyed on the point that was acquired, which may differ from the tip used for the lookup
+	key, err = cacheKey(query, point, epoch, params)
+	if err != nil {
+		return ret, err
+	}
+	entry := &cacheEntry{
+		query: query,
+		value: ret,
+	}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	c.mutex.Lock()
+	c.entries[key] = entry
+	c.mutex.Unlock()
+	return ret, nil
+}
+
+// runQuery runs the query against the ledger state at the current tip, and returns the result along with the
+// point that was acquired. The ledger state is reacquired first if the tip has changed since it was acquired.
+// Without a known tip, the ledger state is reacquired at the node's current tip for each query. The ledger state
+// is released once no more queries are waiting
+func runQuery[T any](
+	c *Cache,
+	queryFunc func(*localstatequery.Snapshot) (T, error),
+) (ret T, point *common.Point, err error) {
+	c.mutex.Lock()
+	c.queryWaiting++
+	c.mutex.Unlock()
+	c.queryMutex.Lock()
+	defer func() {
+		c.mutex.Lock()
+		c.queryWaiting--
+		release := c.queryWaiting == 0
+		c.mutex.Unlock()
+		if release && c.snapshot != nil {
+			releaseErr := c.snapshot.Close()
+			c.snapshot = nil
+			c.acquiredPoint = nil
+			if err == nil && releaseErr != nil {
+				var zero T
+				ret, point, err = zero, nil, releaseErr
+			}
+		}
+		c.queryMutex.Unlock()
+	}()
+	c.mutex.Lock()
+	tip := c.tip
+	c.mutex.Unlock()
+	if c.snapshot == nil {
+		snapshot, err := c.client.Acquire(tip)
+		if err != nil {
+			return ret, nil, err
+		}
+		c.snapshot = snapshot
+		c.acquiredPoint = tip
+	} else if tip == nil || !pointsEqual(tip, c.acquiredPoint) {
+		if err := c.snapshot.ReAcquire(tip); err != nil {
+			// The acquired state is lost after a failure, so start over with the next query
+			_ = c.snapshot.Close()
+			c.snapshot = nil
+			c.acquiredPoint = nil
+			return ret, nil, err
+		}
+		c.acquiredPoint = tip
+	}
+	ret, err = queryFunc(c.snapshot)
+	if err != nil {
+		return ret, nil, err
+	}
+	return ret, c.acquiredPoint, nil
+}
+
+// entryValid returns whether a cached entry can be used based on its age. The tip and epoch that the entry
+// depends on are part of its key
+func (c *Cache) entryValid(entry *cacheEntry) bool {
+	return entry.expires.IsZero() || !time.Now().After(entry.expires)
+}
+
+func (c *Cache) queryTTL(query QueryName) time.Duration {
+	if ttl, ok := c.config.QueryTTLs[query]; ok {
+		return ttl
+	}
+	return c.config.DefaultTTL
+}
+
+// refreshEpoch calculates the epoch for a changed tip and drops cached results from a previous epoch
+func (c *Cache) refreshEpoch() error {
+	c.mutex.Lock()
+	if !c.tipChanged {
+		c.mutex.Unlock()
+		return nil
+	}
+	tip := c.tip
+	interpreter := c.interpreter
+	c.mutex.Unlock()
+	epoch, interpreter, err := c.epochForSlot(interpreter, tip.Slot)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.interpreter = interpreter
+	// Leave the tip marked as changed if it was updated while calculating the epoch
+	if pointsEqual(tip, c.tip) {
+		c.tipChanged = false
+	}
+	if c.epoch != nil && *c.epoch == epoch {
+		return nil
+	}
+	c.epoch = &epoch
+	for key, entry := range c.entries {
+		if queryInvalidation[entry.query] == invalidateOnEpoch {
+			delete(c.entries, key)
+		}
+	}
+	return nil
+}
+
+// epochForSlot returns the epoch for the slot, fetching the era history when the interpreter is missing or
+// the slot is past its horizon
+func (c *Cache) epochForSlot(
+	interpreter *hardfork.Interpreter,
+	slot uint64,
+) (uint64, *hardfork.Interpreter, error) {
+	if interpreter != nil {
+		epoch, _, err := interpreter.SlotToEpoch(slot)
+		if err == nil {
+			return epoch, interpreter, nil
+		}
+		var pastHorizonErr hardfork.PastHorizonError
+		if !errors.As(err, &pastHorizonErr) {
+			return 0, nil, err
+		}
+	}
+	systemStart, err := getCached(c, QuerySystemStart, nil, (*localstatequery.Snapshot).GetSystemStart)
+	if err != nil {
+		return 0, nil, err
+	}
+	// The era history is queried directly, since the cached copy may be what is out of date
+	eraHistory, _, err := runQuery(c, (*localstatequery.Snapshot).GetEraHistory)
+	if err != nil {
+		return 0, nil, err
+	}
+	interpreter, err = hardfork.NewInterpreterFromQuery(systemStart, eraHistory)
+	if err != nil {
+		return 0, nil, err
+	}
+	epoch, _, err := interpreter.SlotToEpoch(slot)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to calculate epoch for tip: %w", err)
+	}
+	return epoch, interpreter, nil
+}
+
+// cacheKey builds the cache key for a query and its params. Queries that depend on the tip are keyed on the
+// acquired point, and queries that depend on the epoch are keyed on the epoch
+func cacheKey(
+	query QueryName,
+	point *common.Point,
+	epoch *uint64,
+	params []any,
+) (string, error) {
+	key := string(query)
+	switch queryInvalidation[query] {
+	case invalidateOnTip:
+		if point == nil {
+			key += "@tip:unknown"
+		} else {
+			key += fmt.Sprintf("@tip:%d.%x", point.Slot, point.Hash)
+		}
+	case invalidateOnEpoch:
+		if epoch == nil {
+			key += "@epoch:unknown"
+		} else {
+			key += fmt.Sprintf("@epoch:%d", *epoch)
+		}
+	}
+	if len(params) == 0 {
+		return key, nil
+	}
+	paramsCbor, err := cbor.Encode(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode query params: %w", err)
+	}
+	return key + ":" + hex.EncodeToString(paramsCbor), nil
+}
+
+func pointsEqual(a *common.Point, b *common.Point) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Slot == b.Slot && bytes.Equal(a.Hash, b.Hash)
+}
@@ -0,0 +1,387 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querycache_test
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	ouroboros "github.com/blinklabs-io/gouroboros"
+	"github.com/blinklabs-io/gouroboros/cbor"
+	"github.com/blinklabs-io/gouroboros/ledger"
+	ocommon "github.com/blinklabs-io/gouroboros/protocol/common"
+	"github.com/blinklabs-io/gouroboros/protocol/localstatequery"
+	"github.com/blinklabs-io/gouroboros/querycache"
+	"go.uber.org/goleak"
+)
+
+// testQueryHandler answers queries from static data and the acquired point, and counts the queries that it
+// receives
+type testQueryHandler struct {
+	localstatequery.UnimplementedQueryHandler
+	eraHistory []localstatequery.EraHistoryResult
+	counts     map[string]int
+	mutex      sync.Mutex
+	point      *ocommon.Point
+	acquires   []*ocommon.Point
+	releases   int
+}
+
+func newTestQueryHandler(t *testing.T) *testQueryHandler {
+	// A single era with 100 slot epochs and 1s slots, which ends at slot 1000
+	eraHistoryCbor, err := cbor.Encode(
+		[]any{
+			[]any{
+				[]any{0, 0, 0},
+				[]any{uint64(1000 * 1_000_000_000_000), 1000, 10},
+				[]any{100, 1000, []any{0, 100, 0}},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	h := &testQueryHandler{
+		counts: make(map[string]int),
+	}
+	if _, err := cbor.Decode(eraHistoryCbor, &h.eraHistory); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return h
+}
+
+func (h *testQueryHandler) count(q localstatequery.Query) {
+	h.counts[fmt.Sprintf("%T", q)]++
+}
+
+func (h *testQueryHandler) acquire(
+	ctx localstatequery.CallbackContext,
+	point *ocommon.Point,
+) error {
+	h.mutex.Lock()
+	h.point = point
+	h.acquires = append(h.acquires, point)
+	h.mutex.Unlock()
+	return ctx.Server.SendMessage(localstatequery.NewMsgAcquired())
+}
+
+func (h *testQueryHandler) release(ctx localstatequery.CallbackContext) error {
+	h.mutex.Lock()
+	h.releases++
+	h.mutex.Unlock()
+	return nil
+}
+
+// acquiredSlot returns the slot of the acquired point, or 0 if the tip was acquired without a point
+func (h *testQueryHandler) acquiredSlot() uint64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.point == nil {
+		return 0
+	}
+	return h.point.Slot
+}
+
+func (h *testQueryHandler) HandleSystemStart(
+	ctx localstatequery.CallbackContext,
+	q *localstatequery.SystemStartQuery,
+) (*localstatequery.SystemStartResult, error) {
+	h.count(q)
+	return &localstatequery.SystemStartResult{
+		Year: 2022,
+		Day:  150,
+	}, nil
+}
+
+func (h *testQueryHandler) HandleEraHistory(
+	ctx localstatequery.CallbackContext,
+	q *localstatequery.EraHistoryQuery,
+) ([]localstatequery.EraHistoryResult, error) {
+	h.count(q)
+	return h.eraHistory, nil
+}
+
+func (h *testQueryHandler) HandleCurrentEra(
+	ctx localstatequery.CallbackContext,
+	q *localstatequery.CurrentEraQuery,
+) (int, error) {
+	h.count(q)
+	return ledger.EraIdConway, nil
+}
+
+func (h *testQueryHandler) HandleEpochNo(
+	ctx localstatequery.CallbackContext,
+	q *localstatequery.EpochNoQuery,
+) (int, error) {
+	h.count(q)
+	// 100 slot epochs
+	return int(h.acquiredSlot() / 100), nil
+}
+
+func (h *testQueryHandler) HandleStakePools(
+	ctx localstatequery.CallbackContext,
+	q *localstatequery.StakePoolsQuery,
+) (*localstatequery.StakePoolsResult, error) {
+	h.count(q)
+	return &localstatequery.StakePoolsResult{
+		// The pool ID depends on the acquired point
+		Results: []ledger.PoolId{{byte(h.acquiredSlot())}},
+	}, nil
+}
+
+func newTestConns(
+	t *testing.T,
+	handler *testQueryHandler,
+) (*ouroboros.Connection, *ouroboros.Connection) {
+	serverCfg := localstatequery.NewConfig(
+		localstatequery.WithQueryHandler(handler),
+		localstatequery.WithAcquireFunc(handler.acquire),
+		localstatequery.WithReAcquireFunc(handler.acquire),
+		localstatequery.WithReleaseFunc(handler.release),
+	)
+	clientConn, serverConn := net.Pipe()
+	serverConnChan := make(chan *ouroboros.Connection, 1)
+	go func() {
+		oConn, err := ouroboros.New(
+			ouroboros.WithConnection(serverConn),
+			ouroboros.WithNetworkMagic(ouroboros.NetworkPreview.NetworkMagic),
+			ouroboros.WithServer(true),
+			ouroboros.WithLocalStateQueryConfig(serverCfg),
+		)
+		if err != nil {
+			panic(err)
+		}
+		serverConnChan <- oConn
+	}()
+	oConn, err := ouroboros.New(
+		ouroboros.WithConnection(clientConn),
+		ouroboros.WithNetworkMagic(ouroboros.NetworkPreview.NetworkMagic),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error when creating Ouroboros object: %s", err)
+	}
+	return oConn, <-serverConnChan
+}
+
+func closeTestConns(t *testing.T, conns ...*ouroboros.Connection) {
+	for _, oConn := range conns {
+		if err := oConn.Close(); err != nil {
+			t.Fatalf("unexpected error when closing Ouroboros object: %s", err)
+		}
+		select {
+		case <-oConn.ErrorChan():
+		case <-time.After(10 * time.Second):
+			t.Errorf("did not shutdown within timeout")
+		}
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	handler := newTestQueryHandler(t)
+	clientConn, serverConn := newTestConns(t, handler)
+	defer closeTestConns(t, clientConn, serverConn)
+	cache := querycache.New(clientConn.LocalStateQuery().Client)
+	defer cache.Close()
+	testDefs := []struct {
+		tipSlot            uint64
+		expectedEpoch      int
+		expectedEpochNo    int
+		expectedStakePools int
+	}{
+		{
+			tipSlot:            10,
+			expectedEpoch:      0,
+			expectedEpochNo:    1,
+			expectedStakePools: 1,
+		},
+		// A new tip in the same epoch only invalidates the tip-dependent results
+		{
+			tipSlot:            20,
+			expectedEpoch:      0,
+			expectedEpochNo:    1,
+			expectedStakePools: 2,
+		},
+		// A new tip in the next epoch invalidates the epoch-dependent results
+		{
+			tipSlot:            150,
+			expectedEpoch:      1,
+			expectedEpochNo:    2,
+			expectedStakePools: 3,
+		},
+	}
+	for _, testDef := range testDefs {
+		cache.SetTip(ocommon.NewPoint(testDef.tipSlot, []byte{0xab}))
+		// Repeated queries should be answered from the cache
+		for i := 0; i < 2; i++ {
+			epochNo, err := cache.GetEpochNo()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if epochNo != testDef.expectedEpoch {
+				t.Fatalf("did not get expected epoch at slot %d: got %d, expected %d", testDef.tipSlot, epochNo, testDef.expectedEpoch)
+			}
+			// The result must come from the ledger state at the new tip
+			stakePools, err := cache.GetStakePools()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			expectedPools := []ledger.PoolId{{byte(testDef.tipSlot)}}
+			if !reflect.DeepEqual(stakePools.Results, expectedPools) {
+				t.Fatalf("did not get expected stake pools at slot %d: got %v, expected %v", testDef.tipSlot, stakePools.Results, expectedPools)
+			}
+		}
+		if count := handler.counts["*localstatequery.EpochNoQuery"]; count != testDef.expectedEpochNo {
+			t.Fatalf("did not get expected epoch query count at slot %d: got %d, expected %d", testDef.tipSlot, count, testDef.expectedEpochNo)
+		}
+		if count := handler.counts["*localstatequery.StakePoolsQuery"]; count != testDef.expectedStakePools {
+			t.Fatalf("did not get expected stake pools query count at slot %d: got %d, expected %d", testDef.tipSlot, count, testDef.expectedStakePools)
+		}
+	}
+	// The system start and era history are only needed once to calculate epochs
+	if count := handler.counts["*localstatequery.SystemStartQuery"]; count != 1 {
+		t.Fatalf("did not get expected system start query count: got %d, expected %d", count, 1)
+	}
+	if count := handler.counts["*localstatequery.EraHistoryQuery"]; count != 1 {
+		t.Fatalf("did not get expected era history query count: got %d, expected %d", count, 1)
+	}
+	// A tip past the era history horizon can't be mapped to an epoch
+	cache.SetTip(ocommon.NewPoint(1500, []byte{0xab}))
+	if _, err := cache.GetEpochNo(); err == nil {
+		t.Fatalf("did not get expected error for tip past horizon")
+	}
+	if count := handler.counts["*localstatequery.EraHistoryQuery"]; count != 2 {
+		t.Fatalf("did not get expected era history query count: got %d, expected %d", count, 2)
+	}
+	// The ledger state is acquired at each new tip, and released after each query
+	var acquiredSlots []uint64
+	handler.mutex.Lock()
+	for _, point := range handler.acquires {
+		if point == nil {
+			t.Fatalf("ledger state was acquired without a point")
+		}
+		if len(acquiredSlots) == 0 || acquiredSlots[len(acquiredSlots)-1] != point.Slot {
+			acquiredSlots = append(acquiredSlots, point.Slot)
+		}
+	}
+	handler.mutex.Unlock()
+	expectedSlots := []uint64{10, 20, 150, 1500}
+	if !reflect.DeepEqual(acquiredSlots, expectedSlots) {
+		t.Fatalf("did not get expected acquired slots: got %v, expected %v", acquiredSlots, expectedSlots)
+	}
+	// The server doesn't reply to a release, so we wait for it to be handled
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		handler.mutex.Lock()
+		acquireCount := len(handler.acquires)
+		releaseCount := handler.releases
+		handler.mutex.Unlock()
+		if releaseCount == acquireCount {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("ledger state was not released after each query: %d acquires, %d releases", acquireCount, releaseCount)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCacheSharedClient(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	handler := newTestQueryHandler(t)
+	clientConn, serverConn := newTestConns(t, handler)
+	defer closeTestConns(t, clientConn, serverConn)
+	client := clientConn.LocalStateQuery().Client
+	cache := querycache.New(client)
+	cache.SetTip(ocommon.NewPoint(10, []byte{0xab}))
+	if _, err := cache.GetStakePools(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// The client can be used directly between cache queries without closing the cache
+	doneChan := make(chan error, 1)
+	go func() {
+		_, err := client.GetCurrentEra()
+		doneChan <- err
+	}()
+	select {
+	case err := <-doneChan:
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("client was still in use by the cache")
+	}
+	if _, err := cache.GetStakePools(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if count := handler.counts["*localstatequery.StakePoolsQuery"]; count != 1 {
+		t.Fatalf("did not get expected stake pools query count: got %d, expected %d", count, 1)
+	}
+}
+
+func TestCacheQueryTTL(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	handler := newTestQueryHandler(t)
+	clientConn, serverConn := newTestConns(t, handler)
+	defer closeTestConns(t, clientConn, serverConn)
+	cache := querycache.New(
+		clientConn.LocalStateQuery().Client,
+		querycache.WithQueryTTL(querycache.QueryStakePools, 50*time.Millisecond),
+	)
+	defer cache.Close()
+	for i := 0; i < 2; i++ {
+		if _, err := cache.GetStakePools(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, err := cache.GetSystemStart(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := cache.GetStakePools(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := cache.GetSystemStart(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// Without a tip, results that depend on the epoch aren't cached without a TTL
+	for i := 0; i < 2; i++ {
+		if _, err := cache.GetEpochNo(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if count := handler.counts["*localstatequery.EpochNoQuery"]; count != 2 {
+		t.Fatalf("did not get expected epoch query count: got %d, expected %d", count, 2)
+	}
+	// Only the query with a TTL override should have expired
+	if count := handler.counts["*localstatequery.StakePoolsQuery"]; count != 2 {
+		t.Fatalf("did not get expected stake pools query count: got %d, expected %d", count, 2)
+	}
+	if count := handler.counts["*localstatequery.SystemStartQuery"]; count != 1 {
+		t.Fatalf("did not get expected system start query count: got %d, expected %d", count, 1)
+	}
+	// Invalidating the cache drops all results
+	cache.Invalidate()
+	if _, err := cache.GetSystemStart(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if count := handler.counts["*localstatequery.SystemStartQuery"]; count != 2 {
+		t.Fatalf("did not get expected system start query count: got %d, expected %d", count, 2)
+	}
+}
@@ -0,0 +1,364 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package txcollector provides a managed inbound tx-submission loop for collecting transactions from peers
+//
+// A Collector runs the tx-submission server loop for each peer that sends the Init message. Transaction IDs are
+// deduplicated across all peers, so each transaction body is only fetched once. Fetched transactions are
+// decoded and passed to an optional accept function before being delivered through a shared channel.
+// Transactions that can't be decoded or that are rejected are reported to an optional error function.
+package txcollector
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/blinklabs-io/gouroboros/connection"
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/protocol/txsubmission"
+)
+
+// Tx represents a transaction collected from a peer
+type Tx struct {
+	ConnectionId connection.ConnectionId
+	TxId         txsubmission.TxId
+	Tx           ledger.Transaction
+}
+
+// AcceptFunc validates a transaction received from a peer. Returning an error rejects the transaction, and it
+// won't be fetched again from any peer until it's forgotten
+type AcceptFunc func(connection.ConnectionId, ledger.Transaction) error
+
+// ErrorFunc is called with a DecodeError or RejectError for each transaction from a peer that isn't delivered
+type ErrorFunc func(connection.ConnectionId, error)
+
+// DecodeError is reported when a transaction body received from a peer can't be decoded
+type DecodeError struct {
+	EraId uint16
+	Err   error
+}
+
+func (e DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode transaction for era ID %d: %s", e.EraId, e.Err)
+}
+
+func (e DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// RejectError is reported when a transaction is rejected by the accept function
+type RejectError struct {
+	TxId txsubmission.TxId
+	Err  error
+}
+
+func (e RejectError) Error() string {
+	return fmt.Sprintf("transaction %x rejected: %s", e.TxId.TxId, e.Err)
+}
+
+func (e RejectError) Unwrap() error {
+	return e.Err
+}
+
+// Config is used to configure a Collector
+type Config struct {
+	AcceptFunc AcceptFunc
+	ErrorFunc  ErrorFunc
+	BufferSize int
+	// MaxTxIds is the maximum number of transaction IDs requested from a peer at once
+	MaxTxIds int
+	// KnownTxTTL is how long a transaction ID is remembered for deduplication
+	KnownTxTTL time.Duration
+}
+
+// CollectorOptionFunc represents a function used to modify the Collector config
+type CollectorOptionFunc func(*Config)
+
+// NewConfig returns a new Collector config object with the provided options
+func NewConfig(options ...CollectorOptionFunc) Config {
+	c := Config{
+		BufferSize: 100,
+		MaxTxIds:   10,
+		KnownTxTTL: 1 * time.Hour,
+	}
+	// Apply provided options functions
+	for _, option := range options {
+		option(&c)
+	}
+	return c
+}
+
+// WithAcceptFunc specifies a function to validate transactions before they're delivered
+func WithAcceptFunc(acceptFunc AcceptFunc) CollectorOptionFunc {
+	return func(c *Config) {
+		c.AcceptFunc = acceptFunc
+	}
+}
+
+// WithErrorFunc specifies a function to be notified of transactions that couldn't be decoded or were rejected
+func WithErrorFunc(errorFunc ErrorFunc) CollectorOptionFunc {
+	return func(c *Config) {
+		c.ErrorFunc = errorFunc
+	}
+}
+
+// WithBufferSize specifies the number of transactions that can be buffered before peers stop being asked for
+// more transactions
+func WithBufferSize(size int) CollectorOptionFunc {
+	return func(c *Config) {
+		c.BufferSize = size
+	}
+}
+
+// WithMaxTxIds specifies the maximum number of transaction IDs requested from a peer at once
+func WithMaxTxIds(maxTxIds int) CollectorOptionFunc {
+	return func(c *Config) {
+		c.MaxTxIds = maxTxIds
+	}
+}
+
+// WithKnownTxTTL specifies how long a transaction ID is remembered for deduplication
+func WithKnownTxTTL(ttl time.Duration) CollectorOptionFunc {
+	return func(c *Config) {
+		c.KnownTxTTL = ttl
+	}
+}
+
+// knownTx tracks a transaction ID that has been seen from any peer
+type knownTx struct {
+	// fetching is true while a peer is fetching the transaction body
+	fetching bool
+	added    time.Time
+}
+
+// Collector collects transactions from the tx-submission protocol of any number of peers
+type Collector struct {
+	config     *Config
+	txChan     chan Tx
+	doneChan   chan struct{}
+	onceStop   sync.Once
+	knownMutex sync.Mutex
+	knownTxs   map[txsubmission.TxId]*knownTx
+}
+
+// New returns a new Collector object with the provided options
+func New(options ...CollectorOptionFunc) *Collector {
+	cfg := NewConfig(options...)
+	c := &Collector{
+		config:   &cfg,
+		txChan:   make(chan Tx, cfg.BufferSize),
+		doneChan: make(chan struct{}),
+		knownTxs: make(map[txsubmission.TxId]*knownTx),
+	}
+	return c
+}
+
+// TxSubmissionOptions returns the options needed to start collecting transactions from peers. They must be
+// included in the tx-submission config for each inbound connection
+func (c *Collector) TxSubmissionOptions() []txsubmission.TxSubmissionOptionFunc {
+	return []txsubmission.TxSubmissionOptionFunc{
+		txsubmission.WithInitFunc(c.handleInit),
+	}
+}
+
+// TxChan returns the channel of collected transactions
+func (c *Collector) TxChan() <-chan Tx {
+	return c.txChan
+}
+
+// Forget removes a transaction ID from the deduplication set, which allows it to be collected again. This is
+// typically called when a transaction leaves the mempool
+func (c *Collector) Forget(txId txsubmission.TxId) {
+	c.knownMutex.Lock()
+	defer c.knownMutex.Unlock()
+	delete(c.knownTxs, txId)
+}
+
+// Stop stops collecting transactions. Peer loops exit after their current request completes
+func (c *Collector) Stop() {
+	c.onceStop.Do(func() {
+		close(c.doneChan)
+	})
+}
+
+func (c *Collector) handleInit(ctx txsubmission.CallbackContext) error {
+	// The Init callback is called from the protocol message handler, so the peer loop must run separately
+	go c.runPeer(ctx.ConnectionId, ctx.Server)
+	return nil
+}
+
+func (c *Collector) runPeer(
+	connId connection.ConnectionId,
+	server *txsubmission.Server,
+) {
+	blocking := true
+	for {
+		select {
+		case <-c.doneChan:
+			return
+		default:
+		}
+		txIds, err := server.RequestTxIds(blocking, c.config.MaxTxIds)
+		if err != nil {
+			// The connection has closed
+			return
+		}
+		// Wait for new transactions when the peer has nothing more to offer
+		blocking = len(txIds) == 0
+		if len(txIds) == 0 {
+			continue
+		}
+		// The bodies must be fetched before the IDs are acknowledged by the next request
+		wantedTxIds := c.claimTxIds(txIds)
+		if len(wantedTxIds) == 0 {
+			continue
+		}
+		txBodies, err := server.RequestTxs(wantedTxIds)
+		if err != nil {
+			c.releaseTxIds(wantedTxIds)
+			return
+		}
+		if !c.processTxs(connId, wantedTxIds, txBodies) {
+			return
+		}
+	}
+}
+
+// claimTxIds returns the transaction IDs that aren't already known, and marks them as being fetched
+func (c *Collector) claimTxIds(
+	txIds []txsubmission.TxIdAndSize,
+) []txsubmission.TxId {
+	c.knownMutex.Lock()
+	defer c.knownMutex.Unlock()
+	now := time.Now()
+	ret := []txsubmission.TxId{}
+	for _, txIdAndSize := range txIds {
+		txId := txIdAndSize.TxId
+		if known, ok := c.knownTxs[txId]; ok {
+			if known.fetching || now.Sub(known.added) < c.config.KnownTxTTL {
+				continue
+			}
+		}
+		c.knownTxs[txId] = &knownTx{
+			fetching: true,
+			added:    now,
+		}
+		ret = append(ret, txId)
+	}
+	return ret
+}
+
+// releaseTxIds removes the transaction IDs that couldn't be fetched, so that they can be fetched from another
+// peer
+func (c *Collector) releaseTxIds(txIds []txsubmission.TxId) {
+	c.knownMutex.Lock()
+	defer c.knownMutex.Unlock()
+	for _, txId := range txIds {
+		if known, ok := c.knownTxs[txId]; ok && known.fetching {
+			delete(c.knownTxs, txId)
+		}
+	}
+}
+
+// markTxsFetched marks fetched transaction IDs as known, whether or not they were accepted
+func (c *Collector) markTxsFetched(txIds []txsubmission.TxId) {
+	c.knownMutex.Lock()
+	defer c.knownMutex.Unlock()
+	now := time.Now()
+	for _, txId := range txIds {
+		c.knownTxs[txId] = &knownTx{
+			added: now,
+		}
+	}
+	// Prune expired entries
+	for txId, known := range c.knownTxs {
+		if !known.fetching && now.Sub(known.added) >= c.config.KnownTxTTL {
+			delete(c.knownTxs, txId)
+		}
+	}
+}
+
+// processTxs decodes and validates the fetched transactions and delivers the accepted ones. It returns false if
+// the collector was stopped
+func (c *Collector) processTxs(
+	connId connection.ConnectionId,
+	txIds []txsubmission.TxId,
+	txBodies []txsubmission.TxBody,
+) bool {
+	// Match the bodies to the requested IDs by hash, since the peer omits transactions that it no longer has
+	txIdsByHash := make(map[string]txsubmission.TxId, len(txIds))
+	for _, txId := range txIds {
+		txIdsByHash[hex.EncodeToString(txId.TxId[:])] = txId
+	}
+	var fetchedTxIds []txsubmission.TxId
+	var acceptedTxs []Tx
+	decodeFailed := false
+	for _, txBody := range txBodies {
+		tx, err := ledger.NewTransactionFromCbor(uint(txBody.EraId), txBody.TxBody)
+		if err != nil {
+			decodeFailed = true
+			c.reportError(connId, DecodeError{EraId: txBody.EraId, Err: err})
+			continue
+		}
+		txId, ok := txIdsByHash[tx.Hash()]
+		if !ok {
+			continue
+		}
+		delete(txIdsByHash, tx.Hash())
+		fetchedTxIds = append(fetchedTxIds, txId)
+		if c.config.AcceptFunc != nil {
+			if err := c.config.AcceptFunc(connId, tx); err != nil {
+				c.reportError(connId, RejectError{TxId: txId, Err: err})
+				continue
+			}
+		}
+		acceptedTxs = append(
+			acceptedTxs,
+			Tx{
+				ConnectionId: connId,
+				TxId:         txId,
+				Tx:           tx,
+			},
+		)
+	}
+	missingTxIds := make([]txsubmission.TxId, 0, len(txIdsByHash))
+	for _, txId := range txIdsByHash {
+		missingTxIds = append(missingTxIds, txId)
+	}
+	if decodeFailed {
+		// A body that can't be decoded can't be matched to its ID, so the unmatched IDs are treated as fetched.
+		// Otherwise the same undecodable bodies would be fetched again from every peer
+		fetchedTxIds = append(fetchedTxIds, missingTxIds...)
+	} else {
+		// Transactions that weren't received can be fetched from another peer
+		c.releaseTxIds(missingTxIds)
+	}
+	c.markTxsFetched(fetchedTxIds)
+	for _, tx := range acceptedTxs {
+		select {
+		case c.txChan <- tx:
+		case <-c.doneChan:
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Collector) reportError(connId connection.ConnectionId, err error) {
+	if c.config.ErrorFunc != nil {
+		c.config.ErrorFunc(connId, err)
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package txcollector_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	ouroboros "github.com/blinklabs-io/gouroboros"
+	"github.com/blinklabs-io/gouroboros/cbor"
+	"github.com/blinklabs-io/gouroboros/connection"
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/protocol/txsubmission"
+	"github.com/blinklabs-io/gouroboros/txcollector"
+	"go.uber.org/goleak"
+)
+
+// testTx builds a minimal Conway transaction, which is made unique by its fee
+func testTx(t *testing.T, fee uint64) (txsubmission.TxId, txsubmission.TxBody) {
+	txCbor, err := cbor.Encode(
+		[]any{
+			map[uint]any{
+				0: []any{[]any{make([]byte, 32), 0}},
+				1: []any{},
+				2: fee,
+			},
+			map[uint]any{},
+			true,
+			nil,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tx, err := ledger.NewTransactionFromCbor(ledger.TxTypeConway, txCbor)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	txId := txsubmission.TxId{EraId: ledger.TxTypeConway}
+	txHash, err := hex.DecodeString(tx.Hash())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	copy(txId.TxId[:], txHash)
+	return txId, txsubmission.TxBody{EraId: ledger.TxTypeConway, TxBody: txCbor}
+}
+
+// testMempool is a static Mempool implementation that counts requests for each transaction body
+type testMempool struct {
+	sync.Mutex
+	txs      []txsubmission.MempoolTx
+	bodies   map[txsubmission.TxId]txsubmission.TxBody
+	fetches  map[txsubmission.TxId]int
+	waitChan chan struct{}
+}
+
+func newTestMempool(t *testing.T, fees ...uint64) *testMempool {
+	m := &testMempool{
+		bodies:   make(map[txsubmission.TxId]txsubmission.TxBody),
+		fetches:  make(map[txsubmission.TxId]int),
+		waitChan: make(chan struct{}),
+	}
+	for idx, fee := range fees {
+		txId, txBody := testTx(t, fee)
+		m.txs = append(
+			m.txs,
+			txsubmission.MempoolTx{
+				Position: uint64(idx + 1),
+				TxId:     txId,
+				Size:     uint32(len(txBody.TxBody)),
+			},
+		)
+		m.bodies[txId] = txBody
+	}
+	return m
+}
+
+func (m *testMempool) TxsAfter(position uint64, count int) []txsubmission.MempoolTx {
+	m.Lock()
+	defer m.Unlock()
+	var ret []txsubmission.MempoolTx
+	for _, tx := range m.txs {
+		if tx.Position > position && len(ret) < count {
+			ret = append(ret, tx)
+		}
+	}
+	return ret
+}
+
+func (m *testMempool) Tx(txId txsubmission.TxId) (txsubmission.TxBody, bool) {
+	m.Lock()
+	defer m.Unlock()
+	m.fetches[txId]++
+	body, ok := m.bodies[txId]
+	return body, ok
+}
+
+func (m *testMempool) WaitChan(position uint64) <-chan struct{} {
+	// No transactions are added after creation
+	return m.waitChan
+}
+
+func (m *testMempool) fetchCount(txId txsubmission.TxId) int {
+	m.Lock()
+	defer m.Unlock()
+	return m.fetches[txId]
+}
+
+// newPeerConns returns a connected pair of connections, with the client offering transactions from the mempool
+// to the collector
+func newPeerConns(
+	t *testing.T,
+	mempool *testMempool,
+	collector *txcollector.Collector,
+) (*ouroboros.Connection, *ouroboros.Connection) {
+	clientConn, serverConn := net.Pipe()
+	serverConnChan := make(chan *ouroboros.Connection, 1)
+	go func() {
+		oConn, err := ouroboros.New(
+			ouroboros.WithConnection(serverConn),
+			ouroboros.WithNetworkMagic(ouroboros.NetworkPreview.NetworkMagic),
+			ouroboros.WithServer(true),
+			ouroboros.WithNodeToNode(true),
+			ouroboros.WithTxSubmissionConfig(
+				txsubmission.NewConfig(collector.TxSubmissionOptions()...),
+			),
+		)
+		if err != nil {
+			panic(err)
+		}
+		serverConnChan <- oConn
+	}()
+	oConn, err := ouroboros.New(
+		ouroboros.WithConnection(clientConn),
+		ouroboros.WithNetworkMagic(ouroboros.NetworkPreview.NetworkMagic),
+		ouroboros.WithNodeToNode(true),
+		ouroboros.WithTxSubmissionConfig(
+			txsubmission.NewConfig(
+				txsubmission.WithMempool(mempool),
+			),
+		),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error when creating Ouroboros object: %s", err)
+	}
+	return oConn, <-serverConnChan
+}
+
+func closeTestConns(t *testing.T, conns ...*ouroboros.Connection) {
+	for _, oConn := range conns {
+		if err := oConn.Close(); err != nil {
+			t.Fatalf("unexpected error when closing Ouroboros object: %s", err)
+		}
+		select {
+		case <-oConn.ErrorChan():
+		case <-time.After(10 * time.Second):
+			t.Errorf("did not shutdown within timeout")
+		}
+	}
+}
+
+func TestCollector(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	tx1, _ := testTx(t, 1)
+	tx2, _ := testTx(t, 2)
+	tx3, _ := testTx(t, 3)
+	rejectedChan := make(chan txsubmission.TxId, 10)
+	collector := txcollector.New(
+		txcollector.WithAcceptFunc(
+			func(connId connection.ConnectionId, tx ledger.Transaction) error {
+				if tx.Fee() == 3 {
+					return errors.New("rejected")
+				}
+				return nil
+			},
+		),
+		txcollector.WithErrorFunc(
+			func(connId connection.ConnectionId, err error) {
+				var rejectErr txcollector.RejectError
+				if errors.As(err, &rejectErr) {
+					rejectedChan <- rejectErr.TxId
+				}
+			},
+		),
+	)
+	defer collector.Stop()
+	// Both peers offer the second transaction
+	mempool1 := newTestMempool(t, 1, 2)
+	mempool2 := newTestMempool(t, 2, 3)
+	clientConn1, serverConn1 := newPeerConns(t, mempool1, collector)
+	defer closeTestConns(t, clientConn1, serverConn1)
+	clientConn1.TxSubmission().Client.Init()
+	// Wait for the first peer to deliver its transactions before connecting the second
+	collectedTxs := map[txsubmission.TxId]int{}
+	for i := 0; i < 2; i++ {
+		select {
+		case tx := <-collector.TxChan():
+			collectedTxs[tx.TxId]++
+		case <-time.After(5 * time.Second):
+			t.Fatalf("did not receive expected transaction")
+		}
+	}
+	clientConn2, serverConn2 := newPeerConns(t, mempool2, collector)
+	defer closeTestConns(t, clientConn2, serverConn2)
+	clientConn2.TxSubmission().Client.Init()
+	select {
+	case txId := <-rejectedChan:
+		if txId != tx3 {
+			t.Fatalf("did not get expected rejected transaction: %x", txId.TxId)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("did not receive expected transaction")
+	}
+	// The rejected transaction is not delivered, and the duplicate is only delivered once
+	select {
+	case tx := <-collector.TxChan():
+		t.Fatalf("received unexpected transaction: %x", tx.TxId.TxId)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if collectedTxs[tx1] != 1 || collectedTxs[tx2] != 1 {
+		t.Fatalf("did not collect expected transactions: %v", collectedTxs)
+	}
+	if count := mempool2.fetchCount(tx2); count != 0 {
+		t.Fatalf("duplicate transaction was fetched %d times from second peer", count)
+	}
+}
+
+func TestCollectorUndecodableTx(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	decodeErrChan := make(chan error, 10)
+	collector := txcollector.New(
+		txcollector.WithErrorFunc(
+			func(connId connection.ConnectionId, err error) {
+				decodeErrChan <- err
+			},
+		),
+	)
+	defer collector.Stop()
+	// Both peers offer the same transaction with a body that can't be decoded
+	mempool1 := newTestMempool(t, 1)
+	mempool2 := newTestMempool(t, 1)
+	txId := mempool1.txs[0].TxId
+	for _, mempool := range []*testMempool{mempool1, mempool2} {
+		mempool.bodies[txId] = txsubmission.TxBody{EraId: ledger.TxTypeConway, TxBody: []byte{0x80}}
+	}
+	clientConn1, serverConn1 := newPeerConns(t, mempool1, collector)
+	defer closeTestConns(t, clientConn1, serverConn1)
+	clientConn1.TxSubmission().Client.Init()
+	select {
+	case err := <-decodeErrChan:
+		var decodeErr txcollector.DecodeError
+		if !errors.As(err, &decodeErr) {
+			t.Fatalf("did not get expected decode error, got: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("did not receive expected decode error")
+	}
+	// The transaction is not fetched again from another peer
+	clientConn2, serverConn2 := newPeerConns(t, mempool2, collector)
+	defer closeTestConns(t, clientConn2, serverConn2)
+	clientConn2.TxSubmission().Client.Init()
+	select {
+	case tx := <-collector.TxChan():
+		t.Fatalf("received unexpected transaction: %x", tx.TxId.TxId)
+	case err := <-decodeErrChan:
+		t.Fatalf("received unexpected error: %s", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if count := mempool2.fetchCount(txId); count != 0 {
+		t.Fatalf("undecodable transaction was fetched %d times from second peer", count)
+	}
+}
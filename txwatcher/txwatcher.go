@@ -0,0 +1,576 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package txwatcher provides a submit-and-confirm workflow for transactions
+//
+// A Watcher follows the chain using a BlockStream and reports the lifecycle of watched transactions: entering
+// the mempool, inclusion in a block, confirmation at a configured depth, rollback, and expiry past the
+// transaction TTL. Transactions can be submitted through the watcher using local-tx-submission, or watched
+// after being submitted elsewhere, in which case local-tx-monitor can optionally be polled to detect them in
+// the mempool.
+package txwatcher
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	ouroboros "github.com/blinklabs-io/gouroboros"
+	"github.com/blinklabs-io/gouroboros/blockstream"
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/protocol/chainsync"
+	"github.com/blinklabs-io/gouroboros/protocol/common"
+)
+
+// Status is an enum of the transaction lifecycle states
+type Status uint
+
+const (
+	StatusInMempool  Status = 1
+	StatusIncluded   Status = 2
+	StatusConfirmed  Status = 3
+	StatusRolledBack Status = 4
+	StatusExpired    Status = 5
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusInMempool:
+		return "InMempool"
+	case StatusIncluded:
+		return "Included"
+	case StatusConfirmed:
+		return "Confirmed"
+	case StatusRolledBack:
+		return "RolledBack"
+	case StatusExpired:
+		return "Expired"
+	}
+	return fmt.Sprintf("Status(%d)", uint(s))
+}
+
+// Event represents a change in the lifecycle of a watched transaction
+type Event struct {
+	Status Status
+	TxHash string
+	// Point is the point of the block that included the transaction for Included and Confirmed events, the
+	// rollback point for RolledBack events, and the first block at or past the TTL for Expired events
+	Point       common.Point
+	BlockNumber uint64
+	// Depth is the number of blocks on top of the block that included the transaction
+	Depth uint64
+}
+
+// EventFunc is called for each event on any watched transaction
+type EventFunc func(Event)
+
+// EventBufferFullError is returned by Watch.Err when the watch was closed because its event buffer was full
+var EventBufferFullError = errors.New("watch event buffer is full")
+
+// Config is used to configure a Watcher
+type Config struct {
+	// ConfirmationDepth is the number of blocks on top of the including block before a transaction is confirmed
+	ConfirmationDepth uint64
+	// MempoolPollInterval is how often local-tx-monitor is polled for watched transactions that haven't been
+	// seen in the mempool. Polling is disabled when this is zero
+	MempoolPollInterval time.Duration
+	BufferSize          int
+	EventFunc           EventFunc
+	BlockStreamOptions  []blockstream.BlockStreamOptionFunc
+}
+
+// WatcherOptionFunc represents a function used to modify the Watcher config
+type WatcherOptionFunc func(*Config)
+
+// NewConfig returns a new Watcher config object with the provided options
+func NewConfig(options ...WatcherOptionFunc) Config {
+	c := Config{
+		ConfirmationDepth: 10,
+		BufferSize:        10,
+	}
+	// Apply provided options functions
+	for _, option := range options {
+		option(&c)
+	}
+	return c
+}
+
+// WithConfirmationDepth specifies the number of blocks on top of the including block before a transaction is
+// confirmed
+func WithConfirmationDepth(depth uint64) WatcherOptionFunc {
+	return func(c *Config) {
+		c.ConfirmationDepth = depth
+	}
+}
+
+// WithMempoolPollInterval specifies how often local-tx-monitor is polled for watched transactions
+func WithMempoolPollInterval(interval time.Duration) WatcherOptionFunc {
+	return func(c *Config) {
+		c.MempoolPollInterval = interval
+	}
+}
+
+// WithBufferSize specifies the number of events that can be buffered for each watched transaction. A watch whose
+// buffer fills up is closed, and its Err method returns EventBufferFullError
+func WithBufferSize(size int) WatcherOptionFunc {
+	return func(c *Config) {
+		c.BufferSize = size
+	}
+}
+
+// WithEventFunc specifies a callback function for events on any watched transaction. It's called from the
+// goroutine that follows the chain, so it should not block
+func WithEventFunc(eventFunc EventFunc) WatcherOptionFunc {
+	return func(c *Config) {
+		c.EventFunc = eventFunc
+	}
+}
+
+// WithBlockStreamOptions specifies options for the underlying BlockStream
+func WithBlockStreamOptions(
+	options ...blockstream.BlockStreamOptionFunc,
+) WatcherOptionFunc {
+	return func(c *Config) {
+		c.BlockStreamOptions = options
+	}
+}
+
+// Watch tracks the lifecycle of a single transaction
+type Watch struct {
+	watcher   *Watcher
+	txHash    string
+	ttl       uint64
+	eventChan chan Event
+	sendMutex sync.Mutex
+	closed    bool
+	err       error
+	// The below are only accessed with the watcher mutex held
+	inMempool   bool
+	included    bool
+	point       common.Point
+	blockNumber uint64
+}
+
+// TxHash returns the hex-encoded hash of the watched transaction
+func (w *Watch) TxHash() string {
+	return w.txHash
+}
+
+// EventChan returns the channel of events for the transaction. It's closed after the transaction is confirmed
+// or expires, the watch is cancelled, or the buffer fills up because events aren't being read
+func (w *Watch) EventChan() <-chan Event {
+	return w.eventChan
+}
+
+// Err returns the reason the event channel was closed before the transaction was confirmed or expired, or nil
+func (w *Watch) Err() error {
+	w.sendMutex.Lock()
+	defer w.sendMutex.Unlock()
+	return w.err
+}
+
+// Cancel stops watching the transaction
+func (w *Watch) Cancel() {
+	w.watcher.removeWatch(w)
+	w.close()
+}
+
+func (w *Watch) close() {
+	w.closeWithError(nil)
+}
+
+func (w *Watch) closeWithError(err error) {
+	w.sendMutex.Lock()
+	defer w.sendMutex.Unlock()
+	if !w.closed {
+		close(w.eventChan)
+		w.closed = true
+		w.err = err
+	}
+}
+
+// sendEvent sends an event without blocking, so that a consumer that isn't reading events can't stall the
+// watcher. It returns false if the buffer is full
+func (w *Watch) sendEvent(evt Event) bool {
+	w.sendMutex.Lock()
+	defer w.sendMutex.Unlock()
+	if w.closed {
+		return true
+	}
+	select {
+	case w.eventChan <- evt:
+		return true
+	default:
+		return false
+	}
+}
+
+// Watcher follows the chain and reports the lifecycle of watched transactions
+type Watcher struct {
+	config        *Config
+	conn          *ouroboros.Connection
+	stream        *blockstream.BlockStream
+	errorChan     chan error
+	doneChan      chan struct{}
+	submittedChan chan *Watch
+	onceStop      sync.Once
+	mutex         sync.Mutex
+	watches       map[string]*Watch
+}
+
+// New returns a new Watcher object with the provided options
+func New(options ...WatcherOptionFunc) *Watcher {
+	cfg := NewConfig(options...)
+	w := &Watcher{
+		config:        &cfg,
+		stream:        blockstream.New(cfg.BlockStreamOptions...),
+		errorChan:     make(chan error, 1),
+		doneChan:      make(chan struct{}),
+		submittedChan: make(chan *Watch),
+		watches:       make(map[string]*Watch),
+	}
+	return w
+}
+
+// ChainSyncOptions returns the options needed to route chain-sync callbacks into the Watcher. They must be
+// included in the chain-sync config for the connection passed to Start
+func (w *Watcher) ChainSyncOptions() []chainsync.ChainSyncOptionFunc {
+	return w.stream.ChainSyncOptions()
+}
+
+// Start begins following the chain on the provided connection from the provided intersect point(s). The
+// intersect should be at or near the tip, since transactions are only matched in blocks received after they
+// are watched
+func (w *Watcher) Start(
+	conn *ouroboros.Connection,
+	intersectPoints []common.Point,
+) error {
+	w.conn = conn
+	if err := w.stream.Start(conn, intersectPoints); err != nil {
+		return err
+	}
+	go w.run()
+	return nil
+}
+
+// ErrorChan returns a channel that receives the error that stopped the Watcher
+func (w *Watcher) ErrorChan() <-chan error {
+	return w.errorChan
+}
+
+// Stop stops following the chain and cancels all watched transactions
+func (w *Watcher) Stop() {
+	w.onceStop.Do(func() {
+		close(w.doneChan)
+		_ = w.stream.Stop()
+		w.mutex.Lock()
+		watches := w.watches
+		w.watches = make(map[string]*Watch)
+		w.mutex.Unlock()
+		for _, watch := range watches {
+			watch.close()
+		}
+	})
+}
+
+// Watch starts watching a transaction that was submitted elsewhere. The TTL is the first slot in which the
+// transaction can no longer be included, or zero if it has none
+func (w *Watcher) Watch(txHash string, ttl uint64) (*Watch, error) {
+	if _, err := hex.DecodeString(txHash); err != nil {
+		return nil, fmt.Errorf("invalid transaction hash: %w", err)
+	}
+	return w.addWatch(txHash, ttl)
+}
+
+// Submit submits a transaction using local-tx-submission and starts watching it. The StatusInMempool event is
+// sent once the node accepts the transaction, and a rejection is returned as an error
+func (w *Watcher) Submit(tx ledger.Transaction) (*Watch, error) {
+	if w.conn == nil || w.conn.LocalTxSubmission() == nil {
+		return nil, errors.New("local-tx-submission is not available")
+	}
+	// Start watching before submitting, so that we can't miss the transaction being included
+	watch, err := w.addWatch(tx.Hash(), tx.TTL())
+	if err != nil {
+		return nil, err
+	}
+	result, err := w.conn.LocalTxSubmission().Client.SubmitTransaction(tx)
+	if err != nil {
+		watch.Cancel()
+		return nil, err
+	}
+	if !result.Accepted {
+		watch.Cancel()
+		return nil, result.Rejection
+	}
+	// The event is sent from the run loop, so that it can't be reordered with the chain events
+	select {
+	case w.submittedChan <- watch:
+	case <-w.doneChan:
+	}
+	return watch, nil
+}
+
+func (w *Watcher) addWatch(txHash string, ttl uint64) (*Watch, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	select {
+	case <-w.doneChan:
+		return nil, errors.New("watcher is stopped")
+	default:
+	}
+	if _, ok := w.watches[txHash]; ok {
+		return nil, fmt.Errorf("transaction %s is already being watched", txHash)
+	}
+	watch := &Watch{
+		watcher:   w,
+		txHash:    txHash,
+		ttl:       ttl,
+		eventChan: make(chan Event, w.config.BufferSize),
+	}
+	w.watches[txHash] = watch
+	return watch, nil
+}
+
+func (w *Watcher) removeWatch(watch *Watch) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.watches[watch.txHash] == watch {
+		delete(w.watches, watch.txHash)
+	}
+}
+
+func (w *Watcher) sendEvent(watch *Watch, evt Event) {
+	if w.config.EventFunc != nil {
+		w.config.EventFunc(evt)
+	}
+	if !watch.sendEvent(evt) {
+		w.removeWatch(watch)
+		watch.closeWithError(EventBufferFullError)
+	}
+}
+
+func (w *Watcher) run() {
+	var tickerChan <-chan time.Time
+	if w.config.MempoolPollInterval > 0 && w.conn.LocalTxMonitor() != nil {
+		ticker := time.NewTicker(w.config.MempoolPollInterval)
+		defer ticker.Stop()
+		tickerChan = ticker.C
+	}
+	for {
+		select {
+		case <-w.doneChan:
+			return
+		case err := <-w.stream.ErrorChan():
+			w.errorChan <- err
+			w.Stop()
+			return
+		case evt := <-w.stream.EventChan():
+			switch evt.Type {
+			case blockstream.EventTypeRollForward:
+				w.handleRollForward(evt)
+			case blockstream.EventTypeRollBackward:
+				w.handleRollBackward(evt)
+			}
+		case watch := <-w.submittedChan:
+			w.handleSubmitted(watch)
+		case <-tickerChan:
+			if err := w.pollMempool(); err != nil {
+				w.errorChan <- fmt.Errorf("failed to poll mempool: %w", err)
+				w.Stop()
+				return
+			}
+		}
+	}
+}
+
+// pendingEvent is an event to be sent after the watcher mutex is released
+type pendingEvent struct {
+	watch *Watch
+	event Event
+	// done indicates that the watch is finished after this event
+	done bool
+}
+
+func (w *Watcher) sendPendingEvents(events []pendingEvent) {
+	for _, evt := range events {
+		w.sendEvent(evt.watch, evt.event)
+		if evt.done {
+			evt.watch.close()
+		}
+	}
+}
+
+func (w *Watcher) handleSubmitted(watch *Watch) {
+	w.mutex.Lock()
+	// The transaction may have already been seen in the mempool or included
+	sendEvent := !watch.inMempool && !watch.included
+	watch.inMempool = true
+	w.mutex.Unlock()
+	if sendEvent {
+		w.sendEvent(
+			watch,
+			Event{
+				Status: StatusInMempool,
+				TxHash: watch.txHash,
+			},
+		)
+	}
+}
+
+func (w *Watcher) handleRollForward(evt blockstream.Event) {
+	block := evt.Block
+	blockNumber := block.BlockNumber()
+	var events []pendingEvent
+	w.mutex.Lock()
+	// Check for watched transactions in the block
+	for _, tx := range block.Transactions() {
+		watch, ok := w.watches[tx.Hash()]
+		if !ok || watch.included {
+			continue
+		}
+		watch.included = true
+		watch.point = evt.Point
+		watch.blockNumber = blockNumber
+		events = append(
+			events,
+			pendingEvent{
+				watch: watch,
+				event: Event{
+					Status:      StatusIncluded,
+					TxHash:      watch.txHash,
+					Point:       evt.Point,
+					BlockNumber: blockNumber,
+				},
+			},
+		)
+	}
+	for txHash, watch := range w.watches {
+		if watch.included {
+			// Check for confirmation
+			depth := blockNumber - watch.blockNumber
+			if depth < w.config.ConfirmationDepth {
+				continue
+			}
+			delete(w.watches, txHash)
+			events = append(
+				events,
+				pendingEvent{
+					watch: watch,
+					event: Event{
+						Status:      StatusConfirmed,
+						TxHash:      watch.txHash,
+						Point:       watch.point,
+						BlockNumber: watch.blockNumber,
+						Depth:       depth,
+					},
+					done: true,
+				},
+			)
+		} else if watch.ttl > 0 && block.SlotNumber() >= watch.ttl {
+			// The transaction can no longer be included
+			delete(w.watches, txHash)
+			events = append(
+				events,
+				pendingEvent{
+					watch: watch,
+					event: Event{
+						Status:      StatusExpired,
+						TxHash:      watch.txHash,
+						Point:       evt.Point,
+						BlockNumber: blockNumber,
+					},
+					done: true,
+				},
+			)
+		}
+	}
+	w.mutex.Unlock()
+	w.sendPendingEvents(events)
+}
+
+func (w *Watcher) handleRollBackward(evt blockstream.Event) {
+	var events []pendingEvent
+	w.mutex.Lock()
+	for _, watch := range w.watches {
+		if !watch.included || watch.point.Slot <= evt.Point.Slot {
+			continue
+		}
+		// The including block was rolled back, so the transaction may be included again
+		watch.included = false
+		events = append(
+			events,
+			pendingEvent{
+				watch: watch,
+				event: Event{
+					Status: StatusRolledBack,
+					TxHash: watch.txHash,
+					Point:  evt.Point,
+				},
+			},
+		)
+	}
+	w.mutex.Unlock()
+	w.sendPendingEvents(events)
+}
+
+func (w *Watcher) pollMempool() error {
+	w.mutex.Lock()
+	var watches []*Watch
+	for _, watch := range w.watches {
+		if !watch.inMempool && !watch.included {
+			watches = append(watches, watch)
+		}
+	}
+	w.mutex.Unlock()
+	if len(watches) == 0 {
+		return nil
+	}
+	client := w.conn.LocalTxMonitor().Client
+	var events []pendingEvent
+	for _, watch := range watches {
+		// The hash was validated when the watch was added
+		txHash, _ := hex.DecodeString(watch.txHash)
+		// This acquires a new mempool snapshot if one isn't already held
+		found, err := client.HasTx(txHash)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		w.mutex.Lock()
+		if !watch.inMempool && !watch.included {
+			watch.inMempool = true
+			events = append(
+				events,
+				pendingEvent{
+					watch: watch,
+					event: Event{
+						Status: StatusInMempool,
+						TxHash: watch.txHash,
+					},
+				},
+			)
+		}
+		w.mutex.Unlock()
+	}
+	// Release the snapshot so that the next poll acquires a current one
+	if err := client.Release(); err != nil {
+		return err
+	}
+	w.sendPendingEvents(events)
+	return nil
+}
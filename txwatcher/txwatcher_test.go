@@ -0,0 +1,661 @@
+// Copyright 2024 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package txwatcher_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	ouroboros "github.com/blinklabs-io/gouroboros"
+	"github.com/blinklabs-io/gouroboros/cbor"
+	"github.com/blinklabs-io/gouroboros/internal/test"
+	"github.com/blinklabs-io/gouroboros/ledger"
+	"github.com/blinklabs-io/gouroboros/protocol"
+	"github.com/blinklabs-io/gouroboros/protocol/chainsync"
+	ocommon "github.com/blinklabs-io/gouroboros/protocol/common"
+	"github.com/blinklabs-io/gouroboros/protocol/localtxmonitor"
+	"github.com/blinklabs-io/gouroboros/protocol/localtxsubmission"
+	"github.com/blinklabs-io/gouroboros/txwatcher"
+
+	ouroboros_mock "github.com/blinklabs-io/ouroboros-mock"
+	"go.uber.org/goleak"
+)
+
+// testTx builds a minimal Babbage transaction, which is made unique by its fee
+func testTx(t *testing.T, fee uint64) ([]byte, ledger.Transaction) {
+	txCbor, err := cbor.Encode(
+		[]any{
+			map[uint]any{
+				0: []any{[]any{make([]byte, 32), 0}},
+				1: []any{},
+				2: fee,
+			},
+			map[uint]any{},
+			true,
+			nil,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tx, err := ledger.NewTransactionFromCbor(ledger.TxTypeBabbage, txCbor)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return txCbor, tx
+}
+
+// newTestServerConns starts an in-process node-to-client server that never sends any blocks, and returns the
+// client and server connections. The watcher chain-sync options are applied to the client connection
+func newTestServerConns(
+	t *testing.T,
+	watcher *txwatcher.Watcher,
+	localTxSubmissionCfg localtxsubmission.Config,
+	localTxMonitorCfg localtxmonitor.Config,
+) (*ouroboros.Connection, *ouroboros.Connection) {
+	chainSyncCfg := chainsync.NewConfig(
+		chainsync.WithFindIntersectFunc(
+			func(ctx chainsync.CallbackContext, points []ocommon.Point) (ocommon.Point, chainsync.Tip, error) {
+				return points[0], chainsync.Tip{Point: points[0]}, nil
+			},
+		),
+		chainsync.WithRequestNextFunc(
+			func(ctx chainsync.CallbackContext) error {
+				return ctx.Server.AwaitReply()
+			},
+		),
+	)
+	clientConn, serverConn := net.Pipe()
+	serverConnChan := make(chan *ouroboros.Connection, 1)
+	go func() {
+		oConn, err := ouroboros.New(
+			ouroboros.WithConnection(serverConn),
+			ouroboros.WithNetworkMagic(ouroboros.NetworkPreview.NetworkMagic),
+			ouroboros.WithServer(true),
+			ouroboros.WithChainSyncConfig(chainSyncCfg),
+			ouroboros.WithLocalTxSubmissionConfig(localTxSubmissionCfg),
+			ouroboros.WithLocalTxMonitorConfig(localTxMonitorCfg),
+		)
+		if err != nil {
+			panic(err)
+		}
+		serverConnChan <- oConn
+	}()
+	oConn, err := ouroboros.New(
+		ouroboros.WithConnection(clientConn),
+		ouroboros.WithNetworkMagic(ouroboros.NetworkPreview.NetworkMagic),
+		ouroboros.WithChainSyncConfig(
+			chainsync.NewConfig(watcher.ChainSyncOptions()...),
+		),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error when creating Ouroboros object: %s", err)
+	}
+	return oConn, <-serverConnChan
+}
+
+func closeTestConns(t *testing.T, conns ...*ouroboros.Connection) {
+	for _, oConn := range conns {
+		if err := oConn.Close(); err != nil {
+			t.Fatalf("unexpected error when closing Ouroboros object: %s", err)
+		}
+		select {
+		case <-oConn.ErrorChan():
+		case <-time.After(10 * time.Second):
+			t.Errorf("did not shutdown within timeout")
+		}
+	}
+}
+
+// testBlock builds a Babbage block containing minimal transactions with the provided fees. It returns the block
+// CBOR and the hashes of the transactions
+func testBlock(
+	t *testing.T,
+	slot uint64,
+	blockNumber uint64,
+	fees ...uint64,
+) ([]byte, []string) {
+	header := ledger.BabbageBlockHeader{}
+	header.Body.BlockNumber = blockNumber
+	header.Body.Slot = slot
+	headerCbor, err := cbor.Encode(&header)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	txBodies := []any{}
+	txWitnesses := []any{}
+	for _, fee := range fees {
+		txBodies = append(
+			txBodies,
+			map[uint]any{
+				0: []any{[]any{make([]byte, 32), 0}},
+				1: []any{},
+				2: fee,
+			},
+		)
+		txWitnesses = append(txWitnesses, map[uint]any{})
+	}
+	blockCbor, err := cbor.Encode(
+		[]any{
+			cbor.RawMessage(headerCbor),
+			txBodies,
+			txWitnesses,
+			map[uint]any{},
+			[]any{},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	block, err := ledger.NewBabbageBlockFromCbor(blockCbor)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var txHashes []string
+	for _, tx := range block.Transactions() {
+		txHashes = append(txHashes, tx.Hash())
+	}
+	return blockCbor, txHashes
+}
+
+func expectEvent(t *testing.T, watch *txwatcher.Watch, expected txwatcher.Event) {
+	select {
+	case evt, ok := <-watch.EventChan():
+		if !ok {
+			t.Fatalf("event channel closed, expected %s event", expected.Status)
+		}
+		if !reflect.DeepEqual(evt, expected) {
+			t.Fatalf("did not receive expected event\n  got:    %#v\n  wanted: %#v", evt, expected)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("did not receive expected %s event", expected.Status)
+	}
+}
+
+func expectClosed(t *testing.T, watch *txwatcher.Watch) {
+	select {
+	case evt, ok := <-watch.EventChan():
+		if ok {
+			t.Fatalf("received unexpected event: %#v", evt)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("event channel was not closed")
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	intersect := ocommon.NewPoint(10, test.DecodeHexString("123456789abcdef0"))
+	tip := chainsync.Tip{
+		BlockNumber: 12345,
+		Point:       ocommon.NewPoint(23456, test.DecodeHexString("0123456789abcdef")),
+	}
+	rollbackPoint := ocommon.NewPoint(50, test.DecodeHexString("abcdef0123456789"))
+	block1Cbor, txHashes := testBlock(t, 100, 1, 1)
+	txHash := txHashes[0]
+	_, txHashes = testBlock(t, 100, 1, 2)
+	expiredTxHash := txHashes[0]
+	// The transaction is included again in a different block after the rollback
+	block1bCbor, _ := testBlock(t, 101, 1, 1)
+	block2Cbor, _ := testBlock(t, 110, 2)
+	block3Cbor, _ := testBlock(t, 120, 3)
+	block1, _ := ledger.NewBabbageBlockFromCbor(block1Cbor)
+	block1b, _ := ledger.NewBabbageBlockFromCbor(block1bCbor)
+	block2, _ := ledger.NewBabbageBlockFromCbor(block2Cbor)
+	blockPoint := func(block ledger.Block) ocommon.Point {
+		return ocommon.NewPoint(block.SlotNumber(), test.DecodeHexString(block.Hash()))
+	}
+	chainSyncMessages := []protocol.Message{
+		chainsync.NewMsgRollBackward(intersect, tip),
+		chainsync.NewMsgRollForwardNtC(ledger.BlockTypeBabbage, block1Cbor, tip),
+		chainsync.NewMsgRollBackward(rollbackPoint, tip),
+		chainsync.NewMsgRollForwardNtC(ledger.BlockTypeBabbage, block1bCbor, tip),
+		chainsync.NewMsgRollForwardNtC(ledger.BlockTypeBabbage, block2Cbor, tip),
+		chainsync.NewMsgRollForwardNtC(ledger.BlockTypeBabbage, block3Cbor, tip),
+	}
+	conversation := []ouroboros_mock.ConversationEntry{
+		ouroboros_mock.ConversationEntryHandshakeRequestGeneric,
+		ouroboros_mock.ConversationEntryHandshakeNtCResponse,
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  chainsync.ProtocolIdNtC,
+			MessageType: chainsync.MessageTypeFindIntersect,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: chainsync.ProtocolIdNtC,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				chainsync.NewMsgIntersectFound(intersect, tip),
+			},
+		},
+	}
+	for _, msg := range chainSyncMessages {
+		conversation = append(
+			conversation,
+			ouroboros_mock.ConversationEntryInput{
+				ProtocolId:  chainsync.ProtocolIdNtC,
+				MessageType: chainsync.MessageTypeRequestNext,
+			},
+			ouroboros_mock.ConversationEntryOutput{
+				ProtocolId: chainsync.ProtocolIdNtC,
+				IsResponse: true,
+				Messages:   []protocol.Message{msg},
+			},
+		)
+	}
+	conversation = append(
+		conversation,
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  chainsync.ProtocolIdNtC,
+			MessageType: chainsync.MessageTypeRequestNext,
+		},
+	)
+	mockConn := ouroboros_mock.NewConnection(
+		ouroboros_mock.ProtocolRoleClient,
+		conversation,
+	)
+	// Async mock connection error handler
+	asyncErrChan := make(chan error, 1)
+	go func() {
+		err := <-mockConn.(*ouroboros_mock.Connection).ErrorChan()
+		if err != nil {
+			asyncErrChan <- fmt.Errorf("received unexpected error: %s", err)
+		}
+		close(asyncErrChan)
+	}()
+	watcher := txwatcher.New(txwatcher.WithConfirmationDepth(2))
+	oConn, err := ouroboros.New(
+		ouroboros.WithConnection(mockConn),
+		ouroboros.WithNetworkMagic(ouroboros_mock.MockNetworkMagic),
+		ouroboros.WithChainSyncConfig(
+			chainsync.NewConfig(watcher.ChainSyncOptions()...),
+		),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error when creating Ouroboros object: %s", err)
+	}
+	// Async error handler
+	go func() {
+		err, ok := <-oConn.ErrorChan()
+		if !ok {
+			return
+		}
+		// We can't call t.Fatalf() from a different Goroutine, so we panic instead
+		panic(fmt.Sprintf("unexpected Ouroboros error: %s", err))
+	}()
+	watch, err := watcher.Watch(txHash, 0)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	expiredWatch, err := watcher.Watch(expiredTxHash, 110)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err := watcher.Start(oConn, []ocommon.Point{intersect}); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	expectEvent(
+		t,
+		watch,
+		txwatcher.Event{
+			Status:      txwatcher.StatusIncluded,
+			TxHash:      txHash,
+			Point:       blockPoint(block1),
+			BlockNumber: 1,
+		},
+	)
+	expectEvent(
+		t,
+		watch,
+		txwatcher.Event{
+			Status: txwatcher.StatusRolledBack,
+			TxHash: txHash,
+			Point:  rollbackPoint,
+		},
+	)
+	expectEvent(
+		t,
+		watch,
+		txwatcher.Event{
+			Status:      txwatcher.StatusIncluded,
+			TxHash:      txHash,
+			Point:       blockPoint(block1b),
+			BlockNumber: 1,
+		},
+	)
+	expectEvent(
+		t,
+		watch,
+		txwatcher.Event{
+			Status:      txwatcher.StatusConfirmed,
+			TxHash:      txHash,
+			Point:       blockPoint(block1b),
+			BlockNumber: 1,
+			Depth:       2,
+		},
+	)
+	expectClosed(t, watch)
+	// The first block past the TTL expires the transaction
+	expectEvent(
+		t,
+		expiredWatch,
+		txwatcher.Event{
+			Status:      txwatcher.StatusExpired,
+			TxHash:      expiredTxHash,
+			Point:       blockPoint(block2),
+			BlockNumber: 2,
+		},
+	)
+	expectClosed(t, expiredWatch)
+	// Wait for mock connection shutdown
+	select {
+	case err, ok := <-asyncErrChan:
+		if ok {
+			t.Fatal(err.Error())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("did not complete within timeout")
+	}
+	// The last block request is still outstanding, so this doesn't send anything to the mock connection
+	watcher.Stop()
+	// Close Ouroboros connection
+	if err := oConn.Close(); err != nil {
+		t.Fatalf("unexpected error when closing Ouroboros object: %s", err)
+	}
+	// Wait for connection shutdown
+	select {
+	case <-oConn.ErrorChan():
+	case <-time.After(10 * time.Second):
+		t.Errorf("did not shutdown within timeout")
+	}
+}
+
+func TestWatcherSubmit(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	acceptedTxCbor, acceptedTx := testTx(t, 1)
+	_, rejectedTx := testTx(t, 2)
+	watcher := txwatcher.New()
+	clientConn, serverConn := newTestServerConns(
+		t,
+		watcher,
+		localtxsubmission.NewConfig(
+			localtxsubmission.WithSubmitTxFunc(
+				func(ctx localtxsubmission.CallbackContext, tx interface{}) error {
+					submitTx := tx.(localtxsubmission.MsgSubmitTxTransaction)
+					if !reflect.DeepEqual(submitTx.Raw.Content, acceptedTxCbor) {
+						return errors.New("rejected")
+					}
+					return nil
+				},
+			),
+		),
+		localtxmonitor.NewConfig(),
+	)
+	defer closeTestConns(t, clientConn, serverConn)
+	defer watcher.Stop()
+	if err := watcher.Start(clientConn, []ocommon.Point{ocommon.NewPointOrigin()}); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	// An accepted transaction is reported as being in the mempool
+	watch, err := watcher.Submit(acceptedTx)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if watch.TxHash() != acceptedTx.Hash() {
+		t.Fatalf("did not get expected transaction hash: got %s, expected %s", watch.TxHash(), acceptedTx.Hash())
+	}
+	expectEvent(
+		t,
+		watch,
+		txwatcher.Event{
+			Status: txwatcher.StatusInMempool,
+			TxHash: acceptedTx.Hash(),
+		},
+	)
+	// A rejected transaction returns the rejection and isn't watched
+	if _, err := watcher.Submit(rejectedTx); err == nil {
+		t.Fatalf("did not receive expected error")
+	} else {
+		var rejectErr localtxsubmission.TransactionRejectedError
+		if !errors.As(err, &rejectErr) {
+			t.Fatalf("did not receive expected rejection error, got: %s", err)
+		}
+	}
+	rejectedWatch, err := watcher.Watch(rejectedTx.Hash(), 0)
+	if err != nil {
+		t.Fatalf("rejected transaction is still being watched: %s", err)
+	}
+	rejectedWatch.Cancel()
+	expectClosed(t, rejectedWatch)
+}
+
+func TestWatcherMempoolPoll(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	txCbor, tx := testTx(t, 1)
+	watcher := txwatcher.New(
+		txwatcher.WithMempoolPollInterval(50 * time.Millisecond),
+	)
+	clientConn, serverConn := newTestServerConns(
+		t,
+		watcher,
+		localtxsubmission.NewConfig(),
+		localtxmonitor.NewConfig(
+			localtxmonitor.WithGetMempoolFunc(
+				func(ctx localtxmonitor.CallbackContext) (uint64, uint32, []localtxmonitor.TxAndEraId, error) {
+					return 100, 100000, []localtxmonitor.TxAndEraId{
+						{
+							EraId: ledger.TxTypeBabbage,
+							Tx:    txCbor,
+						},
+					}, nil
+				},
+			),
+		),
+	)
+	defer closeTestConns(t, clientConn, serverConn)
+	defer watcher.Stop()
+	// A transaction submitted elsewhere is found by polling the mempool
+	watch, err := watcher.Watch(tx.Hash(), 0)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err := watcher.Start(clientConn, []ocommon.Point{ocommon.NewPointOrigin()}); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	expectEvent(
+		t,
+		watch,
+		txwatcher.Event{
+			Status: txwatcher.StatusInMempool,
+			TxHash: tx.Hash(),
+		},
+	)
+	// The event is only sent once, even though the transaction is still in the mempool
+	select {
+	case evt, ok := <-watch.EventChan():
+		if ok {
+			t.Fatalf("received unexpected event: %#v", evt)
+		}
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestWatcherEventBufferFull(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	intersect := ocommon.NewPoint(10, test.DecodeHexString("123456789abcdef0"))
+	tip := chainsync.Tip{
+		BlockNumber: 12345,
+		Point:       ocommon.NewPoint(23456, test.DecodeHexString("0123456789abcdef")),
+	}
+	rollbackPoint := ocommon.NewPoint(50, test.DecodeHexString("abcdef0123456789"))
+	block1Cbor, txHashes := testBlock(t, 100, 1, 1)
+	unreadTxHash := txHashes[0]
+	// The unread transaction is included again after the rollback, ahead of the other transaction
+	block1bCbor, txHashes := testBlock(t, 101, 1, 1, 2)
+	txHash := txHashes[1]
+	block2Cbor, _ := testBlock(t, 110, 2)
+	block1b, _ := ledger.NewBabbageBlockFromCbor(block1bCbor)
+	block1bPoint := ocommon.NewPoint(block1b.SlotNumber(), test.DecodeHexString(block1b.Hash()))
+	chainSyncMessages := []protocol.Message{
+		chainsync.NewMsgRollBackward(intersect, tip),
+		chainsync.NewMsgRollForwardNtC(ledger.BlockTypeBabbage, block1Cbor, tip),
+		chainsync.NewMsgRollBackward(rollbackPoint, tip),
+		chainsync.NewMsgRollForwardNtC(ledger.BlockTypeBabbage, block1bCbor, tip),
+		chainsync.NewMsgRollForwardNtC(ledger.BlockTypeBabbage, block2Cbor, tip),
+	}
+	conversation := []ouroboros_mock.ConversationEntry{
+		ouroboros_mock.ConversationEntryHandshakeRequestGeneric,
+		ouroboros_mock.ConversationEntryHandshakeNtCResponse,
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  chainsync.ProtocolIdNtC,
+			MessageType: chainsync.MessageTypeFindIntersect,
+		},
+		ouroboros_mock.ConversationEntryOutput{
+			ProtocolId: chainsync.ProtocolIdNtC,
+			IsResponse: true,
+			Messages: []protocol.Message{
+				chainsync.NewMsgIntersectFound(intersect, tip),
+			},
+		},
+	}
+	for _, msg := range chainSyncMessages {
+		conversation = append(
+			conversation,
+			ouroboros_mock.ConversationEntryInput{
+				ProtocolId:  chainsync.ProtocolIdNtC,
+				MessageType: chainsync.MessageTypeRequestNext,
+			},
+			ouroboros_mock.ConversationEntryOutput{
+				ProtocolId: chainsync.ProtocolIdNtC,
+				IsResponse: true,
+				Messages:   []protocol.Message{msg},
+			},
+		)
+	}
+	conversation = append(
+		conversation,
+		ouroboros_mock.ConversationEntryInput{
+			ProtocolId:  chainsync.ProtocolIdNtC,
+			MessageType: chainsync.MessageTypeRequestNext,
+		},
+	)
+	mockConn := ouroboros_mock.NewConnection(
+		ouroboros_mock.ProtocolRoleClient,
+		conversation,
+	)
+	// Async mock connection error handler
+	asyncErrChan := make(chan error, 1)
+	go func() {
+		err := <-mockConn.(*ouroboros_mock.Connection).ErrorChan()
+		if err != nil {
+			asyncErrChan <- fmt.Errorf("received unexpected error: %s", err)
+		}
+		close(asyncErrChan)
+	}()
+	watcher := txwatcher.New(
+		txwatcher.WithConfirmationDepth(1),
+		txwatcher.WithBufferSize(2),
+	)
+	oConn, err := ouroboros.New(
+		ouroboros.WithConnection(mockConn),
+		ouroboros.WithNetworkMagic(ouroboros_mock.MockNetworkMagic),
+		ouroboros.WithChainSyncConfig(
+			chainsync.NewConfig(watcher.ChainSyncOptions()...),
+		),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error when creating Ouroboros object: %s", err)
+	}
+	// Async error handler
+	go func() {
+		err, ok := <-oConn.ErrorChan()
+		if !ok {
+			return
+		}
+		// We can't call t.Fatalf() from a different Goroutine, so we panic instead
+		panic(fmt.Sprintf("unexpected Ouroboros error: %s", err))
+	}()
+	unreadWatch, err := watcher.Watch(unreadTxHash, 0)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	watch, err := watcher.Watch(txHash, 0)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err := watcher.Start(oConn, []ocommon.Point{intersect}); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	// The watch that isn't being read doesn't hold up events for other watches
+	expectEvent(
+		t,
+		watch,
+		txwatcher.Event{
+			Status:      txwatcher.StatusIncluded,
+			TxHash:      txHash,
+			Point:       block1bPoint,
+			BlockNumber: 1,
+		},
+	)
+	expectEvent(
+		t,
+		watch,
+		txwatcher.Event{
+			Status:      txwatcher.StatusConfirmed,
+			TxHash:      txHash,
+			Point:       block1bPoint,
+			BlockNumber: 1,
+			Depth:       1,
+		},
+	)
+	expectClosed(t, watch)
+	if err := watch.Err(); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	// The buffered events are still delivered before the unread watch is closed
+	for _, status := range []txwatcher.Status{txwatcher.StatusIncluded, txwatcher.StatusRolledBack} {
+		evt, ok := <-unreadWatch.EventChan()
+		if !ok || evt.Status != status {
+			t.Fatalf("did not receive expected %s event, got: %#v", status, evt)
+		}
+	}
+	expectClosed(t, unreadWatch)
+	if err := unreadWatch.Err(); !errors.Is(err, txwatcher.EventBufferFullError) {
+		t.Fatalf("did not receive expected error, got: %v", err)
+	}
+	// Wait for mock connection shutdown
+	select {
+	case err, ok := <-asyncErrChan:
+		if ok {
+			t.Fatal(err.Error())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("did not complete within timeout")
+	}
+	watcher.Stop()
+	// Close Ouroboros connection
+	if err := oConn.Close(); err != nil {
+		t.Fatalf("unexpected error when closing Ouroboros object: %s", err)
+	}
+	// Wait for connection shutdown
+	select {
+	case <-oConn.ErrorChan():
+	case <-time.After(10 * time.Second):
+		t.Errorf("did not shutdown within timeout")
+	}
+}